@@ -0,0 +1,136 @@
+package pelican
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// AcceleratorTable is a parsed RT_ACCELERATOR resource
+type AcceleratorTable struct {
+	ID       uint32 `json:"id"`
+	Language uint32 `json:"language"`
+
+	Accelerators []*Accelerator `json:"accelerators"`
+}
+
+// Accelerator is a keyboard shortcut (ACCELTABLEENTRY)
+type Accelerator struct {
+	// FVIRTKEY, FSHIFT, FCONTROL, FALT, etc.
+	Flags uint32 `json:"flags"`
+	// A virtual-key code if FVIRTKEY is set, a character otherwise
+	Key uint32 `json:"key"`
+	// Command sent when the shortcut is pressed
+	Command uint32 `json:"command"`
+}
+
+// ACCEL flags
+const (
+	accelVirtKey = 0x01
+	accelShift   = 0x04
+	accelControl = 0x08
+	accelAlt     = 0x10
+	accelEnd     = 0x80
+)
+
+// String returns the shortcut as usually shown in menus, like "Ctrl+O"
+func (a *Accelerator) String() string {
+	var parts []string
+	if a.Flags&accelControl != 0 {
+		parts = append(parts, "Ctrl")
+	}
+	if a.Flags&accelAlt != 0 {
+		parts = append(parts, "Alt")
+	}
+	if a.Flags&accelShift != 0 {
+		parts = append(parts, "Shift")
+	}
+
+	var key string
+	switch {
+	case a.Flags&accelVirtKey == 0 && 0 < a.Key && a.Key <= 26:
+		// "^C" in resource scripts, which is a control character
+		key = string(rune('A' + a.Key - 1))
+		if a.Flags&accelControl == 0 {
+			parts = append([]string{"Ctrl"}, parts...)
+		}
+	case a.Flags&accelVirtKey == 0:
+		key = string(rune(a.Key))
+	case ('0' <= a.Key && a.Key <= '9') || ('A' <= a.Key && a.Key <= 'Z'):
+		key = string(rune(a.Key))
+	case 0x70 <= a.Key && a.Key <= 0x87:
+		// VK_F1 to VK_F24
+		key = fmt.Sprintf("F%d", a.Key-0x70+1)
+	default:
+		if name, ok := virtualKeyNames[a.Key]; ok {
+			key = name
+		} else {
+			key = fmt.Sprintf("#%d", a.Key)
+		}
+	}
+	return strings.Join(append(parts, key), "+")
+}
+
+// https://docs.microsoft.com/en-us/windows/win32/inputdev/virtual-key-codes
+var virtualKeyNames = map[uint32]string{
+	0x08: "Backspace",
+	0x09: "Tab",
+	0x0d: "Enter",
+	0x13: "Pause",
+	0x1b: "Esc",
+	0x20: "Space",
+	0x21: "PgUp",
+	0x22: "PgDn",
+	0x23: "End",
+	0x24: "Home",
+	0x25: "Left",
+	0x26: "Up",
+	0x27: "Right",
+	0x28: "Down",
+	0x2c: "PrtSc",
+	0x2d: "Ins",
+	0x2e: "Del",
+	0x6a: "Num *",
+	0x6b: "Num +",
+	0x6d: "Num -",
+	0x6e: "Num .",
+	0x6f: "Num /",
+}
+
+func (params *ProbeParams) parseAccelerators(info *PeInfo, id uint32, language uint32, rawData []byte) error {
+	br := bytes.NewReader(rawData)
+
+	at := &AcceleratorTable{
+		ID:       id,
+		Language: language,
+	}
+
+	for br.Len() > 0 {
+		var entry struct {
+			Flags   uint16
+			Key     uint16
+			Command uint16
+			Padding uint16
+		}
+		err := binary.Read(br, binary.LittleEndian, &entry)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		at.Accelerators = append(at.Accelerators, &Accelerator{
+			Flags:   uint32(entry.Flags &^ accelEnd),
+			Key:     uint32(entry.Key),
+			Command: uint32(entry.Command),
+		})
+		if entry.Flags&accelEnd != 0 {
+			break
+		}
+	}
+
+	params.Consumer.Debugf("accelerator table %d: %d entries", id, len(at.Accelerators))
+	info.Accelerators = append(info.Accelerators, at)
+	return nil
+}
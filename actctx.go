@@ -0,0 +1,159 @@
+package pelican
+
+import (
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// AssemblySource tells where a side-by-side assembly would be found
+type AssemblySource string
+
+const (
+	// In the application directory
+	AssemblyPrivate AssemblySource = "private"
+	// In WinSxS, it ships with Windows
+	AssemblySystem AssemblySource = "system"
+	// In WinSxS, if a redistributable is installed
+	AssemblyRedist AssemblySource = "redist"
+	// Nowhere the loader looks, the executable won't start
+	AssemblyMissing AssemblySource = "missing"
+)
+
+// ActivationContext lists the side-by-side assemblies an executable
+// binds to: its dependent assemblies, the dependent assemblies of those
+// that are private, and so on.
+type ActivationContext struct {
+	// Slash-separated path of the executable
+	Path       string             `json:"path"`
+	Assemblies []*ContextAssembly `json:"assemblies"`
+}
+
+// ContextAssembly is an assembly of an ActivationContext
+type ContextAssembly struct {
+	Identity *AssemblyIdentity `json:"identity"`
+	Source   AssemblySource    `json:"source"`
+	// Name of the assembly that depends on this one,
+	// empty for dependencies of the executable itself
+	RequiredBy string `json:"requiredBy,omitempty"`
+	// Slash-separated path of the manifest, for private assemblies
+	Manifest string `json:"manifest,omitempty"`
+	// Name of the redistributable that installs it,
+	// for example "Visual C++ 2008"
+	Redist string `json:"redist,omitempty"`
+}
+
+// Public key tokens of assemblies signed by Microsoft
+const (
+	windowsPublicKeyToken = "6595b64144ccf1df"
+	vcPublicKeyToken      = "1fc8b3b9a1e18e3b"
+)
+
+// Microsoft.VC80.CRT, Microsoft.VC90.MFC, etc.
+var vcAssemblyRegexp = regexp.MustCompile(`^microsoft\.vc(\d+)\.`)
+
+// Missing returns the assemblies of ac that can't be found
+func (ac *ActivationContext) Missing() []*ContextAssembly {
+	var res []*ContextAssembly
+	for _, ca := range ac.Assemblies {
+		if ca.Source == AssemblyMissing {
+			res = append(res, ca)
+		}
+	}
+	return res
+}
+
+// buildActivationContexts computes the ActivationContext of every
+// executable of files that has dependent assemblies. Private assemblies
+// are looked up in the directory of the executable, for dependencies of
+// dependencies too, like the loader does.
+func buildActivationContexts(fsys fs.FS, files map[string]*PeInfo) ([]*ActivationContext, error) {
+	var res []*ActivationContext
+	for p, info := range files {
+		if strings.ToLower(path.Ext(p)) != ".exe" || len(info.DependentAssemblies) == 0 {
+			continue
+		}
+
+		ac := &ActivationContext{Path: p}
+		type pending struct {
+			id         *AssemblyIdentity
+			requiredBy string
+		}
+		var queue []pending
+		for _, da := range info.DependentAssemblies {
+			queue = append(queue, pending{id: da})
+		}
+
+		// assemblies are only bound once, and may depend on each other
+		seen := make(map[string]bool)
+		for len(queue) > 0 {
+			next := queue[0]
+			queue = queue[1:]
+			key := strings.ToLower(next.id.Name)
+			if key == "" || seen[key] {
+				continue
+			}
+			seen[key] = true
+
+			ca := &ContextAssembly{
+				Identity:   next.id,
+				RequiredBy: next.requiredBy,
+			}
+			ac.Assemblies = append(ac.Assemblies, ca)
+
+			am, manifestPath, err := findPrivateAssembly(fsys, path.Dir(p), next.id.Name)
+			if err != nil {
+				return nil, errors.WithMessagef(err, "while reading manifest of assembly %s", next.id.Name)
+			}
+			if am != nil {
+				ca.Source = AssemblyPrivate
+				ca.Manifest = manifestPath
+				for _, dep := range am.dependencies {
+					queue = append(queue, pending{id: dep, requiredBy: next.id.Name})
+				}
+				continue
+			}
+			ca.Source, ca.Redist = classifySharedAssembly(next.id)
+		}
+		res = append(res, ac)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Path < res[j].Path
+	})
+	return res, nil
+}
+
+// classifySharedAssembly guesses whether an assembly that isn't
+// deployed with the application can be found in WinSxS
+func classifySharedAssembly(id *AssemblyIdentity) (AssemblySource, string) {
+	name := strings.ToLower(id.Name)
+	if m := vcAssemblyRegexp.FindStringSubmatch(name); m != nil {
+		if redist, ok := legacyCRTRedists[m[1]]; ok {
+			return AssemblyRedist, "Visual C++ " + redist
+		}
+	}
+	if _, ok := sxsSystemAssemblies[name]; ok {
+		return AssemblySystem, ""
+	}
+
+	switch strings.ToLower(id.PublicKeyToken) {
+	case windowsPublicKeyToken:
+		return AssemblySystem, ""
+	case vcPublicKeyToken:
+		return AssemblyRedist, "Visual C++"
+	}
+	return AssemblyMissing, ""
+}
+
+func (ca *ContextAssembly) missingMessage() string {
+	if ca.RequiredBy != "" {
+		return fmt.Sprintf("side-by-side assembly %s (required by %s) is missing", ca.Identity.Name, ca.RequiredBy)
+	}
+	return fmt.Sprintf("side-by-side assembly %s is missing", ca.Identity.Name)
+}
@@ -0,0 +1,100 @@
+package pelican
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const engineManifest = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<assembly xmlns="urn:schemas-microsoft-com:asm.v1" manifestVersion="1.0">
+  <assemblyIdentity type="win32" name="Itch.Engine" version="2.0.0.0" processorArchitecture="x86"/>
+  <file name="engine.dll"/>
+  <dependency>
+    <dependentAssembly>
+      <assemblyIdentity type="win32" name="Microsoft.VC90.CRT" version="9.0.21022.8" processorArchitecture="x86" publicKeyToken="1fc8b3b9a1e18e3b"/>
+    </dependentAssembly>
+  </dependency>
+  <dependency>
+    <dependentAssembly>
+      <assemblyIdentity type="win32" name="Itch.Plugins" version="1.0.0.0" processorArchitecture="x86"/>
+    </dependentAssembly>
+  </dependency>
+</assembly>`
+
+const pluginsManifest = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<assembly xmlns="urn:schemas-microsoft-com:asm.v1" manifestVersion="1.0">
+  <assemblyIdentity type="win32" name="Itch.Plugins" version="1.0.0.0" processorArchitecture="x86"/>
+  <file name="plugins.dll"/>
+  <dependency>
+    <dependentAssembly>
+      <assemblyIdentity type="win32" name="Itch.Engine" version="2.0.0.0" processorArchitecture="x86"/>
+    </dependentAssembly>
+  </dependency>
+  <dependency>
+    <dependentAssembly>
+      <assemblyIdentity type="win32" name="Itch.Audio" version="1.0.0.0" processorArchitecture="x86"/>
+    </dependentAssembly>
+  </dependency>
+</assembly>`
+
+func Test_ActivationContexts(t *testing.T) {
+	files := map[string]*PeInfo{
+		"game.exe": {DependentAssemblies: []*AssemblyIdentity{
+			{Name: "Itch.Engine", Version: "2.0.0.0"},
+			{Name: "Microsoft.Windows.Common-Controls", Version: "6.0.0.0", PublicKeyToken: "6595b64144ccf1df"},
+		}},
+		"tools/tool.exe": {DependentAssemblies: []*AssemblyIdentity{
+			{Name: "Microsoft.VC80.CRT", Version: "8.0.50727.6195", PublicKeyToken: "1fc8b3b9a1e18e3b"},
+			{Name: "Microsoft.Windows.SystemCompatible", PublicKeyToken: "6595B64144CCF1DF"},
+		}},
+		"tools/other.exe": {},
+		"engine.dll":      {},
+	}
+	fsys := fstest.MapFS{
+		"Itch.Engine/Itch.Engine.manifest": {Data: []byte(engineManifest)},
+		"Itch.Plugins.manifest":            {Data: []byte(pluginsManifest)},
+		"Microsoft.VC90.CRT.manifest":      {Data: []byte(vc90Manifest)},
+	}
+
+	contexts, err := buildActivationContexts(fsys, files)
+	assert.NoError(t, err)
+	if !assert.Len(t, contexts, 2) {
+		return
+	}
+
+	type summary struct {
+		name, source, requiredBy, manifest, redist string
+	}
+	summarize := func(ac *ActivationContext) []summary {
+		var res []summary
+		for _, ca := range ac.Assemblies {
+			res = append(res, summary{ca.Identity.Name, string(ca.Source), ca.RequiredBy, ca.Manifest, ca.Redist})
+		}
+		return res
+	}
+
+	ac := contexts[0]
+	assert.EqualValues(t, "game.exe", ac.Path)
+	assert.EqualValues(t, []summary{
+		{"Itch.Engine", "private", "", "Itch.Engine/Itch.Engine.manifest", ""},
+		{"Microsoft.Windows.Common-Controls", "system", "", "", ""},
+		{"Microsoft.VC90.CRT", "private", "Itch.Engine", "Microsoft.VC90.CRT.manifest", ""},
+		// depends back on Itch.Engine, which is only bound once
+		{"Itch.Plugins", "private", "Itch.Engine", "Itch.Plugins.manifest", ""},
+		{"Itch.Audio", "missing", "Itch.Plugins", "", ""},
+	}, summarize(ac))
+	if assert.Len(t, ac.Missing(), 1) {
+		assert.EqualValues(t, "side-by-side assembly Itch.Audio (required by Itch.Plugins) is missing", ac.Missing()[0].missingMessage())
+	}
+
+	// private assemblies are only looked up next to the executable
+	ac = contexts[1]
+	assert.EqualValues(t, "tools/tool.exe", ac.Path)
+	assert.EqualValues(t, []summary{
+		{"Microsoft.VC80.CRT", "redist", "", "", "Visual C++ 2005"},
+		{"Microsoft.Windows.SystemCompatible", "system", "", "", ""},
+	}, summarize(ac))
+	assert.Empty(t, ac.Missing())
+}
@@ -0,0 +1,165 @@
+package pelican
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/itchio/pelican/pe"
+)
+
+// Well-known exports, keyed by "dll!function" (dll name lowercased), and
+// the version of Windows that introduced them. Header fields are often left
+// at whatever the toolchain defaults to, so static imports are a much better
+// indicator of what a binary actually needs.
+//
+// Only functions that are commonly imported statically are listed: anything
+// resolved via GetProcAddress doesn't prevent a binary from starting.
+// Windows 10 functions are in windows10Imports.
+var apiVersions = map[string]WindowsVersion{
+	// Windows XP
+	"kernel32.dll!GetNativeSystemInfo":            WindowsXP,
+	"kernel32.dll!IsWow64Process":                 WindowsXP,
+	"kernel32.dll!GetLogicalProcessorInformation": WindowsXP,
+	"kernel32.dll!AddVectoredExceptionHandler":    WindowsXP,
+	"kernel32.dll!RemoveVectoredExceptionHandler": WindowsXP,
+	"kernel32.dll!GetModuleHandleExA":             WindowsXP,
+	"kernel32.dll!GetModuleHandleExW":             WindowsXP,
+	"kernel32.dll!AttachConsole":                  WindowsXP,
+	"kernel32.dll!GetSystemTimes":                 WindowsXP,
+	"user32.dll!RegisterRawInputDevices":          WindowsXP,
+	"user32.dll!GetRawInputData":                  WindowsXP,
+	"user32.dll!GetRawInputDeviceList":            WindowsXP,
+	"user32.dll!PrintWindow":                      WindowsXP,
+
+	// Windows Vista
+	"kernel32.dll!InitializeConditionVariable":    WindowsVista,
+	"kernel32.dll!SleepConditionVariableCS":       WindowsVista,
+	"kernel32.dll!SleepConditionVariableSRW":      WindowsVista,
+	"kernel32.dll!WakeConditionVariable":          WindowsVista,
+	"kernel32.dll!WakeAllConditionVariable":       WindowsVista,
+	"kernel32.dll!InitializeSRWLock":              WindowsVista,
+	"kernel32.dll!AcquireSRWLockExclusive":        WindowsVista,
+	"kernel32.dll!AcquireSRWLockShared":           WindowsVista,
+	"kernel32.dll!ReleaseSRWLockExclusive":        WindowsVista,
+	"kernel32.dll!ReleaseSRWLockShared":           WindowsVista,
+	"kernel32.dll!InitOnceExecuteOnce":            WindowsVista,
+	"kernel32.dll!InitOnceBeginInitialize":        WindowsVista,
+	"kernel32.dll!InitOnceComplete":               WindowsVista,
+	"kernel32.dll!GetTickCount64":                 WindowsVista,
+	"kernel32.dll!CreateEventExW":                 WindowsVista,
+	"kernel32.dll!CreateMutexExW":                 WindowsVista,
+	"kernel32.dll!CreateSemaphoreExW":             WindowsVista,
+	"kernel32.dll!InitializeCriticalSectionEx":    WindowsVista,
+	"kernel32.dll!GetFinalPathNameByHandleW":      WindowsVista,
+	"kernel32.dll!GetFileInformationByHandleEx":   WindowsVista,
+	"kernel32.dll!SetFileInformationByHandle":     WindowsVista,
+	"kernel32.dll!QueryFullProcessImageNameW":     WindowsVista,
+	"kernel32.dll!CancelIoEx":                     WindowsVista,
+	"kernel32.dll!CreateSymbolicLinkW":            WindowsVista,
+	"kernel32.dll!GetLocaleInfoEx":                WindowsVista,
+	"kernel32.dll!LCIDToLocaleName":               WindowsVista,
+	"kernel32.dll!LocaleNameToLCID":               WindowsVista,
+	"kernel32.dll!GetUserDefaultLocaleName":       WindowsVista,
+	"kernel32.dll!CompareStringEx":                WindowsVista,
+	"kernel32.dll!FlsAlloc":                       WindowsVista,
+	"kernel32.dll!FlsGetValue":                    WindowsVista,
+	"kernel32.dll!FlsSetValue":                    WindowsVista,
+	"kernel32.dll!FlsFree":                        WindowsVista,
+	"user32.dll!SetProcessDPIAware":               WindowsVista,
+	"user32.dll!ChangeWindowMessageFilter":        WindowsVista,
+	"user32.dll!RegisterPowerSettingNotification": WindowsVista,
+
+	// Windows 7
+	"kernel32.dll!TryAcquireSRWLockExclusive":       Windows7,
+	"kernel32.dll!TryAcquireSRWLockShared":          Windows7,
+	"kernel32.dll!GetActiveProcessorCount":          Windows7,
+	"kernel32.dll!GetMaximumProcessorCount":         Windows7,
+	"kernel32.dll!GetLogicalProcessorInformationEx": Windows7,
+	"kernel32.dll!SetThreadGroupAffinity":           Windows7,
+	"kernel32.dll!QueryUnbiasedInterruptTime":       Windows7,
+	"kernel32.dll!GetCurrentProcessorNumberEx":      Windows7,
+	"user32.dll!RegisterTouchWindow":                Windows7,
+	"user32.dll!GetTouchInputInfo":                  Windows7,
+	"user32.dll!CloseTouchInputHandle":              Windows7,
+	"user32.dll!ChangeWindowMessageFilterEx":        Windows7,
+	"user32.dll!SetGestureConfig":                   Windows7,
+
+	// Windows 8
+	"kernel32.dll!GetSystemTimePreciseAsFileTime": Windows8,
+	"kernel32.dll!CreateFile2":                    Windows8,
+	"kernel32.dll!GetCurrentThreadStackLimits":    Windows8,
+	"kernel32.dll!PrefetchVirtualMemory":          Windows8,
+	"kernel32.dll!GetFirmwareType":                Windows8,
+	"user32.dll!GetPointerInfo":                   Windows8,
+	"user32.dll!GetPointerType":                   Windows8,
+	"user32.dll!GetPointerFrameInfo":              Windows8,
+	"user32.dll!EnableMouseInPointer":             Windows8,
+	"ntdll.dll!RtlWaitOnAddress":                  Windows8,
+	"ntdll.dll!RtlWakeAddressSingle":              Windows8,
+
+	// Windows 8.1
+	"user32.dll!LogicalToPhysicalPointForPerMonitorDPI": Windows81,
+	"user32.dll!PhysicalToLogicalPointForPerMonitorDPI": Windows81,
+}
+
+// DLLs that don't exist at all before a certain version of Windows
+var dllVersions = map[string]WindowsVersion{
+	"dwmapi.dll":    WindowsVista,
+	"dxgi.dll":      WindowsVista,
+	"d3d10.dll":     WindowsVista,
+	"d3d11.dll":     WindowsVista,
+	"xinput1_4.dll": Windows8,
+	"shcore.dll":    Windows81,
+	"d3d12.dll":     Windows10,
+}
+
+// apiSetMinVersion is the version that introduced API sets (api-ms-win-*)
+var apiSetMinVersion = Windows7
+
+// The Universal CRT's API sets (api-ms-win-crt-*) are real DLLs installed
+// by the Redistributable, which supports Windows Vista SP2 and later
+var ucrtAPISetMinVersion = WindowsVista
+
+// inferImportsMinVersion returns the minimum version of Windows required
+// by a binary's static imports, along with the imports that require it.
+// libraries are DLL names.
+func inferImportsMinVersion(libraries []string, symbols []pe.ImportedSymbol) (WindowsVersion, []string) {
+	var minVersion WindowsVersion
+	var culprits []string
+
+	consider := func(wv WindowsVersion, culprit string) {
+		if minVersion.Less(wv) {
+			minVersion = wv
+			culprits = nil
+		}
+		if wv == minVersion {
+			culprits = append(culprits, culprit)
+		}
+	}
+
+	for _, lib := range libraries {
+		lib = strings.ToLower(lib)
+		if wv, ok := dllVersions[lib]; ok {
+			consider(wv, lib)
+		} else if strings.HasPrefix(lib, "api-ms-win-crt-") {
+			consider(ucrtAPISetMinVersion, lib)
+		} else if strings.HasPrefix(lib, "api-ms-win-") {
+			consider(apiSetMinVersion, lib)
+		}
+	}
+
+	for _, sym := range symbols {
+		if sym.ByOrdinal() {
+			continue
+		}
+		key := strings.ToLower(sym.DLL) + "!" + sym.Name
+		if wv, ok := apiVersions[key]; ok {
+			consider(wv, key)
+		} else if windows10Imports[key] {
+			consider(Windows10, key)
+		}
+	}
+
+	sort.Strings(culprits)
+	return minVersion, culprits
+}
@@ -0,0 +1,67 @@
+package pelican
+
+import "strings"
+
+// ArmVerdict tells whether a binary runs on Windows on ARM (arm64)
+type ArmVerdict string
+
+const (
+	// Built for ARM, no emulation needed
+	ArmVerdictNative ArmVerdict = "native"
+	// x86 binaries are emulated by every arm64 release of Windows
+	ArmVerdictEmulated ArmVerdict = "emulated"
+	// x64 binaries are only emulated by Windows 11
+	ArmVerdictEmulatedWindows11 ArmVerdict = "emulatedWindows11"
+	// Kernel drivers can't be emulated, and neither can
+	// binaries that need one to run
+	ArmVerdictUnsupported ArmVerdict = "unsupported"
+)
+
+// ArmEmulation tells whether a binary runs on Windows on ARM,
+// and what that's based on
+type ArmEmulation struct {
+	Verdict ArmVerdict `json:"verdict"`
+	// For example "x64 emulation requires Windows 11", or "imports ntoskrnl.exe"
+	Reasons []string `json:"reasons,omitempty"`
+}
+
+// armEmulation returns whether info runs on Windows on ARM,
+// from its architecture and imports. It must be called after
+// findWineNotes, whose kernel driver notes it reuses.
+func armEmulation(info *PeInfo) *ArmEmulation {
+	ae := &ArmEmulation{}
+	switch info.Arch {
+	case ArchArm64:
+		ae.Verdict = ArmVerdictNative
+	case ArchArm:
+		ae.Verdict = ArmVerdictNative
+		ae.Reasons = append(ae.Reasons, "32-bit ARM binaries don't run on Windows 11 24H2 and later")
+	case Arch386:
+		ae.Verdict = ArmVerdictEmulated
+	case ArchAmd64:
+		ae.Verdict = ArmVerdictEmulatedWindows11
+		ae.Reasons = append(ae.Reasons, "x64 emulation requires Windows 11")
+	default:
+		return nil
+	}
+
+	if info.Arch == ArchArm64 {
+		return ae
+	}
+
+	// drivers are never emulated, they'd have to be built for arm64
+	var drivers []string
+	if info.Subsystem == SubsystemNative {
+		drivers = append(drivers, "is a native (kernel mode) binary")
+	}
+	for _, wn := range info.WineNotes {
+		if wn.Topic == wineKernelDriver.topic {
+			drivers = append(drivers, wn.Evidence)
+		}
+	}
+	if len(drivers) > 0 {
+		ae.Verdict = ArmVerdictUnsupported
+		ae.Reasons = []string{"needs a kernel driver, which can't be emulated: " + strings.Join(drivers, ", ")}
+	}
+	return ae
+}
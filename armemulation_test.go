@@ -0,0 +1,37 @@
+package pelican
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ArmEmulation(t *testing.T) {
+	assert.EqualValues(t, &ArmEmulation{Verdict: ArmVerdictNative}, armEmulation(&PeInfo{Arch: ArchArm64}))
+	assert.EqualValues(t, &ArmEmulation{Verdict: ArmVerdictEmulated}, armEmulation(&PeInfo{Arch: Arch386}))
+	assert.EqualValues(t, &ArmEmulation{
+		Verdict: ArmVerdictEmulatedWindows11,
+		Reasons: []string{"x64 emulation requires Windows 11"},
+	}, armEmulation(&PeInfo{Arch: ArchAmd64}))
+	assert.Nil(t, armEmulation(&PeInfo{}))
+
+	info := &PeInfo{
+		Arch:        Arch386,
+		Protections: []*Protection{{Name: "starforce", Kind: ProtectionDRM}},
+	}
+	info.WineNotes = findWineNotes(info, nil)
+	assert.EqualValues(t, &ArmEmulation{
+		Verdict: ArmVerdictUnsupported,
+		Reasons: []string{"needs a kernel driver, which can't be emulated: uses starforce"},
+	}, armEmulation(info))
+
+	// arm64 drivers are fine
+	driver := &PeInfo{Arch: ArchArm64, Subsystem: SubsystemNative, Imports: []string{"ntoskrnl.exe"}}
+	driver.WineNotes = findWineNotes(driver, nil)
+	assert.EqualValues(t, ArmVerdictNative, armEmulation(driver).Verdict)
+	driver.Arch = ArchAmd64
+	assert.EqualValues(t, &ArmEmulation{
+		Verdict: ArmVerdictUnsupported,
+		Reasons: []string{"needs a kernel driver, which can't be emulated: is a native (kernel mode) binary, imports ntoskrnl.exe"},
+	}, armEmulation(driver))
+}
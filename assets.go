@@ -0,0 +1,164 @@
+package pelican
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"hash"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/itchio/httpkit/eos"
+	"github.com/itchio/pelican/resources"
+	"github.com/pkg/errors"
+)
+
+// AssetKind tells what an Asset is
+type AssetKind string
+
+const (
+	// An .ico file, see ExtractIcons
+	AssetIcon AssetKind = "icon"
+	// An application manifest (XML), see ExtractManifests
+	AssetManifest AssetKind = "manifest"
+)
+
+var assetKinds = map[string]AssetKind{
+	".ico":      AssetIcon,
+	".manifest": AssetManifest,
+}
+
+// Asset is a file extracted from a binary and written to
+// ProbeParams.AssetDir, named after its contents
+type Asset struct {
+	Kind AssetKind `json:"kind"`
+	// ID or name of the resource it was extracted from,
+	// for example "101" for an icon group
+	Name string `json:"name"`
+	// SHA-256 of the contents, in hex
+	SHA256 string `json:"sha256"`
+	Size   int64  `json:"size"`
+	// Name of the file in ProbeParams.AssetDir: the SHA-256
+	// and an extension, for example "9f86d0...0f00a08.ico"
+	File string `json:"file"`
+}
+
+// assetSink is a Sink that names items after their contents, and
+// records them as assets. Items that already exist aren't rewritten.
+type assetSink struct {
+	dir    string
+	assets []*Asset
+}
+
+var _ Sink = (*assetSink)(nil)
+
+type assetWriter struct {
+	sink *assetSink
+	name string
+	f    *os.File
+	h    hash.Hash
+	size int64
+}
+
+func (as *assetSink) Create(name string) (io.WriteCloser, error) {
+	err := os.MkdirAll(as.dir, 0755)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	// the final name is only known once it's been written
+	f, err := os.CreateTemp(as.dir, ".asset-*")
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return &assetWriter{
+		sink: as,
+		name: name,
+		f:    f,
+		h:    sha256.New(),
+	}, nil
+}
+
+func (aw *assetWriter) Write(p []byte) (int, error) {
+	n, err := aw.f.Write(p)
+	aw.h.Write(p[:n])
+	aw.size += int64(n)
+	return n, err
+}
+
+func (aw *assetWriter) Close() error {
+	tmp := aw.f.Name()
+	err := aw.f.Close()
+	if err != nil {
+		os.Remove(tmp)
+		return errors.WithStack(err)
+	}
+
+	ext := path.Ext(aw.name)
+	sum := hex.EncodeToString(aw.h.Sum(nil))
+	asset := &Asset{
+		Kind:   assetKinds[ext],
+		Name:   strings.TrimSuffix(aw.name, ext),
+		SHA256: sum,
+		Size:   aw.size,
+		File:   sum + ext,
+	}
+
+	dest := filepath.Join(aw.sink.dir, asset.File)
+	if _, err := os.Stat(dest); err == nil {
+		// same name, same contents
+		os.Remove(tmp)
+	} else {
+		err = os.Rename(tmp, dest)
+		if err != nil {
+			os.Remove(tmp)
+			return errors.WithStack(err)
+		}
+	}
+
+	aw.sink.assets = append(aw.sink.assets, asset)
+	return nil
+}
+
+// ExtractManifests writes every application manifest of file
+// to sink, named "<id or name>.manifest".
+func ExtractManifests(file eos.File, sink Sink, params ProbeParams) error {
+	params.setDefaults()
+	img, err := params.resourceImageOf(file)
+	if err != nil {
+		return err
+	}
+	if img == nil {
+		return nil
+	}
+
+	return params.walkResources(nil, img, func(re *resources.Entry) error {
+		if re.Type != ResourceTypeManifest || re.TypeName != "" || re.Outside {
+			return nil
+		}
+		r, err := img.Open(re)
+		if err != nil {
+			return err
+		}
+		return writeItem(sink, fmt.Sprintf("%s.manifest", re.IDString()), r)
+	})
+}
+
+// writeAssets extracts the icons and manifests of file to
+// params.AssetDir, see Asset
+func (params *ProbeParams) writeAssets(file eos.File) ([]*Asset, error) {
+	sink := &assetSink{dir: extendedPath(params.AssetDir)}
+
+	err := ExtractIcons(file, sink, *params)
+	if err != nil {
+		return nil, errors.WithMessage(err, "while extracting icons")
+	}
+	err = ExtractManifests(file, sink, *params)
+	if err != nil {
+		return nil, errors.WithMessage(err, "while extracting manifests")
+	}
+	return sink.assets, nil
+}
@@ -0,0 +1,86 @@
+package pelican_test
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/itchio/httpkit/eos"
+	"github.com/itchio/pelican"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Assets(t *testing.T) {
+	assetDir := t.TempDir()
+	cache := &countingCache{Cache: pelican.NewDiskCache(t.TempDir())}
+
+	probe := func(path string) *pelican.PeInfo {
+		f, err := eos.Open(path)
+		assert.NoError(t, err)
+		defer f.Close()
+
+		params := testProbeParams(t)
+		params.AssetDir = assetDir
+		params.Cache = cache
+		info, err := pelican.Probe(f, params)
+		assert.NoError(t, err)
+		return info
+	}
+
+	info := probe("./testdata/resourceful/resourceful32-mingw.exe")
+	assert.EqualValues(t, 1, len(info.Assets))
+	icon := info.Assets[0]
+	assert.EqualValues(t, pelican.AssetIcon, icon.Kind)
+	assert.EqualValues(t, "101", icon.Name)
+	assert.EqualValues(t, icon.SHA256+".ico", icon.File)
+
+	expected, err := ioutil.ReadFile("./testdata/resourceful/pelican.ico")
+	assert.NoError(t, err)
+	sum := sha256.Sum256(expected)
+	assert.EqualValues(t, hex.EncodeToString(sum[:]), icon.SHA256)
+	assert.EqualValues(t, len(expected), icon.Size)
+	written, err := ioutil.ReadFile(filepath.Join(assetDir, icon.File))
+	assert.NoError(t, err)
+	assert.EqualValues(t, expected, written)
+
+	// extracted again, even though the result is cached
+	info = probe("./testdata/resourceful/resourceful32-mingw.exe")
+	assert.EqualValues(t, 1, cache.hits)
+	assert.EqualValues(t, []*pelican.Asset{icon}, info.Assets)
+
+	info = probe("./testdata/wincdemu/WinCDEmu-4.1.exe")
+	var kinds []pelican.AssetKind
+	for _, a := range info.Assets {
+		kinds = append(kinds, a.Kind)
+	}
+	assert.Contains(t, kinds, pelican.AssetManifest)
+
+	entries, err := ioutil.ReadDir(assetDir)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1+len(info.Assets), len(entries), "no temporary files are left")
+}
+
+func Test_ReprobeAssets(t *testing.T) {
+	f, err := eos.Open("./testdata/resourceful/resourceful32-mingw.exe")
+	assert.NoError(t, err)
+	defer f.Close()
+
+	previous, err := pelican.Probe(f, testProbeParams(t))
+	assert.NoError(t, err)
+	assert.Empty(t, previous.Assets)
+
+	params := testProbeParams(t)
+	params.AssetDir = t.TempDir()
+	expected, err := pelican.Probe(f, params)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, len(expected.Assets))
+
+	params.AssetDir = t.TempDir()
+	info, err := pelican.Reprobe(f, previous, params)
+	assert.NoError(t, err)
+	assert.EqualValues(t, expected.Assets, info.Assets)
+	_, err = ioutil.ReadFile(filepath.Join(params.AssetDir, info.Assets[0].File))
+	assert.NoError(t, err)
+}
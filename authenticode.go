@@ -0,0 +1,101 @@
+package pelican
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/asn1"
+	"encoding/hex"
+	"math/big"
+
+	"github.com/itchio/pelican/pe"
+	"github.com/pkg/errors"
+)
+
+// only the parts of PKCS #7 needed to find the signing certificate,
+// see RFC 2315
+type pkcs7ContentInfo struct {
+	ContentType asn1.ObjectIdentifier
+	Content     asn1.RawValue `asn1:"explicit,tag:0"`
+}
+
+type pkcs7SignedData struct {
+	Version          int
+	DigestAlgorithms asn1.RawValue
+	ContentInfo      asn1.RawValue
+	Certificates     asn1.RawValue     `asn1:"optional,tag:0"`
+	CRLs             asn1.RawValue     `asn1:"optional,tag:1"`
+	SignerInfos      []pkcs7SignerInfo `asn1:"set"`
+}
+
+type pkcs7SignerInfo struct {
+	Version               int
+	IssuerAndSerialNumber pkcs7IssuerAndSerial
+	DigestAlgorithm       asn1.RawValue
+	// authenticated attributes, signature, etc. follow
+	Rest asn1.RawContent `asn1:"optional"`
+}
+
+type pkcs7IssuerAndSerial struct {
+	Issuer       asn1.RawValue
+	SerialNumber *big.Int
+}
+
+var oidSignedData = asn1.ObjectIdentifier{1, 2, 840, 113549, 1, 7, 2}
+
+// signingCertificate returns the certificate the first Authenticode
+// signature of the certificate table was made with
+func signingCertificate(certs []pe.Certificate) (*x509.Certificate, error) {
+	for _, c := range certs {
+		if der := c.PKCS7(); der != nil {
+			return parseSigningCertificate(der)
+		}
+	}
+	return nil, errors.Errorf("certificate table has no Authenticode signature")
+}
+
+func parseSigningCertificate(der []byte) (*x509.Certificate, error) {
+	var ci pkcs7ContentInfo
+	_, err := asn1.Unmarshal(der, &ci)
+	if err != nil {
+		return nil, errors.WithMessage(err, "while parsing PKCS #7 content info")
+	}
+	if !ci.ContentType.Equal(oidSignedData) {
+		return nil, errors.Errorf("unexpected PKCS #7 content type %s", ci.ContentType)
+	}
+
+	var sd pkcs7SignedData
+	_, err = asn1.Unmarshal(ci.Content.Bytes, &sd)
+	if err != nil {
+		return nil, errors.WithMessage(err, "while parsing PKCS #7 signed data")
+	}
+	if len(sd.SignerInfos) == 0 {
+		return nil, errors.Errorf("signature has no signer")
+	}
+	signer := sd.SignerInfos[0].IssuerAndSerialNumber
+
+	rest := sd.Certificates.Bytes
+	for len(rest) > 0 {
+		var raw asn1.RawValue
+		rest, err = asn1.Unmarshal(rest, &raw)
+		if err != nil {
+			return nil, errors.WithMessage(err, "while parsing certificates")
+		}
+
+		cert, err := x509.ParseCertificate(raw.FullBytes)
+		if err != nil {
+			// some chains have certificates Go doesn't like,
+			// which are never the signing one in practice
+			continue
+		}
+		if bytes.Equal(cert.RawIssuer, signer.Issuer.FullBytes) && cert.SerialNumber.Cmp(signer.SerialNumber) == 0 {
+			return cert, nil
+		}
+	}
+	return nil, errors.Errorf("signing certificate not found")
+}
+
+func certificateSHA256(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,135 @@
+package pelican_test
+
+import (
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/itchio/httpkit/eos"
+	"github.com/itchio/pelican"
+	"github.com/itchio/pelican/pe"
+)
+
+// To compare performance before and after a change, run:
+//
+//   go test -run XXX -bench . -benchmem -count 10 > old.txt
+//
+// on both versions, then compare them with benchstat
+// (golang.org/x/perf/cmd/benchstat). The reads/op metric is what
+// matters most for remote files, see latencyFile.
+
+var benchFixtures = []string{
+	"./testdata/hello/hello64-msvc.exe",
+	"./testdata/resourceful/resourceful32-mingw.exe",
+	"./testdata/pidgin/pidgin-uninst.exe",
+	"./testdata/wincdemu/WinCDEmu-4.1.exe",
+}
+
+// simulated round-trip time for latencyFile reads, a lot
+// shorter than the real thing so benchmarks finish in time
+const benchLatency = 100 * time.Microsecond
+
+// latencyFile simulates a file on high-latency storage (HTTP, network
+// file systems) by sleeping before every read. It counts reads, since
+// on real remote files they dominate probe time.
+type latencyFile struct {
+	eos.File
+	reads int64
+}
+
+func (lf *latencyFile) ReadAt(p []byte, off int64) (int, error) {
+	atomic.AddInt64(&lf.reads, 1)
+	time.Sleep(benchLatency)
+	return lf.File.ReadAt(p, off)
+}
+
+func openBenchFixture(b *testing.B, path string) eos.File {
+	f, err := eos.Open(path)
+	if err != nil {
+		b.Fatal(err)
+	}
+	b.Cleanup(func() { f.Close() })
+	return f
+}
+
+func openBenchPeFile(b *testing.B, path string) *pe.File {
+	f := openBenchFixture(b, path)
+	stats, err := f.Stat()
+	if err != nil {
+		b.Fatal(err)
+	}
+
+	pf, err := pe.NewFile(f, stats.Size())
+	if err != nil {
+		b.Fatal(err)
+	}
+	return pf
+}
+
+func Benchmark_Probe(b *testing.B) {
+	params := pelican.ProbeParams{Strict: true}
+
+	for _, path := range benchFixtures {
+		b.Run(filepath.Base(path), func(b *testing.B) {
+			b.Run("local", func(b *testing.B) {
+				f := openBenchFixture(b, path)
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					_, err := pelican.Probe(f, params)
+					if err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+
+			b.Run("latency", func(b *testing.B) {
+				lf := &latencyFile{File: openBenchFixture(b, path)}
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					_, err := pelican.Probe(lf, params)
+					if err != nil {
+						b.Fatal(err)
+					}
+				}
+				b.ReportMetric(float64(lf.reads)/float64(b.N), "reads/op")
+			})
+		})
+	}
+}
+
+func Benchmark_ParseImports(b *testing.B) {
+	params := pelican.ProbeParams{Strict: true}
+
+	for _, path := range benchFixtures {
+		b.Run(filepath.Base(path), func(b *testing.B) {
+			pf := openBenchPeFile(b, path)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				err := pelican.ParseImports(new(pelican.PeInfo), pf, params)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func Benchmark_ParseResources(b *testing.B) {
+	params := pelican.ProbeParams{Strict: true}
+
+	for _, path := range benchFixtures {
+		b.Run(filepath.Base(path), func(b *testing.B) {
+			pf := openBenchPeFile(b, path)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				err := pelican.ParseResources(new(pelican.PeInfo), pf, params)
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,90 @@
+package pelican
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// BitnessMismatch is an app-local DLL an executable would load that's
+// built for another architecture. The loader then fails with "not a
+// valid Win32 application" (0xc000007b), without naming either file.
+type BitnessMismatch struct {
+	// Slash-separated path (relative to the directory) of the executable
+	Path string `json:"path"`
+	Arch Arch   `json:"arch"`
+	// Slash-separated path of the DLL
+	DLL     string `json:"dll"`
+	DLLArch Arch   `json:"dllArch"`
+	// The executable itself, or an app-local DLL it loads
+	ImportedBy string `json:"importedBy"`
+	Message    string `json:"message"`
+}
+
+// findBitnessMismatches follows the imports of every executable through
+// the app-local DLLs they resolve to (the application directory is
+// searched first), and reports those of another architecture.
+func findBitnessMismatches(files map[string]*PeInfo, redirections []*SxSRedirection) []*BitnessMismatch {
+	byLowerPath := lowerPathIndex(files)
+	sxs := sxsByExe(redirections)
+
+	var res []*BitnessMismatch
+	for exe, info := range files {
+		if strings.ToLower(path.Ext(exe)) != ".exe" || info.Arch == "" {
+			continue
+		}
+		appDir := path.Dir(exe)
+
+		visited := map[string]bool{exe: true}
+		queue := []string{exe}
+		for len(queue) > 0 {
+			importer := queue[0]
+			queue = queue[1:]
+
+			for _, imp := range importsOf(files[importer]) {
+				name := strings.ToLower(imp.lib)
+				lookup := path.Join(strings.ToLower(appDir), name)
+				if r := sxs[exe][name]; r != nil {
+					if r.Target == "" {
+						continue
+					}
+					lookup = strings.ToLower(r.Target)
+				} else if knownDLLs[name] {
+					continue
+				}
+
+				dll, ok := byLowerPath[lookup]
+				if !ok || visited[dll] {
+					continue
+				}
+				visited[dll] = true
+
+				dllArch := files[dll].Arch
+				if dllArch == "" {
+					continue
+				}
+				if dllArch != info.Arch {
+					res = append(res, &BitnessMismatch{
+						Path:       exe,
+						Arch:       info.Arch,
+						DLL:        dll,
+						DLLArch:    dllArch,
+						ImportedBy: importer,
+						Message:    fmt.Sprintf("%s executable loads %s DLL %s", info.Arch, dllArch, dll),
+					})
+					continue
+				}
+				queue = append(queue, dll)
+			}
+		}
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Path != res[j].Path {
+			return res[i].Path < res[j].Path
+		}
+		return res[i].DLL < res[j].DLL
+	})
+	return res
+}
@@ -0,0 +1,30 @@
+package pelican
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_FindBitnessMismatches(t *testing.T) {
+	files := map[string]*PeInfo{
+		"bin/game.exe":      {Arch: ArchAmd64, Imports: []string{"KERNEL32.dll", "Engine.dll"}},
+		"bin/engine.dll":    {Arch: ArchAmd64, Imports: []string{"kernel32.dll", "fmod.dll", "steam_api.dll"}},
+		"bin/fmod.dll":      {Arch: Arch386},
+		"bin/steam_api.dll": {Arch: Arch386, Imports: []string{"tier0.dll"}},
+		"bin/tier0.dll":     {Arch: Arch386},
+		// not in the application directory
+		"engine.dll": {Arch: Arch386},
+		// KnownDLLs are always loaded from the system directory
+		"bin/kernel32.dll": {Arch: Arch386},
+	}
+
+	var got []string
+	for _, bm := range findBitnessMismatches(files, nil) {
+		got = append(got, bm.ImportedBy+" -> "+bm.DLL)
+	}
+	assert.EqualValues(t, []string{
+		"bin/engine.dll -> bin/fmod.dll",
+		"bin/engine.dll -> bin/steam_api.dll",
+	}, got)
+}
@@ -0,0 +1,142 @@
+package pelican
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+)
+
+// SchemaVersion is bumped whenever Probe would return different
+// results for the same file, which invalidates cached results.
+const SchemaVersion = 47
+
+// CacheKey identifies a probe result
+type CacheKey struct {
+	// Hex-encoded SHA-256 of the whole file
+	SHA256        string
+	SchemaVersion int
+	// Digest of ProbeParams.OrdinalNames, empty if there are none,
+	// see OrdinalNames.Digest
+	OrdinalNames string
+	// ProbeParams.Strict: results of non-strict probes can have
+	// warnings strict probes fail with
+	Strict bool
+}
+
+func (ck CacheKey) String() string {
+	return fmt.Sprintf("v%d/%s", ck.SchemaVersion, ck.name())
+}
+
+// name identifies ck within a SchemaVersion
+func (ck CacheKey) name() string {
+	name := ck.SHA256
+	if ck.OrdinalNames != "" {
+		name += "-" + ck.OrdinalNames
+	}
+	if ck.Strict {
+		name += "-strict"
+	}
+	return name
+}
+
+// A Cache stores probe results, so that unchanged files don't
+// need to be parsed again.
+type Cache interface {
+	// Get returns nil (and no error) if there is no result for key
+	Get(key CacheKey) (*PeInfo, error)
+	Put(key CacheKey, info *PeInfo) error
+}
+
+func (params *ProbeParams) cacheKeyFor(r io.ReaderAt, size int64) (CacheKey, error) {
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	h := sha256.New()
+	_, err := io.CopyBuffer(h, io.NewSectionReader(r, 0, size), *buf)
+	if err != nil {
+		return CacheKey{}, errors.WithStack(err)
+	}
+
+	return CacheKey{
+		SHA256:        hex.EncodeToString(h.Sum(nil)),
+		SchemaVersion: SchemaVersion,
+		OrdinalNames:  params.OrdinalNames.Digest(),
+		Strict:        params.Strict,
+	}, nil
+}
+
+type diskCache struct {
+	dir string
+}
+
+var _ Cache = (*diskCache)(nil)
+
+// NewDiskCache returns a Cache that stores results as JSON files in dir
+func NewDiskCache(dir string) Cache {
+	return &diskCache{dir: extendedPath(dir)}
+}
+
+func (dc *diskCache) path(key CacheKey) string {
+	shard := key.SHA256
+	if len(shard) > 2 {
+		shard = shard[:2]
+	}
+	return filepath.Join(dc.dir, fmt.Sprintf("v%d", key.SchemaVersion), shard, key.name()+".json")
+}
+
+func (dc *diskCache) Get(key CacheKey) (*PeInfo, error) {
+	data, err := ioutil.ReadFile(dc.path(key))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, errors.WithStack(err)
+	}
+
+	info := &PeInfo{}
+	err = json.Unmarshal(data, info)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return info, nil
+}
+
+func (dc *diskCache) Put(key CacheKey, info *PeInfo) error {
+	data, err := json.Marshal(info)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	dest := dc.path(key)
+	err = os.MkdirAll(filepath.Dir(dest), 0755)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	// write to a temporary file first so concurrent readers
+	// never see partial results
+	tmp, err := ioutil.TempFile(filepath.Dir(dest), ".pelican-cache-")
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = tmp.Write(data)
+	if err != nil {
+		tmp.Close()
+		return errors.WithStack(err)
+	}
+	err = tmp.Close()
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	return errors.WithStack(os.Rename(tmp.Name(), dest))
+}
@@ -0,0 +1,167 @@
+package pelican_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/itchio/httpkit/eos"
+	"github.com/itchio/pelican"
+	"github.com/stretchr/testify/assert"
+)
+
+type countingCache struct {
+	pelican.Cache
+	hits int
+	puts int
+}
+
+func (cc *countingCache) Get(key pelican.CacheKey) (*pelican.PeInfo, error) {
+	info, err := cc.Cache.Get(key)
+	if info != nil {
+		cc.hits++
+	}
+	return info, err
+}
+
+func (cc *countingCache) Put(key pelican.CacheKey, info *pelican.PeInfo) error {
+	cc.puts++
+	return cc.Cache.Put(key, info)
+}
+
+func Test_DiskCache(t *testing.T) {
+	cacheDir, err := ioutil.TempDir("", "pelican-cache")
+	assert.NoError(t, err)
+	defer os.RemoveAll(cacheDir)
+
+	cache := &countingCache{Cache: pelican.NewDiskCache(cacheDir)}
+	params := testProbeParams(t)
+	params.Cache = cache
+
+	probe := func() *pelican.PeInfo {
+		f, err := eos.Open("./testdata/resourceful/resourceful64-mingw.exe")
+		assert.NoError(t, err)
+		defer f.Close()
+
+		info, err := pelican.Probe(f, params)
+		assert.NoError(t, err)
+		return info
+	}
+
+	first := probe()
+	assert.EqualValues(t, 0, cache.hits)
+	assert.EqualValues(t, 1, cache.puts)
+
+	second := probe()
+	assert.EqualValues(t, 1, cache.hits)
+	assert.EqualValues(t, 1, cache.puts)
+	assert.EqualValues(t, first, second)
+	assertResources(t, second)
+
+	// ProbeDir goes through Probe, so it honors the cache too
+	dir, err := ioutil.TempDir("", "pelican-cache-dir")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	copyFixture(t, "./testdata/resourceful/resourceful64-mingw.exe", filepath.Join(dir, "game.exe"))
+
+	_, err = pelican.ProbeDir(os.DirFS(dir), params)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, cache.hits)
+}
+
+func Test_DiskCacheMemoryBudget(t *testing.T) {
+	cacheDir, err := ioutil.TempDir("", "pelican-cache")
+	assert.NoError(t, err)
+	defer os.RemoveAll(cacheDir)
+
+	cache := &countingCache{Cache: pelican.NewDiskCache(cacheDir)}
+	probe := func(maxMemory int64) *pelican.PeInfo {
+		f, err := eos.Open("./testdata/pidgin/pidgin-uninst.exe")
+		assert.NoError(t, err)
+		defer f.Close()
+
+		params := testProbeParams(t)
+		params.Cache = cache
+		params.MaxMemory = maxMemory
+		info, err := pelican.Probe(f, params)
+		assert.NoError(t, err)
+		return info
+	}
+
+	// the data scan is skipped, so the result isn't cached
+	degraded := probe(4096)
+	assert.Nil(t, degraded.Indicators)
+	assert.EqualValues(t, 0, cache.puts)
+
+	full := probe(0)
+	assert.EqualValues(t, 0, cache.hits)
+	assert.EqualValues(t, 1, cache.puts)
+	assert.NotNil(t, full.Indicators)
+}
+
+func Test_DiskCacheOrdinalNames(t *testing.T) {
+	cache := &countingCache{Cache: pelican.NewDiskCache(t.TempDir())}
+	probe := func(ordinalNames pelican.OrdinalNames) *pelican.PeInfo {
+		f, err := eos.Open("./testdata/pidgin/pidgin-uninst.exe")
+		assert.NoError(t, err)
+		defer f.Close()
+
+		params := testProbeParams(t)
+		params.Cache = cache
+		params.OrdinalNames = ordinalNames
+		info, err := pelican.Probe(f, params)
+		assert.NoError(t, err)
+		return info
+	}
+
+	probe(nil)
+	// those change which functions are imported, so they
+	// don't share cached results with the defaults
+	custom := pelican.OrdinalNames{"comctl32.dll": {17: "MyInitCommonControls"}}
+	probe(custom)
+	assert.EqualValues(t, 0, cache.hits)
+	assert.EqualValues(t, 2, cache.puts)
+
+	probe(pelican.OrdinalNames{"comctl32.dll": {17: "MyInitCommonControls"}})
+	assert.EqualValues(t, 1, cache.hits)
+	probe(nil)
+	assert.EqualValues(t, 2, cache.hits)
+	assert.EqualValues(t, 2, cache.puts)
+}
+
+func Test_DiskCacheStrict(t *testing.T) {
+	data, err := ioutil.ReadFile("./testdata/wincdemu/WinCDEmu-4.1.exe")
+	assert.NoError(t, err)
+	// mangle the PKCS #7 blob, see Test_InvalidSignature
+	for i := 1690808 + 8; i < 1690808+64; i++ {
+		data[i] = 0xff
+	}
+	fsys := fstest.MapFS{"setup.exe": {Data: data}}
+
+	cache := &countingCache{Cache: pelican.NewDiskCache(t.TempDir())}
+	probe := func(strict bool) (*pelican.PeInfo, error) {
+		f, err := fsys.Open("setup.exe")
+		assert.NoError(t, err)
+		defer f.Close()
+
+		params := testProbeParams(t)
+		params.Cache = cache
+		params.Strict = strict
+		return pelican.Probe(f.(eos.File), params)
+	}
+
+	_, err = probe(false)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, cache.puts)
+
+	// the cached result has a warning strict probes fail with
+	_, err = probe(true)
+	assert.Error(t, err)
+	assert.EqualValues(t, 0, cache.hits)
+
+	_, err = probe(false)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, cache.hits)
+}
@@ -0,0 +1,100 @@
+package pelican
+
+import (
+	"regexp"
+	"strings"
+)
+
+// CEFInfo is set for binaries that are, or import, the Chromium Embedded
+// Framework (libcef.dll). Its Chromium version is what matters for
+// security advisories, old ones are often shipped for years.
+type CEFInfo struct {
+	// Set if the binary imports libcef.dll, rather than being it.
+	// The versions are then unknown, see DirInfo.CEFApps
+	ImportsLibCEF bool `json:"importsLibcef,omitempty"`
+	// For example "120.1.10+g3ce3184+chromium-120.0.6099.129",
+	// or "3.3626.1895.g7001d56" for CEF 3
+	Version string `json:"version,omitempty"`
+	// For example "120.0.6099.129". CEF 3 versions only include
+	// the Chromium branch, so it's unknown for those.
+	ChromiumVersion string `json:"chromiumVersion,omitempty"`
+}
+
+// CEFApp is an executable that loads an app-local libcef.dll,
+// directly or through other DLLs
+type CEFApp struct {
+	// Slash-separated path of the executable
+	Path string `json:"path"`
+	// Slash-separated path of libcef.dll
+	LibCEF          string `json:"libcef"`
+	Version         string `json:"version,omitempty"`
+	ChromiumVersion string `json:"chromiumVersion,omitempty"`
+}
+
+const libCEF = "libcef.dll"
+
+// CEF versions include the Chromium version since CEF 73,
+// see https://bitbucket.org/chromiumembedded/cef/wiki/BranchesAndBuilding
+var cefChromiumRegexp = regexp.MustCompile(`\+chromium-(\d+\.\d+\.\d+\.\d+)$`)
+
+// detectCEF sets info.CEF, and reports libcef.dll as a bundled library
+// (along with Chromium) if info is libcef.dll itself
+func detectCEF(info *PeInfo) {
+	props := info.VersionProperties
+	if strings.EqualFold(props["OriginalFilename"], libCEF) || strings.HasPrefix(props["FileDescription"], "Chromium Embedded Framework") {
+		version := props["FileVersion"]
+		if version == "" {
+			version = props["ProductVersion"]
+		}
+		cef := &CEFInfo{Version: version}
+		if m := cefChromiumRegexp.FindStringSubmatch(version); m != nil {
+			cef.ChromiumVersion = m[1]
+		}
+		info.CEF = cef
+
+		info.BundledLibraries = append(info.BundledLibraries, &BundledLibrary{
+			Name:    "cef",
+			Version: cef.Version,
+			Source:  LibrarySourceVersionInfo,
+		})
+		if cef.ChromiumVersion != "" {
+			info.BundledLibraries = append(info.BundledLibraries, &BundledLibrary{
+				Name:    "chromium",
+				Version: cef.ChromiumVersion,
+				Source:  LibrarySourceVersionInfo,
+			})
+		}
+		return
+	}
+
+	for _, lib := range info.Imports {
+		if strings.EqualFold(lib, libCEF) {
+			info.CEF = &CEFInfo{ImportsLibCEF: true}
+			return
+		}
+	}
+}
+
+// findCEFApps returns the executables whose load order
+// includes an app-local libcef.dll
+func findCEFApps(files map[string]*PeInfo, loadOrder []*LoadOrderReport) []*CEFApp {
+	var res []*CEFApp
+	for _, r := range loadOrder {
+		for _, e := range r.Entries {
+			if e.Resolution != ResolutionAppLocal || !strings.EqualFold(e.DLL, libCEF) {
+				continue
+			}
+
+			app := &CEFApp{
+				Path:   r.Path,
+				LibCEF: e.Path,
+			}
+			if cef := files[e.Path].CEF; cef != nil {
+				app.Version = cef.Version
+				app.ChromiumVersion = cef.ChromiumVersion
+			}
+			res = append(res, app)
+		}
+	}
+	return res
+}
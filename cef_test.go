@@ -0,0 +1,45 @@
+package pelican_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/itchio/pelican"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_CEF(t *testing.T) {
+	// see testdata/resourceful/make-libcef.py
+	libcef := fixtureFile(t, "./testdata/resourceful/resourceful32-libcef.dll")
+	fsys := fstest.MapFS{
+		"bin/app.exe":    importing(t, "./testdata/hello/hello32-mingw.exe", "libcef.dll"),
+		"bin/libcef.dll": libcef,
+		"other.exe":      importing(t, "./testdata/hello/hello32-mingw.exe", "LIBCEF.DLL"),
+	}
+
+	di, err := pelican.ProbeDir(fsys, testProbeParams(t))
+	assert.NoError(t, err)
+
+	cef := di.Files["bin/libcef.dll"].CEF
+	assert.EqualValues(t, &pelican.CEFInfo{
+		Version:         "120.1.10+g3ce3184+chromium-120.0.6099.129",
+		ChromiumVersion: "120.0.6099.129",
+	}, cef)
+	assert.EqualValues(t, []*pelican.BundledLibrary{
+		{Name: "cef", Version: "120.1.10+g3ce3184+chromium-120.0.6099.129", Source: pelican.LibrarySourceVersionInfo},
+		{Name: "chromium", Version: "120.0.6099.129", Source: pelican.LibrarySourceVersionInfo},
+	}, di.Files["bin/libcef.dll"].BundledLibraries)
+
+	assert.EqualValues(t, &pelican.CEFInfo{ImportsLibCEF: true}, di.Files["bin/app.exe"].CEF)
+	assert.EqualValues(t, &pelican.CEFInfo{ImportsLibCEF: true}, di.Files["other.exe"].CEF)
+
+	// other.exe's libcef.dll is missing
+	assert.EqualValues(t, []*pelican.CEFApp{
+		{
+			Path:            "bin/app.exe",
+			LibCEF:          "bin/libcef.dll",
+			Version:         "120.1.10+g3ce3184+chromium-120.0.6099.129",
+			ChromiumVersion: "120.0.6099.129",
+		},
+	}, di.CEFApps)
+}
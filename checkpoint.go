@@ -0,0 +1,151 @@
+package pelican
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/fs"
+	"os"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// Checkpoint records the files a StreamDir scan has processed, in a file,
+// so that an interrupted scan can be resumed instead of starting over.
+// See ProbeParams.Checkpoint.
+//
+// The file has one JSON object per line, it's only ever appended to.
+type Checkpoint struct {
+	file    *os.File
+	enc     *json.Encoder
+	entries map[string]*checkpointEntry
+}
+
+type checkpointEntry struct {
+	Path    string    `json:"path"`
+	Size    int64     `json:"size"`
+	ModTime time.Time `json:"modTime"`
+	// Hex-encoded SHA-256 of the whole file
+	SHA256 string `json:"sha256"`
+}
+
+// OpenCheckpoint opens (or creates) the checkpoint file at name,
+// and loads the entries it already has. Lines that can't be parsed,
+// like one cut short by a crash, are ignored: those files are processed again.
+func OpenCheckpoint(name string) (*Checkpoint, error) {
+	f, err := os.OpenFile(extendedPath(name), os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	c := &Checkpoint{
+		file:    f,
+		enc:     json.NewEncoder(f),
+		entries: make(map[string]*checkpointEntry),
+	}
+
+	br := bufio.NewReader(f)
+	var last string
+	for {
+		line, err := br.ReadString('\n')
+		if line != "" {
+			last = line
+			e := &checkpointEntry{}
+			if json.Unmarshal([]byte(line), e) == nil && e.Path != "" {
+				c.entries[e.Path] = e
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			f.Close()
+			return nil, errors.WithStack(err)
+		}
+	}
+
+	if last != "" && last[len(last)-1] != '\n' {
+		// don't glue the next entry to a truncated one
+		_, err = f.Write([]byte{'\n'})
+		if err != nil {
+			f.Close()
+			return nil, errors.WithStack(err)
+		}
+	}
+	return c, nil
+}
+
+// Len returns the number of files recorded as processed
+func (c *Checkpoint) Len() int {
+	return len(c.entries)
+}
+
+// Close closes the checkpoint file
+func (c *Checkpoint) Close() error {
+	return errors.WithStack(c.file.Close())
+}
+
+// done returns true if p was processed in the state described by stats,
+// hashing it only if its size matches but its modification time doesn't.
+// Otherwise, it returns the hash of p, if it computed it.
+func (c *Checkpoint) done(fsys fs.FS, p string, stats fs.FileInfo) (bool, string, error) {
+	e := c.entries[p]
+	if e == nil || e.Size != stats.Size() {
+		return false, "", nil
+	}
+	if e.ModTime.Equal(stats.ModTime()) {
+		return true, e.SHA256, nil
+	}
+
+	// touched, but maybe not modified
+	hash, err := hashFSPath(fsys, p)
+	if err != nil {
+		return false, "", err
+	}
+	return hash == e.SHA256, hash, nil
+}
+
+// record appends an entry for p. hash is computed if empty.
+func (c *Checkpoint) record(fsys fs.FS, p string, stats fs.FileInfo, hash string) error {
+	if hash == "" {
+		var err error
+		hash, err = hashFSPath(fsys, p)
+		if err != nil {
+			return err
+		}
+	}
+
+	e := &checkpointEntry{
+		Path:    p,
+		Size:    stats.Size(),
+		ModTime: stats.ModTime(),
+		SHA256:  hash,
+	}
+	err := c.enc.Encode(e)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	c.entries[p] = e
+	return nil
+}
+
+func hashFSPath(fsys fs.FS, p string) (string, error) {
+	f, err := fsys.Open(p)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	defer f.Close()
+
+	buf := getBuffer()
+	defer putBuffer(buf)
+
+	h := sha256.New()
+	_, err = io.CopyBuffer(h, f, *buf)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
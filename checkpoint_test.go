@@ -0,0 +1,95 @@
+package pelican_test
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/itchio/pelican"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Checkpoint(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pelican-checkpoint")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	gameDir := filepath.Join(dir, "game")
+	copyFixture(t, "./testdata/hello/hello32-mingw.exe", filepath.Join(gameDir, "a.exe"))
+	copyFixture(t, "./testdata/hello/hello64-mingw.exe", filepath.Join(gameDir, "b.exe"))
+	copyFixture(t, "./testdata/forwarders/core.dll", filepath.Join(gameDir, "c.dll"))
+	fsys := os.DirFS(gameDir)
+	checkpointPath := filepath.Join(dir, "checkpoint.ndjson")
+
+	scan := func(stopAfter int) ([]string, error) {
+		cp, err := pelican.OpenCheckpoint(checkpointPath)
+		assert.NoError(t, err)
+		defer cp.Close()
+
+		ctx, cancel := context.WithCancel(context.Background())
+		defer cancel()
+
+		params := testProbeParams(t)
+		params.Context = ctx
+		params.Checkpoint = cp
+
+		var paths []string
+		err = pelican.StreamDir(fsys, params, func(fr *pelican.FileResult) error {
+			paths = append(paths, fr.Path)
+			if len(paths) == stopAfter {
+				cancel()
+			}
+			return nil
+		})
+		return paths, err
+	}
+
+	// interrupted after the first file
+	paths, err := scan(1)
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.EqualValues(t, []string{"a.exe"}, paths)
+
+	// resumed
+	paths, err = scan(-1)
+	assert.NoError(t, err)
+	assert.EqualValues(t, []string{"b.exe", "c.dll"}, paths)
+
+	paths, err = scan(-1)
+	assert.NoError(t, err)
+	assert.Empty(t, paths)
+
+	// touched files are hashed, and only processed if they changed
+	later := time.Now().Add(time.Hour)
+	assert.NoError(t, os.Chtimes(filepath.Join(gameDir, "a.exe"), later, later))
+	copyFixture(t, "./testdata/hello/hello32-mingw.exe", filepath.Join(gameDir, "b.exe"))
+	assert.NoError(t, os.Chtimes(filepath.Join(gameDir, "b.exe"), later, later))
+	paths, err = scan(-1)
+	assert.NoError(t, err)
+	assert.EqualValues(t, []string{"b.exe"}, paths)
+
+	// a line cut short by a crash is ignored
+	f, err := os.OpenFile(checkpointPath, os.O_WRONLY|os.O_APPEND, 0644)
+	assert.NoError(t, err)
+	_, err = f.Write([]byte(`{"path":"c.d`))
+	assert.NoError(t, err)
+	assert.NoError(t, f.Close())
+
+	cp, err := pelican.OpenCheckpoint(checkpointPath)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 3, cp.Len())
+	assert.NoError(t, cp.Close())
+
+	copyFixture(t, "./testdata/hello/hello64-mingw.exe", filepath.Join(gameDir, "d.exe"))
+	paths, err = scan(-1)
+	assert.NoError(t, err)
+	assert.EqualValues(t, []string{"d.exe"}, paths)
+
+	cp, err = pelican.OpenCheckpoint(checkpointPath)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 4, cp.Len())
+	assert.NoError(t, cp.Close())
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/itchio/pelican"
+	"github.com/itchio/pelican/encode"
+	"github.com/pkg/errors"
+)
+
+var depsCommand = &command{
+	usage: "<exe> [--dir folder] [--json] [--strict]",
+	help:  "Print the DLLs exe loads, and where Windows finds them (or doesn't)",
+	run:   runDeps,
+}
+
+// depsResult is what "deps --json" prints
+type depsResult struct {
+	Tree              *pelican.DependencyNode    `json:"tree"`
+	BitnessMismatches []*pelican.BitnessMismatch `json:"bitnessMismatches,omitempty"`
+	CanStart          bool                       `json:"canStart"`
+}
+
+func runDeps(args []string) error {
+	fs := flag.NewFlagSet("deps", flag.ContinueOnError)
+	dir := fs.String("dir", "", "folder the game ships in (defaults to the folder of exe)")
+	asJSON := fs.Bool("json", false, "print the tree as JSON")
+	strict := fs.Bool("strict", false, "fail on files that can't be fully probed")
+
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+
+	exe := positional[0]
+	if *dir == "" {
+		*dir = filepath.Dir(exe)
+	}
+	rel, err := filepath.Rel(*dir, exe)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+	if rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return errors.Errorf("%s isn't in %s", exe, *dir)
+	}
+	rel = filepath.ToSlash(rel)
+
+	g := pelican.NewDependencyGraph(pelican.FSResolver(pelican.DirFS(*dir)), pelican.ProbeParams{
+		Consumer: consumer(),
+		Strict:   *strict,
+	})
+	err = g.Add(rel)
+	if err != nil {
+		return err
+	}
+
+	var report *pelican.LoadOrderReport
+	for _, r := range g.LoadOrder() {
+		if r.Path == rel {
+			report = r
+		}
+	}
+	if report == nil {
+		return errors.Errorf("%s isn't an executable", exe)
+	}
+
+	var mismatches []*pelican.BitnessMismatch
+	for _, bm := range g.BitnessMismatches() {
+		if bm.Path == rel {
+			mismatches = append(mismatches, bm)
+		}
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(&depsResult{
+			Tree:              report.Tree(),
+			BitnessMismatches: mismatches,
+			CanStart:          report.CanStart,
+		})
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	} else {
+		err = encode.DependencyTree(os.Stdout, report.Tree())
+		if err != nil {
+			return err
+		}
+		for _, bm := range mismatches {
+			fmt.Println(bm.Message)
+		}
+	}
+
+	if !report.CanStart {
+		return errors.Errorf("%s can't start", exe)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+// Command pelican probes Windows executables from the command line
+//
+// Usage:
+//
+//	pelican deps <exe> [--dir folder] [--json] [--strict]
+//	pelican verify <folder> [--fail-on severity] [--rules file] [--severity check=severity]... [--json] [--strict]
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+
+	"github.com/itchio/headway/state"
+)
+
+type command struct {
+	usage string
+	help  string
+	run   func(args []string) error
+}
+
+var commands = map[string]*command{
+	"deps":   depsCommand,
+	"verify": verifyCommand,
+}
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+		os.Exit(2)
+	}
+
+	cmd, ok := commands[os.Args[1]]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "pelican: unknown command %q\n", os.Args[1])
+		usage()
+		os.Exit(2)
+	}
+
+	err := cmd.run(os.Args[2:])
+	if err == flag.ErrHelp {
+		os.Exit(2)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "pelican: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	var names []string
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(os.Stderr, "Usage:\n\n")
+	for _, name := range names {
+		fmt.Fprintf(os.Stderr, "  pelican %s %s\n        %s\n", name, commands[name].usage, commands[name].help)
+	}
+}
+
+// parseFlags parses args with fs, allowing flags after positional
+// arguments, and returns the positional arguments. Errors have
+// already been printed along with usage, so flag.ErrHelp is returned.
+func parseFlags(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		err := fs.Parse(args)
+		if err != nil {
+			return nil, flag.ErrHelp
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+// consumer prints warnings and errors to stderr
+func consumer() *state.Consumer {
+	return &state.Consumer{
+		OnMessage: func(level string, msg string) {
+			if level == "warning" || level == "error" {
+				fmt.Fprintf(os.Stderr, "%s: %s\n", level, msg)
+			}
+		},
+	}
+}
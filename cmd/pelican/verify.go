@@ -0,0 +1,140 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/itchio/pelican"
+	"github.com/pkg/errors"
+)
+
+var verifyCommand = &command{
+	usage: "<folder> [--fail-on severity] [--rules file] [--severity check=severity]... [--json] [--strict]",
+	help:  "Check a folder before uploading it: missing DLLs, elevation, unsigned binaries, etc.",
+	run:   runVerify,
+}
+
+// verifyResult is what "verify --json" prints
+type verifyResult struct {
+	Findings []*pelican.Finding `json:"findings"`
+	Failed   bool               `json:"failed"`
+}
+
+func parseSeverity(s string) (pelican.Severity, error) {
+	switch sev := pelican.Severity(s); sev {
+	case pelican.SeverityInfo, pelican.SeverityWarn, pelican.SeverityError:
+		return sev, nil
+	}
+	return "", errors.Errorf("unknown severity %q (expected info, warn or error)", s)
+}
+
+// severityOverrides is the value of --severity flags
+type severityOverrides pelican.Rules
+
+func (so severityOverrides) String() string {
+	var parts []string
+	for check, sev := range so {
+		parts = append(parts, fmt.Sprintf("%s=%s", check, sev))
+	}
+	return strings.Join(parts, ",")
+}
+
+func (so severityOverrides) Set(s string) error {
+	tokens := strings.SplitN(s, "=", 2)
+	if len(tokens) != 2 {
+		return errors.Errorf("expected check=severity, got %q", s)
+	}
+	return pelican.Rules(so).Set(pelican.Check(tokens[0]), pelican.Severity(tokens[1]))
+}
+
+func runVerify(args []string) error {
+	fs := flag.NewFlagSet("verify", flag.ContinueOnError)
+	failOn := fs.String("fail-on", string(pelican.SeverityError), "exit with an error on findings at least this severe (info, warn or error)")
+	rulesPath := fs.String("rules", "", "YAML or JSON file setting the severity of checks, for example \"unsigned: error\"")
+	overrides := make(severityOverrides)
+	fs.Var(overrides, "severity", "change the severity of a check, for example unsigned=error or arch-mix=off (repeatable)")
+	asJSON := fs.Bool("json", false, "print findings as JSON")
+	strict := fs.Bool("strict", false, "fail on files that can't be fully probed")
+
+	positional, err := parseFlags(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 1 {
+		fs.Usage()
+		return flag.ErrHelp
+	}
+	threshold, err := parseSeverity(*failOn)
+	if err != nil {
+		return err
+	}
+
+	rules := make(pelican.Rules)
+	if *rulesPath != "" {
+		data, err := ioutil.ReadFile(*rulesPath)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		rules, err = pelican.ParseRules(data)
+		if err != nil {
+			return errors.WithMessagef(err, "while parsing %s", *rulesPath)
+		}
+	}
+	// --severity flags take precedence over the rules file
+	for check, sev := range overrides {
+		rules[check] = sev
+	}
+
+	folder := positional[0]
+	di, err := pelican.ProbeDir(pelican.DirFS(folder), pelican.ProbeParams{
+		Consumer:            consumer(),
+		Strict:              *strict,
+		ElevationHeuristics: true,
+	})
+	if err != nil {
+		return err
+	}
+
+	findings := di.Verify(rules)
+	failed := false
+	for _, f := range findings {
+		if f.Severity.AtLeast(threshold) {
+			failed = true
+		}
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		err = enc.Encode(&verifyResult{
+			Findings: findings,
+			Failed:   failed,
+		})
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	} else {
+		tw := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		for _, f := range findings {
+			p := f.Path
+			if p == "" {
+				p = "."
+			}
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", f.Severity, f.Check, p, f.Message)
+		}
+		err = tw.Flush()
+		if err != nil {
+			return errors.WithStack(err)
+		}
+	}
+
+	if failed {
+		return errors.Errorf("%s has findings of severity %s or more", folder, threshold)
+	}
+	return nil
+}
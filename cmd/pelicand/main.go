@@ -0,0 +1,52 @@
+// Command pelicand serves pelican over HTTP, for services that
+// aren't written in Go
+//
+// Usage:
+//
+//	pelicand [--listen address] [--concurrency n] [--max-size bytes] [--cache-dir folder]
+//
+// API:
+//
+//	POST /probe
+//	    Probes the file sent as the request body, or if the request is
+//	    JSON ({"url": "https://..."}), the file at that URL, which must
+//	    support range requests. Replies with the PeInfo as JSON, or
+//	    with {"error": "..."}.
+//	GET /health
+//	    Replies with 200 OK.
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"runtime"
+
+	"github.com/itchio/headway/state"
+	"github.com/itchio/pelican"
+)
+
+func main() {
+	listen := flag.String("listen", "127.0.0.1:8080", "address to listen on")
+	concurrency := flag.Int("concurrency", runtime.NumCPU(), "maximum number of files probed at once, other requests wait")
+	maxSize := flag.Int64("max-size", 1<<30, "maximum size of uploaded files, in bytes")
+	cacheDir := flag.String("cache-dir", "", "if set, results are cached in this folder, keyed by SHA-256")
+	flag.Parse()
+
+	params := pelican.ProbeParams{
+		Consumer: &state.Consumer{
+			OnMessage: func(level string, msg string) {
+				if level == "warning" || level == "error" {
+					log.Printf("%s: %s", level, msg)
+				}
+			},
+		},
+	}
+	if *cacheDir != "" {
+		params.Cache = pelican.NewDiskCache(*cacheDir)
+	}
+
+	s := newServer(params, *concurrency, *maxSize)
+	log.Printf("Listening on %s", *listen)
+	log.Fatal(http.ListenAndServe(*listen, s))
+}
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/itchio/httpkit/eos"
+	"github.com/itchio/pelican"
+	"github.com/pkg/errors"
+)
+
+type server struct {
+	mux     *http.ServeMux
+	params  pelican.ProbeParams
+	maxSize int64
+	// holds a value for every file being probed
+	slots chan struct{}
+}
+
+// probeRequest is the body of JSON requests to /probe
+type probeRequest struct {
+	URL string `json:"url"`
+}
+
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+func newServer(params pelican.ProbeParams, concurrency int, maxSize int64) *server {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	s := &server{
+		mux:     http.NewServeMux(),
+		params:  params,
+		maxSize: maxSize,
+		slots:   make(chan struct{}, concurrency),
+	}
+	s.mux.HandleFunc("/probe", s.handleProbe)
+	s.mux.HandleFunc("/health", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	return s
+}
+
+func (s *server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *server) handleProbe(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		w.Header().Set("Allow", http.MethodPost)
+		replyError(w, http.StatusMethodNotAllowed, errors.New("only POST is supported"))
+		return
+	}
+
+	select {
+	case s.slots <- struct{}{}:
+		defer func() { <-s.slots }()
+	case <-r.Context().Done():
+		return
+	}
+
+	var f eos.File
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "application/json" {
+		var req probeRequest
+		err := json.NewDecoder(io.LimitReader(r.Body, 64*1024)).Decode(&req)
+		if err != nil {
+			replyError(w, http.StatusBadRequest, errors.WithMessage(err, "while decoding request"))
+			return
+		}
+		u, err := url.Parse(req.URL)
+		// eos opens anything else as a local file
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			replyError(w, http.StatusBadRequest, errors.Errorf("invalid URL %q, expected http or https", req.URL))
+			return
+		}
+		f, err = eos.Open(req.URL)
+		if err != nil {
+			replyError(w, http.StatusBadGateway, errors.WithMessagef(err, "while opening %s", req.URL))
+			return
+		}
+	} else {
+		tmp, err := s.receive(r)
+		if err != nil {
+			status := http.StatusBadRequest
+			if errors.Is(err, errTooLarge) {
+				status = http.StatusRequestEntityTooLarge
+			}
+			replyError(w, status, err)
+			return
+		}
+		defer os.Remove(tmp.Name())
+		f = tmp
+	}
+	defer f.Close()
+
+	info, err := pelican.Probe(f, s.params)
+	if err != nil {
+		replyError(w, http.StatusUnprocessableEntity, err)
+		return
+	}
+	reply(w, http.StatusOK, info)
+}
+
+var errTooLarge = errors.New("file too large")
+
+// receive writes the request body to a temporary file,
+// since probing needs random access
+func (s *server) receive(r *http.Request) (*os.File, error) {
+	if r.ContentLength > s.maxSize {
+		return nil, errTooLarge
+	}
+
+	tmp, err := os.CreateTemp("", "pelicand-*.bin")
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	// one more byte, to tell files that are exactly maxSize apart
+	n, err := io.Copy(tmp, io.LimitReader(r.Body, s.maxSize+1))
+	if err == nil && n > s.maxSize {
+		err = errTooLarge
+	}
+	if err == nil && n == 0 {
+		err = errors.New("empty request body, expected a file")
+	}
+	if err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return nil, err
+	}
+	return tmp, nil
+}
+
+func reply(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	err := json.NewEncoder(w).Encode(v)
+	if err != nil {
+		log.Printf("Could not write response: %v", err)
+	}
+}
+
+func replyError(w http.ResponseWriter, status int, err error) {
+	reply(w, status, &errorResponse{Error: err.Error()})
+}
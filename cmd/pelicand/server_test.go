@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/itchio/headway/state"
+	"github.com/itchio/pelican"
+	"github.com/stretchr/testify/assert"
+)
+
+const fixture = "../../testdata/hello/hello32-mingw.exe"
+
+func testServer(t *testing.T) *httptest.Server {
+	ts := httptest.NewServer(newServer(pelican.ProbeParams{
+		Consumer: &state.Consumer{},
+	}, 2, 1024*1024))
+	t.Cleanup(ts.Close)
+	return ts
+}
+
+func decode(t *testing.T, res *http.Response, v interface{}) {
+	defer res.Body.Close()
+	assert.EqualValues(t, "application/json", res.Header.Get("Content-Type"))
+	assert.NoError(t, json.NewDecoder(res.Body).Decode(v))
+}
+
+func Test_ProbeUpload(t *testing.T) {
+	ts := testServer(t)
+	data, err := ioutil.ReadFile(fixture)
+	assert.NoError(t, err)
+
+	res, err := http.Post(ts.URL+"/probe", "application/octet-stream", bytes.NewReader(data))
+	assert.NoError(t, err)
+	assert.EqualValues(t, http.StatusOK, res.StatusCode)
+	var info pelican.PeInfo
+	decode(t, res, &info)
+	assert.EqualValues(t, pelican.Arch386, info.Arch)
+	assert.Contains(t, info.Imports, "msvcrt.dll")
+
+	res, err = http.Post(ts.URL+"/probe", "application/octet-stream", strings.NewReader("not a PE file"))
+	assert.NoError(t, err)
+	assert.EqualValues(t, http.StatusUnprocessableEntity, res.StatusCode)
+	var er errorResponse
+	decode(t, res, &er)
+	assert.NotEmpty(t, er.Error)
+
+	res, err = http.Post(ts.URL+"/probe", "application/octet-stream", bytes.NewReader(make([]byte, 1024*1024+1)))
+	assert.NoError(t, err)
+	assert.EqualValues(t, http.StatusRequestEntityTooLarge, res.StatusCode)
+	res.Body.Close()
+
+	res, err = http.Get(ts.URL + "/probe")
+	assert.NoError(t, err)
+	assert.EqualValues(t, http.StatusMethodNotAllowed, res.StatusCode)
+	res.Body.Close()
+}
+
+func Test_ProbeURL(t *testing.T) {
+	ts := testServer(t)
+	files := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.ServeFile(w, r, fixture)
+	}))
+	defer files.Close()
+
+	post := func(body string) *http.Response {
+		res, err := http.Post(ts.URL+"/probe", "application/json; charset=utf-8", strings.NewReader(body))
+		assert.NoError(t, err)
+		return res
+	}
+
+	res := post(`{"url": "` + files.URL + `/game.exe"}`)
+	assert.EqualValues(t, http.StatusOK, res.StatusCode)
+	var info pelican.PeInfo
+	decode(t, res, &info)
+	assert.EqualValues(t, pelican.Arch386, info.Arch)
+
+	// local files aren't served
+	res = post(`{"url": "` + fixture + `"}`)
+	assert.EqualValues(t, http.StatusBadRequest, res.StatusCode)
+	res.Body.Close()
+	res = post(`{"url": "file:///etc/passwd"}`)
+	assert.EqualValues(t, http.StatusBadRequest, res.StatusCode)
+	res.Body.Close()
+}
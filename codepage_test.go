@@ -0,0 +1,41 @@
+package pelican_test
+
+import (
+	"testing"
+
+	"github.com/itchio/httpkit/eos"
+	"github.com/itchio/pelican"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_VersionCodePages(t *testing.T) {
+	// see testdata/resourceful/make-locales.py
+	f, err := eos.Open("./testdata/resourceful/resourceful32-ja.exe")
+	assert.NoError(t, err)
+	defer f.Close()
+
+	info, err := pelican.Probe(f, testProbeParams(t))
+	assert.NoError(t, err)
+
+	vp := info.VersionProperties
+	assert.EqualValues(t, "Pelican Games", vp["CompanyName"])
+	// widened Shift-JIS
+	assert.EqualValues(t, "ゲーム ランチャー", vp["FileDescription"])
+	// proper UTF-16
+	assert.EqualValues(t, "ペリカン・アドベンチャー", vp["ProductName"])
+	assert.EqualValues(t, "1.0.0", vp["ProductVersion"])
+
+	// the Russian table is picked from the Translation, over the German
+	// one listed before it, and its code page too, since the key has none
+	f, err = eos.Open("./testdata/resourceful/resourceful32-ru.exe")
+	assert.NoError(t, err)
+	defer f.Close()
+
+	info, err = pelican.Probe(f, testProbeParams(t))
+	assert.NoError(t, err)
+
+	vp = info.VersionProperties
+	assert.EqualValues(t, "Пеликан Геймс", vp["CompanyName"])
+	assert.EqualValues(t, "Приключения пеликана", vp["ProductName"])
+	assert.EqualValues(t, "приключения пеликана", info.CanonicalProductName)
+}
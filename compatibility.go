@@ -0,0 +1,180 @@
+package pelican
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/itchio/pelican/pe"
+)
+
+// WindowsVersion is an NT version number, as found in
+// PE headers (6.1 is Windows 7, 10.0 is Windows 10, etc.)
+type WindowsVersion struct {
+	Major uint32 `json:"major"`
+	Minor uint32 `json:"minor"`
+}
+
+func newWindowsVersion(major, minor uint16) WindowsVersion {
+	return WindowsVersion{Major: uint32(major), Minor: uint32(minor)}
+}
+
+func (wv WindowsVersion) Less(other WindowsVersion) bool {
+	if wv.Major != other.Major {
+		return wv.Major < other.Major
+	}
+	return wv.Minor < other.Minor
+}
+
+func (wv WindowsVersion) IsZero() bool {
+	return wv.Major == 0 && wv.Minor == 0
+}
+
+var (
+	WindowsNT4   = WindowsVersion{4, 0}
+	Windows2000  = WindowsVersion{5, 0}
+	WindowsXP    = WindowsVersion{5, 1}
+	WindowsXP64  = WindowsVersion{5, 2}
+	WindowsVista = WindowsVersion{6, 0}
+	Windows7     = WindowsVersion{6, 1}
+	Windows8     = WindowsVersion{6, 2}
+	Windows81    = WindowsVersion{6, 3}
+	Windows10    = WindowsVersion{10, 0}
+)
+
+var windowsVersionNames = map[WindowsVersion]string{
+	WindowsNT4:   "Windows NT 4.0",
+	Windows2000:  "Windows 2000",
+	WindowsXP:    "Windows XP",
+	WindowsXP64:  "Windows XP x64",
+	WindowsVista: "Windows Vista",
+	Windows7:     "Windows 7",
+	Windows8:     "Windows 8",
+	Windows81:    "Windows 8.1",
+	Windows10:    "Windows 10",
+}
+
+func (wv WindowsVersion) String() string {
+	if name, ok := windowsVersionNames[wv]; ok {
+		return name
+	}
+	if wv.Less(WindowsNT4) {
+		return fmt.Sprintf("Windows NT %d.%d", wv.Major, wv.Minor)
+	}
+	return fmt.Sprintf("Windows %d.%d", wv.Major, wv.Minor)
+}
+
+// cf. https://docs.microsoft.com/en-us/windows/win32/sysinfo/targeting-your-application-at-windows-8-1
+var supportedOSGUIDs = map[string]WindowsVersion{
+	"{e2011457-1546-43c5-a5fe-008deee3d3f0}": WindowsVista,
+	"{35138b9a-5d96-4fbd-8e2d-a2440225f93a}": Windows7,
+	"{4a2f28e3-53b9-4441-ba9c-d69d4a4a6e38}": Windows8,
+	"{1f676c76-80e1-4239-95bb-83d0f6d0da78}": Windows81,
+	"{8e0f7a12-bfb3-4fe8-b9a5-48fd50a15a9a}": Windows10,
+}
+
+// Functions only exported by Windows 10 and later. Importing
+// them statically prevents the binary from starting on anything older.
+// Those of older versions are in apiVersions.
+var windows10Imports = map[string]bool{
+	"kernel32.dll!SetThreadDescription":              true,
+	"kernel32.dll!GetThreadDescription":              true,
+	"kernel32.dll!CreatePseudoConsole":               true,
+	"kernel32.dll!ResizePseudoConsole":               true,
+	"kernel32.dll!ClosePseudoConsole":                true,
+	"user32.dll!GetDpiForWindow":                     true,
+	"user32.dll!GetDpiForSystem":                     true,
+	"user32.dll!GetSystemMetricsForDpi":              true,
+	"user32.dll!AdjustWindowRectExForDpi":            true,
+	"user32.dll!EnableNonClientDpiScaling":           true,
+	"user32.dll!SetThreadDpiAwarenessContext":        true,
+	"user32.dll!SetProcessDpiAwarenessContext":       true,
+	"user32.dll!GetWindowDpiAwarenessContext":        true,
+	"user32.dll!AreDpiAwarenessContextsEqual":        true,
+	"user32.dll!SystemParametersInfoForDpi":          true,
+	"user32.dll!GetAwarenessFromDpiAwarenessContext": true,
+	"ntdll.dll!RtlGetDeviceFamilyInfoEnum":           true,
+}
+
+// Compatibility summarizes which versions of Windows a binary
+// is expected to run on, according to different sources
+type Compatibility struct {
+	// Minimum versions declared in the optional header
+	MinOSVersion     WindowsVersion `json:"minOsVersion"`
+	SubsystemVersion WindowsVersion `json:"subsystemVersion"`
+
+	// Versions the manifest declares support for (supportedOS)
+	SupportedOS []WindowsVersion `json:"supportedOs,omitempty"`
+
+	// Minimum version inferred from statically imported
+	// functions and libraries, see inferImportsMinVersion
+	ImportsMinVersion WindowsVersion `json:"importsMinVersion"`
+	// Imports that require ImportsMinVersion
+	ImportsRequiring []string `json:"importsRequiring,omitempty"`
+
+	// Human-readable verdict, like "Windows 7+ (declared), Windows 10+ (by imports)"
+	Summary string `json:"summary"`
+
+	// DllCharacteristics flags of the optional header, which affect
+	// how the binary runs (ASLR, DEP, Remote Desktop shims, etc.)
+	Flags []*ImageFlag `json:"flags,omitempty"`
+}
+
+// DeclaredMinVersion returns the highest of the versions declared in
+// the optional header, both of which are checked by the loader.
+func (c *Compatibility) DeclaredMinVersion() WindowsVersion {
+	if c.MinOSVersion.Less(c.SubsystemVersion) {
+		return c.SubsystemVersion
+	}
+	return c.MinOSVersion
+}
+
+func computeCompatibility(info *PeInfo, pf *pe.File, symbols []pe.ImportedSymbol) *Compatibility {
+	c := &Compatibility{}
+
+	oh := pf.OptionalHeaderFields()
+	c.MinOSVersion = newWindowsVersion(oh.MajorOperatingSystemVersion, oh.MinorOperatingSystemVersion)
+	c.SubsystemVersion = newWindowsVersion(oh.MajorSubsystemVersion, oh.MinorSubsystemVersion)
+
+	if info.AssemblyInfo != nil {
+		for _, id := range info.AssemblyInfo.SupportedOS {
+			if wv, ok := supportedOSGUIDs[strings.ToLower(id)]; ok {
+				c.SupportedOS = append(c.SupportedOS, wv)
+			}
+		}
+	}
+
+	c.ImportsMinVersion, c.ImportsRequiring = inferImportsMinVersion(info.Imports, symbols)
+	c.summarize()
+	c.Flags = imageFlags(pf)
+
+	return c
+}
+
+func (c *Compatibility) summarize() {
+	declared := c.DeclaredMinVersion()
+
+	var parts []string
+	if !declared.IsZero() {
+		parts = append(parts, fmt.Sprintf("%s+ (declared)", declared))
+	}
+	if len(c.SupportedOS) > 0 {
+		lowest, highest := c.SupportedOS[0], c.SupportedOS[0]
+		for _, wv := range c.SupportedOS {
+			if wv.Less(lowest) {
+				lowest = wv
+			}
+			if highest.Less(wv) {
+				highest = wv
+			}
+		}
+		if lowest == highest {
+			parts = append(parts, fmt.Sprintf("%s (manifest)", lowest))
+		} else {
+			parts = append(parts, fmt.Sprintf("%s to %s (manifest)", lowest, highest))
+		}
+	}
+	if declared.Less(c.ImportsMinVersion) {
+		parts = append(parts, fmt.Sprintf("%s+ (by imports)", c.ImportsMinVersion))
+	}
+	c.Summary = strings.Join(parts, ", ")
+}
@@ -0,0 +1,77 @@
+package pelican
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/itchio/pelican/pe"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_InferImportsMinVersion(t *testing.T) {
+	wv, culprits := inferImportsMinVersion(
+		[]string{"KERNEL32.dll", "USER32.dll"},
+		importedSymbols(
+			"GetTickCount64:KERNEL32.dll",
+			"GetDpiForWindow:USER32.dll",
+			"SetThreadDescription:KERNEL32.dll",
+			"CreateWindowExW:USER32.dll",
+			"#17:COMCTL32.dll",
+		),
+	)
+	assert.EqualValues(t, Windows10, wv)
+	assert.EqualValues(t, []string{"kernel32.dll!SetThreadDescription", "user32.dll!GetDpiForWindow"}, culprits)
+
+	wv, culprits = inferImportsMinVersion([]string{"api-ms-win-core-synch-l1-2-0.dll", "SHCORE.dll"}, nil)
+	assert.EqualValues(t, Windows81, wv)
+	assert.EqualValues(t, []string{"shcore.dll"}, culprits)
+
+	// the Universal CRT runs on Vista, other API sets need Windows 7
+	wv, culprits = inferImportsMinVersion([]string{"KERNEL32.dll", "api-ms-win-crt-runtime-l1-1-0.dll", "api-ms-win-crt-stdio-l1-1-0.dll"}, nil)
+	assert.EqualValues(t, WindowsVista, wv)
+	assert.EqualValues(t, []string{"api-ms-win-crt-runtime-l1-1-0.dll", "api-ms-win-crt-stdio-l1-1-0.dll"}, culprits)
+
+	wv, culprits = inferImportsMinVersion([]string{"api-ms-win-crt-runtime-l1-1-0.dll", "api-ms-win-core-synch-l1-2-0.dll"}, nil)
+	assert.EqualValues(t, Windows7, wv)
+	assert.EqualValues(t, []string{"api-ms-win-core-synch-l1-2-0.dll"}, culprits)
+
+	wv, culprits = inferImportsMinVersion(nil, importedSymbols("ExitProcess:KERNEL32.dll"))
+	assert.True(t, wv.IsZero())
+	assert.Empty(t, culprits)
+}
+
+func Test_ImageFlags(t *testing.T) {
+	pf := &pe.File{OptionalHeader: &pe.OptionalHeader32{
+		DllCharacteristics: pe.IMAGE_DLLCHARACTERISTICS_NO_ISOLATION |
+			pe.IMAGE_DLLCHARACTERISTICS_NO_BIND |
+			pe.IMAGE_DLLCHARACTERISTICS_TERMINAL_SERVER_AWARE | 0x1,
+	}}
+
+	flags := imageFlags(pf)
+	var names []string
+	for _, f := range flags {
+		names = append(names, f.Name)
+	}
+	assert.EqualValues(t, []string{"0x1", "NO_ISOLATION", "NO_BIND", "TERMINAL_SERVER_AWARE"}, names)
+	assert.Empty(t, flags[0].Effect)
+	assert.Contains(t, flags[1].Effect, "manifest is ignored")
+	assert.Contains(t, flags[3].Effect, "Remote Desktop")
+
+	assert.Empty(t, imageFlags(&pe.File{OptionalHeader: &pe.OptionalHeader64{}}))
+}
+
+// importedSymbols parses symbols formatted like pe.ImportedSymbol.String
+func importedSymbols(symbols ...string) []pe.ImportedSymbol {
+	var res []pe.ImportedSymbol
+	for _, s := range symbols {
+		i := strings.LastIndex(s, ":")
+		sym := pe.ImportedSymbol{Name: s[:i], DLL: s[i+1:]}
+		if strings.HasPrefix(sym.Name, "#") {
+			ordinal, _ := strconv.Atoi(sym.Name[1:])
+			sym.Name, sym.Ordinal = "", uint32(ordinal)
+		}
+		res = append(res, sym)
+	}
+	return res
+}
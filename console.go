@@ -0,0 +1,85 @@
+package pelican
+
+import (
+	"strings"
+
+	"github.com/itchio/pelican/pe"
+)
+
+// ConsoleBehavior is set for binaries that deal with consoles in a way
+// their subsystem doesn't tell, which often surprises users at launch:
+// GUI binaries that open a console window anyway, console binaries
+// that get rid of theirs (which flashes on screen), etc.
+type ConsoleBehavior struct {
+	// GUI binary that opens a console window (AllocConsole)
+	AllocatesConsole bool `json:"allocatesConsole,omitempty"`
+	// GUI binary that writes to the console it was started
+	// from, if any (AttachConsole)
+	AttachesConsole bool `json:"attachesConsole,omitempty"`
+	// Console binary that detaches from its console (FreeConsole) or
+	// hides its window, so a console window flashes at launch
+	HidesConsole bool `json:"hidesConsole,omitempty"`
+	// Console binary with a WinMain function, usually linked for the
+	// wrong subsystem. Only known for binaries with a symbol table.
+	HasWinMain bool `json:"hasWinMain,omitempty"`
+	// Human-readable explanations of the above
+	Notes []string `json:"notes"`
+}
+
+// detectConsoleBehavior returns nil if the subsystem of info tells
+// the whole story
+func detectConsoleBehavior(info *PeInfo, pf *pe.File, symbols []pe.ImportedSymbol) *ConsoleBehavior {
+	imported := make(map[string]bool)
+	for _, sym := range symbols {
+		if !sym.ByOrdinal() {
+			imported[sym.Name] = true
+		}
+	}
+
+	cb := &ConsoleBehavior{}
+	switch info.Subsystem {
+	case SubsystemGUI:
+		if imported["AllocConsole"] {
+			cb.AllocatesConsole = true
+			cb.Notes = append(cb.Notes, "GUI executable opens a console window (AllocConsole)")
+		}
+		if imported["AttachConsole"] {
+			cb.AttachesConsole = true
+			cb.Notes = append(cb.Notes, "GUI executable writes to the console it's started from (AttachConsole)")
+		}
+	case SubsystemConsole:
+		switch {
+		case imported["FreeConsole"]:
+			cb.HidesConsole = true
+			cb.Notes = append(cb.Notes, "Console executable detaches from its console (FreeConsole), a console window flashes at launch")
+		case imported["GetConsoleWindow"] && imported["ShowWindow"]:
+			cb.HidesConsole = true
+			cb.Notes = append(cb.Notes, "Console executable may hide its console window, a console window flashes at launch")
+		}
+		if hasWinMain(pf) {
+			cb.HasWinMain = true
+			cb.Notes = append(cb.Notes, "Console executable has a WinMain function, it was probably meant to be a GUI executable")
+		}
+	}
+
+	if len(cb.Notes) == 0 {
+		return nil
+	}
+	return cb
+}
+
+// hasWinMain looks for WinMain in the COFF symbol table, which
+// MinGW keeps unless the binary is stripped
+func hasWinMain(pf *pe.File) bool {
+	for _, sym := range pf.Symbols {
+		// 32-bit stdcall names are decorated, like "_WinMain@16"
+		name := strings.TrimPrefix(sym.Name, "_")
+		if i := strings.Index(name, "@"); i >= 0 {
+			name = name[:i]
+		}
+		if name == "WinMain" || name == "wWinMain" {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,50 @@
+package pelican
+
+import (
+	"testing"
+
+	"github.com/itchio/pelican/pe"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ConsoleBehavior(t *testing.T) {
+	detect := func(subsystem Subsystem, symbols []string, coffSymbols ...string) *ConsoleBehavior {
+		pf := &pe.File{}
+		for _, name := range coffSymbols {
+			pf.Symbols = append(pf.Symbols, &pe.Symbol{Name: name})
+		}
+		return detectConsoleBehavior(&PeInfo{Subsystem: subsystem}, pf, importedSymbols(symbols...))
+	}
+
+	cb := detect(SubsystemGUI, []string{"AllocConsole:KERNEL32.dll", "AttachConsole:KERNEL32.dll", "CreateWindowExW:USER32.dll"})
+	if assert.NotNil(t, cb) {
+		assert.True(t, cb.AllocatesConsole)
+		assert.True(t, cb.AttachesConsole)
+		assert.False(t, cb.HidesConsole)
+		assert.Len(t, cb.Notes, 2)
+	}
+
+	// that's expected from console executables
+	assert.Nil(t, detect(SubsystemConsole, []string{"AllocConsole:KERNEL32.dll"}))
+
+	cb = detect(SubsystemConsole, []string{"GetConsoleWindow:KERNEL32.dll", "ShowWindow:USER32.dll"})
+	if assert.NotNil(t, cb) {
+		assert.True(t, cb.HidesConsole)
+	}
+	assert.Nil(t, detect(SubsystemConsole, []string{"GetConsoleWindow:KERNEL32.dll"}))
+
+	cb = detect(SubsystemConsole, []string{"FreeConsole:KERNEL32.dll"}, "_main", "_WinMain@16")
+	if assert.NotNil(t, cb) {
+		assert.True(t, cb.HidesConsole)
+		assert.True(t, cb.HasWinMain)
+		assert.EqualValues(t, []string{
+			"Console executable detaches from its console (FreeConsole), a console window flashes at launch",
+			"Console executable has a WinMain function, it was probably meant to be a GUI executable",
+		}, cb.Notes)
+	}
+	cb = detect(SubsystemConsole, nil, "wWinMain")
+	if assert.NotNil(t, cb) {
+		assert.True(t, cb.HasWinMain)
+	}
+	assert.Nil(t, detect(SubsystemGUI, nil, "WinMain"))
+}
@@ -0,0 +1,112 @@
+package pelican
+
+import (
+	"regexp"
+	"strings"
+)
+
+// CRTLinkage describes how a binary uses the C runtime
+type CRTLinkage string
+
+const (
+	// The C runtime is linked statically (no runtime DLL imported)
+	CRTStatic CRTLinkage = "static"
+	// msvcrt.dll, which ships with Windows. Used by MinGW and Visual C++ 6.
+	CRTSystem CRTLinkage = "system"
+	// msvcr70.dll to msvcr120.dll (Visual C++ 2002 to 2013)
+	CRTLegacy CRTLinkage = "legacy"
+	// The Universal CRT, ucrtbase.dll or api-ms-win-crt-*.dll,
+	// usually with vcruntime140.dll (Visual C++ 2015 and later)
+	CRTUniversal CRTLinkage = "ucrt"
+)
+
+// CRTInfo describes the C runtime a binary depends on
+type CRTInfo struct {
+	Linkage CRTLinkage `json:"linkage"`
+	// Runtime DLLs the binary imports, as found in PeInfo.Imports
+	Libraries []string `json:"libraries,omitempty"`
+	// Version of the Visual C++ Redistributable that must be installed,
+	// for example "2013" or "2015-2022". Empty for static and system
+	// runtimes, which have no prerequisites, and for binaries that only
+	// use the Universal CRT and require Windows 10, which ships it.
+	Redist string `json:"redist,omitempty"`
+}
+
+// also matches the debug builds (msvcr120d.dll, ucrtbased.dll, etc.)
+var (
+	legacyCRTRegexp    = regexp.MustCompile(`^msvc[rp](\d+)d?\.dll$`)
+	universalCRTRegexp = regexp.MustCompile(`^(ucrtbased?|api-ms-win-crt-.*|(vcruntime|msvcp|concrt|vccorlib)140(_\d+)?d?)\.dll$`)
+)
+
+// Visual C++ version number to Redistributable name
+var legacyCRTRedists = map[string]string{
+	"70":  "2002",
+	"71":  "2003",
+	"80":  "2005",
+	"90":  "2008",
+	"100": "2010",
+	"110": "2012",
+	"120": "2013",
+}
+
+// classifyCRT looks at the imports of info to figure out how it uses the
+// C runtime. When no runtime DLL is imported, it's considered static if
+// the entry point is a known C/C++ CRT stub, otherwise nil is returned.
+// It must run after computeCompatibility, which tells whether the
+// binary may run on versions of Windows older than 10.
+func classifyCRT(info *PeInfo) *CRTInfo {
+	var universal, legacy, system []string
+	var legacyRedist string
+	needsRedist := false
+	for _, lib := range info.Imports {
+		name := strings.ToLower(lib)
+		switch {
+		case name == "msvcrt.dll":
+			system = append(system, lib)
+		case universalCRTRegexp.MatchString(name):
+			universal = append(universal, lib)
+			// the Universal CRT itself ships with Windows 10 and later,
+			// older versions get it from the Redistributable
+			isUCRT := strings.HasPrefix(name, "ucrtbase") || strings.HasPrefix(name, "api-ms-win-crt-")
+			if !isUCRT || !requiresWindows10(info) {
+				needsRedist = true
+			}
+		default:
+			if m := legacyCRTRegexp.FindStringSubmatch(name); m != nil {
+				if redist, ok := legacyCRTRedists[m[1]]; ok {
+					legacy = append(legacy, lib)
+					legacyRedist = redist
+				}
+			}
+		}
+	}
+
+	switch {
+	case len(universal) > 0:
+		ci := &CRTInfo{Linkage: CRTUniversal, Libraries: universal}
+		if needsRedist {
+			ci.Redist = "2015-2022"
+		}
+		return ci
+	case len(legacy) > 0:
+		return &CRTInfo{Linkage: CRTLegacy, Libraries: legacy, Redist: legacyRedist}
+	case len(system) > 0:
+		return &CRTInfo{Linkage: CRTSystem, Libraries: system}
+	}
+
+	switch info.EntryPointStub {
+	case StubMSVC, StubMinGW:
+		return &CRTInfo{Linkage: CRTStatic}
+	}
+	return nil
+}
+
+// requiresWindows10 returns true if info can't run on anything older
+// than Windows 10, according to its optional header or its imports
+func requiresWindows10(info *PeInfo) bool {
+	c := info.Compatibility
+	if c == nil {
+		return false
+	}
+	return !c.DeclaredMinVersion().Less(Windows10) || !c.ImportsMinVersion.Less(Windows10)
+}
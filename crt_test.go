@@ -0,0 +1,50 @@
+package pelican
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ClassifyCRT(t *testing.T) {
+	crt := classifyCRT(&PeInfo{Imports: []string{"KERNEL32.dll", "MSVCP140.dll", "VCRUNTIME140.dll", "api-ms-win-crt-runtime-l1-1-0.dll"}})
+	assert.EqualValues(t, CRTUniversal, crt.Linkage)
+	assert.EqualValues(t, "2015-2022", crt.Redist)
+	assert.EqualValues(t, []string{"MSVCP140.dll", "VCRUNTIME140.dll", "api-ms-win-crt-runtime-l1-1-0.dll"}, crt.Libraries)
+
+	// the UCRT alone needs no redistributable on Windows 10...
+	crt = classifyCRT(&PeInfo{
+		Imports:       []string{"ucrtbase.dll"},
+		Compatibility: &Compatibility{MinOSVersion: Windows10},
+	})
+	assert.EqualValues(t, CRTUniversal, crt.Linkage)
+	assert.EqualValues(t, "", crt.Redist)
+
+	crt = classifyCRT(&PeInfo{
+		Imports:       []string{"api-ms-win-crt-runtime-l1-1-0.dll"},
+		Compatibility: &Compatibility{MinOSVersion: Windows7, ImportsMinVersion: Windows10},
+	})
+	assert.EqualValues(t, "", crt.Redist)
+
+	// ...but older versions get it from the redistributable
+	crt = classifyCRT(&PeInfo{
+		Imports:       []string{"KERNEL32.dll", "api-ms-win-crt-runtime-l1-1-0.dll", "api-ms-win-crt-stdio-l1-1-0.dll"},
+		Compatibility: &Compatibility{MinOSVersion: WindowsVista, SubsystemVersion: WindowsVista},
+	})
+	assert.EqualValues(t, CRTUniversal, crt.Linkage)
+	assert.EqualValues(t, "2015-2022", crt.Redist)
+	assert.EqualValues(t, []string{"api-ms-win-crt-runtime-l1-1-0.dll", "api-ms-win-crt-stdio-l1-1-0.dll"}, crt.Libraries)
+
+	crt = classifyCRT(&PeInfo{Imports: []string{"ucrtbase.dll"}})
+	assert.EqualValues(t, "2015-2022", crt.Redist)
+
+	crt = classifyCRT(&PeInfo{Imports: []string{"MSVCR120.dll", "MSVCP120D.dll"}})
+	assert.EqualValues(t, CRTLegacy, crt.Linkage)
+	assert.EqualValues(t, "2013", crt.Redist)
+
+	crt = classifyCRT(&PeInfo{Imports: []string{"KERNEL32.dll"}, EntryPointStub: StubMSVC})
+	assert.EqualValues(t, CRTStatic, crt.Linkage)
+
+	// Delphi has its own runtime library
+	assert.Nil(t, classifyCRT(&PeInfo{Imports: []string{"KERNEL32.dll"}, EntryPointStub: StubDelphi}))
+}
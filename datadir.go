@@ -0,0 +1,86 @@
+package pelican
+
+import (
+	"io"
+
+	"github.com/itchio/pelican/pe"
+	"github.com/pkg/errors"
+)
+
+// DataDirectoryFunc receives the raw contents of a data directory,
+// index being one of the pe.IMAGE_DIRECTORY_ENTRY_* constants.
+type DataDirectoryFunc func(index int, dir pe.DataDirectory, data []byte)
+
+// data directories pelican parses itself, which are
+// not passed to ProbeParams.OnUnknownDataDirectory
+var modeledDataDirectories = map[int]bool{
+	pe.IMAGE_DIRECTORY_ENTRY_IMPORT:       true,
+	pe.IMAGE_DIRECTORY_ENTRY_RESOURCE:     true,
+	pe.IMAGE_DIRECTORY_ENTRY_SECURITY:     true,
+	pe.IMAGE_DIRECTORY_ENTRY_EXPORT:       true,
+	pe.IMAGE_DIRECTORY_ENTRY_DELAY_IMPORT: true,
+	pe.IMAGE_DIRECTORY_ENTRY_TLS:          true,
+	pe.IMAGE_DIRECTORY_ENTRY_DEBUG:        true,
+	pe.IMAGE_DIRECTORY_ENTRY_LOAD_CONFIG:  true,
+}
+
+func dataDirectories(pf *pe.File) []pe.DataDirectory {
+	oh := pf.OptionalHeaderFields()
+	return oh.Directories()
+}
+
+// readDataDirectory returns the contents of a data directory. They're
+// addressed by RVA, except for the certificate table, which is addressed
+// by file offset and isn't mapped in memory.
+func readDataDirectory(r io.ReaderAt, size int64, pf *pe.File, index int, dd pe.DataDirectory) ([]byte, error) {
+	if index == pe.IMAGE_DIRECTORY_ENTRY_SECURITY {
+		if int64(dd.VirtualAddress)+int64(dd.Size) > size {
+			return nil, errors.Errorf("certificate table (%d bytes at %x) extends past end of file", dd.Size, dd.VirtualAddress)
+		}
+		data := make([]byte, dd.Size)
+		_, err := r.ReadAt(data, int64(dd.VirtualAddress))
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return data, nil
+	}
+
+	for _, s := range pf.Sections {
+		start := uint64(s.VirtualAddress)
+		end := start + uint64(s.Size)
+		if uint64(dd.VirtualAddress) < start || uint64(dd.VirtualAddress)+uint64(dd.Size) > end {
+			continue
+		}
+
+		data, err := s.DataRange(int64(uint64(dd.VirtualAddress)-start), int64(dd.Size))
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		return data, nil
+	}
+	return nil, errors.Errorf("data directory (%d bytes at RVA %x) is not contained in any section", dd.Size, dd.VirtualAddress)
+}
+
+func (params *ProbeParams) visitUnknownDataDirectories(info *PeInfo, r io.ReaderAt, size int64, pf *pe.File) error {
+	for index, dd := range dataDirectories(pf) {
+		if modeledDataDirectories[index] || dd.Size == 0 {
+			continue
+		}
+
+		if !params.reserveOrWarn(info, int64(dd.Size), "data directory %d", index) {
+			continue
+		}
+		data, err := readDataDirectory(r, size, pf, index, dd)
+		if err != nil {
+			params.release(int64(dd.Size))
+			if params.Strict {
+				return errors.WithMessagef(err, "while reading data directory %d", index)
+			}
+			params.warn(info, WarningDataDirectoryInvalid, err, "Could not read data directory %d", index)
+			continue
+		}
+		params.OnUnknownDataDirectory(index, dd, data)
+		params.release(int64(dd.Size))
+	}
+	return nil
+}
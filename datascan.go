@@ -0,0 +1,83 @@
+package pelican
+
+import (
+	"io"
+
+	"github.com/itchio/pelican/pe"
+	"github.com/pkg/errors"
+)
+
+// Data sections are scanned for strings in chunks, so that large ones
+// are never read into memory all at once
+const (
+	// only this much of the data sections are scanned
+	maxDataScanSize   = 64 * 1024 * 1024
+	dataScanChunkSize = 1024 * 1024
+	// longer than any string we look for, so that none is missed when
+	// it straddles two chunks
+	dataScanOverlap = 256
+)
+
+// scanDataSections calls each visitor with successive chunks of the
+// initialized, non-executable sections of pf. Consecutive chunks overlap
+// by dataScanOverlap bytes, so visitors may see the same string twice.
+func scanDataSections(pf *pe.File, visitors ...func(chunk []byte)) error {
+	budget := int64(maxDataScanSize)
+	pbuf := getBuffer()
+	defer putBuffer(pbuf)
+	buf := *pbuf
+	for _, s := range pf.Sections {
+		if s.Characteristics&pe.IMAGE_SCN_CNT_INITIALIZED_DATA == 0 || s.Characteristics&pe.IMAGE_SCN_MEM_EXECUTE != 0 {
+			continue
+		}
+
+		r := s.Open()
+		kept := 0
+		for budget > 0 {
+			n, err := io.ReadFull(r, buf[kept:])
+			if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+				return errors.WithMessagef(err, "while reading section %s", s.Name)
+			}
+			if n == 0 {
+				break
+			}
+			budget -= int64(n)
+
+			chunk := buf[:kept+n]
+			for _, visit := range visitors {
+				visit(chunk)
+			}
+
+			kept = copy(buf, chunk[len(chunk)-min(len(chunk), dataScanOverlap):])
+		}
+	}
+	return nil
+}
+
+// narrowUTF16 returns the printable ASCII characters of chunk that are
+// encoded as UTF-16LE, at either alignment. Runs of them are terminated
+// by NUL bytes. A run that reaches the end of chunk is left out, since
+// it may be cut off.
+func narrowUTF16(chunk []byte) []byte {
+	var res []byte
+	for align := 0; align < 2; align++ {
+		runStart := len(res)
+		for i := align; i+1 < len(chunk); i += 2 {
+			if chunk[i+1] == 0 && chunk[i] >= 0x20 && chunk[i] <= 0x7e {
+				res = append(res, chunk[i])
+			} else if len(res) > runStart {
+				res = append(res, 0)
+				runStart = len(res)
+			}
+		}
+		res = res[:runStart]
+	}
+	return res
+}
+
+func min(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,85 @@
+package pelican
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/itchio/pelican/pe"
+	"github.com/pkg/errors"
+)
+
+// VS_FIXEDFILEINFO.dwFileFlags
+const (
+	vsFFDebug      = 0x1
+	vsFFPrerelease = 0x2
+)
+
+// Debug builds of the Visual C++ runtime. They're not redistributable,
+// so binaries linked against them only run where Visual Studio is installed.
+var debugCRTRegexp = regexp.MustCompile(`^(msvcrt|msvc[rp]\d+|vcruntime\d+(_1)?|ucrtbase|concrt\d+|vcomp\d+)d\.dll$`)
+
+func isDebugCRT(lib string) bool {
+	return debugCRTRegexp.MatchString(strings.ToLower(lib))
+}
+
+func importsDebugCRT(imports []string) bool {
+	for _, lib := range imports {
+		if isDebugCRT(lib) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyFileFlags sets IsDebugBuild and IsPrerelease from the
+// dwFileFlags of VS_FIXEDFILEINFO
+func applyFileFlags(info *PeInfo, ffi *VsFixedFileInfo) {
+	flags := ffi.DwFileFlags & ffi.DwFileFlagsMask
+	if flags&vsFFDebug != 0 {
+		info.IsDebugBuild = true
+	}
+	if flags&vsFFPrerelease != 0 {
+		info.IsPrerelease = true
+	}
+}
+
+// PDBInfo identifies the PDB file that holds the debug information of
+// a binary, from its CodeView record, see pe.File.CodeView
+type PDBInfo struct {
+	// Path of the PDB when the binary was linked, for example
+	// "C:\\build\\game\\Release\\game.pdb"
+	Path string `json:"path"`
+	// For example "3F2504E0-4F89-11D3-9A0C-0305E82C3301", empty for
+	// the PDB 2.0 format (NB10), which is identified by a timestamp
+	GUID string `json:"guid,omitempty"`
+	Age  uint32 `json:"age"`
+	// What symbol servers file the PDB under, for example
+	// "3F2504E04F8911D39A0C0305E82C33012A": it's at
+	// <base name of Path>/<key>/<base name of Path>
+	SymbolServerKey string `json:"symbolServerKey"`
+}
+
+// parseCodeView fills info.PDB
+func (params *ProbeParams) parseCodeView(info *PeInfo, pf *pe.File) error {
+	cv, err := pf.CodeView()
+	if err != nil {
+		if params.Strict {
+			return errors.WithMessage(err, "while parsing CodeView record")
+		}
+		params.warn(info, WarningDataDirectoryInvalid, err, "Could not parse CodeView record")
+		return nil
+	}
+	if cv == nil {
+		return nil
+	}
+
+	info.PDB = &PDBInfo{
+		Path:            cv.PDBPath,
+		Age:             cv.Age,
+		SymbolServerKey: cv.SymbolServerKey(),
+	}
+	if cv.Signature == "RSDS" {
+		info.PDB.GUID = cv.GUID.String()
+	}
+	return nil
+}
@@ -0,0 +1,19 @@
+package pelican
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_IsDebugCRT(t *testing.T) {
+	for _, lib := range []string{"msvcrtd.dll", "MSVCR100D.dll", "msvcp140d.dll", "VCRUNTIME140D.dll", "vcruntime140_1d.dll", "ucrtbased.dll"} {
+		assert.True(t, isDebugCRT(lib), lib)
+	}
+	for _, lib := range []string{"msvcrt.dll", "MSVCR100.dll", "vcruntime140.dll", "ucrtbase.dll", "d3d11.dll", "msvcrtd.exe"} {
+		assert.False(t, isDebugCRT(lib), lib)
+	}
+
+	assert.True(t, importsDebugCRT([]string{"KERNEL32.dll", "ucrtbased.dll"}))
+	assert.False(t, importsDebugCRT(nil))
+}
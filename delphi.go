@@ -0,0 +1,103 @@
+package pelican
+
+import (
+	"fmt"
+
+	"github.com/itchio/pelican/pe"
+)
+
+// DelphiInfo is set for binaries that were probably built with
+// Borland / Embarcadero Delphi or C++ Builder
+type DelphiInfo struct {
+	// Probable version range, for example "2009 or later".
+	// Empty if the evidence doesn't tell.
+	Version string `json:"version,omitempty"`
+	// What gave it away, for example "DVCLAL resource"
+	Evidence []string `json:"evidence"`
+}
+
+// RCDATA resources only the Delphi / C++ Builder linker emits
+var delphiResources = map[string]bool{
+	// license information, checked at runtime by the VCL
+	"DVCLAL": true,
+	// units contained in the binary
+	"PACKAGEINFO": true,
+}
+
+// noteDelphiResource is called by parseResources for every named RCDATA
+// resource. Those are conclusive on their own, see detectDelphi.
+func noteDelphiResource(info *PeInfo, name string) {
+	if !delphiResources[name] {
+		return
+	}
+	if info.Delphi == nil {
+		info.Delphi = &DelphiInfo{}
+	}
+	info.Delphi.Evidence = append(info.Delphi.Evidence, fmt.Sprintf("%s resource", name))
+}
+
+// detectDelphi completes the evidence gathered from resources with
+// weaker signals (section names, imports, entry point), which only
+// count when there's more than one of them.
+func detectDelphi(info *PeInfo, pf *pe.File) {
+	d := info.Delphi
+	if d == nil {
+		d = &DelphiInfo{}
+	}
+	conclusive := len(d.Evidence) > 0
+
+	if info.EntryPointStub == StubDelphi {
+		d.Evidence = append(d.Evidence, "entry point stub")
+	}
+
+	// Up to Delphi 2007, the linker named sections CODE, DATA and BSS.
+	// From Delphi 2009 on, they're named like everyone else's, except
+	// for .itext (initialization code) and .didata (delay imports).
+	var oldSections, newSections bool
+	for _, s := range pf.Sections {
+		switch s.Name {
+		case "CODE", "DATA", "BSS":
+			oldSections = true
+		case ".itext", ".didata":
+			newSections = true
+		}
+	}
+	if oldSections {
+		d.Evidence = append(d.Evidence, "CODE/DATA/BSS sections")
+	}
+	if newSections {
+		d.Evidence = append(d.Evidence, ".itext/.didata sections")
+	}
+
+	// Delphi's linker doesn't emit the import lookup table, so imports
+	// are only listed by the import address table
+	ids, err := pf.ImportDescriptors()
+	if err == nil && len(ids) > 0 {
+		noLookupTable := true
+		for _, id := range ids {
+			if id.OriginalFirstThunk != 0 {
+				noLookupTable = false
+				break
+			}
+		}
+		if noLookupTable {
+			d.Evidence = append(d.Evidence, "imports without OriginalFirstThunk")
+		}
+	}
+
+	if !conclusive && len(d.Evidence) < 2 {
+		info.Delphi = nil
+		return
+	}
+
+	switch {
+	case info.Arch == ArchAmd64:
+		// 64-bit support came with XE2
+		d.Version = "XE2 or later"
+	case newSections:
+		d.Version = "2009 or later"
+	case oldSections:
+		d.Version = "2007 or earlier"
+	}
+	info.Delphi = d
+}
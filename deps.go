@@ -0,0 +1,178 @@
+package pelican
+
+import (
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/itchio/httpkit/eos"
+	"github.com/pkg/errors"
+)
+
+// DLLResolver opens the files a DependencyGraph needs, wherever they
+// are: on disk, in an archive, on a remote server, etc.
+type DLLResolver interface {
+	// Open opens the file at the slash-separated path p, which should
+	// be looked up case-insensitively, as the loader does. It returns an
+	// error satisfying errors.Is(err, fs.ErrNotExist) if there's none.
+	Open(p string) (eos.File, error)
+}
+
+// DLLResolverFunc is a function used as a DLLResolver
+type DLLResolverFunc func(p string) (eos.File, error)
+
+// Open calls f(p)
+func (f DLLResolverFunc) Open(p string) (eos.File, error) {
+	return f(p)
+}
+
+// FSResolver returns a DLLResolver for the files of fsys (see DirFS,
+// zip.Reader). Files that don't support random access are read into memory.
+func FSResolver(fsys fs.FS) DLLResolver {
+	return &fsResolver{fsys: fsys}
+}
+
+type fsResolver struct {
+	fsys fs.FS
+}
+
+func (r *fsResolver) Open(p string) (eos.File, error) {
+	f, err := r.fsys.Open(p)
+	if errors.Is(err, fs.ErrNotExist) {
+		f, err = r.openFold(p)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ef, err := asEOSFile(f)
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if _, ok := f.(eos.File); !ok {
+		// it's been read into memory
+		f.Close()
+	}
+	return ef, nil
+}
+
+// openFold opens p, ignoring the case of its base name
+func (r *fsResolver) openFold(p string) (fs.File, error) {
+	dir, base := path.Split(p)
+	dir = path.Clean(dir)
+	entries, err := fs.ReadDir(r.fsys, dir)
+	if err == nil {
+		for _, e := range entries {
+			if strings.EqualFold(e.Name(), base) && e.Type().IsRegular() {
+				return r.fsys.Open(path.Join(dir, e.Name()))
+			}
+		}
+	}
+	return nil, &fs.PathError{Op: "open", Path: p, Err: fs.ErrNotExist}
+}
+
+// DependencyGraph probes executables and the app-local DLLs they load,
+// opening files only when an import refers to them, instead of probing
+// a whole directory upfront like ProbeDir. Executables can be added one
+// at a time, and DLLs they share are only probed once.
+//
+// Side-by-side assemblies aren't looked up, since the resolver can't
+// list them: DLLs they provide are considered app-local or system DLLs.
+type DependencyGraph struct {
+	// Probed files, keyed by slash-separated path, as opened
+	Files map[string]*PeInfo
+
+	resolver DLLResolver
+	params   ProbeParams
+	// lower-cased paths of files probed or looked for
+	visited map[string]bool
+}
+
+// NewDependencyGraph returns an empty graph that opens files with resolver
+func NewDependencyGraph(resolver DLLResolver, params ProbeParams) *DependencyGraph {
+	params.setDefaults()
+	return &DependencyGraph{
+		Files:    make(map[string]*PeInfo),
+		resolver: resolver,
+		params:   params,
+		visited:  make(map[string]bool),
+	}
+}
+
+// Add probes the executable at the slash-separated path exe, then the
+// app-local DLLs it imports, transitively. DLLs that can't be found are
+// left out, they're reported as missing by LoadOrder. Files that can't be
+// probed are skipped with a warning, except in strict mode.
+func (g *DependencyGraph) Add(exe string) error {
+	exe = path.Clean(exe)
+	if g.Files[exe] != nil {
+		return nil
+	}
+
+	info, err := g.probe(exe)
+	if err != nil {
+		return errors.WithMessagef(err, "while probing %s", exe)
+	}
+	g.visited[strings.ToLower(exe)] = true
+	g.Files[exe] = info
+
+	appDir := path.Dir(exe)
+	queue := []*PeInfo{info}
+	for len(queue) > 0 {
+		importer := queue[0]
+		queue = queue[1:]
+
+		for _, imp := range importsOf(importer) {
+			lib := imp.lib
+			name := strings.ToLower(lib)
+			// the application directory isn't searched for those
+			if knownDLLs[name] || isAPISet(name) {
+				continue
+			}
+
+			p := path.Join(appDir, lib)
+			if g.visited[strings.ToLower(p)] {
+				continue
+			}
+			g.visited[strings.ToLower(p)] = true
+
+			dll, err := g.probe(p)
+			if err != nil {
+				if errors.Is(err, fs.ErrNotExist) {
+					continue
+				}
+				if g.params.Strict {
+					return errors.WithMessagef(err, "while probing %s", p)
+				}
+				g.params.Consumer.Warnf("Could not probe %s: %+v", p, err)
+				continue
+			}
+			g.Files[p] = dll
+			queue = append(queue, dll)
+		}
+	}
+	return nil
+}
+
+func (g *DependencyGraph) probe(p string) (*PeInfo, error) {
+	f, err := g.resolver.Open(p)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	defer f.Close()
+
+	return Probe(f, g.params)
+}
+
+// LoadOrder returns how the imports of every executable added resolve,
+// see DirInfo.LoadOrder
+func (g *DependencyGraph) LoadOrder() []*LoadOrderReport {
+	return buildLoadOrder(g.Files, nil, g.BitnessMismatches())
+}
+
+// BitnessMismatches returns the app-local DLLs of another architecture
+// executables added load, see DirInfo.BitnessMismatches
+func (g *DependencyGraph) BitnessMismatches() []*BitnessMismatch {
+	return findBitnessMismatches(g.Files, nil)
+}
@@ -0,0 +1,95 @@
+package pelican_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"testing"
+	"testing/fstest"
+
+	"github.com/itchio/httpkit/eos"
+	"github.com/itchio/pelican"
+	"github.com/stretchr/testify/assert"
+)
+
+// importing renames the msvcrt.dll import of a hello fixture
+func importing(t *testing.T, src string, dll string) *fstest.MapFile {
+	data, err := ioutil.ReadFile(src)
+	assert.NoError(t, err)
+	assert.EqualValues(t, len("msvcrt.dll"), len(dll))
+	data = bytes.Replace(data, []byte("msvcrt.dll"), []byte(dll), 1)
+	return &fstest.MapFile{Data: data, Mode: 0644}
+}
+
+func Test_DependencyGraph(t *testing.T) {
+	fsys := fstest.MapFS{
+		"game.exe":       importing(t, "./testdata/hello/hello32-mingw.exe", "ENGINE.dll"),
+		"editor.exe":     importing(t, "./testdata/hello/hello32-mingw.exe", "engine.dll"),
+		"engine.dll":     importing(t, "./testdata/hello/hello32-mingw.exe", "fmodex.dll"),
+		"tool64.exe":     importing(t, "./testdata/hello/hello64-mingw.exe", "engine.dll"),
+		"unrelated.dll":  fixtureFile(t, "./testdata/hello/hello32-mingw.exe"),
+		"lib/fmodex.dll": fixtureFile(t, "./testdata/hello/hello32-mingw.exe"),
+	}
+
+	var opened []string
+	resolver := pelican.FSResolver(fsys)
+	g := pelican.NewDependencyGraph(pelican.DLLResolverFunc(func(p string) (eos.File, error) {
+		opened = append(opened, p)
+		return resolver.Open(p)
+	}), testProbeParams(t))
+
+	assert.NoError(t, g.Add("game.exe"))
+	// KERNEL32.dll is a KnownDLL, it's never looked up
+	assert.EqualValues(t, []string{"game.exe", "ENGINE.dll", "fmodex.dll"}, opened)
+	assert.EqualValues(t, 2, len(g.Files))
+	assert.NotNil(t, g.Files["ENGINE.dll"])
+
+	// engine.dll was already probed
+	opened = nil
+	assert.NoError(t, g.Add("editor.exe"))
+	assert.NoError(t, g.Add("tool64.exe"))
+	assert.EqualValues(t, []string{"editor.exe", "tool64.exe"}, opened)
+
+	reports := g.LoadOrder()
+	assert.EqualValues(t, 3, len(reports))
+	for _, r := range reports {
+		assert.False(t, r.CanStart, r.Path)
+	}
+	r := reports[1]
+	assert.EqualValues(t, "game.exe", r.Path)
+	assert.EqualValues(t, []*pelican.LoadOrderEntry{
+		{DLL: "KERNEL32.dll", ImportedBy: "game.exe", Resolution: pelican.ResolutionKnownDLL},
+		{DLL: "ENGINE.dll", ImportedBy: "game.exe", Resolution: pelican.ResolutionAppLocal, Path: "ENGINE.dll"},
+		{DLL: "fmodex.dll", ImportedBy: "ENGINE.dll", Resolution: pelican.ResolutionMissing},
+	}, r.Entries)
+
+	mismatches := g.BitnessMismatches()
+	assert.EqualValues(t, 1, len(mismatches))
+	assert.EqualValues(t, "tool64.exe", mismatches[0].Path)
+	assert.EqualValues(t, "ENGINE.dll", mismatches[0].DLL)
+
+	assert.Error(t, g.Add("missing.exe"))
+}
+
+func Test_LoadOrderTree(t *testing.T) {
+	r := &pelican.LoadOrderReport{
+		Path: "bin/game.exe",
+		Entries: []*pelican.LoadOrderEntry{
+			{DLL: "KERNEL32.dll", ImportedBy: "bin/game.exe", Resolution: pelican.ResolutionKnownDLL},
+			{DLL: "engine.dll", ImportedBy: "bin/game.exe", Resolution: pelican.ResolutionAppLocal, Path: "bin/engine.dll"},
+			{DLL: "MSVCP140.dll", ImportedBy: "bin/engine.dll", Resolution: pelican.ResolutionRedist, Redist: "Visual C++ 2015-2022", Hijackable: true},
+			{DLL: "fmodex.dll", ImportedBy: "bin/engine.dll", Resolution: pelican.ResolutionMissing, FoundAt: []string{"lib/fmodex.dll"}},
+		},
+	}
+
+	assert.EqualValues(t, &pelican.DependencyNode{
+		Name: "game.exe",
+		Path: "bin/game.exe",
+		Children: []*pelican.DependencyNode{
+			{Name: "KERNEL32.dll", Resolution: pelican.ResolutionKnownDLL},
+			{Name: "engine.dll", Path: "bin/engine.dll", Resolution: pelican.ResolutionAppLocal, Children: []*pelican.DependencyNode{
+				{Name: "MSVCP140.dll", Resolution: pelican.ResolutionRedist, Redist: "Visual C++ 2015-2022", Hijackable: true},
+				{Name: "fmodex.dll", Resolution: pelican.ResolutionMissing, FoundAt: []string{"lib/fmodex.dll"}},
+			}},
+		},
+	}, r.Tree())
+}
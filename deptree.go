@@ -0,0 +1,61 @@
+package pelican
+
+import "path"
+
+// DependencyNode is a file in the dependency tree of an executable,
+// see LoadOrderReport.Tree
+type DependencyNode struct {
+	// As imported, for example "KERNEL32.dll", or the base
+	// name of the executable for the root of the tree
+	Name string `json:"name"`
+	// Slash-separated path, for the executable, app-local
+	// DLLs and those from private assemblies
+	Path string `json:"path,omitempty"`
+	// Empty for the root of the tree
+	Resolution DLLResolution `json:"resolution,omitempty"`
+	Redist     string        `json:"redist,omitempty"`
+	Hijackable bool          `json:"hijackable,omitempty"`
+	FoundAt    []string      `json:"foundAt,omitempty"`
+	Delayed    bool          `json:"delayed,omitempty"`
+	// The DLLs it imports, for the executable and app-local DLLs
+	Children []*DependencyNode `json:"children,omitempty"`
+}
+
+// Tree returns the entries of r as a tree rooted at the executable.
+// Like the loader, it lists every DLL once, under the first
+// file that imports it.
+func (r *LoadOrderReport) Tree() *DependencyNode {
+	byImporter := make(map[string][]*LoadOrderEntry)
+	for _, e := range r.Entries {
+		byImporter[e.ImportedBy] = append(byImporter[e.ImportedBy], e)
+	}
+
+	var fill func(node *DependencyNode)
+	fill = func(node *DependencyNode) {
+		entries := byImporter[node.Path]
+		// expand every file once, even if it ends up importing itself
+		delete(byImporter, node.Path)
+		for _, e := range entries {
+			child := &DependencyNode{
+				Name:       e.DLL,
+				Path:       e.Path,
+				Resolution: e.Resolution,
+				Redist:     e.Redist,
+				Hijackable: e.Hijackable,
+				FoundAt:    e.FoundAt,
+				Delayed:    e.Delayed,
+			}
+			if e.Resolution == ResolutionAppLocal {
+				fill(child)
+			}
+			node.Children = append(node.Children, child)
+		}
+	}
+
+	root := &DependencyNode{
+		Name: path.Base(r.Path),
+		Path: r.Path,
+	}
+	fill(root)
+	return root
+}
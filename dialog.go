@@ -0,0 +1,305 @@
+package pelican
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// DialogTemplate is a parsed RT_DIALOG resource, either
+// a classic DLGTEMPLATE or an extended DLGTEMPLATEEX
+type DialogTemplate struct {
+	ID       uint32 `json:"id"`
+	Language uint32 `json:"language"`
+	Extended bool   `json:"extended"`
+
+	Style   uint32 `json:"style"`
+	ExStyle uint32 `json:"exStyle"`
+	X       int32  `json:"x"`
+	Y       int32  `json:"y"`
+	Width   int32  `json:"width"`
+	Height  int32  `json:"height"`
+
+	Menu     string `json:"menu,omitempty"`
+	Class    string `json:"class,omitempty"`
+	Title    string `json:"title,omitempty"`
+	FontName string `json:"fontName,omitempty"`
+	FontSize uint32 `json:"fontSize,omitempty"`
+
+	Controls []*DialogControl `json:"controls"`
+}
+
+// DialogControl is a single control (DLGITEMTEMPLATE or
+// DLGITEMTEMPLATEEX) of a dialog template
+type DialogControl struct {
+	ID      uint32 `json:"id"`
+	Class   string `json:"class"`
+	Text    string `json:"text,omitempty"`
+	Style   uint32 `json:"style"`
+	ExStyle uint32 `json:"exStyle"`
+	X       int32  `json:"x"`
+	Y       int32  `json:"y"`
+	Width   int32  `json:"width"`
+	Height  int32  `json:"height"`
+}
+
+// DS_SETFONT, also part of DS_SHELLFONT
+const dsSetFont = 0x40
+
+// https://docs.microsoft.com/en-us/windows/win32/api/winuser/ns-winuser-dlgitemtemplate
+var dialogClassNames = map[uint16]string{
+	0x0080: "Button",
+	0x0081: "Edit",
+	0x0082: "Static",
+	0x0083: "ListBox",
+	0x0084: "ScrollBar",
+	0x0085: "ComboBox",
+}
+
+func (params *ProbeParams) parseDialog(info *PeInfo, id uint32, language uint32, rawData []byte) error {
+	consumer := params.Consumer
+	br := bytes.NewReader(rawData)
+
+	read := func(data interface{}) error {
+		return errors.WithStack(binary.Read(br, binary.LittleEndian, data))
+	}
+
+	// cf. https://docs.microsoft.com/en-us/windows/win32/dlgbox/dlgtemplateex
+	// items are aligned on a 32-bit boundary, relative to the start of the template
+	alignDword := func() error {
+		offset, err := br.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		mod4 := offset % 4
+		if mod4 > 0 {
+			_, err = br.Seek(4-mod4, io.SeekCurrent)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+		}
+		return nil
+	}
+
+	readString := func() (string, error) {
+		var res []byte
+		buf := make([]byte, 2)
+		for {
+			_, err := io.ReadFull(br, buf)
+			if err != nil {
+				return "", errors.WithStack(err)
+			}
+			if buf[0] == 0 && buf[1] == 0 {
+				break
+			}
+			res = append(res, buf...)
+		}
+		return DecodeUTF16(res), nil
+	}
+
+	// reads a sz_Or_Ord field: either empty, a 16-bit ordinal,
+	// or a null-terminated UTF-16 string
+	readSzOrOrd := func(names map[uint16]string) (string, error) {
+		var first uint16
+		err := read(&first)
+		if err != nil {
+			return "", err
+		}
+
+		switch first {
+		case 0x0000:
+			return "", nil
+		case 0xffff:
+			var ord uint16
+			err := read(&ord)
+			if err != nil {
+				return "", err
+			}
+			if name, ok := names[ord]; ok {
+				return name, nil
+			}
+			return fmt.Sprintf("#%d", ord), nil
+		default:
+			_, err := br.Seek(-2, io.SeekCurrent)
+			if err != nil {
+				return "", errors.WithStack(err)
+			}
+			return readString()
+		}
+	}
+
+	dt := &DialogTemplate{
+		ID:       id,
+		Language: language,
+	}
+
+	var header struct {
+		DlgVer    uint16
+		Signature uint16
+	}
+	err := read(&header)
+	if err != nil {
+		return err
+	}
+	dt.Extended = header.DlgVer == 1 && header.Signature == 0xffff
+
+	var numItems uint16
+	if dt.Extended {
+		var helpID uint32
+		err = read(&helpID)
+		if err != nil {
+			return err
+		}
+		err = read(&dt.ExStyle)
+		if err != nil {
+			return err
+		}
+		err = read(&dt.Style)
+		if err != nil {
+			return err
+		}
+	} else {
+		_, err = br.Seek(0, io.SeekStart)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		err = read(&dt.Style)
+		if err != nil {
+			return err
+		}
+		err = read(&dt.ExStyle)
+		if err != nil {
+			return err
+		}
+	}
+
+	err = read(&numItems)
+	if err != nil {
+		return err
+	}
+	var rect [4]int16
+	err = read(&rect)
+	if err != nil {
+		return err
+	}
+	dt.X, dt.Y, dt.Width, dt.Height = int32(rect[0]), int32(rect[1]), int32(rect[2]), int32(rect[3])
+
+	dt.Menu, err = readSzOrOrd(nil)
+	if err != nil {
+		return err
+	}
+	dt.Class, err = readSzOrOrd(nil)
+	if err != nil {
+		return err
+	}
+	dt.Title, err = readSzOrOrd(nil)
+	if err != nil {
+		return err
+	}
+
+	if dt.Style&dsSetFont != 0 {
+		var pointSize uint16
+		err = read(&pointSize)
+		if err != nil {
+			return err
+		}
+		dt.FontSize = uint32(pointSize)
+		if dt.Extended {
+			var fontAttributes struct {
+				Weight  uint16
+				Italic  uint8
+				Charset uint8
+			}
+			err = read(&fontAttributes)
+			if err != nil {
+				return err
+			}
+		}
+		dt.FontName, err = readString()
+		if err != nil {
+			return err
+		}
+	}
+
+	for i := uint16(0); i < numItems; i++ {
+		err = alignDword()
+		if err != nil {
+			return err
+		}
+
+		dc := &DialogControl{}
+		if dt.Extended {
+			var helpID uint32
+			err = read(&helpID)
+			if err != nil {
+				return err
+			}
+			err = read(&dc.ExStyle)
+			if err != nil {
+				return err
+			}
+			err = read(&dc.Style)
+			if err != nil {
+				return err
+			}
+		} else {
+			err = read(&dc.Style)
+			if err != nil {
+				return err
+			}
+			err = read(&dc.ExStyle)
+			if err != nil {
+				return err
+			}
+		}
+
+		err = read(&rect)
+		if err != nil {
+			return err
+		}
+		dc.X, dc.Y, dc.Width, dc.Height = int32(rect[0]), int32(rect[1]), int32(rect[2]), int32(rect[3])
+
+		if dt.Extended {
+			err = read(&dc.ID)
+			if err != nil {
+				return err
+			}
+		} else {
+			var id16 uint16
+			err = read(&id16)
+			if err != nil {
+				return err
+			}
+			dc.ID = uint32(id16)
+		}
+
+		dc.Class, err = readSzOrOrd(dialogClassNames)
+		if err != nil {
+			return err
+		}
+		dc.Text, err = readSzOrOrd(nil)
+		if err != nil {
+			return err
+		}
+
+		var extraCount uint16
+		err = read(&extraCount)
+		if err != nil {
+			return err
+		}
+		_, err = br.Seek(int64(extraCount), io.SeekCurrent)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		consumer.Debugf("dialog %d: control %d (%s) %q", id, dc.ID, dc.Class, dc.Text)
+		dt.Controls = append(dt.Controls, dc)
+	}
+
+	info.Dialogs = append(info.Dialogs, dt)
+	return nil
+}
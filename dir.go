@@ -0,0 +1,420 @@
+package pelican
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/itchio/httpkit/eos"
+	"github.com/pkg/errors"
+)
+
+// DirInfo contains information about all PE files in a directory
+type DirInfo struct {
+	// Probed files, keyed by slash-separated path relative to the directory
+	Files map[string]*PeInfo `json:"files"`
+
+	// DLLs executables bind to through side-by-side assemblies
+	SxSRedirections []*SxSRedirection `json:"sxsRedirections,omitempty"`
+	// App-local DLLs named like system DLLs
+	ShadowedDLLs []*ShadowedDLL `json:"shadowedDlls,omitempty"`
+	// Exports forwarded to DLLs of the directory that can't resolve them
+	BrokenForwarders []*BrokenForwarder `json:"brokenForwarders,omitempty"`
+	// App-local DLLs loaded by executables of another architecture
+	BitnessMismatches []*BitnessMismatch `json:"bitnessMismatches,omitempty"`
+	// How the imports of each executable resolve
+	LoadOrder []*LoadOrderReport `json:"loadOrder,omitempty"`
+	// Side-by-side assemblies each executable binds to, transitively
+	ActivationContexts []*ActivationContext `json:"activationContexts,omitempty"`
+	// Builds of the same executable for different architectures
+	SiblingBuilds []*SiblingBuilds `json:"siblingBuilds,omitempty"`
+	// Executables that load an app-local Chromium Embedded Framework
+	CEFApps []*CEFApp `json:"cefApps,omitempty"`
+}
+
+// ShadowedDLL is an app-local DLL (next to an executable) that
+// has the same name as a system DLL. This is how most mod loaders work,
+// but it's also a common source of DLL hijacking and loading issues.
+type ShadowedDLL struct {
+	// Slash-separated path relative to the directory
+	Path string `json:"path"`
+	// KnownDLLs are always loaded from the system directory,
+	// so the app-local copy is ignored by the loader
+	KnownDLL bool `json:"knownDll"`
+	// Set if the executables next to it bind to this side-by-side
+	// assembly instead, so the app-local copy is ignored
+	SxSAssembly string `json:"sxsAssembly,omitempty"`
+	Message     string `json:"message"`
+}
+
+var probedExtensions = map[string]bool{
+	".exe": true,
+	".dll": true,
+	".scr": true,
+	".cpl": true,
+}
+
+// ProbeDir probes all executables and libraries found in fsys (recursively).
+// Use DirFS to probe a directory on disk.
+func ProbeDir(fsys fs.FS, params ProbeParams) (*DirInfo, error) {
+	params.setDefaults()
+	consumer := params.Consumer
+
+	di := &DirInfo{
+		Files: make(map[string]*PeInfo),
+	}
+	exportTables := make(map[string]*exportTable)
+
+	err := walkProbed(params.Context, fsys, func(p string) error {
+		info, et, err := probeFSPath(fsys, p, params, true)
+		if err != nil {
+			if params.Strict {
+				return errors.WithMessagef(err, "while probing %s", p)
+			}
+			consumer.Warnf("Could not probe %s: %+v", p, err)
+			return nil
+		}
+		di.Files[p] = info
+		exportTables[p] = et
+		return nil
+	})
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	di.SxSRedirections, err = findSxSRedirections(fsys, di.Files)
+	if err != nil {
+		if params.Strict {
+			return nil, errors.WithMessage(err, "while resolving side-by-side assemblies")
+		}
+		consumer.Warnf("Could not resolve side-by-side assemblies: %+v", err)
+	}
+	sxs := sxsByDir(di.Files, di.SxSRedirections)
+
+	di.ShadowedDLLs = findShadowedDLLs(di.Files, sxs)
+	for _, sd := range di.ShadowedDLLs {
+		consumer.Warnf("%s: %s", sd.Path, sd.Message)
+	}
+
+	di.BrokenForwarders = findBrokenForwarders(exportTables, sxs)
+	for _, bf := range di.BrokenForwarders {
+		consumer.Warnf("%s: export %s is broken: %s", bf.Path, bf.Export, bf.Message)
+	}
+
+	di.BitnessMismatches = findBitnessMismatches(di.Files, di.SxSRedirections)
+	for _, bm := range di.BitnessMismatches {
+		consumer.Warnf("%s: %s", bm.Path, bm.Message)
+	}
+
+	di.LoadOrder = buildLoadOrder(di.Files, di.SxSRedirections, di.BitnessMismatches)
+	for _, r := range di.LoadOrder {
+		for _, msg := range r.missingDLLs(true) {
+			consumer.Warnf("%s: %s", r.Path, msg)
+		}
+	}
+
+	di.CEFApps = findCEFApps(di.Files, di.LoadOrder)
+
+	di.SiblingBuilds = findSiblingBuilds(di.Files)
+	for _, sb := range di.SiblingBuilds {
+		consumer.Debugf("%s has builds for several architectures: %d", sb.Name, len(sb.Builds))
+	}
+
+	di.ActivationContexts, err = buildActivationContexts(fsys, di.Files)
+	if err != nil {
+		if params.Strict {
+			return nil, errors.WithMessage(err, "while building activation contexts")
+		}
+		consumer.Warnf("Could not build activation contexts: %+v", err)
+	}
+	for _, ac := range di.ActivationContexts {
+		for _, ca := range ac.Missing() {
+			consumer.Warnf("%s: %s", ac.Path, ca.missingMessage())
+		}
+	}
+
+	return di, nil
+}
+
+// lowerPathIndex maps the lower-cased paths of files to the paths
+// themselves, since the loader looks files up case-insensitively
+func lowerPathIndex(files map[string]*PeInfo) map[string]string {
+	res := make(map[string]string)
+	for p := range files {
+		res[strings.ToLower(p)] = p
+	}
+	return res
+}
+
+// FileResult is the probe result of a single file, see StreamDir
+type FileResult struct {
+	// Slash-separated path relative to the directory
+	Path string  `json:"path"`
+	Info *PeInfo `json:"info,omitempty"`
+	// Set instead of Info if the file could not be probed
+	Error string `json:"error,omitempty"`
+}
+
+// StreamDir probes the same files as ProbeDir, but calls fn with each
+// result as soon as it's available instead of accumulating them, so that
+// very large directories can be processed in constant memory. Directory-wide
+// analyses (load order, side-by-side assemblies, etc.) need all files at
+// once, so they're not performed.
+//
+// In non-strict mode, files that can't be probed are passed to fn with
+// their Error set. Errors returned by fn stop the walk, and are returned.
+// Batch scans can be canceled and resumed with ProbeParams.Context and
+// ProbeParams.Checkpoint.
+func StreamDir(fsys fs.FS, params ProbeParams, fn func(fr *FileResult) error) error {
+	params.setDefaults()
+	consumer := params.Consumer
+
+	cp := params.Checkpoint
+	err := walkProbed(params.Context, fsys, func(p string) error {
+		var stats fs.FileInfo
+		var hash string
+		if cp != nil {
+			var err error
+			stats, err = fs.Stat(fsys, p)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
+			var done bool
+			done, hash, err = cp.done(fsys, p, stats)
+			if err != nil {
+				return errors.WithMessagef(err, "while checking %s against checkpoint", p)
+			}
+			if done {
+				consumer.Debugf("Skipping %s, already processed", p)
+				return nil
+			}
+		}
+
+		fr := &FileResult{Path: p}
+		info, _, err := probeFSPath(fsys, p, params, false)
+		if err != nil {
+			if params.Strict {
+				return errors.WithMessagef(err, "while probing %s", p)
+			}
+			consumer.Warnf("Could not probe %s: %+v", p, err)
+			fr.Error = err.Error()
+		} else {
+			fr.Info = info
+		}
+
+		err = fn(fr)
+		if err != nil {
+			return err
+		}
+		if cp != nil && fr.Error == "" {
+			err = cp.record(fsys, p, stats, hash)
+			if err != nil {
+				return errors.WithMessage(err, "while recording checkpoint")
+			}
+		}
+		return nil
+	})
+	return errors.WithStack(err)
+}
+
+// walkProbed calls fn with the path of every file of fsys
+// that ProbeDir probes, in lexical order, until ctx is done
+func walkProbed(ctx context.Context, fsys fs.FS, fn func(p string) error) error {
+	return fs.WalkDir(fsys, ".", func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		if !probedExtensions[strings.ToLower(path.Ext(p))] {
+			return nil
+		}
+		return fn(p)
+	})
+}
+
+// probeFSPath probes p, and reads its export table if withExports is set
+func probeFSPath(fsys fs.FS, p string, params ProbeParams, withExports bool) (*PeInfo, *exportTable, error) {
+	f, err := fsys.Open(p)
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+	defer f.Close()
+
+	ef, err := asEOSFile(f)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	info, err := Probe(ef, params)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if !withExports {
+		return info, nil, nil
+	}
+
+	// only used for directory-wide analysis, so it's not part of PeInfo
+	et, err := readExportTable(ef)
+	if err != nil {
+		if params.Strict {
+			return nil, nil, errors.WithMessage(err, "while reading exports")
+		}
+		params.Consumer.Warnf("Could not read exports of %s: %+v", p, err)
+	}
+	return info, et, nil
+}
+
+// memFile is an eos.File backed by memory
+type memFile struct {
+	*bytes.Reader
+	stats fs.FileInfo
+}
+
+var _ eos.File = (*memFile)(nil)
+
+func (mf *memFile) Close() error               { return nil }
+func (mf *memFile) Stat() (os.FileInfo, error) { return mf.stats, nil }
+
+// asEOSFile returns f itself if it supports random access (like *os.File),
+// otherwise it reads it fully into memory (files from archives, etc.)
+func asEOSFile(f fs.File) (eos.File, error) {
+	if ef, ok := f.(eos.File); ok {
+		return ef, nil
+	}
+
+	stats, err := f.Stat()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	data, err := io.ReadAll(f)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return &memFile{
+		Reader: bytes.NewReader(data),
+		stats:  stats,
+	}, nil
+}
+
+// see HKLM\SYSTEM\CurrentControlSet\Control\Session Manager\KnownDLLs
+// (as of Windows 10), plus ntdll which is always implied
+var knownDLLs = map[string]bool{
+	"advapi32.dll": true,
+	"clbcatq.dll":  true,
+	"combase.dll":  true,
+	"comdlg32.dll": true,
+	"coml2.dll":    true,
+	"difxapi.dll":  true,
+	"gdi32.dll":    true,
+	"gdiplus.dll":  true,
+	"imagehlp.dll": true,
+	"imm32.dll":    true,
+	"kernel32.dll": true,
+	"msctf.dll":    true,
+	"msvcrt.dll":   true,
+	"normaliz.dll": true,
+	"nsi.dll":      true,
+	"ntdll.dll":    true,
+	"ole32.dll":    true,
+	"oleaut32.dll": true,
+	"psapi.dll":    true,
+	"rpcrt4.dll":   true,
+	"sechost.dll":  true,
+	"setupapi.dll": true,
+	"shcore.dll":   true,
+	"shell32.dll":  true,
+	"shlwapi.dll":  true,
+	"user32.dll":   true,
+	"wldap32.dll":  true,
+	"ws2_32.dll":   true,
+}
+
+// System DLLs that aren't KnownDLLs, and are commonly shipped
+// app-local by mod loaders, injectors or wrappers
+var shadowableSystemDLLs = map[string]bool{
+	"version.dll":   true,
+	"winmm.dll":     true,
+	"winhttp.dll":   true,
+	"wininet.dll":   true,
+	"dbghelp.dll":   true,
+	"comctl32.dll":  true,
+	"dsound.dll":    true,
+	"ddraw.dll":     true,
+	"d3d8.dll":      true,
+	"d3d9.dll":      true,
+	"d3d10.dll":     true,
+	"d3d11.dll":     true,
+	"d3d12.dll":     true,
+	"dxgi.dll":      true,
+	"opengl32.dll":  true,
+	"dinput.dll":    true,
+	"dinput8.dll":   true,
+	"xinput1_3.dll": true,
+	"xinput1_4.dll": true,
+	"uxtheme.dll":   true,
+	"iphlpapi.dll":  true,
+	"winspool.drv":  true,
+}
+
+func findShadowedDLLs(files map[string]*PeInfo, sxs map[string]map[string]*SxSRedirection) []*ShadowedDLL {
+	// only DLLs next to an executable are searched first by the loader
+	exeDirs := make(map[string]bool)
+	for p := range files {
+		if strings.ToLower(path.Ext(p)) == ".exe" {
+			exeDirs[path.Dir(p)] = true
+		}
+	}
+
+	var res []*ShadowedDLL
+	for p := range files {
+		if !exeDirs[path.Dir(p)] {
+			continue
+		}
+
+		name := strings.ToLower(path.Base(p))
+		if r := sxs[path.Dir(p)][name]; r != nil {
+			if r.Target != p {
+				res = append(res, &ShadowedDLL{
+					Path:        p,
+					SxSAssembly: r.Assembly,
+					Message:     fmt.Sprintf("is ignored: executables bind to the %s side-by-side assembly instead", r.Assembly),
+				})
+			}
+			continue
+		}
+
+		switch {
+		case knownDLLs[name]:
+			res = append(res, &ShadowedDLL{
+				Path:     p,
+				KnownDLL: true,
+				Message:  "shadows a KnownDLL: the loader will ignore it and use the system copy",
+			})
+		case shadowableSystemDLLs[name]:
+			res = append(res, &ShadowedDLL{
+				Path:    p,
+				Message: "shadows a system DLL: it will be loaded instead of the system copy",
+			})
+		}
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Path < res[j].Path
+	})
+	return res
+}
@@ -0,0 +1,237 @@
+package pelican_test
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/itchio/pelican"
+	"github.com/stretchr/testify/assert"
+)
+
+func copyFixture(t *testing.T, src string, dst string) {
+	data, err := ioutil.ReadFile(src)
+	assert.NoError(t, err)
+	assert.NoError(t, os.MkdirAll(filepath.Dir(dst), 0755))
+	assert.NoError(t, ioutil.WriteFile(dst, data, 0644))
+}
+
+func fixtureFile(t *testing.T, src string) *fstest.MapFile {
+	data, err := ioutil.ReadFile(src)
+	assert.NoError(t, err)
+	return &fstest.MapFile{Data: data, Mode: 0644}
+}
+
+func Test_ProbeDir(t *testing.T) {
+	hello := fixtureFile(t, "./testdata/hello/hello32-mingw.exe")
+	fsys := fstest.MapFS{
+		"game.exe":         hello,
+		"version.dll":      hello,
+		"KERNEL32.dll":     hello,
+		"plugins/dxgi.dll": hello,
+		"hello.c":          fixtureFile(t, "./testdata/hello/hello.c"),
+	}
+
+	di, err := pelican.ProbeDir(fsys, testProbeParams(t))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 4, len(di.Files))
+	assert.NotNil(t, di.Files["plugins/dxgi.dll"])
+
+	// plugins/dxgi.dll isn't next to an executable
+	assert.EqualValues(t, 2, len(di.ShadowedDLLs))
+	assert.EqualValues(t, "KERNEL32.dll", di.ShadowedDLLs[0].Path)
+	assert.True(t, di.ShadowedDLLs[0].KnownDLL)
+	assert.EqualValues(t, "version.dll", di.ShadowedDLLs[1].Path)
+	assert.False(t, di.ShadowedDLLs[1].KnownDLL)
+}
+
+func Test_ProbeDirSiblingBuilds(t *testing.T) {
+	fsys := fstest.MapFS{
+		"x86/game.exe": fixtureFile(t, "./testdata/hello/hello32-mingw.exe"),
+		"x64/game.exe": fixtureFile(t, "./testdata/hello/hello64-mingw.exe"),
+	}
+
+	di, err := pelican.ProbeDir(fsys, testProbeParams(t))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, len(di.SiblingBuilds))
+	sb := di.SiblingBuilds[0]
+	assert.EqualValues(t, "game", sb.Name)
+	assert.EqualValues(t, "x64/game.exe", sb.Pick(pelican.ArchAmd64).Path)
+	assert.EqualValues(t, "x86/game.exe", sb.Pick(pelican.Arch386).Path)
+}
+
+func Test_BrokenForwarders(t *testing.T) {
+	// see testdata/forwarders/make-dlls.py
+	fsys := fstest.MapFS{
+		"game.exe":        fixtureFile(t, "./testdata/hello/hello32-mingw.exe"),
+		"engine.dll":      fixtureFile(t, "./testdata/forwarders/engine.dll"),
+		"core.dll":        fixtureFile(t, "./testdata/forwarders/core.dll"),
+		"mods/engine.dll": fixtureFile(t, "./testdata/forwarders/engine.dll"),
+	}
+
+	di, err := pelican.ProbeDir(fsys, testProbeParams(t))
+	assert.NoError(t, err)
+	assert.EqualValues(t, []*pelican.BrokenForwarder{
+		{
+			Path:    "core.dll",
+			Export:  "Loop",
+			Chain:   []string{"engine.Loop", "core.Loop"},
+			Message: "forwarder loop: core.Loop forwards back to core.dll!Loop",
+		},
+		{
+			Path:    "engine.dll",
+			Export:  "Free",
+			Chain:   []string{"core.Free"},
+			Message: "core.dll does not export Free",
+		},
+		{
+			Path:    "engine.dll",
+			Export:  "Loop",
+			Chain:   []string{"core.Loop", "engine.Loop"},
+			Message: "forwarder loop: engine.Loop forwards back to engine.dll!Loop",
+		},
+	}, di.BrokenForwarders)
+}
+
+func Test_SxSRedirections(t *testing.T) {
+	// the pidgin uninstaller depends on Microsoft.Windows.Common-Controls
+	fsys := fstest.MapFS{
+		"setup.exe":    fixtureFile(t, "./testdata/pidgin/pidgin-uninst.exe"),
+		"comctl32.dll": fixtureFile(t, "./testdata/hello/hello32-mingw.exe"),
+	}
+
+	di, err := pelican.ProbeDir(fsys, testProbeParams(t))
+	assert.NoError(t, err)
+	assert.EqualValues(t, []*pelican.SxSRedirection{
+		{
+			Path:     "setup.exe",
+			DLL:      "comctl32.dll",
+			Assembly: "Microsoft.Windows.Common-Controls",
+		},
+	}, di.SxSRedirections)
+
+	assert.EqualValues(t, 1, len(di.ShadowedDLLs))
+	sd := di.ShadowedDLLs[0]
+	assert.EqualValues(t, "comctl32.dll", sd.Path)
+	assert.EqualValues(t, "Microsoft.Windows.Common-Controls", sd.SxSAssembly)
+	assert.False(t, sd.KnownDLL)
+
+	// without the dependency, the app-local copy is loaded
+	fsys["setup.exe"] = fixtureFile(t, "./testdata/hello/hello32-mingw.exe")
+	di, err = pelican.ProbeDir(fsys, testProbeParams(t))
+	assert.NoError(t, err)
+	assert.Empty(t, di.SxSRedirections)
+	assert.EqualValues(t, 1, len(di.ShadowedDLLs))
+	assert.Empty(t, di.ShadowedDLLs[0].SxSAssembly)
+}
+
+func Test_BitnessMismatches(t *testing.T) {
+	// the pidgin uninstaller is 32-bit and imports VERSION.dll and COMCTL32.dll
+	hello64 := fixtureFile(t, "./testdata/hello/hello64-mingw.exe")
+	fsys := fstest.MapFS{
+		"setup.exe":    fixtureFile(t, "./testdata/pidgin/pidgin-uninst.exe"),
+		"version.dll":  hello64,
+		"COMCTL32.dll": hello64,
+	}
+
+	di, err := pelican.ProbeDir(fsys, testProbeParams(t))
+	assert.NoError(t, err)
+
+	// COMCTL32.dll is redirected to WinSxS, so only version.dll is loaded
+	assert.EqualValues(t, []*pelican.BitnessMismatch{
+		{
+			Path:       "setup.exe",
+			Arch:       pelican.Arch386,
+			DLL:        "version.dll",
+			DLLArch:    pelican.ArchAmd64,
+			ImportedBy: "setup.exe",
+			Message:    "386 executable loads amd64 DLL version.dll",
+		},
+	}, di.BitnessMismatches)
+
+	assert.EqualValues(t, 1, len(di.LoadOrder))
+	assert.False(t, di.LoadOrder[0].CanStart)
+}
+
+func Test_StreamDir(t *testing.T) {
+	fsys := fstest.MapFS{
+		"game.exe":          fixtureFile(t, "./testdata/hello/hello32-mingw.exe"),
+		"bin/tool.exe":      fixtureFile(t, "./testdata/hello/hello64-mingw.exe"),
+		"bin/broken.dll":    &fstest.MapFile{Data: []byte("MZ not really")},
+		"bin/readme.txt":    &fstest.MapFile{Data: []byte("hi")},
+		"plugins/stars.scr": fixtureFile(t, "./testdata/applets/stars.scr"),
+	}
+
+	params := testProbeParams(t)
+	params.Strict = false
+	var results []*pelican.FileResult
+	err := pelican.StreamDir(fsys, params, func(fr *pelican.FileResult) error {
+		results = append(results, fr)
+		return nil
+	})
+	assert.NoError(t, err)
+
+	var paths []string
+	for _, fr := range results {
+		paths = append(paths, fr.Path)
+	}
+	assert.EqualValues(t, []string{"bin/broken.dll", "bin/tool.exe", "game.exe", "plugins/stars.scr"}, paths)
+	assert.Nil(t, results[0].Info)
+	assert.NotEmpty(t, results[0].Error)
+	assert.EqualValues(t, pelican.ArchAmd64, results[1].Info.Arch)
+	assert.Empty(t, results[1].Error)
+
+	// strict mode stops at the first error
+	err = pelican.StreamDir(fsys, testProbeParams(t), func(fr *pelican.FileResult) error {
+		return nil
+	})
+	assert.Error(t, err)
+
+	// and so does fn
+	stop := errors.New("stop")
+	count := 0
+	err = pelican.StreamDir(fsys, params, func(fr *pelican.FileResult) error {
+		count++
+		return stop
+	})
+	assert.True(t, errors.Is(err, stop))
+	assert.EqualValues(t, 1, count)
+}
+
+func Test_DirFS(t *testing.T) {
+	data, err := ioutil.ReadFile("./testdata/hello/hello32-mingw.exe")
+	assert.NoError(t, err)
+
+	// deep enough to go over MAX_PATH on Windows
+	dir := t.TempDir()
+	deep := strings.Repeat("ゲームのフォルダ/", 12) + "실행.exe"
+	dest := filepath.Join(dir, filepath.FromSlash(deep))
+	assert.NoError(t, os.MkdirAll(filepath.Dir(dest), 0755))
+	assert.NoError(t, ioutil.WriteFile(dest, data, 0644))
+
+	fsys := pelican.DirFS(dir)
+	assert.NoError(t, fstest.TestFS(fsys, deep))
+	_, err = fsys.Open("../" + deep)
+	assert.Error(t, err)
+
+	di, err := pelican.ProbeDir(fsys, testProbeParams(t))
+	assert.NoError(t, err)
+	assert.Contains(t, di.Files, deep)
+	assert.EqualValues(t, pelican.Arch386, di.Files[deep].Arch)
+
+	// extracted items can be named anything
+	sink := pelican.NewDirSink(filepath.Join(dir, "extracted"))
+	w, err := sink.Create("アイコン/101.ico")
+	assert.NoError(t, err)
+	_, err = io.WriteString(w, "icon")
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+	written, err := ioutil.ReadFile(filepath.Join(dir, "extracted", "アイコン", "101.ico"))
+	assert.NoError(t, err)
+	assert.EqualValues(t, "icon", string(written))
+}
@@ -0,0 +1,53 @@
+package pelican
+
+import (
+	"fmt"
+
+	"github.com/itchio/pelican/pe"
+)
+
+// ImageFlag is one of the IMAGE_DLLCHARACTERISTICS_* flags set in the
+// optional header. Despite their name, they apply to executables too,
+// and change how Windows loads and runs the binary.
+type ImageFlag struct {
+	// Without the IMAGE_DLLCHARACTERISTICS_ prefix, for example
+	// "TERMINAL_SERVER_AWARE", or hexadecimal for unknown flags
+	Name string `json:"name"`
+	// What it changes at runtime, empty for unknown flags
+	Effect string `json:"effect,omitempty"`
+}
+
+// cf. https://docs.microsoft.com/en-us/windows/win32/debug/pe-format#dll-characteristics
+var imageFlagEffects = map[pe.DllCharacteristics]string{
+	pe.IMAGE_DLLCHARACTERISTICS_HIGH_ENTROPY_VA: "can be loaded anywhere in the 64-bit address space (high-entropy ASLR)",
+	pe.IMAGE_DLLCHARACTERISTICS_DYNAMIC_BASE:    "loaded at a random address (ASLR), so hardcoded pointers (trainers, mods) break",
+	pe.IMAGE_DLLCHARACTERISTICS_FORCE_INTEGRITY: "refuses to load unless its signature is valid",
+	pe.IMAGE_DLLCHARACTERISTICS_NX_COMPAT:       "runs with data execution prevention (DEP), so code generated without marking it executable crashes",
+	pe.IMAGE_DLLCHARACTERISTICS_NO_ISOLATION:    "its manifest is ignored, so no side-by-side assemblies are loaded (visual styles, private runtimes)",
+	pe.IMAGE_DLLCHARACTERISTICS_NO_SEH:          "doesn't use structured exception handling, exceptions raised in its code can't be handled there",
+	pe.IMAGE_DLLCHARACTERISTICS_NO_BIND:         "must not be bound, so imports are always resolved at load time",
+	pe.IMAGE_DLLCHARACTERISTICS_APPCONTAINER:    "must run in an AppContainer (sandboxed, like UWP apps)",
+	pe.IMAGE_DLLCHARACTERISTICS_WDM_DRIVER:      "is a WDM kernel driver, it can't be launched",
+	pe.IMAGE_DLLCHARACTERISTICS_GUARD_CF:        "indirect calls are checked by Control Flow Guard, so hooking them (overlays, injectors) may crash",
+	pe.IMAGE_DLLCHARACTERISTICS_TERMINAL_SERVER_AWARE: "Windows doesn't apply Remote Desktop compatibility shims " +
+		"(per-user redirection of INI files and HKEY_LOCAL_MACHINE), which binaries without it get",
+}
+
+// imageFlags returns the DllCharacteristics flags set in pf's optional header
+func imageFlags(pf *pe.File) []*ImageFlag {
+	dc := pe.DllCharacteristics(pf.OptionalHeaderFields().DllCharacteristics)
+
+	var res []*ImageFlag
+	for bit := uint(0); bit < 16; bit++ {
+		flag := pe.DllCharacteristics(1 << bit)
+		if dc&flag == 0 {
+			continue
+		}
+		if name, ok := pe.DllCharacteristicsNames[flag]; ok {
+			res = append(res, &ImageFlag{Name: name, Effect: imageFlagEffects[flag]})
+		} else {
+			res = append(res, &ImageFlag{Name: fmt.Sprintf("0x%x", uint16(flag))})
+		}
+	}
+	return res
+}
@@ -0,0 +1,87 @@
+package pelican
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// Windows' installer detection looks for these keywords in the
+// file name and some version properties, cf.
+// https://docs.microsoft.com/en-us/windows/security/identity-protection/user-account-control/how-user-account-control-works
+var installerKeywords = []string{"install", "setup", "update", "patch"}
+
+var installerVersionProperties = []string{
+	"CompanyName",
+	"ProductName",
+	"FileDescription",
+	"OriginalFilename",
+	"InternalName",
+}
+
+// guessElevation returns the reasons why Windows' installer detection
+// would elevate the binary, if any.
+func guessElevation(info *PeInfo, name string) []string {
+	if info.AssemblyInfo != nil && info.AssemblyInfo.RequestedExecutionLevel != "" {
+		// an explicit level disables installer detection
+		return nil
+	}
+
+	if info.Arch != Arch386 {
+		// installer detection only applies to 32-bit executables
+		return nil
+	}
+
+	var reasons []string
+
+	findKeyword := func(s string) string {
+		s = strings.ToLower(s)
+		for _, kw := range installerKeywords {
+			if strings.Contains(s, kw) {
+				return kw
+			}
+		}
+		return ""
+	}
+
+	base := strings.TrimSuffix(name, filepath.Ext(name))
+	if kw := findKeyword(base); kw != "" {
+		reasons = append(reasons, fmt.Sprintf("file name contains %q", kw))
+	}
+
+	for _, key := range installerVersionProperties {
+		if kw := findKeyword(info.VersionProperties[key]); kw != "" {
+			reasons = append(reasons, fmt.Sprintf("%s contains %q", key, kw))
+		}
+	}
+
+	if len(reasons) == 0 {
+		return nil
+	}
+
+	if info.AssemblyInfo == nil {
+		if info.Subsystem == SubsystemGUI {
+			reasons = append(reasons, "GUI executable has no manifest")
+		} else {
+			reasons = append(reasons, "executable has no manifest")
+		}
+	} else {
+		reasons = append(reasons, "manifest has no requestedExecutionLevel")
+	}
+	return reasons
+}
+
+// RequiresElevationHeuristic is like RequiresElevation, but also
+// considers Windows' installer detection, which elevates executables
+// that look like installers even when their manifest doesn't ask for it.
+// It requires ProbeParams.ElevationHeuristics to be set.
+//
+// It also returns human-readable reasons for its verdict.
+func (pi *PeInfo) RequiresElevationHeuristic() (bool, []string) {
+	if pi.AssemblyInfo != nil && pi.AssemblyInfo.RequestedExecutionLevel != "" {
+		reason := fmt.Sprintf("manifest requests %q execution level", pi.AssemblyInfo.RequestedExecutionLevel)
+		return pi.RequiresElevation(), []string{reason}
+	}
+
+	return len(pi.ElevationReasons) > 0, pi.ElevationReasons
+}
@@ -0,0 +1,31 @@
+package pelican
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_GuessElevation(t *testing.T) {
+	info := &PeInfo{Arch: Arch386, Subsystem: SubsystemGUI}
+	assert.EqualValues(t, []string{
+		`file name contains "setup"`,
+		"GUI executable has no manifest",
+	}, guessElevation(info, "setup.exe"))
+
+	info.Subsystem = SubsystemConsole
+	assert.EqualValues(t, []string{
+		`file name contains "setup"`,
+		"executable has no manifest",
+	}, guessElevation(info, "setup.exe"))
+
+	info.AssemblyInfo = &AssemblyInfo{}
+	assert.EqualValues(t, []string{
+		`file name contains "setup"`,
+		"manifest has no requestedExecutionLevel",
+	}, guessElevation(info, "setup.exe"))
+
+	info.AssemblyInfo.RequestedExecutionLevel = "asInvoker"
+	assert.Nil(t, guessElevation(info, "setup.exe"))
+	assert.Nil(t, guessElevation(&PeInfo{Arch: Arch386}, "game.exe"))
+}
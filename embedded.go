@@ -0,0 +1,151 @@
+package pelican
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+
+	"github.com/itchio/httpkit/eos"
+	"github.com/itchio/pelican/pe"
+	"github.com/pkg/errors"
+)
+
+// how much of a file is read at a time when looking for embedded images
+const embeddedScanChunkSize = 1024 * 1024
+
+// bounds the work done on files that are full of executables,
+// or of bytes that look like the start of one
+const maxEmbeddedImages = 256
+
+// real DOS stubs are short, e_lfanew values past that are junk
+const maxPEHeaderOffset = 4096
+
+// EmbeddedImage is a PE image found inside another file (installer
+// data, archive, firmware blob, dropper, etc.)
+type EmbeddedImage struct {
+	// Where the image starts in the file
+	Offset int64 `json:"offset"`
+	// How many bytes it spans, see pe.File.ImageSize
+	Size int64   `json:"size"`
+	Info *PeInfo `json:"info,omitempty"`
+	// Set instead of Info if the image could not be probed
+	Error string `json:"error,omitempty"`
+}
+
+// FindEmbeddedImages returns the location of the PE images in r, which
+// is size bytes long, in the order they appear. An image at offset 0 (r
+// being a PE file itself) is included. Images inside others, for example
+// in their resources or overlay, are found as well.
+func FindEmbeddedImages(r io.ReaderAt, size int64) ([]*EmbeddedImage, error) {
+	var res []*EmbeddedImage
+	buf := make([]byte, embeddedScanChunkSize)
+	for chunkOffset := int64(0); chunkOffset < size && len(res) < maxEmbeddedImages; {
+		n, err := r.ReadAt(buf, chunkOffset)
+		if err != nil && err != io.EOF {
+			return nil, errors.WithStack(err)
+		}
+		if n < 2 {
+			break
+		}
+		chunk := buf[:n]
+
+		for i := 0; len(res) < maxEmbeddedImages; i++ {
+			j := bytes.Index(chunk[i:], []byte("MZ"))
+			if j < 0 {
+				break
+			}
+			i += j
+			offset := chunkOffset + int64(i)
+			ok, err := isEmbeddedImage(r, offset, size)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+
+			pf, err := pe.NewFileAt(r, offset, size-offset)
+			if err != nil {
+				// the headers past the signature are invalid
+				continue
+			}
+			res = append(res, &EmbeddedImage{
+				Offset: offset,
+				Size:   pf.ImageSize(),
+			})
+		}
+
+		// "MZ" may straddle chunks
+		chunkOffset += int64(n) - 1
+	}
+	return res, nil
+}
+
+// isEmbeddedImage returns true if the "MZ" at offset in r is followed
+// by the offset of a PE signature, like in a DOS header
+func isEmbeddedImage(r io.ReaderAt, offset int64, size int64) (bool, error) {
+	var dosHeader [0x40]byte
+	if offset+int64(len(dosHeader)) > size {
+		return false, nil
+	}
+	_, err := r.ReadAt(dosHeader[:], offset)
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+
+	lfanew := int64(binary.LittleEndian.Uint32(dosHeader[0x3c:]))
+	if lfanew < int64(len(dosHeader)) || lfanew > maxPEHeaderOffset || offset+lfanew+4 > size {
+		return false, nil
+	}
+	var sig [4]byte
+	_, err = r.ReadAt(sig[:], offset+lfanew)
+	if err != nil {
+		return false, errors.WithStack(err)
+	}
+	return sig == [4]byte{'P', 'E', 0, 0}, nil
+}
+
+// ProbeEmbedded probes each image FindEmbeddedImages finds in file.
+// params.Cache, AssetDir and ElevationHeuristics are ignored, since
+// embedded images aren't files of their own. params.MaxMemory
+// applies to each image separately.
+//
+// In non-strict mode, images that can't be probed are returned
+// with their Error set.
+func ProbeEmbedded(file eos.File, params ProbeParams) ([]*EmbeddedImage, error) {
+	params.setDefaults()
+
+	stats, err := file.Stat()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	r := params.readerAt(file)
+
+	images, err := FindEmbeddedImages(r, stats.Size())
+	if err != nil {
+		return nil, errors.WithMessage(err, "while looking for embedded images")
+	}
+
+	for _, ei := range images {
+		// each image gets the whole memory budget
+		params.ioErr = nil
+		params.memoryUsed = 0
+		info, err := params.probe(io.NewSectionReader(r, ei.Offset, ei.Size), ei.Size)
+		if err == nil && params.ioErr != nil {
+			err = errors.WithMessage(params.ioErr, "while reading file")
+		}
+		if err != nil {
+			if params.Strict {
+				return nil, errors.WithMessagef(err, "while probing image at 0x%x", ei.Offset)
+			}
+			params.Consumer.Warnf("Could not probe image at 0x%x: %+v", ei.Offset, err)
+			ei.Error = err.Error()
+			continue
+		}
+		if params.Redact {
+			info = info.Redacted()
+		}
+		ei.Info = info
+	}
+	return images, nil
+}
@@ -0,0 +1,53 @@
+package pelican_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/itchio/httpkit/eos"
+	"github.com/itchio/pelican"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ProbeEmbedded(t *testing.T) {
+	exe32, err := ioutil.ReadFile("./testdata/hello/hello32-mingw.exe")
+	assert.NoError(t, err)
+	exe64, err := ioutil.ReadFile("./testdata/hello/hello64-msvc.exe")
+	assert.NoError(t, err)
+
+	var blob []byte
+	blob = append(blob, bytes.Repeat([]byte{0xcc}, 1000)...)
+	blob = append(blob, exe32...)
+	// looks like the start of a DOS header, but isn't
+	blob = append(blob, "MZ not an executable"...)
+	blob = append(blob, bytes.Repeat([]byte{0}, 100)...)
+	offset64 := len(blob)
+	blob = append(blob, exe64...)
+	blob = append(blob, "trailing data"...)
+
+	images, err := pelican.FindEmbeddedImages(bytes.NewReader(blob), int64(len(blob)))
+	assert.NoError(t, err)
+	if assert.Len(t, images, 2) {
+		assert.EqualValues(t, 1000, images[0].Offset)
+		assert.EqualValues(t, len(exe32), images[0].Size)
+		assert.EqualValues(t, offset64, images[1].Offset)
+		assert.EqualValues(t, len(exe64), images[1].Size)
+	}
+
+	path := filepath.Join(t.TempDir(), "blob.bin")
+	assert.NoError(t, ioutil.WriteFile(path, blob, 0644))
+	f, err := eos.Open(path)
+	assert.NoError(t, err)
+	defer f.Close()
+
+	images, err = pelican.ProbeEmbedded(f, testProbeParams(t))
+	assert.NoError(t, err)
+	if assert.Len(t, images, 2) {
+		assert.EqualValues(t, pelican.Arch386, images[0].Info.Arch)
+		assert.EqualValues(t, len(exe32), images[0].Info.Size)
+		assert.EqualValues(t, pelican.ArchAmd64, images[1].Info.Arch)
+		assert.Contains(t, images[1].Info.Imports, "KERNEL32.dll")
+	}
+}
@@ -0,0 +1,354 @@
+// Package encode renders probe results in human-friendly formats,
+// for command-line tools and reports.
+package encode
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/itchio/pelican"
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// YAML writes v (typically a *pelican.PeInfo or *pelican.DirInfo) as YAML,
+// using the same field names and order as its JSON encoding.
+func YAML(w io.Writer, v interface{}) error {
+	js, err := json.Marshal(v)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	// JSON is valid YAML, and MapSlice preserves key order
+	var doc yaml.MapSlice
+	err = yaml.Unmarshal(js, &doc)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	out, err := yaml.Marshal(doc)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	_, err = w.Write(out)
+	return errors.WithStack(err)
+}
+
+// NDJSONWriter writes values as newline-delimited JSON, one compact
+// object per line, so that results can be consumed as they're written.
+// It's safe for concurrent use.
+type NDJSONWriter struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+}
+
+// NewNDJSONWriter returns an NDJSONWriter that writes to w
+func NewNDJSONWriter(w io.Writer) *NDJSONWriter {
+	return &NDJSONWriter{enc: json.NewEncoder(w)}
+}
+
+// Write writes v as a single line
+func (nw *NDJSONWriter) Write(v interface{}) error {
+	nw.mu.Lock()
+	defer nw.mu.Unlock()
+	return errors.WithStack(nw.enc.Encode(v))
+}
+
+// WriteResult writes fr as a single line. It can be passed
+// to pelican.StreamDir as-is.
+func (nw *NDJSONWriter) WriteResult(fr *pelican.FileResult) error {
+	return nw.Write(fr)
+}
+
+// Table writes info as a series of aligned plain-text tables
+func Table(w io.Writer, info *pelican.PeInfo) error {
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+
+	section := func(title string) {
+		fmt.Fprintf(tw, "\n%s\n%s\n", title, strings.Repeat("=", len(title)))
+	}
+	row := func(cells ...string) {
+		fmt.Fprintf(tw, "%s\n", strings.Join(cells, "\t"))
+	}
+
+	section("General")
+	row("Arch", info.Arch.String())
+	row("Subsystem", string(info.Subsystem))
+	if info.Kind != pelican.ImageKindUnknown {
+		row("Kind", string(info.Kind))
+	}
+	if info.EntryPointStub != pelican.StubUnknown {
+		row("Entry point", string(info.EntryPointStub))
+	}
+	if len(info.TLSCallbacks) > 0 {
+		row("TLS callbacks", fmt.Sprint(len(info.TLSCallbacks)))
+	}
+	if info.IsDebugBuild {
+		row("Debug build", "yes")
+	}
+	if info.IsPrerelease {
+		row("Prerelease", "yes")
+	}
+	if lc := info.LoadConfig; lc != nil {
+		var mitigations []string
+		if lc.SecurityCookie != 0 {
+			mitigations = append(mitigations, "stack cookie")
+		}
+		if lc.SafeSEH {
+			mitigations = append(mitigations, "SafeSEH")
+		}
+		if lc.CFG {
+			mitigations = append(mitigations, "Control Flow Guard")
+		}
+		if len(mitigations) > 0 {
+			row("Mitigations", strings.Join(mitigations, ", "))
+		}
+	}
+	if pdb := info.PDB; pdb != nil {
+		row("PDB", pdb.Path, pdb.SymbolServerKey)
+	}
+	if crt := info.CRT; crt != nil {
+		row("C runtime", string(crt.Linkage), crt.Redist)
+	}
+	if d := info.Delphi; d != nil {
+		row("Delphi", d.Version, strings.Join(d.Evidence, ", "))
+	}
+	if info.Compatibility != nil {
+		row("Compatibility", info.Compatibility.Summary)
+		for _, f := range info.Compatibility.Flags {
+			row("Flag", f.Name, f.Effect)
+		}
+	}
+	if e := info.Entropy; e != nil {
+		entropy := fmt.Sprintf("%.2f", e.File)
+		if e.Sampled {
+			entropy += " (sampled)"
+		}
+		row("Entropy", entropy)
+	}
+	if im := info.ImportMetrics; im != nil {
+		row("Imports", fmt.Sprintf("%d DLLs, %d functions", im.DLLs, im.Functions), fmt.Sprintf("IAT of %d bytes (%d expected)", im.IATSize, im.ExpectedIATSize))
+	}
+	if c := info.Console; c != nil {
+		for _, note := range c.Notes {
+			row("Console", note)
+		}
+	}
+	if c := info.CEF; c != nil {
+		if c.ImportsLibCEF {
+			row("CEF", "imports libcef.dll")
+		} else {
+			row("CEF", c.Version)
+		}
+	}
+	if inst := info.Installer; inst != nil {
+		row("Installer", string(inst.Type), strings.Join(inst.SilentArgs, " "))
+	}
+	if l := info.Launcher; l != nil {
+		row("Launcher", strings.Join(l.Targets, ", "), l.Evidence)
+	}
+	if ae := info.ArmEmulation; ae != nil {
+		row("Windows on ARM", string(ae.Verdict), strings.Join(ae.Reasons, ", "))
+	}
+	if elevate, reasons := info.RequiresElevationHeuristic(); elevate {
+		row("Elevation", strings.Join(reasons, ", "))
+	}
+
+	if len(info.Protections) > 0 {
+		section("Protections")
+		for _, p := range info.Protections {
+			row(p.Name, string(p.Kind), p.Evidence)
+		}
+	}
+
+	if len(info.WineNotes) > 0 {
+		section("Wine/Proton")
+		for _, wn := range info.WineNotes {
+			row(string(wn.Severity), wn.Message, wn.Evidence)
+		}
+	}
+
+	if len(info.BundledLibraries) > 0 {
+		section("Bundled libraries")
+		for _, bl := range info.BundledLibraries {
+			row(bl.Name, bl.Version, string(bl.Source))
+		}
+	}
+
+	if ind := info.Indicators; ind != nil {
+		section("Indicators")
+		for _, u := range ind.URLs {
+			row("URL", u)
+		}
+		for _, k := range ind.RegistryKeys {
+			row("Registry key", k)
+		}
+		for _, m := range ind.Mutexes {
+			row("Mutex", m)
+		}
+		for _, p := range ind.Pipes {
+			row("Pipe", p)
+		}
+		for _, p := range ind.BuildPaths {
+			row("Build path", p)
+		}
+	}
+
+	if len(info.Provenance) > 0 {
+		section("Provenance")
+		for _, pf := range info.Provenance {
+			row(string(pf.Kind), pf.Detail, pf.Path)
+		}
+	}
+
+	if len(info.VersionProperties) > 0 {
+		section("Version properties")
+		var keys []string
+		for k := range info.VersionProperties {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			row(k, info.VersionProperties[k])
+		}
+	}
+
+	if ai := info.AssemblyInfo; ai != nil {
+		section("Manifest")
+		if ai.Identity != nil {
+			row("Identity", ai.Identity.Name, ai.Identity.Version, ai.Identity.ProcessorArchitecture)
+		}
+		if ai.Description != "" {
+			row("Description", ai.Description)
+		}
+		if ai.RequestedExecutionLevel != "" {
+			row("Execution level", ai.RequestedExecutionLevel)
+		}
+		for _, cs := range ai.ComServers {
+			for _, cc := range cs.Classes {
+				row("COM class", cc.CLSID, cc.ProgID, cs.File)
+			}
+			for _, tl := range cs.TypeLibs {
+				row("Type library", tl.TypeLibID, tl.Version, cs.File)
+			}
+		}
+	}
+
+	if len(info.DependentAssemblies) > 0 {
+		section("Dependent assemblies")
+		row("NAME", "VERSION", "ARCH", "PUBLIC KEY TOKEN")
+		for _, da := range info.DependentAssemblies {
+			row(da.Name, da.Version, da.ProcessorArchitecture, da.PublicKeyToken)
+		}
+	}
+
+	if len(info.Imports) > 0 {
+		section("Imports")
+		for _, lib := range info.Imports {
+			row(lib)
+		}
+	}
+
+	if len(info.DelayImports) > 0 {
+		section("Delay-loaded imports")
+		for _, lib := range info.DelayImports {
+			row(lib)
+		}
+	}
+
+	if rh := info.RichHeader; rh != nil {
+		section("Rich header")
+		checksum := "valid checksum"
+		if !rh.ChecksumValid {
+			checksum = "invalid checksum"
+		}
+		row("Key", fmt.Sprintf("0x%08x", rh.Key), checksum)
+		row("PRODUCT ID", "BUILD", "COUNT")
+		for _, e := range rh.Entries {
+			row(fmt.Sprintf("0x%x", e.ProductID), fmt.Sprint(e.Build), fmt.Sprint(e.Count))
+		}
+	}
+
+	if len(info.Assets) > 0 {
+		section("Assets")
+		for _, a := range info.Assets {
+			row(string(a.Kind), a.Name, a.File)
+		}
+	}
+
+	if len(info.Extensions) > 0 {
+		section("Extensions")
+		var names []string
+		for name := range info.Extensions {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			row(name, string(info.Extensions[name]))
+		}
+	}
+
+	if len(info.Warnings) > 0 {
+		section("Warnings")
+		for _, w := range info.Warnings {
+			row(string(w.Severity), string(w.Code), w.Message)
+		}
+	}
+
+	return errors.WithStack(tw.Flush())
+}
+
+// DependencyTree writes root as an ASCII tree, one file per line,
+// followed by where the loader finds it, for example:
+//
+//	game.exe
+//	|-- KERNEL32.dll [known-dll]
+//	`-- engine.dll [app-local]
+//	    `-- fmodex.dll [missing, copy at lib/fmodex.dll]
+//
+// Delay-loaded DLLs are marked "delayed".
+func DependencyTree(w io.Writer, root *pelican.DependencyNode) error {
+	var sb strings.Builder
+	sb.WriteString(root.Name + "\n")
+
+	var walk func(node *pelican.DependencyNode, prefix string)
+	walk = func(node *pelican.DependencyNode, prefix string) {
+		for i, child := range node.Children {
+			branch, indent := "|-- ", "|   "
+			if i == len(node.Children)-1 {
+				branch, indent = "`-- ", "    "
+			}
+			fmt.Fprintf(&sb, "%s%s%s [%s]\n", prefix, branch, child.Name, dependencyMarker(child))
+			walk(child, prefix+indent)
+		}
+	}
+	walk(root, "")
+
+	_, err := io.WriteString(w, sb.String())
+	return errors.WithStack(err)
+}
+
+// dependencyMarker describes where node is found, for DependencyTree
+func dependencyMarker(node *pelican.DependencyNode) string {
+	marker := string(node.Resolution)
+	switch node.Resolution {
+	case pelican.ResolutionRedist:
+		marker += ": " + node.Redist
+	case pelican.ResolutionSxS:
+		marker += ": " + node.Path
+	case pelican.ResolutionMissing:
+		if len(node.FoundAt) > 0 {
+			marker += ", copy at " + strings.Join(node.FoundAt, ", ")
+		}
+	}
+	if node.Delayed {
+		marker += ", delayed"
+	}
+	return marker
+}
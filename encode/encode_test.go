@@ -0,0 +1,87 @@
+package encode_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/itchio/headway/state"
+	"github.com/itchio/httpkit/eos"
+	"github.com/itchio/pelican"
+	"github.com/itchio/pelican/encode"
+	"github.com/stretchr/testify/assert"
+)
+
+func probeFixture(t *testing.T, path string) *pelican.PeInfo {
+	f, err := eos.Open(path)
+	assert.NoError(t, err)
+	defer f.Close()
+
+	info, err := pelican.Probe(f, pelican.ProbeParams{
+		Consumer: &state.Consumer{},
+		Strict:   true,
+	})
+	assert.NoError(t, err)
+	return info
+}
+
+func Test_YAML(t *testing.T) {
+	info := probeFixture(t, "../testdata/wincdemu/WinCDEmu-4.1.exe")
+
+	var sb strings.Builder
+	assert.NoError(t, encode.YAML(&sb, info))
+	out := sb.String()
+	assert.True(t, strings.HasPrefix(out, "arch: \"386\"\n"), "keeps JSON field order")
+	assert.Contains(t, out, "requestedExecutionLevel: requireAdministrator")
+}
+
+func Test_Table(t *testing.T) {
+	info := probeFixture(t, "../testdata/wincdemu/WinCDEmu-4.1.exe")
+
+	var sb strings.Builder
+	assert.NoError(t, encode.Table(&sb, info))
+	out := sb.String()
+	assert.Contains(t, out, "Execution level  requireAdministrator\n")
+	assert.Contains(t, out, "Microsoft.Windows.Common-Controls  6.0.0.0  *     6595b64144ccf1df\n")
+}
+
+func Test_NDJSON(t *testing.T) {
+	info := probeFixture(t, "../testdata/hello/hello32-mingw.exe")
+
+	var sb strings.Builder
+	nw := encode.NewNDJSONWriter(&sb)
+	assert.NoError(t, nw.WriteResult(&pelican.FileResult{Path: "game.exe", Info: info}))
+	assert.NoError(t, nw.WriteResult(&pelican.FileResult{Path: "broken.dll", Error: "invalid\nheaders"}))
+
+	lines := strings.Split(sb.String(), "\n")
+	assert.EqualValues(t, 3, len(lines))
+	assert.True(t, strings.HasPrefix(lines[0], `{"path":"game.exe","info":{"arch":"386",`))
+	assert.EqualValues(t, `{"path":"broken.dll","error":"invalid\nheaders"}`, lines[1])
+	assert.EqualValues(t, "", lines[2])
+}
+
+func Test_DependencyTree(t *testing.T) {
+	root := &pelican.DependencyNode{
+		Name: "game.exe",
+		Path: "game.exe",
+		Children: []*pelican.DependencyNode{
+			{Name: "KERNEL32.dll", Resolution: pelican.ResolutionKnownDLL},
+			{Name: "engine.dll", Path: "engine.dll", Resolution: pelican.ResolutionAppLocal, Children: []*pelican.DependencyNode{
+				{Name: "MSVCP140.dll", Resolution: pelican.ResolutionRedist, Redist: "Visual C++ 2015-2022"},
+				{Name: "fmodex.dll", Resolution: pelican.ResolutionMissing, FoundAt: []string{"lib/fmodex.dll"}},
+			}},
+			{Name: "USER32.dll", Resolution: pelican.ResolutionKnownDLL},
+		},
+	}
+
+	var sb strings.Builder
+	assert.NoError(t, encode.DependencyTree(&sb, root))
+	assert.EqualValues(t, strings.Join([]string{
+		"game.exe",
+		"|-- KERNEL32.dll [known-dll]",
+		"|-- engine.dll [app-local]",
+		"|   |-- MSVCP140.dll [redist: Visual C++ 2015-2022]",
+		"|   `-- fmodex.dll [missing, copy at lib/fmodex.dll]",
+		"`-- USER32.dll [known-dll]",
+		"",
+	}, "\n"), sb.String())
+}
@@ -0,0 +1,222 @@
+package pelican
+
+import (
+	"io"
+	"math"
+	"sort"
+
+	"github.com/itchio/pelican/pe"
+	"github.com/pkg/errors"
+)
+
+// EntropyInfo contains the Shannon entropy of a binary and of its
+// sections, in bits per byte (from 0 to 8). Compressed or encrypted data
+// is close to 8, packed executables usually have sections above 7.
+type EntropyInfo struct {
+	File     float64           `json:"file"`
+	Sections []*SectionEntropy `json:"sections,omitempty"`
+	// Set if only windows spread across the file were read, see
+	// ProbeParams.EntropySampleSize. The values are then estimates.
+	Sampled bool `json:"sampled,omitempty"`
+	// How much of the file was read to compute the above
+	BytesRead int64 `json:"bytesRead"`
+}
+
+// SectionEntropy is the entropy of the raw data of a section
+type SectionEntropy struct {
+	Name    string  `json:"name"`
+	Entropy float64 `json:"entropy"`
+}
+
+// size of the windows read when sampling
+const entropyWindowSize = 64 * 1024
+
+// probeEntropy fills info.Entropy
+func (params *ProbeParams) probeEntropy(info *PeInfo, r io.ReaderAt, size int64, pf *pe.File) error {
+	info.Entropy = nil
+	if !params.reserveOrWarn(info, pooledBufferSize, "entropy computation") {
+		return nil
+	}
+	defer params.release(pooledBufferSize)
+
+	ei, err := params.computeEntropy(r, size, pf)
+	if err != nil {
+		if params.Strict {
+			return errors.WithMessage(err, "while computing entropy")
+		}
+		params.warn(info, WarningSectionUnreadable, err, "Could not compute entropy")
+		return nil
+	}
+	info.Entropy = ei
+	return nil
+}
+
+// computeEntropy reads all of r, or samples it if it's larger than
+// params.EntropySampleSize. Sampling reads windows spread evenly across
+// the file, and across each section so that small ones aren't missed.
+// Only the former count towards the entropy of the whole file, so that
+// it isn't skewed towards small sections.
+func (params *ProbeParams) computeEntropy(r io.ReaderAt, size int64, pf *pe.File) (*EntropyInfo, error) {
+	type sectionRange struct {
+		fileRange
+		histogram [256]int64
+	}
+	var sections []*sectionRange
+	var sectionsSize int64
+	for _, s := range pf.Sections {
+		sr := &sectionRange{fileRange: fileRange{start: int64(s.Offset), end: int64(s.Offset) + int64(s.Size)}}
+		if sr.end > size {
+			sr.end = size
+		}
+		if sr.start < sr.end {
+			sectionsSize += sr.end - sr.start
+		}
+		sections = append(sections, sr)
+	}
+
+	ei := &EntropyInfo{}
+	fileWindows := []fileRange{{start: 0, end: size}}
+	windows := fileWindows
+	if params.EntropySampleSize > 0 && size > params.EntropySampleSize {
+		ei.Sampled = true
+		budget := params.EntropySampleSize / 2
+		fileWindows = sampleWindows(fileRange{start: 0, end: size}, budget)
+		windows = append([]fileRange(nil), fileWindows...)
+		for _, sr := range sections {
+			if sr.start >= sr.end {
+				continue
+			}
+			share := budget * (sr.end - sr.start) / sectionsSize
+			windows = append(windows, sampleWindows(sr.fileRange, share)...)
+		}
+		windows = mergeRanges(windows)
+	}
+
+	pbuf := getBuffer()
+	defer putBuffer(pbuf)
+	buf := *pbuf
+
+	var histogram [256]int64
+	for _, w := range windows {
+		for offset := w.start; offset < w.end; {
+			chunk := buf
+			if int64(len(chunk)) > w.end-offset {
+				chunk = chunk[:w.end-offset]
+			}
+			n, err := r.ReadAt(chunk, offset)
+			if n < len(chunk) {
+				if err == nil {
+					err = io.ErrUnexpectedEOF
+				}
+				return nil, errors.WithMessagef(err, "while reading %d bytes at %x", len(chunk), offset)
+			}
+
+			for _, fw := range fileWindows {
+				countBytes(&histogram, chunk, offset, fw)
+			}
+			for _, sr := range sections {
+				countBytes(&sr.histogram, chunk, offset, sr.fileRange)
+			}
+			offset += int64(n)
+			ei.BytesRead += int64(n)
+		}
+	}
+
+	ei.File = shannonEntropy(&histogram)
+	for i, s := range pf.Sections {
+		ei.Sections = append(ei.Sections, &SectionEntropy{
+			Name:    s.Name,
+			Entropy: shannonEntropy(&sections[i].histogram),
+		})
+	}
+	return ei, nil
+}
+
+// countBytes adds the bytes of chunk (read at offset) that are within fr
+// to histogram
+func countBytes(histogram *[256]int64, chunk []byte, offset int64, fr fileRange) {
+	lo, hi := max64(fr.start, offset), min64(fr.end, offset+int64(len(chunk)))
+	if lo >= hi {
+		return
+	}
+	for _, b := range chunk[lo-offset : hi-offset] {
+		histogram[b]++
+	}
+}
+
+// sampleWindows returns windows of entropyWindowSize spread evenly
+// across fr, totalling about budget bytes (at least one window)
+func sampleWindows(fr fileRange, budget int64) []fileRange {
+	length := fr.end - fr.start
+	if length <= budget || length <= entropyWindowSize {
+		return []fileRange{fr}
+	}
+
+	count := budget / entropyWindowSize
+	if count < 1 {
+		count = 1
+	}
+	step := length / count
+
+	var res []fileRange
+	for i := int64(0); i < count; i++ {
+		start := fr.start + i*step
+		res = append(res, fileRange{start: start, end: min64(start+entropyWindowSize, fr.end)})
+	}
+	return res
+}
+
+// mergeRanges sorts ranges and merges those that overlap,
+// so that no byte is counted twice
+func mergeRanges(ranges []fileRange) []fileRange {
+	sort.Slice(ranges, func(i, j int) bool {
+		return ranges[i].start < ranges[j].start
+	})
+
+	var res []fileRange
+	for _, fr := range ranges {
+		if len(res) > 0 && fr.start <= res[len(res)-1].end {
+			last := &res[len(res)-1]
+			last.end = max64(last.end, fr.end)
+			continue
+		}
+		res = append(res, fr)
+	}
+	return res
+}
+
+// shannonEntropy returns the entropy of a byte histogram in bits
+// per byte, rounded to 3 decimals so that results are stable
+func shannonEntropy(histogram *[256]int64) float64 {
+	var total int64
+	for _, count := range histogram {
+		total += count
+	}
+	if total == 0 {
+		return 0
+	}
+
+	var entropy float64
+	for _, count := range histogram {
+		if count == 0 {
+			continue
+		}
+		p := float64(count) / float64(total)
+		entropy -= p * math.Log2(p)
+	}
+	return math.Round(entropy*1000) / 1000
+}
+
+func min64(a, b int64) int64 {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func max64(a, b int64) int64 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,62 @@
+package pelican_test
+
+import (
+	"testing"
+
+	"github.com/itchio/httpkit/eos"
+	"github.com/itchio/pelican"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Entropy(t *testing.T) {
+	f, err := eos.Open("./testdata/wincdemu/WinCDEmu-4.1.exe")
+	assert.NoError(t, err)
+	defer f.Close()
+
+	stats, err := f.Stat()
+	assert.NoError(t, err)
+
+	params := testProbeParams(t)
+	info, err := pelican.Probe(f, params)
+	assert.NoError(t, err)
+	assert.Nil(t, info.Entropy)
+
+	params.Entropy = true
+	info, err = pelican.Probe(f, params)
+	assert.NoError(t, err)
+	full := info.Entropy
+	assert.False(t, full.Sampled)
+	assert.EqualValues(t, stats.Size(), full.BytesRead)
+	assert.EqualValues(t, []string{"UPX0", "UPX1", ".rsrc"}, sectionNames(full))
+	// packed with UPX
+	assert.True(t, full.File > 7.5, "%v", full.File)
+	assert.EqualValues(t, 0, full.Sections[0].Entropy)
+	assert.True(t, full.Sections[1].Entropy > 7.5, "%v", full.Sections[1].Entropy)
+
+	params.EntropySampleSize = 256 * 1024
+	info, err = pelican.Probe(f, params)
+	assert.NoError(t, err)
+	sampled := info.Entropy
+	assert.True(t, sampled.Sampled)
+	assert.True(t, sampled.BytesRead <= 320*1024, "read %d bytes", sampled.BytesRead)
+	assert.InDelta(t, full.File, sampled.File, 0.2)
+	assert.InDelta(t, full.Sections[1].Entropy, sampled.Sections[1].Entropy, 0.2)
+
+	// a result cached without entropy gets it on the way out
+	params.Cache = pelican.NewDiskCache(t.TempDir())
+	params.Entropy = false
+	_, err = pelican.Probe(f, params)
+	assert.NoError(t, err)
+	params.Entropy = true
+	info, err = pelican.Probe(f, params)
+	assert.NoError(t, err)
+	assert.NotNil(t, info.Entropy)
+}
+
+func sectionNames(ei *pelican.EntropyInfo) []string {
+	var names []string
+	for _, s := range ei.Sections {
+		names = append(names, s.Name)
+	}
+	return names
+}
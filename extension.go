@@ -0,0 +1,88 @@
+package pelican
+
+import (
+	"encoding/json"
+	"sort"
+
+	"github.com/itchio/pelican/pe"
+	"github.com/pkg/errors"
+)
+
+// ExtensionFunc is a custom analysis, see ProbeParams.Extensions.
+// It's called once every built-in analysis is done, and returns a value
+// to store in PeInfo.Extensions, or nil to store nothing. The value
+// must be JSON-serializable.
+//
+// info must not be modified, and pf is only valid during the call.
+type ExtensionFunc func(pf *pe.File, info *PeInfo) (interface{}, error)
+
+// runExtensions stores the results of params.Extensions in info,
+// in order of name. If onlyMissing is set, those info already
+// has results for are skipped.
+func (params *ProbeParams) runExtensions(info *PeInfo, pf *pe.File, onlyMissing bool) error {
+	var names []string
+	for name := range params.Extensions {
+		if _, ok := info.Extensions[name]; onlyMissing && ok {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		data, err := callExtension(params.Extensions[name], pf, info)
+		if err != nil {
+			if params.Strict {
+				return errors.WithMessagef(err, "in extension %s", name)
+			}
+			params.warn(info, WarningExtensionFailed, err, "Extension %s failed", name)
+			continue
+		}
+		if data == nil {
+			continue
+		}
+		if info.Extensions == nil {
+			info.Extensions = make(map[string]json.RawMessage)
+		}
+		info.Extensions[name] = data
+	}
+	return nil
+}
+
+func callExtension(fn ExtensionFunc, pf *pe.File, info *PeInfo) (json.RawMessage, error) {
+	v, err := fn(pf, info)
+	if err != nil || v == nil {
+		return nil, err
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return data, nil
+}
+
+// missingExtensions returns true if info has no results for
+// some of params.Extensions. Those that returned nil are run again.
+func (params *ProbeParams) missingExtensions(info *PeInfo) bool {
+	for name := range params.Extensions {
+		if _, ok := info.Extensions[name]; !ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Extension decodes the result of the extension called name into v,
+// which should be a pointer to the type it returned. It returns false
+// if there's no such result.
+func (pi *PeInfo) Extension(name string, v interface{}) (bool, error) {
+	data, ok := pi.Extensions[name]
+	if !ok {
+		return false, nil
+	}
+	err := json.Unmarshal(data, v)
+	if err != nil {
+		return true, errors.WithMessagef(err, "while decoding extension %s", name)
+	}
+	return true, nil
+}
@@ -0,0 +1,116 @@
+package pelican_test
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/itchio/httpkit/eos"
+	"github.com/itchio/pelican"
+	"github.com/itchio/pelican/pe"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+type sectionCount struct {
+	Sections int    `json:"sections"`
+	Company  string `json:"company"`
+}
+
+func countSections(pf *pe.File, info *pelican.PeInfo) (interface{}, error) {
+	return &sectionCount{
+		Sections: len(pf.Sections),
+		Company:  info.VersionProperties["CompanyName"],
+	}, nil
+}
+
+func Test_Extensions(t *testing.T) {
+	probe := func(params pelican.ProbeParams) (*pelican.PeInfo, error) {
+		f, err := eos.Open("./testdata/resourceful/resourceful32-mingw.exe")
+		assert.NoError(t, err)
+		defer f.Close()
+
+		return pelican.Probe(f, params)
+	}
+
+	params := testProbeParams(t)
+	params.Extensions = map[string]pelican.ExtensionFunc{
+		"example.com/sections": countSections,
+		"example.com/nothing": func(pf *pe.File, info *pelican.PeInfo) (interface{}, error) {
+			return nil, nil
+		},
+	}
+	info, err := probe(params)
+	assert.NoError(t, err)
+	assert.Len(t, info.Extensions, 1)
+
+	// survives a JSON round trip
+	data, err := json.Marshal(info)
+	assert.NoError(t, err)
+	decoded := &pelican.PeInfo{}
+	assert.NoError(t, json.Unmarshal(data, decoded))
+
+	var sc sectionCount
+	ok, err := decoded.Extension("example.com/sections", &sc)
+	assert.NoError(t, err)
+	assert.True(t, ok)
+	assert.True(t, sc.Sections > 0)
+	assert.EqualValues(t, "itch corp.", sc.Company)
+
+	ok, err = decoded.Extension("example.com/nothing", &sc)
+	assert.NoError(t, err)
+	assert.False(t, ok)
+
+	failing := map[string]pelican.ExtensionFunc{
+		"example.com/failing": func(pf *pe.File, info *pelican.PeInfo) (interface{}, error) {
+			return nil, errors.New("no can do")
+		},
+	}
+	params.Extensions = failing
+	_, err = probe(params)
+	assert.Error(t, err)
+
+	params.Strict = false
+	info, err = probe(params)
+	assert.NoError(t, err)
+	assert.Empty(t, info.Extensions)
+	if assert.Len(t, info.Warnings, 1) {
+		assert.EqualValues(t, pelican.WarningExtensionFailed, info.Warnings[0].Code)
+	}
+}
+
+func Test_ExtensionsCached(t *testing.T) {
+	cacheDir, err := ioutil.TempDir("", "pelican-cache")
+	assert.NoError(t, err)
+	defer os.RemoveAll(cacheDir)
+
+	probe := func(params pelican.ProbeParams) *pelican.PeInfo {
+		f, err := eos.Open("./testdata/resourceful/resourceful32-mingw.exe")
+		assert.NoError(t, err)
+		defer f.Close()
+
+		info, err := pelican.Probe(f, params)
+		assert.NoError(t, err)
+		return info
+	}
+
+	cache := &countingCache{Cache: pelican.NewDiskCache(cacheDir)}
+	params := testProbeParams(t)
+	params.Cache = cache
+	info := probe(params)
+	assert.Empty(t, info.Extensions)
+
+	// the cached result doesn't have it, so it's run
+	calls := 0
+	params.Extensions = map[string]pelican.ExtensionFunc{
+		"example.com/sections": func(pf *pe.File, info *pelican.PeInfo) (interface{}, error) {
+			calls++
+			return countSections(pf, info)
+		},
+	}
+	info = probe(params)
+	assert.EqualValues(t, 1, cache.hits)
+	assert.EqualValues(t, 1, calls)
+	assert.Contains(t, info.Extensions, "example.com/sections")
+}
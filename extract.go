@@ -0,0 +1,288 @@
+package pelican
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/itchio/httpkit/eos"
+	"github.com/itchio/pelican/pe"
+	"github.com/itchio/pelican/resources"
+	"github.com/pkg/errors"
+)
+
+// A Sink receives extracted items, which are streamed to the writers
+// it returns instead of being buffered in memory.
+type Sink interface {
+	// Create returns a writer for the item with the given slash-separated
+	// name. pelican closes it once the item has been written.
+	Create(name string) (io.WriteCloser, error)
+}
+
+type dirSink struct {
+	dir string
+}
+
+var _ Sink = (*dirSink)(nil)
+
+// NewDirSink returns a Sink that writes items as files in dir. On Windows,
+// long paths are supported, and characters that aren't allowed in file
+// names are replaced, see DirFS.
+func NewDirSink(dir string) Sink {
+	return &dirSink{dir: extendedPath(dir)}
+}
+
+func (ds *dirSink) Create(name string) (io.WriteCloser, error) {
+	dest, err := ds.itemPath(name)
+	if err != nil {
+		return nil, err
+	}
+	err = os.MkdirAll(filepath.Dir(dest), 0755)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	f, err := os.Create(dest)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return f, nil
+}
+
+// itemPath returns where the item with the given name is written. Names
+// are made of resource names, which come from the binary, so those that
+// would end up outside of ds.dir are rejected.
+func (ds *dirSink) itemPath(name string) (string, error) {
+	if path.IsAbs(name) || filepath.IsAbs(name) || filepath.VolumeName(name) != "" {
+		return "", errors.Errorf("refusing to write item with absolute name %q", name)
+	}
+	for _, e := range strings.Split(name, "/") {
+		if e == ".." {
+			return "", errors.Errorf("refusing to write item %q outside of %s", name, ds.dir)
+		}
+	}
+
+	dest := filepath.Join(ds.dir, filepath.FromSlash(localFileName(path.Clean(name))))
+	rel, err := filepath.Rel(ds.dir, dest)
+	if err != nil || rel == "." || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", errors.Errorf("refusing to write item %q outside of %s", name, ds.dir)
+	}
+	return dest, nil
+}
+
+func writeItem(sink Sink, name string, r io.Reader) error {
+	w, err := sink.Create(name)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	_, err = io.Copy(w, r)
+	if err != nil {
+		w.Close()
+		return errors.WithMessagef(err, "while writing %s", name)
+	}
+	return errors.WithStack(w.Close())
+}
+
+// OverlayRange returns the location of the overlay: data appended
+// after the last section, as installers and self-extracting archives do.
+// The certificate table is excluded when it's at the end of the file.
+// size is 0 if there is no overlay.
+func OverlayRange(file eos.File) (offset int64, size int64, err error) {
+	stats, err := file.Stat()
+	if err != nil {
+		return 0, 0, errors.WithStack(err)
+	}
+
+	pf, err := pe.NewFile(ioErrorReaderAt{file}, stats.Size())
+	if err != nil {
+		return 0, 0, errors.WithStack(err)
+	}
+
+	offset, size = overlayRange(pf, stats.Size())
+	return offset, size, nil
+}
+
+func overlayRange(pf *pe.File, fileSize int64) (int64, int64) {
+	var start int64
+	for _, s := range pf.Sections {
+		end := int64(s.Offset) + int64(s.Size)
+		if end > start {
+			start = end
+		}
+	}
+
+	end := fileSize
+	dirs := dataDirectories(pf)
+	if len(dirs) > pe.IMAGE_DIRECTORY_ENTRY_SECURITY {
+		cert := dirs[pe.IMAGE_DIRECTORY_ENTRY_SECURITY]
+		if cert.Size > 0 && int64(cert.VirtualAddress)+int64(cert.Size) == fileSize && int64(cert.VirtualAddress) >= start {
+			end = int64(cert.VirtualAddress)
+		}
+	}
+
+	if start >= end {
+		return start, 0
+	}
+	return start, end - start
+}
+
+// ExtractOverlay streams the overlay of file (see OverlayRange) to w,
+// and returns the number of bytes written.
+func ExtractOverlay(file eos.File, w io.Writer) (int64, error) {
+	offset, size, err := OverlayRange(file)
+	if err != nil {
+		return 0, err
+	}
+
+	n, err := io.Copy(w, io.NewSectionReader(ioErrorReaderAt{file}, offset, size))
+	if err != nil {
+		return n, errors.WithStack(err)
+	}
+	return n, nil
+}
+
+func (params *ProbeParams) resourceImageOf(file eos.File) (*resources.Image, error) {
+	stats, err := file.Stat()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	pf, err := pe.NewFile(params.readerAt(file), stats.Size())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	return findResources(pf), nil
+}
+
+// ExtractResources writes the raw data of every resource of file to sink,
+// named "<type>/<id or name>/<language>", for example "Manifest/1/1033"
+// or "RcData/CONFIG/0".
+func ExtractResources(file eos.File, sink Sink, params ProbeParams) error {
+	params.setDefaults()
+	img, err := params.resourceImageOf(file)
+	if err != nil {
+		return err
+	}
+	if img == nil {
+		return nil
+	}
+
+	return params.walkResources(nil, img, func(re *resources.Entry) error {
+		if re.Outside {
+			params.Consumer.Warnf("%s resource %s lies outside the resource section (packed executable?), skipping", re.TypeString(), re.IDString())
+			return nil
+		}
+		name := fmt.Sprintf("%s/%s/%d", re.TypeString(), re.IDString(), re.Language)
+		return writeItem(sink, name, io.NewSectionReader(img, re.Offset, int64(re.Size)))
+	})
+}
+
+// ICONDIRENTRY, as found in .ico files
+type iconDirEntry struct {
+	Width       uint8
+	Height      uint8
+	ColorCount  uint8
+	Reserved    uint8
+	Planes      uint16
+	BitCount    uint16
+	BytesInRes  uint32
+	ImageOffset uint32
+}
+
+// ExtractIcons rebuilds an .ico file for every icon group of file
+// and writes it to sink, named "<group id or name>.ico".
+func ExtractIcons(file eos.File, sink Sink, params ProbeParams) error {
+	params.setDefaults()
+	img, err := params.resourceImageOf(file)
+	if err != nil {
+		return err
+	}
+	if img == nil {
+		return nil
+	}
+
+	// group entries refer to individual icons by ID
+	var groups []*resources.Entry
+	icons := make(map[uint32]*resources.Entry)
+	err = params.walkResources(nil, img, func(re *resources.Entry) error {
+		switch {
+		case re.Outside, re.TypeName != "":
+			// compressed, or not a standard type
+		case re.Type == ResourceTypeGroupIcon:
+			entry := *re
+			groups = append(groups, &entry)
+		case re.Type == ResourceTypeIcon && re.Name == "":
+			entry := *re
+			icons[re.ID] = &entry
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, group := range groups {
+		err := writeIcon(sink, img, group, icons)
+		if err != nil {
+			if params.Strict {
+				return errors.WithMessagef(err, "while extracting icon group %s", group.IDString())
+			}
+			params.Consumer.Warnf("Could not extract icon group %s: %+v", group.IDString(), err)
+		}
+	}
+	return nil
+}
+
+func writeIcon(sink Sink, img *resources.Image, group *resources.Entry, icons map[uint32]*resources.Entry) error {
+	br := io.NewSectionReader(img, group.Offset, int64(group.Size))
+	var dir resources.GroupIconDir
+	err := binary.Read(br, binary.LittleEndian, &dir)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	entries := make([]resources.GroupIconDirEntry, dir.Count)
+	err = binary.Read(br, binary.LittleEndian, entries)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	// the header and directory are small, the images are streamed after them
+	header := new(bytes.Buffer)
+	err = binary.Write(header, binary.LittleEndian, dir)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	offset := uint32(binary.Size(dir)) + uint32(dir.Count)*uint32(binary.Size(iconDirEntry{}))
+	readers := []io.Reader{header}
+	for _, e := range entries {
+		icon, ok := icons[uint32(e.ID)]
+		if !ok {
+			return errors.Errorf("icon %d is missing", e.ID)
+		}
+		err = binary.Write(header, binary.LittleEndian, iconDirEntry{
+			Width:       e.Width,
+			Height:      e.Height,
+			ColorCount:  e.ColorCount,
+			Planes:      e.Planes,
+			BitCount:    e.BitCount,
+			BytesInRes:  icon.Size,
+			ImageOffset: offset,
+		})
+		if err != nil {
+			return errors.WithStack(err)
+		}
+		offset += icon.Size
+		readers = append(readers, io.NewSectionReader(img, icon.Offset, int64(icon.Size)))
+	}
+
+	return writeItem(sink, group.IDString()+".ico", io.MultiReader(readers...))
+}
@@ -0,0 +1,126 @@
+package pelican_test
+
+import (
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/itchio/httpkit/eos"
+	"github.com/itchio/pelican"
+	"github.com/stretchr/testify/assert"
+)
+
+type memSink struct {
+	items map[string]*bytes.Buffer
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nwc nopWriteCloser) Close() error { return nil }
+
+func (ms *memSink) Create(name string) (io.WriteCloser, error) {
+	buf := new(bytes.Buffer)
+	ms.items[name] = buf
+	return nopWriteCloser{buf}, nil
+}
+
+func newMemSink() *memSink {
+	return &memSink{items: make(map[string]*bytes.Buffer)}
+}
+
+func Test_ExtractResources(t *testing.T) {
+	f, err := eos.Open("./testdata/resourceful/resourceful32-mingw.exe")
+	assert.NoError(t, err)
+	defer f.Close()
+
+	sink := newMemSink()
+	assert.NoError(t, pelican.ExtractResources(f, sink, testProbeParams(t)))
+	assert.Len(t, sink.items, 7)
+	assert.Contains(t, sink.items, "GroupIcon/101/1033")
+	assert.Contains(t, sink.items, "Version/1/1033")
+
+	sink = newMemSink()
+	assert.NoError(t, pelican.ExtractIcons(f, sink, testProbeParams(t)))
+	assert.Len(t, sink.items, 1)
+
+	expected, err := ioutil.ReadFile("./testdata/resourceful/pelican.ico")
+	assert.NoError(t, err)
+	assert.True(t, bytes.Equal(expected, sink.items["101.ico"].Bytes()))
+}
+
+func Test_ExtractResourcesTraversal(t *testing.T) {
+	data, err := ioutil.ReadFile("./testdata/resourceful/resourceful32-blobs.exe")
+	assert.NoError(t, err)
+	// rename the README.HTM resource so that it's
+	// written to "HTML/../../pwned/1033"
+	name := func(s string) []byte {
+		var res []byte
+		for _, r := range s {
+			res = append(res, byte(r), 0)
+		}
+		return res
+	}
+	assert.Equal(t, 1, bytes.Count(data, name("README.HTM")))
+	data = bytes.Replace(data, name("README.HTM"), name("../../pwnd"), 1)
+
+	fsys := fstest.MapFS{"blobs.exe": {Data: data}}
+	f, err := fsys.Open("blobs.exe")
+	assert.NoError(t, err)
+	defer f.Close()
+
+	sink := newMemSink()
+	assert.NoError(t, pelican.ExtractResources(f.(eos.File), sink, testProbeParams(t)))
+	assert.Contains(t, sink.items, "HTML/../../pwnd/1033")
+
+	dir := t.TempDir()
+	out := filepath.Join(dir, "a", "b")
+	err = pelican.ExtractResources(f.(eos.File), pelican.NewDirSink(out), testProbeParams(t))
+	assert.Error(t, err)
+	_, err = os.Stat(filepath.Join(dir, "pwnd"))
+	assert.True(t, os.IsNotExist(err))
+
+	for _, name := range []string{"../escape", "/etc/passwd", "a/../../escape", ".", ""} {
+		_, err = pelican.NewDirSink(out).Create(name)
+		assert.Error(t, err, name)
+	}
+	w, err := pelican.NewDirSink(out).Create("a/./c")
+	assert.NoError(t, err)
+	assert.NoError(t, w.Close())
+	_, err = os.Stat(filepath.Join(out, "a", "c"))
+	assert.NoError(t, err)
+}
+
+func Test_ExtractOverlay(t *testing.T) {
+	extract := func(path string) (int64, []byte) {
+		f, err := eos.Open(path)
+		assert.NoError(t, err)
+		defer f.Close()
+
+		offset, size, err := pelican.OverlayRange(f)
+		assert.NoError(t, err)
+
+		buf := new(bytes.Buffer)
+		n, err := pelican.ExtractOverlay(f, buf)
+		assert.NoError(t, err)
+		assert.EqualValues(t, size, n)
+		return offset, buf.Bytes()
+	}
+
+	// the certificate table that follows it is not part of the overlay
+	offset, overlay := extract("./testdata/pidgin/pidgin-uninst.exe")
+	assert.EqualValues(t, 51712, offset)
+	assert.EqualValues(t, 53213, len(overlay))
+	assert.EqualValues(t, "NullsoftInst", string(overlay[8:20]))
+
+	_, overlay = extract("./testdata/stockboy/stockboy_install_sliced.EXE")
+	assert.True(t, bytes.HasPrefix(overlay, []byte(";!@Install@!UTF-8!")))
+
+	_, overlay = extract("./testdata/hello/hello32-msvc.exe")
+	assert.Empty(t, overlay)
+}
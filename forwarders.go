@@ -0,0 +1,174 @@
+package pelican
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/itchio/httpkit/eos"
+	"github.com/itchio/pelican/pe"
+	"github.com/pkg/errors"
+)
+
+// BrokenForwarder is an export forwarded to a DLL of the directory
+// that doesn't export the target, either directly or through more
+// forwarders. Importing it makes the loader fail with "procedure entry
+// point not found", which is hard to trace back to the forwarder.
+type BrokenForwarder struct {
+	// Slash-separated path (relative to the directory) of the DLL with the forwarder
+	Path string `json:"path"`
+	// Exported name, or "#" followed by the ordinal
+	Export string `json:"export"`
+	// Forwarder targets in the order they're resolved,
+	// for example ["core.Alloc", "util.Alloc"]
+	Chain   []string `json:"chain"`
+	Message string   `json:"message"`
+}
+
+// exportTable is what findBrokenForwarders needs to know about
+// the exports of a file
+type exportTable struct {
+	names      map[string]bool
+	forwarders map[string]string
+}
+
+func readExportTable(file eos.File) (*exportTable, error) {
+	stats, err := file.Stat()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	pf, err := pe.NewFile(file, stats.Size())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	names, err := pf.ExportedNames()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	forwarders, err := pf.Forwarders()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	et := &exportTable{
+		names:      make(map[string]bool),
+		forwarders: make(map[string]string),
+	}
+	for _, name := range names {
+		et.names[name] = true
+	}
+	for _, fw := range forwarders {
+		et.forwarders[fw.Name] = fw.Target
+	}
+	return et, nil
+}
+
+// isSystemForwarderTarget returns true for DLLs the loader never
+// takes from the application directory
+func isSystemForwarderTarget(name string) bool {
+	return knownDLLs[name] || strings.HasPrefix(name, "api-ms-") || strings.HasPrefix(name, "ext-ms-")
+}
+
+// findBrokenForwarders follows the forwarders of every file, as long as
+// they lead to DLLs in the same folder (or private side-by-side assemblies).
+// Targets that are system DLLs, or aren't in the directory at all, are
+// assumed to resolve.
+func findBrokenForwarders(tables map[string]*exportTable, sxs map[string]map[string]*SxSRedirection) []*BrokenForwarder {
+	byLowerPath := make(map[string]string)
+	for p := range tables {
+		byLowerPath[strings.ToLower(p)] = p
+	}
+
+	var res []*BrokenForwarder
+	for p, et := range tables {
+		if et == nil {
+			continue
+		}
+		for name, target := range et.forwarders {
+			chain, problem := resolveForwarder(tables, byLowerPath, sxs, p, name, target)
+			if problem == "" {
+				continue
+			}
+			res = append(res, &BrokenForwarder{
+				Path:    p,
+				Export:  name,
+				Chain:   chain,
+				Message: problem,
+			})
+		}
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Path != res[j].Path {
+			return res[i].Path < res[j].Path
+		}
+		return res[i].Export < res[j].Export
+	})
+	return res
+}
+
+// resolveForwarder returns a description of the problem if the forwarder
+// of export name in the file at p doesn't resolve, or "" if it does
+// (or might). The chain of targets followed is returned either way.
+func resolveForwarder(tables map[string]*exportTable, byLowerPath map[string]string, sxs map[string]map[string]*SxSRedirection, p string, name string, target string) ([]string, string) {
+	visited := map[string]bool{
+		p + "!" + name: true,
+	}
+
+	var chain []string
+	for {
+		chain = append(chain, target)
+
+		i := strings.LastIndex(target, ".")
+		if i <= 0 || i == len(target)-1 {
+			return chain, fmt.Sprintf("malformed forwarder %q", target)
+		}
+		dll, sym := strings.ToLower(target[:i]), target[i+1:]
+		if path.Ext(dll) == "" {
+			dll += ".dll"
+		}
+		if isSystemForwarderTarget(dll) || strings.HasPrefix(sym, "#") {
+			return chain, ""
+		}
+
+		// forwarded DLLs are searched for like imported ones, so
+		// side-by-side assemblies, then the directory of the forwarding
+		// DLL come first
+		lookup := strings.ToLower(path.Join(path.Dir(p), dll))
+		if r := sxs[path.Dir(p)][dll]; r != nil {
+			if r.Target == "" {
+				return chain, ""
+			}
+			lookup = strings.ToLower(r.Target)
+		}
+		tp, ok := byLowerPath[lookup]
+		if !ok {
+			return chain, ""
+		}
+
+		key := tp + "!" + sym
+		if visited[key] {
+			return chain, fmt.Sprintf("forwarder loop: %s forwards back to %s", target, key)
+		}
+		visited[key] = true
+
+		et := tables[tp]
+		if et == nil {
+			return chain, fmt.Sprintf("%s has no exports, %s can't be resolved", tp, sym)
+		}
+		if next, ok := et.forwarders[sym]; ok {
+			p, target = tp, next
+			continue
+		}
+		if !et.names[sym] {
+			return chain, fmt.Sprintf("%s does not export %s", tp, sym)
+		}
+		return chain, ""
+	}
+}
@@ -1,6 +1,6 @@
 module github.com/itchio/pelican
 
-go 1.12
+go 1.16
 
 require (
 	github.com/basgys/goxml2json v1.1.0
@@ -11,4 +11,6 @@ require (
 	github.com/kr/pretty v0.1.0 // indirect
 	github.com/pkg/errors v0.9.1
 	github.com/stretchr/testify v1.5.1
+	golang.org/x/text v0.3.2
+	gopkg.in/yaml.v2 v2.2.8
 )
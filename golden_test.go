@@ -0,0 +1,86 @@
+package pelican_test
+
+import (
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/itchio/httpkit/eos"
+	"github.com/itchio/pelican"
+	"github.com/stretchr/testify/assert"
+)
+
+// Every executable in testdata has a golden file in testdata/golden,
+// with its probe result as JSON. When parsing changes on purpose, or
+// when adding a sample binary, regenerate them with:
+//
+//	go test -run Test_Golden -update
+//
+// and review the diff.
+var update = flag.Bool("update", false, "update golden files in testdata/golden")
+
+const goldenDir = "testdata/golden"
+
+func goldenSamples(t *testing.T) []string {
+	var res []string
+	err := filepath.Walk("testdata", func(path string, fi os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if fi.IsDir() {
+			if path == goldenDir {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".exe", ".dll", ".scr", ".cpl":
+			res = append(res, path)
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	return res
+}
+
+func Test_Golden(t *testing.T) {
+	for _, sample := range goldenSamples(t) {
+		rel, err := filepath.Rel("testdata", sample)
+		assert.NoError(t, err)
+		goldenPath := filepath.Join(goldenDir, rel+".json")
+
+		t.Run(filepath.ToSlash(rel), func(t *testing.T) {
+			f, err := eos.Open(sample)
+			assert.NoError(t, err)
+			defer f.Close()
+
+			// not strict, so that warnings show up in golden files
+			params := testProbeParams(t)
+			params.Strict = false
+			params.ElevationHeuristics = true
+			info, err := pelican.Probe(f, params)
+			assert.NoError(t, err)
+
+			actual, err := json.MarshalIndent(info, "", "  ")
+			assert.NoError(t, err)
+			actual = append(actual, '\n')
+
+			if *update {
+				assert.NoError(t, os.MkdirAll(filepath.Dir(goldenPath), 0755))
+				assert.NoError(t, ioutil.WriteFile(goldenPath, actual, 0644))
+				return
+			}
+
+			expected, err := ioutil.ReadFile(goldenPath)
+			if os.IsNotExist(err) {
+				t.Fatalf("%s is missing, run with -update to create it", goldenPath)
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, string(expected), string(actual), "probe result differs from %s, run with -update if that's expected", goldenPath)
+		})
+	}
+}
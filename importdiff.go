@@ -0,0 +1,213 @@
+package pelican
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/itchio/httpkit/eos"
+	"github.com/itchio/pelican/pe"
+	"github.com/pkg/errors"
+)
+
+// ImportDiff lists how the dependencies of a binary changed between two
+// versions, to catch new runtime requirements before they ship.
+// Names are compared case-insensitively, and reported as spelled by
+// the version they're found in.
+type ImportDiff struct {
+	AddedLibraries   []string `json:"addedLibraries,omitempty"`
+	RemovedLibraries []string `json:"removedLibraries,omitempty"`
+
+	// Side-by-side assemblies the manifest depends on
+	AddedAssemblies   []*AssemblyIdentity `json:"addedAssemblies,omitempty"`
+	RemovedAssemblies []*AssemblyIdentity `json:"removedAssemblies,omitempty"`
+
+	// Versions of the Visual C++ Redistributable required, see CRTInfo.Redist.
+	// Only set if they differ.
+	OldRedist string `json:"oldRedist,omitempty"`
+	NewRedist string `json:"newRedist,omitempty"`
+
+	// Minimum versions of Windows inferred from imports, see
+	// Compatibility.ImportsMinVersion. Only set if they differ.
+	OldImportsMinVersion *WindowsVersion `json:"oldImportsMinVersion,omitempty"`
+	NewImportsMinVersion *WindowsVersion `json:"newImportsMinVersion,omitempty"`
+
+	// Only set by DiffImportsFiles, since PeInfo doesn't list them.
+	// Imported symbols are formatted like "function:library",
+	// or "#ordinal:library", see pe.ImportedSymbol.String.
+	AddedSymbols   []string `json:"addedSymbols,omitempty"`
+	RemovedSymbols []string `json:"removedSymbols,omitempty"`
+	AddedExports   []string `json:"addedExports,omitempty"`
+	RemovedExports []string `json:"removedExports,omitempty"`
+}
+
+// IsEmpty returns true if nothing changed
+func (d *ImportDiff) IsEmpty() bool {
+	return len(d.AddedLibraries) == 0 && len(d.RemovedLibraries) == 0 &&
+		len(d.AddedAssemblies) == 0 && len(d.RemovedAssemblies) == 0 &&
+		d.OldRedist == d.NewRedist && d.OldImportsMinVersion == nil && d.NewImportsMinVersion == nil &&
+		len(d.AddedSymbols) == 0 && len(d.RemovedSymbols) == 0 &&
+		len(d.AddedExports) == 0 && len(d.RemovedExports) == 0
+}
+
+// NewRequirements returns human-readable descriptions of what the new
+// version needs that the old one didn't, for example
+// "requires Visual C++ Redistributable 2015-2022 (was none)"
+func (d *ImportDiff) NewRequirements() []string {
+	var res []string
+	for _, lib := range d.AddedLibraries {
+		res = append(res, fmt.Sprintf("imports %s", lib))
+	}
+	for _, ai := range d.AddedAssemblies {
+		res = append(res, fmt.Sprintf("depends on assembly %s %s", ai.Name, ai.Version))
+	}
+	if d.NewRedist != "" && d.NewRedist != d.OldRedist {
+		old := d.OldRedist
+		if old == "" {
+			old = "none"
+		}
+		res = append(res, fmt.Sprintf("requires Visual C++ Redistributable %s (was %s)", d.NewRedist, old))
+	}
+	if d.OldImportsMinVersion != nil && d.NewImportsMinVersion != nil && d.OldImportsMinVersion.Less(*d.NewImportsMinVersion) {
+		res = append(res, fmt.Sprintf("imports require %s (was %s)", d.NewImportsMinVersion, d.OldImportsMinVersion))
+	}
+	return res
+}
+
+// DiffImports compares the dependencies of two versions of a binary
+func DiffImports(before, after *PeInfo) *ImportDiff {
+	d := &ImportDiff{}
+	d.AddedLibraries, d.RemovedLibraries = diffNames(before.Imports, after.Imports, strings.ToLower)
+
+	assemblyKey := func(ai *AssemblyIdentity) string {
+		return strings.ToLower(ai.Name + " " + ai.Version + " " + ai.ProcessorArchitecture)
+	}
+	oldAssemblies := make(map[string]bool)
+	for _, ai := range before.DependentAssemblies {
+		oldAssemblies[assemblyKey(ai)] = true
+	}
+	newAssemblies := make(map[string]bool)
+	for _, ai := range after.DependentAssemblies {
+		newAssemblies[assemblyKey(ai)] = true
+		if !oldAssemblies[assemblyKey(ai)] {
+			d.AddedAssemblies = append(d.AddedAssemblies, ai)
+		}
+	}
+	for _, ai := range before.DependentAssemblies {
+		if !newAssemblies[assemblyKey(ai)] {
+			d.RemovedAssemblies = append(d.RemovedAssemblies, ai)
+		}
+	}
+
+	redist := func(info *PeInfo) string {
+		if info.CRT == nil {
+			return ""
+		}
+		return info.CRT.Redist
+	}
+	if oldRedist, newRedist := redist(before), redist(after); oldRedist != newRedist {
+		d.OldRedist, d.NewRedist = oldRedist, newRedist
+	}
+
+	if before.Compatibility != nil && after.Compatibility != nil {
+		oldVersion, newVersion := before.Compatibility.ImportsMinVersion, after.Compatibility.ImportsMinVersion
+		if oldVersion != newVersion {
+			d.OldImportsMinVersion, d.NewImportsMinVersion = &oldVersion, &newVersion
+		}
+	}
+	return d
+}
+
+// DiffImportsFiles is like DiffImports, but also compares
+// the imported symbols and exports of both files
+func DiffImportsFiles(before, after eos.File, params ProbeParams) (*ImportDiff, error) {
+	oldInfo, err := Probe(before, params)
+	if err != nil {
+		return nil, errors.WithMessage(err, "while probing old version")
+	}
+	newInfo, err := Probe(after, params)
+	if err != nil {
+		return nil, errors.WithMessage(err, "while probing new version")
+	}
+	d := DiffImports(oldInfo, newInfo)
+
+	oldSymbols, oldExports, err := params.readSymbols(before)
+	if err != nil {
+		return nil, errors.WithMessage(err, "while reading old version")
+	}
+	newSymbols, newExports, err := params.readSymbols(after)
+	if err != nil {
+		return nil, errors.WithMessage(err, "while reading new version")
+	}
+
+	// library names are case-insensitive, function names aren't
+	symbolKey := func(sym string) string {
+		if i := strings.LastIndex(sym, ":"); i >= 0 {
+			return sym[:i+1] + strings.ToLower(sym[i+1:])
+		}
+		return sym
+	}
+	d.AddedSymbols, d.RemovedSymbols = diffNames(oldSymbols, newSymbols, symbolKey)
+	d.AddedExports, d.RemovedExports = diffNames(oldExports, newExports, nil)
+	return d, nil
+}
+
+// readSymbols returns the imported symbols and exported names of file
+func (params *ProbeParams) readSymbols(file eos.File) ([]string, []string, error) {
+	stats, err := file.Stat()
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+
+	pf, err := pe.NewFile(params.readerAt(file), stats.Size())
+	if err != nil {
+		return nil, nil, errors.WithStack(err)
+	}
+
+	imported, err := pf.ImportedSymbols()
+	if err != nil {
+		return nil, nil, errors.WithMessage(err, "while parsing imported symbols")
+	}
+	var symbols []string
+	for _, sym := range params.resolveOrdinals(imported) {
+		symbols = append(symbols, sym.String())
+	}
+	exports, err := pf.ExportedNames()
+	if err != nil {
+		return nil, nil, errors.WithMessage(err, "while parsing exports")
+	}
+	return symbols, exports, nil
+}
+
+// diffNames returns the names only found in after, then those only found
+// in before, both sorted. If key is non-nil, names are compared by key.
+func diffNames(before, after []string, key func(s string) string) (added []string, removed []string) {
+	if key == nil {
+		key = func(s string) string { return s }
+	}
+
+	inOld := make(map[string]bool)
+	for _, s := range before {
+		inOld[key(s)] = true
+	}
+	inNew := make(map[string]bool)
+	for _, s := range after {
+		k := key(s)
+		if !inOld[k] && !inNew[k] {
+			added = append(added, s)
+		}
+		inNew[k] = true
+	}
+	seen := make(map[string]bool)
+	for _, s := range before {
+		k := key(s)
+		if !inNew[k] && !seen[k] {
+			removed = append(removed, s)
+		}
+		seen[k] = true
+	}
+
+	sort.Strings(added)
+	sort.Strings(removed)
+	return added, removed
+}
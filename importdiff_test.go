@@ -0,0 +1,82 @@
+package pelican_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/itchio/httpkit/eos"
+	"github.com/itchio/pelican"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_DiffImports(t *testing.T) {
+	before := &pelican.PeInfo{
+		Imports: []string{"KERNEL32.dll", "msvcr120.dll", "d3d9.dll"},
+		DependentAssemblies: []*pelican.AssemblyIdentity{
+			{Name: "Microsoft.Windows.Common-Controls", Version: "6.0.0.0"},
+		},
+		CRT:           &pelican.CRTInfo{Redist: "2013"},
+		Compatibility: &pelican.Compatibility{ImportsMinVersion: pelican.WindowsXP},
+	}
+	after := &pelican.PeInfo{
+		Imports: []string{"kernel32.dll", "VCRUNTIME140.dll", "d3d12.dll", "d3d12.dll"},
+		DependentAssemblies: []*pelican.AssemblyIdentity{
+			{Name: "Microsoft.Windows.Common-Controls", Version: "6.0.0.0"},
+			{Name: "Microsoft.VC90.CRT", Version: "9.0.21022.8"},
+		},
+		CRT:           &pelican.CRTInfo{Redist: "2015-2022"},
+		Compatibility: &pelican.Compatibility{ImportsMinVersion: pelican.Windows10},
+	}
+
+	d := pelican.DiffImports(before, after)
+	assert.False(t, d.IsEmpty())
+	assert.EqualValues(t, []string{"VCRUNTIME140.dll", "d3d12.dll"}, d.AddedLibraries)
+	assert.EqualValues(t, []string{"d3d9.dll", "msvcr120.dll"}, d.RemovedLibraries)
+	assert.EqualValues(t, 1, len(d.AddedAssemblies))
+	assert.Empty(t, d.RemovedAssemblies)
+	assert.EqualValues(t, []string{
+		"imports VCRUNTIME140.dll",
+		"imports d3d12.dll",
+		"depends on assembly Microsoft.VC90.CRT 9.0.21022.8",
+		"requires Visual C++ Redistributable 2015-2022 (was 2013)",
+		"imports require Windows 10 (was Windows XP)",
+	}, d.NewRequirements())
+
+	// the other way around, nothing new is required but the libraries
+	d = pelican.DiffImports(after, before)
+	assert.EqualValues(t, []string{
+		"imports d3d9.dll",
+		"imports msvcr120.dll",
+		"requires Visual C++ Redistributable 2013 (was 2015-2022)",
+	}, d.NewRequirements())
+
+	assert.True(t, pelican.DiffImports(before, before).IsEmpty())
+}
+
+func Test_DiffImportsFiles(t *testing.T) {
+	fsys := fstest.MapFS{
+		"v1/game.exe": fixtureFile(t, "./testdata/hello/hello32-mingw.exe"),
+		"v2/game.exe": importing(t, "./testdata/hello/hello32-mingw.exe", "ENGINE.dll"),
+		"v1/core.dll": fixtureFile(t, "./testdata/forwarders/core.dll"),
+		"v2/core.dll": fixtureFile(t, "./testdata/forwarders/engine.dll"),
+	}
+	open := func(p string) eos.File {
+		f, err := fsys.Open(p)
+		assert.NoError(t, err)
+		return f.(eos.File)
+	}
+
+	d, err := pelican.DiffImportsFiles(open("v1/game.exe"), open("v2/game.exe"), testProbeParams(t))
+	assert.NoError(t, err)
+	assert.EqualValues(t, []string{"ENGINE.dll"}, d.AddedLibraries)
+	assert.EqualValues(t, []string{"msvcrt.dll"}, d.RemovedLibraries)
+	assert.Contains(t, d.AddedSymbols, "puts:ENGINE.dll")
+	assert.Contains(t, d.RemovedSymbols, "puts:msvcrt.dll")
+	assert.NotContains(t, d.AddedSymbols, "ExitProcess:KERNEL32.dll")
+	assert.Empty(t, d.AddedExports)
+
+	d, err = pelican.DiffImportsFiles(open("v1/core.dll"), open("v2/core.dll"), testProbeParams(t))
+	assert.NoError(t, err)
+	assert.EqualValues(t, []string{"Free", "Heap", "Init", "Render"}, d.AddedExports)
+	assert.Empty(t, d.RemovedExports)
+}
@@ -0,0 +1,52 @@
+package pelican
+
+import "github.com/itchio/pelican/pe"
+
+// ImportMetrics are counts and sizes from the import tables of a binary.
+// They're cheap to compute, and packed or hand-crafted binaries
+// usually stand out: few functions, or an IAT that doesn't
+// match the number of imported functions.
+type ImportMetrics struct {
+	// Number of DLLs in the import directory (same as len(PeInfo.Imports))
+	DLLs int64 `json:"dlls"`
+	// Number of DLLs in the delay-load import directory
+	DelayDLLs int64 `json:"delayDlls,omitempty"`
+	// Number of functions imported from the import directory,
+	// by name or by ordinal
+	Functions int64 `json:"functions"`
+	// Of those, how many are imported by an ordinal pelican
+	// doesn't know the name of, see OrdinalNames
+	UnresolvedOrdinals int64 `json:"unresolvedOrdinals,omitempty"`
+	// Size of the import address table, in bytes, as given by its data
+	// directory. 0 if there's none, which the loader tolerates.
+	IATSize int64 `json:"iatSize"`
+	// Size the import address table would have with one slot per
+	// imported function, plus one null slot per DLL, as linkers lay it out
+	ExpectedIATSize int64 `json:"expectedIatSize"`
+}
+
+// importMetrics computes the ImportMetrics of pf, from the imports
+// parseImports found (symbols is after resolveOrdinals)
+func importMetrics(info *PeInfo, pf *pe.File, symbols []pe.ImportedSymbol) *ImportMetrics {
+	oh := pf.OptionalHeaderFields()
+	im := &ImportMetrics{
+		DLLs:      int64(len(info.Imports)),
+		DelayDLLs: int64(len(info.DelayImports)),
+		Functions: int64(len(symbols)),
+		IATSize:   int64(oh.Directory(pe.IMAGE_DIRECTORY_ENTRY_IAT).Size),
+	}
+	for _, sym := range symbols {
+		if sym.ByOrdinal() {
+			im.UnresolvedOrdinals++
+		}
+	}
+
+	slotSize := int64(4)
+	if oh.PE64 {
+		slotSize = 8
+	}
+	if im.DLLs > 0 {
+		im.ExpectedIATSize = (im.Functions + im.DLLs) * slotSize
+	}
+	return im
+}
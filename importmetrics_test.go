@@ -0,0 +1,36 @@
+package pelican
+
+import (
+	"os"
+	"testing"
+
+	"github.com/itchio/pelican/pe"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ImportMetrics(t *testing.T) {
+	f, err := os.Open("./testdata/hello/hello32-msvc.exe")
+	assert.NoError(t, err)
+	defer f.Close()
+	stats, err := f.Stat()
+	assert.NoError(t, err)
+	pf, err := pe.NewFile(f, stats.Size())
+	assert.NoError(t, err)
+
+	info := &PeInfo{Imports: []string{"KERNEL32.dll", "ws2_32.dll"}}
+	im := importMetrics(info, pf, importedSymbols(
+		"CreateFileW:KERNEL32.dll",
+		"#999:ws2_32.dll",
+		"#115:ws2_32.dll",
+	))
+	assert.EqualValues(t, 2, im.DLLs)
+	assert.EqualValues(t, 3, im.Functions)
+	assert.EqualValues(t, 2, im.UnresolvedOrdinals)
+	// 3 functions and 2 null slots, 32-bit
+	assert.EqualValues(t, 20, im.ExpectedIATSize)
+	assert.EqualValues(t, 276, im.IATSize)
+
+	im = importMetrics(&PeInfo{}, pf, nil)
+	assert.EqualValues(t, 0, im.Functions)
+	assert.EqualValues(t, 0, im.ExpectedIATSize)
+}
@@ -0,0 +1,98 @@
+package pelican
+
+import (
+	"regexp"
+)
+
+// Indicators are strings embedded in a binary that are of interest
+// for moderation and security review: what it connects to, which
+// registry keys it touches, etc. They're found in the data sections,
+// as ASCII or UTF-16, but may be unused by the code.
+type Indicators struct {
+	URLs []string `json:"urls,omitempty"`
+	// Registry key paths, for example "HKEY_LOCAL_MACHINE\SOFTWARE\Valve\Steam"
+	RegistryKeys []string `json:"registryKeys,omitempty"`
+	// Named kernel objects (usually mutexes), for example "Global\MyGameInstance"
+	Mutexes []string `json:"mutexes,omitempty"`
+	// Named pipes, for example "\\.\pipe\discord-ipc-0"
+	Pipes []string `json:"pipes,omitempty"`
+	// Absolute paths of source or debug files on the build machine,
+	// for example "C:\Users\dev\game\src\main.cpp". They often
+	// contain user names, see PeInfo.Redacted.
+	BuildPaths []string `json:"buildPaths,omitempty"`
+}
+
+// at most this many of each kind are reported
+const maxIndicators = 100
+
+var (
+	urlRegexp         = regexp.MustCompile(`(?i)\b(?:https?|ftp)://[a-z0-9][a-z0-9.\-]*(?::\d+)?(?:/[!#$%&'()*+,\-./0-9:;=?@A-Z_a-z~]*)?`)
+	registryKeyRegexp = regexp.MustCompile(`(?i)\b(?:HKEY_(?:LOCAL_MACHINE|CURRENT_USER|CLASSES_ROOT|USERS|CURRENT_CONFIG)|HKLM|HKCU|HKCR|HKU|Software|System)\\[\x20-\x5b\x5d-\x7e]+(?:\\[\x20-\x5b\x5d-\x7e]+)*`)
+	mutexRegexp       = regexp.MustCompile(`\b(?:Global|Local)\\[\x21-\x5b\x5d-\x7e]{3,}`)
+	pipeRegexp        = regexp.MustCompile(`\\\\\.\\pipe\\[\x21-\x7e]+`)
+	// printable characters allowed in Windows file names, then in Unix
+	// ones (minus spaces, which are rare there)
+	buildPathRegexp = regexp.MustCompile(`\b[A-Za-z]:\\(?:[\x20\x21\x23-\x29\x2b-\x2e\x30-\x39\x3b\x3d\x40-\x5b\x5d-\x7b\x7d\x7e]+\\)*[\x20\x21\x23-\x29\x2b-\x2e\x30-\x39\x3b\x3d\x40-\x5b\x5d-\x7b\x7d\x7e]+\.` + sourceExtensions +
+		`|/(?:home|Users)/(?:[\x21-\x2e\x30-\x7e]+/)*[\x21-\x2e\x30-\x7e]+\.` + sourceExtensions)
+)
+
+const sourceExtensions = `(?i:pdb|c|cc|cpp|cxx|h|hh|hpp|inl|cs|pas|dpr|rs|go|m|mm)\b`
+
+type indicatorList struct {
+	re    *regexp.Regexp
+	items *[]string
+}
+
+// indicatorScanner collects Indicators, see scanDataSections
+type indicatorScanner struct {
+	indicators Indicators
+	lists      []indicatorList
+	seen       map[string]bool
+}
+
+func newIndicatorScanner() *indicatorScanner {
+	is := &indicatorScanner{
+		seen: make(map[string]bool),
+	}
+	is.lists = []indicatorList{
+		{urlRegexp, &is.indicators.URLs},
+		{registryKeyRegexp, &is.indicators.RegistryKeys},
+		{mutexRegexp, &is.indicators.Mutexes},
+		{pipeRegexp, &is.indicators.Pipes},
+		{buildPathRegexp, &is.indicators.BuildPaths},
+	}
+	return is
+}
+
+func (is *indicatorScanner) scan(chunk []byte) {
+	is.scanNarrow(chunk)
+	is.scanNarrow(narrowUTF16(chunk))
+}
+
+func (is *indicatorScanner) scanNarrow(chunk []byte) {
+	for _, il := range is.lists {
+		for _, loc := range il.re.FindAllIndex(chunk, -1) {
+			if len(*il.items) >= maxIndicators {
+				break
+			}
+			if loc[1] == len(chunk) {
+				// may be cut off, in which case the next chunk has it whole
+				continue
+			}
+			s := string(chunk[loc[0]:loc[1]])
+			if !is.seen[s] {
+				is.seen[s] = true
+				*il.items = append(*il.items, s)
+			}
+		}
+	}
+}
+
+// result returns the collected indicators, or nil if there are none
+func (is *indicatorScanner) result() *Indicators {
+	ind := is.indicators
+	if len(ind.URLs)+len(ind.RegistryKeys)+len(ind.Mutexes)+len(ind.Pipes)+len(ind.BuildPaths) == 0 {
+		return nil
+	}
+	return &ind
+}
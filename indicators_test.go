@@ -0,0 +1,52 @@
+package pelican
+
+import (
+	"testing"
+	"unicode/utf16"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func wide(s string) []byte {
+	var res []byte
+	for _, u := range utf16.Encode([]rune(s)) {
+		res = append(res, byte(u), byte(u>>8))
+	}
+	return append(res, 0, 0)
+}
+
+func Test_NarrowUTF16(t *testing.T) {
+	chunk := append([]byte{0xff}, wide("hello")...)
+	chunk = append(chunk, wide("world")...)
+	assert.EqualValues(t, "hello\x00world\x00", string(narrowUTF16(chunk)))
+
+	// cut off at the end of the chunk
+	assert.EqualValues(t, "", string(narrowUTF16(wide("hello")[:6])))
+}
+
+func Test_IndicatorScanner(t *testing.T) {
+	var chunk []byte
+	chunk = append(chunk, "\x00https://example.org/api?v=1\x00Global\\MyGameInstance\x00"...)
+	chunk = append(chunk, wide(`\\.\pipe\discord-ipc-0`)...)
+	chunk = append(chunk, wide(`HKEY_CURRENT_USER\Software\Example\Game`)...)
+	chunk = append(chunk, "\x00C:\\Users\\Jane Doe\\source\\Game\\x64\\Release\\Game.pdb\x00"...)
+	chunk = append(chunk, wide("/home/ci/build/src/main.cpp")...)
+	chunk = append(chunk, "https://example.org/cut-off"...)
+
+	is := newIndicatorScanner()
+	is.scan(chunk)
+	// chunks overlap, duplicates are ignored
+	is.scan(chunk)
+	assert.EqualValues(t, &Indicators{
+		URLs:         []string{"https://example.org/api?v=1"},
+		RegistryKeys: []string{`HKEY_CURRENT_USER\Software\Example\Game`},
+		Mutexes:      []string{`Global\MyGameInstance`},
+		Pipes:        []string{`\\.\pipe\discord-ipc-0`},
+		BuildPaths: []string{
+			`C:\Users\Jane Doe\source\Game\x64\Release\Game.pdb`,
+			"/home/ci/build/src/main.cpp",
+		},
+	}, is.result())
+
+	assert.Nil(t, newIndicatorScanner().result())
+}
@@ -0,0 +1,106 @@
+package pelican
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/itchio/pelican/pe"
+)
+
+// InstallerType identifies the tool an installer was built with
+type InstallerType string
+
+const (
+	// Nullsoft Scriptable Install System
+	InstallerNSIS InstallerType = "nsis"
+	// Inno Setup
+	InstallerInno InstallerType = "inno"
+	// InstallShield, usually wrapping an MSI package
+	InstallerInstallShield InstallerType = "installshield"
+	// WiX Toolset's Burn bootstrapper
+	InstallerWixBurn InstallerType = "wixburn"
+)
+
+// InstallerInfo is set for binaries that look like installers (or
+// uninstallers) built with a well-known tool, and tells how to run
+// them unattended
+type InstallerInfo struct {
+	Type InstallerType `json:"type"`
+	// What gave it away, for example "entry point stub"
+	Evidence string `json:"evidence"`
+	// Arguments that make it install without asking anything,
+	// for example ["/S"]. They're documented by the tool, but
+	// installer scripts can still show their own dialogs.
+	SilentArgs []string `json:"silentArgs"`
+	// Prefix of the argument that sets the install directory,
+	// for example "/D=", followed by the path (unquoted).
+	// Empty if there isn't one.
+	DirArgPrefix string `json:"dirArgPrefix,omitempty"`
+}
+
+type installerSwitches struct {
+	silentArgs   []string
+	dirArgPrefix string
+}
+
+var installerSwitchesByType = map[InstallerType]installerSwitches{
+	// /D= must be the last argument, and can't be quoted
+	InstallerNSIS: {[]string{"/S"}, "/D="},
+	// https://jrsoftware.org/ishelp/index.php?topic=setupcmdline
+	InstallerInno: {[]string{"/VERYSILENT", "/SUPPRESSMSGBOXES", "/NORESTART", "/SP-"}, "/DIR="},
+	// /v passes the rest to msiexec
+	InstallerInstallShield: {[]string{"/s", "/v/qn"}, ""},
+	InstallerWixBurn:       {[]string{"/quiet", "/norestart"}, ""},
+}
+
+// keyed by the lower-case name of the manifest's assembly identity
+var installerIdentities = map[string]InstallerType{
+	"nullsoft.nsis.exehead": InstallerNSIS,
+	"jr.inno.setup":         InstallerInno,
+}
+
+// detectInstaller sets info.Installer, from the entry point stub,
+// manifest, version info and section names
+func detectInstaller(info *PeInfo, sections []*pe.Section) {
+	info.Installer = nil
+	found := func(typ InstallerType, evidence string, args ...interface{}) {
+		sw := installerSwitchesByType[typ]
+		info.Installer = &InstallerInfo{
+			Type:         typ,
+			Evidence:     fmt.Sprintf(evidence, args...),
+			SilentArgs:   sw.silentArgs,
+			DirArgPrefix: sw.dirArgPrefix,
+		}
+	}
+
+	if ai := info.AssemblyInfo; ai != nil && ai.Identity != nil {
+		if typ, ok := installerIdentities[strings.ToLower(ai.Identity.Name)]; ok {
+			found(typ, "manifest identity %s", ai.Identity.Name)
+			return
+		}
+	}
+
+	if info.EntryPointStub == StubNSIS {
+		found(InstallerNSIS, "entry point stub")
+		return
+	}
+
+	for _, s := range sections {
+		if s.Name == ".wixburn" {
+			found(InstallerWixBurn, "section %s", s.Name)
+			return
+		}
+	}
+
+	props := info.VersionProperties
+	if strings.Contains(props["Comments"], "Inno Setup") {
+		found(InstallerInno, "Comments version property")
+		return
+	}
+	for _, key := range []string{"ProductName", "FileDescription", "CompanyName"} {
+		if strings.Contains(strings.ToLower(props[key]), "installshield") {
+			found(InstallerInstallShield, "%s version property", key)
+			return
+		}
+	}
+}
@@ -0,0 +1,52 @@
+package pelican
+
+import (
+	"testing"
+
+	"github.com/itchio/pelican/pe"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_DetectInstaller(t *testing.T) {
+	detect := func(info *PeInfo, sections ...string) *InstallerInfo {
+		if info.VersionProperties == nil {
+			info.VersionProperties = make(map[string]string)
+		}
+		var ss []*pe.Section
+		for _, name := range sections {
+			ss = append(ss, &pe.Section{SectionHeader: pe.SectionHeader{Name: name}})
+		}
+		detectInstaller(info, ss)
+		return info.Installer
+	}
+
+	assert.Nil(t, detect(&PeInfo{}, ".text", ".rsrc"))
+
+	assert.EqualValues(t, &InstallerInfo{
+		Type:         InstallerInno,
+		Evidence:     "manifest identity JR.Inno.Setup",
+		SilentArgs:   []string{"/VERYSILENT", "/SUPPRESSMSGBOXES", "/NORESTART", "/SP-"},
+		DirArgPrefix: "/DIR=",
+	}, detect(&PeInfo{AssemblyInfo: &AssemblyInfo{Identity: &AssemblyIdentity{Name: "JR.Inno.Setup"}}}))
+
+	inst := detect(&PeInfo{EntryPointStub: StubNSIS})
+	assert.EqualValues(t, InstallerNSIS, inst.Type)
+	assert.EqualValues(t, []string{"/S"}, inst.SilentArgs)
+	assert.EqualValues(t, "/D=", inst.DirArgPrefix)
+
+	inst = detect(&PeInfo{}, ".text", ".wixburn")
+	assert.EqualValues(t, InstallerWixBurn, inst.Type)
+	assert.EqualValues(t, "section .wixburn", inst.Evidence)
+	assert.Empty(t, inst.DirArgPrefix)
+
+	inst = detect(&PeInfo{VersionProperties: map[string]string{
+		"Comments": "This installation was built with Inno Setup.",
+	}})
+	assert.EqualValues(t, InstallerInno, inst.Type)
+
+	inst = detect(&PeInfo{VersionProperties: map[string]string{
+		"ProductName": "InstallShield (R)",
+	}})
+	assert.EqualValues(t, InstallerInstallShield, inst.Type)
+	assert.EqualValues(t, "ProductName version property", inst.Evidence)
+}
@@ -0,0 +1,86 @@
+package pelican
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/itchio/httpkit/htfs"
+	"github.com/pkg/errors"
+)
+
+// IOError is returned (usually wrapped) when reading the file itself
+// failed, as opposed to the file being invalid. For remote files, it's
+// often a network error, so it's worth retrying, see IsRetriable.
+type IOError struct {
+	// Offset and length of the read that failed
+	Offset int64
+	Length int
+	Err    error
+}
+
+func (e *IOError) Error() string {
+	return fmt.Sprintf("reading %d bytes at %d: %v", e.Length, e.Offset, e.Err)
+}
+
+func (e *IOError) Unwrap() error {
+	return e.Err
+}
+
+// Retriable returns false for errors that won't go away on their own,
+// like the server not supporting range requests, or the file being gone
+func (e *IOError) Retriable() bool {
+	if errors.Is(e.Err, context.Canceled) || errors.Is(e.Err, htfs.ErrNotFound) {
+		return false
+	}
+
+	var se *htfs.ServerError
+	if errors.As(e.Err, &se) {
+		if se.Code == htfs.ServerErrorCodeNoRangeSupport {
+			return false
+		}
+		switch se.StatusCode {
+		case http.StatusRequestTimeout, http.StatusTooManyRequests:
+			return true
+		}
+		return se.StatusCode/100 != 4
+	}
+	return true
+}
+
+// IsIOError returns true if err was caused by a failed read, rather
+// than by the file being invalid
+func IsIOError(err error) bool {
+	var ioe *IOError
+	return errors.As(err, &ioe)
+}
+
+// IsRetriable returns true if err was caused by a failed read that
+// might succeed if tried again
+func IsRetriable(err error) bool {
+	var ioe *IOError
+	return errors.As(err, &ioe) && ioe.Retriable()
+}
+
+// ioErrorReaderAt wraps read errors (except io.EOF) in IOError,
+// so they can be told apart from parse errors
+type ioErrorReaderAt struct {
+	r io.ReaderAt
+}
+
+func (r ioErrorReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	n, err := r.r.ReadAt(p, off)
+	if err != nil && err != io.EOF {
+		err = &IOError{Offset: off, Length: len(p), Err: err}
+	}
+	return n, err
+}
+
+// noteIOError remembers the first I/O error that was tolerated as a
+// warning, since even in non-strict mode, it must fail the probe
+func (params *ProbeParams) noteIOError(err error) {
+	if params.ioErr == nil && IsIOError(err) {
+		params.ioErr = err
+	}
+}
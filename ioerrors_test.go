@@ -0,0 +1,138 @@
+package pelican_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/itchio/httpkit/eos"
+	"github.com/itchio/httpkit/htfs"
+	"github.com/itchio/pelican"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/assert"
+)
+
+// failingFile fails all reads past a given offset, like a remote
+// file would when the connection drops
+type failingFile struct {
+	eos.File
+	failAt int64
+	err    error
+}
+
+func (ff *failingFile) ReadAt(p []byte, off int64) (int, error) {
+	if off+int64(len(p)) > ff.failAt {
+		return 0, ff.err
+	}
+	return ff.File.ReadAt(p, off)
+}
+
+func Test_IOErrors(t *testing.T) {
+	probe := func(failAt int64, readErr error, strict bool) error {
+		f, err := eos.Open("./testdata/resourceful/resourceful32-mingw.exe")
+		assert.NoError(t, err)
+		defer f.Close()
+
+		params := testProbeParams(t)
+		params.Strict = strict
+		_, err = pelican.Probe(&failingFile{File: f, failAt: failAt, err: readErr}, params)
+		return err
+	}
+
+	noRange := &htfs.ServerError{
+		Host:    "example.org",
+		Message: "range requests not supported",
+		Code:    htfs.ServerErrorCodeNoRangeSupport,
+	}
+	gone := &htfs.ServerError{
+		Host:       "example.org",
+		Message:    "forbidden",
+		StatusCode: 403,
+	}
+	throttled := &htfs.ServerError{
+		Host:       "example.org",
+		Message:    "too many requests",
+		StatusCode: 429,
+	}
+
+	for _, strict := range []bool{true, false} {
+		for _, failAt := range []int64{0, 4096} {
+			err := probe(failAt, errors.New("connection reset"), strict)
+			assert.Error(t, err)
+			assert.True(t, pelican.IsIOError(err))
+			assert.True(t, pelican.IsRetriable(err))
+
+			err = probe(failAt, noRange, strict)
+			assert.True(t, pelican.IsIOError(err))
+			assert.False(t, pelican.IsRetriable(err))
+
+			err = probe(failAt, gone, strict)
+			assert.True(t, pelican.IsIOError(err))
+			assert.False(t, pelican.IsRetriable(err))
+
+			err = probe(failAt, throttled, strict)
+			assert.True(t, pelican.IsIOError(err))
+			assert.True(t, pelican.IsRetriable(err))
+		}
+	}
+
+	// parse errors aren't I/O errors
+	f, err := eos.Open("./testdata/hello/hello.c")
+	assert.NoError(t, err)
+	defer f.Close()
+	_, err = pelican.Probe(f, testProbeParams(t))
+	assert.Error(t, err)
+	assert.False(t, pelican.IsIOError(err))
+}
+
+// flakyFile fails the first few reads at each offset
+type flakyFile struct {
+	eos.File
+	failures int
+	err      error
+	reads    map[int64]int
+}
+
+func (ff *flakyFile) ReadAt(p []byte, off int64) (int, error) {
+	if ff.reads == nil {
+		ff.reads = make(map[int64]int)
+	}
+	ff.reads[off]++
+	if ff.reads[off] <= ff.failures {
+		return 0, ff.err
+	}
+	return ff.File.ReadAt(p, off)
+}
+
+func Test_RetryPolicy(t *testing.T) {
+	probe := func(failures int, readErr error, policy *pelican.RetryPolicy) error {
+		f, err := eos.Open("./testdata/resourceful/resourceful32-mingw.exe")
+		assert.NoError(t, err)
+		defer f.Close()
+
+		params := testProbeParams(t)
+		params.RetryPolicy = policy
+		_, err = pelican.Probe(&flakyFile{File: f, failures: failures, err: readErr}, params)
+		return err
+	}
+
+	policy := &pelican.RetryPolicy{
+		Attempts: 3,
+		Backoff:  time.Millisecond,
+	}
+	reset := errors.New("connection reset")
+
+	assert.Error(t, probe(2, reset, nil))
+	assert.NoError(t, probe(2, reset, policy))
+	err := probe(3, reset, policy)
+	assert.True(t, pelican.IsRetriable(err))
+
+	// not worth retrying
+	noRange := &htfs.ServerError{
+		Host:    "example.org",
+		Message: "range requests not supported",
+		Code:    htfs.ServerErrorCodeNoRangeSupport,
+	}
+	err = probe(100, noRange, policy)
+	assert.True(t, pelican.IsIOError(err))
+	assert.False(t, pelican.IsRetriable(err))
+}
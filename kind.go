@@ -0,0 +1,86 @@
+package pelican
+
+import (
+	"path/filepath"
+	"strings"
+
+	"github.com/itchio/pelican/pe"
+	"github.com/pkg/errors"
+)
+
+// ImageKind tells what a binary is meant to be used as
+type ImageKind string
+
+const (
+	// ImageKindUnknown is used when the kind couldn't be determined
+	ImageKindUnknown ImageKind = ""
+	// ImageKindExecutable is a regular executable, which can be launched
+	ImageKindExecutable ImageKind = "executable"
+	// ImageKindLibrary is a DLL, loaded by other binaries
+	ImageKindLibrary ImageKind = "library"
+	// ImageKindScreensaver is a .scr file, or an executable that exports
+	// ScreenSaverProc. Windows runs them with /s, /c or /p arguments,
+	// launching them without any shows their settings (or nothing at all).
+	ImageKindScreensaver ImageKind = "screensaver"
+	// ImageKindControlPanel is a .cpl file, or a DLL that exports CPlApplet.
+	// They're opened by the control panel (control.exe), not launched.
+	ImageKindControlPanel ImageKind = "controlPanel"
+)
+
+// IsLaunchable returns true if binaries of that kind can be launched
+// like a game would be. Screensavers technically can, but shouldn't.
+func (k ImageKind) IsLaunchable() bool {
+	return k == ImageKindExecutable
+}
+
+// entry points called by Windows, which give away what a binary is for,
+// see https://docs.microsoft.com/en-us/windows/win32/lwef/screen-saver-library
+// and https://docs.microsoft.com/en-us/windows/win32/shell/control-panel-applications
+var kindExports = map[string]ImageKind{
+	"ScreenSaverProc": ImageKindScreensaver,
+	"CPlApplet":       ImageKindControlPanel,
+}
+
+// classifyKind sets info.Kind from the headers and exports of pf, and
+// returns the exported names. See refineKind for what depends on the
+// file name.
+func (params *ProbeParams) classifyKind(info *PeInfo, pf *pe.File) ([]string, error) {
+	info.Kind = ImageKindExecutable
+	if pf.Characteristics&pe.IMAGE_FILE_DLL != 0 {
+		info.Kind = ImageKindLibrary
+	}
+
+	names, err := pf.ExportedNames()
+	if err != nil {
+		if params.Strict {
+			return nil, errors.WithMessage(err, "while parsing exports")
+		}
+		params.warn(info, WarningExportsInvalid, err, "Could not parse exports")
+		return nil, nil
+	}
+	for _, name := range names {
+		if kind, ok := kindExports[name]; ok {
+			info.Kind = kind
+			break
+		}
+	}
+	return names, nil
+}
+
+// refineKind returns the kind of a binary named name, whose headers and
+// exports say it's of the given kind. Screensavers and control panel
+// applets don't need to export anything special, their extension is
+// enough for Windows to treat them as such.
+func refineKind(kind ImageKind, name string) ImageKind {
+	switch strings.ToLower(filepath.Ext(name)) {
+	case ".scr":
+		if kind == ImageKindExecutable {
+			return ImageKindScreensaver
+		}
+	case ".cpl":
+		if kind == ImageKindLibrary {
+			return ImageKindControlPanel
+		}
+	}
+	return kind
+}
@@ -0,0 +1,62 @@
+package pelican_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/itchio/httpkit/eos"
+	"github.com/itchio/pelican"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Kind(t *testing.T) {
+	kinds := map[string]pelican.ImageKind{
+		"./testdata/hello/hello32-mingw.exe": pelican.ImageKindExecutable,
+		"./testdata/forwarders/core.dll":     pelican.ImageKindLibrary,
+		"./testdata/applets/stars.scr":       pelican.ImageKindScreensaver,
+		"./testdata/applets/plain.scr":       pelican.ImageKindScreensaver,
+		"./testdata/applets/renamed.exe":     pelican.ImageKindScreensaver,
+		"./testdata/applets/desk.cpl":        pelican.ImageKindControlPanel,
+		"./testdata/applets/applet.dll":      pelican.ImageKindControlPanel,
+	}
+	for sample, kind := range kinds {
+		f, err := eos.Open(sample)
+		assert.NoError(t, err)
+
+		info, err := pelican.Probe(f, testProbeParams(t))
+		f.Close()
+		assert.NoError(t, err)
+		assert.EqualValues(t, kind, info.Kind, sample)
+	}
+	assert.True(t, pelican.ImageKindExecutable.IsLaunchable())
+	assert.False(t, pelican.ImageKindScreensaver.IsLaunchable())
+}
+
+func Test_ProbeDirKinds(t *testing.T) {
+	hello := fixtureFile(t, "./testdata/hello/hello32-mingw.exe")
+	core := fixtureFile(t, "./testdata/forwarders/core.dll")
+	fsys := fstest.MapFS{
+		"game.exe":    hello,
+		"game.scr":    hello,
+		"core.dll":    core,
+		"core.cpl":    core,
+		"notcore.scr": core,
+		"readme.txt":  &fstest.MapFile{Data: []byte("hi")},
+	}
+
+	di, err := pelican.ProbeDir(fsys, testProbeParams(t))
+	assert.NoError(t, err)
+
+	kinds := make(map[string]pelican.ImageKind)
+	for p, info := range di.Files {
+		kinds[p] = info.Kind
+	}
+	assert.EqualValues(t, map[string]pelican.ImageKind{
+		"game.exe": pelican.ImageKindExecutable,
+		"game.scr": pelican.ImageKindScreensaver,
+		"core.dll": pelican.ImageKindLibrary,
+		"core.cpl": pelican.ImageKindControlPanel,
+		// a DLL can't be a screensaver
+		"notcore.scr": pelican.ImageKindLibrary,
+	}, kinds)
+}
@@ -0,0 +1,165 @@
+package pelican
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"sync/atomic"
+	"testing"
+
+	"github.com/itchio/pelican/pe"
+	"github.com/stretchr/testify/assert"
+)
+
+// sparseImage is a large file made of a few pieces of data
+// at arbitrary offsets, and zeroes everywhere else
+type sparseImage struct {
+	size   int64
+	pieces map[int64][]byte
+	read   int64
+}
+
+func (si *sparseImage) ReadAt(p []byte, off int64) (int, error) {
+	if off >= si.size {
+		return 0, io.EOF
+	}
+	n := len(p)
+	if int64(n) > si.size-off {
+		n = int(si.size - off)
+	}
+	for i := range p[:n] {
+		p[i] = 0
+	}
+	for start, data := range si.pieces {
+		end := start + int64(len(data))
+		if end <= off || start >= off+int64(n) {
+			continue
+		}
+		lo, hi := start, end
+		if lo < off {
+			lo = off
+		}
+		if hi > off+int64(n) {
+			hi = off + int64(n)
+		}
+		copy(p[lo-off:hi-off], data[lo-start:hi-start])
+	}
+	atomic.AddInt64(&si.read, int64(n))
+
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// makeLargeImage moves the sections of a 32-bit PE file past 2 GiB,
+// grows its resource section (the last one) to 1.5 GiB, moves the import
+// directory to the end of it, and adds an overlay so the file ends
+// past 4 GiB
+func makeLargeImage(t *testing.T, path string) (*sparseImage, []byte) {
+	orig, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+
+	const (
+		shift       = 2 << 30
+		rsrcSize    = 3 << 29
+		importsAt   = 1 << 30
+		overlaySize = 3 << 29
+	)
+
+	peOffset := int(binary.LittleEndian.Uint32(orig[0x3c:]))
+	numSections := int(binary.LittleEndian.Uint16(orig[peOffset+6:]))
+	optionalHeader := peOffset + 24
+	sectionTable := optionalHeader + int(binary.LittleEndian.Uint16(orig[peOffset+20:]))
+	importDirectory := optionalHeader + 96 + 8
+	headersEnd := sectionTable + 40*numSections
+
+	headers := make([]byte, headersEnd)
+	copy(headers, orig)
+	si := &sparseImage{pieces: map[int64][]byte{0: headers}}
+
+	var rsrcOffset int64
+	var rsrcVA uint32
+	for i := 0; i < numSections; i++ {
+		sh := headers[sectionTable+40*i:]
+		va := binary.LittleEndian.Uint32(sh[12:])
+		size := binary.LittleEndian.Uint32(sh[16:])
+		offset := binary.LittleEndian.Uint32(sh[20:])
+		if offset == 0 {
+			continue
+		}
+		si.pieces[shift+int64(offset)] = orig[offset : offset+size]
+		binary.LittleEndian.PutUint32(sh[20:], shift+offset)
+
+		if string(sh[:5]) == ".rsrc" {
+			binary.LittleEndian.PutUint32(sh[8:], rsrcSize)
+			binary.LittleEndian.PutUint32(sh[16:], rsrcSize)
+			rsrcOffset, rsrcVA = shift+int64(offset), va
+		}
+	}
+	assert.NotZero(t, rsrcOffset)
+
+	// the descriptors still point to names and thunks in .idata
+	importsRVA := binary.LittleEndian.Uint32(headers[importDirectory:])
+	importsSize := binary.LittleEndian.Uint32(headers[importDirectory+4:])
+	for i := 0; i < numSections; i++ {
+		sh := orig[sectionTable+40*i:]
+		va := binary.LittleEndian.Uint32(sh[12:])
+		size := binary.LittleEndian.Uint32(sh[16:])
+		offset := binary.LittleEndian.Uint32(sh[20:])
+		if va <= importsRVA && importsRVA < va+size {
+			start := offset + importsRVA - va
+			si.pieces[rsrcOffset+importsAt] = orig[start : start+importsSize]
+		}
+	}
+	binary.LittleEndian.PutUint32(headers[importDirectory:], rsrcVA+importsAt)
+
+	si.size = rsrcOffset + rsrcSize + overlaySize
+	return si, orig
+}
+
+func Test_LargeImage(t *testing.T) {
+	path := "./testdata/resourceful/resourceful32-mingw.exe"
+	si, orig := makeLargeImage(t, path)
+	assert.True(t, si.size > 4<<30)
+
+	params := &ProbeParams{Strict: true}
+	expected, err := params.probe(bytes.NewReader(orig), int64(len(orig)))
+	assert.NoError(t, err)
+
+	// scanning 64 MiB of zeroes for strings takes a while, and isn't
+	// what this is about: the memory budget skips it
+	pf, err := pe.NewFile(si, si.size)
+	assert.NoError(t, err)
+	params = &ProbeParams{Strict: true, MaxMemory: prefetchSize(prefetchGroups(pf)) + 64<<10}
+	info, err := params.probe(si, si.size)
+	assert.NoError(t, err)
+	assert.EqualValues(t, expected.Imports, info.Imports)
+	assert.NotEmpty(t, info.VersionProperties)
+	assert.EqualValues(t, expected.VersionProperties, info.VersionProperties)
+
+	// sections are read piecemeal, not as a whole
+	assert.True(t, si.read < 4<<20, "read %d bytes", si.read)
+
+	si.read = 0
+	params = &ProbeParams{Strict: true, Entropy: true, EntropySampleSize: 8 << 20}
+	pf, err = pe.NewFile(si, si.size)
+	assert.NoError(t, err)
+	assert.NoError(t, params.probeEntropy(info, si, si.size, pf))
+	assert.True(t, info.Entropy.Sampled)
+	assert.True(t, info.Entropy.BytesRead <= 9<<20, "read %d bytes", info.Entropy.BytesRead)
+	assert.True(t, si.read <= 9<<20, "read %d bytes", si.read)
+	// mostly zeroes
+	assert.True(t, info.Entropy.File < 1)
+
+	pf, err = pe.NewFile(si, si.size)
+	assert.NoError(t, err)
+	symbols, err := pf.ImportedSymbols()
+	assert.NoError(t, err)
+	assert.NotEmpty(t, symbols)
+
+	offset, size := overlayRange(pf, si.size)
+	assert.EqualValues(t, int64(2<<30)+11776+3<<29, offset)
+	assert.EqualValues(t, 3<<29, size)
+}
@@ -0,0 +1,178 @@
+package pelican
+
+import (
+	"bytes"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/itchio/pelican/pe"
+)
+
+// LauncherInfo is set for small executables whose main job seems to
+// be starting another executable: splash screens, the bootstrap
+// executables of Unreal Engine games, 32/64-bit pickers, etc.
+type LauncherInfo struct {
+	// Executables named in the binary, which it probably starts, as
+	// written there, for example "Game\Binaries\Win64\Game-Win64-Shipping.exe".
+	// Usually relative to the directory of the launcher.
+	Targets []string `json:"targets"`
+	// What gave it away, for example "imports CreateProcessW"
+	Evidence string `json:"evidence"`
+}
+
+// larger executables do more than starting another one
+const maxLauncherSize = 2 * 1024 * 1024
+
+// at most this many targets are reported
+const maxLauncherTargets = 16
+
+var launcherSymbols = map[string]bool{
+	"CreateProcessA":  true,
+	"CreateProcessW":  true,
+	"ShellExecuteA":   true,
+	"ShellExecuteW":   true,
+	"ShellExecuteExA": true,
+	"ShellExecuteExW": true,
+	"WinExec":         true,
+}
+
+// a whole string that's a relative path to an executable
+var exeNameRegexp = regexp.MustCompile(`(?i)^[\w\-. ()\[\]\\/]{0,200}\w\.exe$`)
+
+// executables that come with Windows (or Steam), which
+// launchers start for other reasons than the game
+var launcherIgnoredTargets = map[string]bool{
+	"cmd.exe":        true,
+	"conhost.exe":    true,
+	"explorer.exe":   true,
+	"iexplore.exe":   true,
+	"msiexec.exe":    true,
+	"notepad.exe":    true,
+	"powershell.exe": true,
+	"reg.exe":        true,
+	"regsvr32.exe":   true,
+	"rundll32.exe":   true,
+	"schtasks.exe":   true,
+	"steam.exe":      true,
+	"svchost.exe":    true,
+	"taskkill.exe":   true,
+	"tasklist.exe":   true,
+	"werfault.exe":   true,
+}
+
+// launcherScanner collects the names of executables embedded in a
+// binary, see scanDataSections
+type launcherScanner struct {
+	targets []string
+	seen    map[string]bool
+}
+
+func newLauncherScanner() *launcherScanner {
+	return &launcherScanner{
+		seen: make(map[string]bool),
+	}
+}
+
+func (ls *launcherScanner) scan(chunk []byte) {
+	strs := bytes.Split(chunk, []byte{0})
+	// the first and last strings may be cut off, but
+	// chunks overlap, so another one has them whole
+	ls.add(strs[1 : len(strs)-1])
+	// narrowUTF16 leaves out the last one already
+	ls.add(bytes.Split(narrowUTF16(chunk), []byte{0}))
+}
+
+func (ls *launcherScanner) add(strs [][]byte) {
+	for _, str := range strs {
+		if len(ls.targets) >= maxLauncherTargets {
+			return
+		}
+		if !exeNameRegexp.Match(str) {
+			continue
+		}
+		s := string(str)
+		key := strings.ToLower(s)
+		if ls.seen[key] || launcherIgnoredTargets[path.Base(strings.ReplaceAll(key, `\`, "/"))] {
+			continue
+		}
+		ls.seen[key] = true
+		ls.targets = append(ls.targets, s)
+	}
+}
+
+// mayBeLauncher returns false if detectLauncher would
+// return nil no matter what info imports and names
+func mayBeLauncher(info *PeInfo) bool {
+	return info.Kind.IsLaunchable() && info.Size <= maxLauncherSize && info.Installer == nil
+}
+
+// detectLauncher returns nil unless info is a small executable that
+// imports a function to start processes, and names other executables.
+// It must be called after probeResources, since the version info names
+// the executable itself.
+func detectLauncher(info *PeInfo, symbols []pe.ImportedSymbol, ls *launcherScanner) *LauncherInfo {
+	if !mayBeLauncher(info) {
+		return nil
+	}
+
+	var evidence string
+	for _, sym := range symbols {
+		if launcherSymbols[sym.Name] {
+			evidence = "imports " + sym.Name
+			break
+		}
+	}
+	if evidence == "" {
+		return nil
+	}
+
+	self := map[string]bool{
+		strings.ToLower(info.VersionProperties["OriginalFilename"]): true,
+		strings.ToLower(info.VersionProperties["InternalName"]):     true,
+	}
+	var targets []string
+	for _, t := range ls.targets {
+		if !self[strings.ToLower(t)] {
+			targets = append(targets, t)
+		}
+	}
+	if len(targets) == 0 {
+		return nil
+	}
+
+	return &LauncherInfo{
+		Targets:  targets,
+		Evidence: evidence,
+	}
+}
+
+// launcherTargets returns the launch candidates of byPath that the
+// launcher at the slash-separated path p starts. Targets are looked up
+// relative to the directory of p, or by base name if that's unambiguous.
+func launcherTargets(p string, li *LauncherInfo, byPath map[string]*LaunchCandidate) []string {
+	byLowerPath := make(map[string]string)
+	byBaseName := make(map[string][]string)
+	for candidate := range byPath {
+		lower := strings.ToLower(candidate)
+		byLowerPath[lower] = candidate
+		byBaseName[path.Base(lower)] = append(byBaseName[path.Base(lower)], candidate)
+	}
+
+	var res []string
+	seen := map[string]bool{p: true}
+	for _, t := range li.Targets {
+		t = strings.ToLower(strings.ReplaceAll(t, `\`, "/"))
+		match, ok := byLowerPath[path.Join(strings.ToLower(path.Dir(p)), t)]
+		if !ok {
+			if others := byBaseName[path.Base(t)]; len(others) == 1 {
+				match, ok = others[0], true
+			}
+		}
+		if ok && !seen[match] {
+			seen[match] = true
+			res = append(res, match)
+		}
+	}
+	return res
+}
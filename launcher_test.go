@@ -0,0 +1,29 @@
+package pelican
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_DetectLauncher(t *testing.T) {
+	lns := newLauncherScanner()
+	lns.scan([]byte("\x00Game.exe\x00cmd.exe\x00Failed to start %s.exe\x00Bin\\x64\\Game.exe\x00"))
+	lns.scan([]byte("\x00\x00" + "G\x00a\x00m\x00e\x00_\x00D\x00X\x001\x002\x00.\x00e\x00x\x00e\x00" + "\x00\x00"))
+	assert.EqualValues(t, []string{"Game.exe", `Bin\x64\Game.exe`, "Game_DX12.exe"}, lns.targets)
+
+	info := &PeInfo{
+		Kind:              ImageKindExecutable,
+		Size:              300 * 1024,
+		VersionProperties: map[string]string{"OriginalFilename": "game.exe"},
+	}
+	symbols := importedSymbols("GetModuleFileNameW:KERNEL32.dll", "CreateProcessW:KERNEL32.dll")
+	assert.EqualValues(t, &LauncherInfo{
+		Targets:  []string{`Bin\x64\Game.exe`, "Game_DX12.exe"},
+		Evidence: "imports CreateProcessW",
+	}, detectLauncher(info, symbols, lns))
+
+	assert.Nil(t, detectLauncher(info, importedSymbols("GetModuleFileNameW:KERNEL32.dll"), lns))
+	info.Size = 40 << 20
+	assert.Nil(t, detectLauncher(info, symbols, lns))
+}
@@ -0,0 +1,214 @@
+package pelican
+
+import (
+	"bytes"
+	"regexp"
+	"strings"
+)
+
+// LibrarySource tells how a bundled library was found
+type LibrarySource string
+
+const (
+	// The file is the library itself, and its version info says so
+	LibrarySourceVersionInfo LibrarySource = "versionInfo"
+	// The library's version string is embedded in the file's data
+	// (usually because it's linked statically)
+	LibrarySourceString LibrarySource = "string"
+	// A string only that library has is embedded in the file's data,
+	// but not its version
+	LibrarySourceMarker LibrarySource = "marker"
+	// The file exports the library's API: it's the library itself,
+	// or links it statically and re-exports it
+	LibrarySourceExports LibrarySource = "exports"
+)
+
+// BundledLibrary is a well-known third-party library found in a binary,
+// reported so that catalog contents can be checked against security advisories
+type BundledLibrary struct {
+	// One of "openssl", "sdl2", "curl", "zlib", "glfw", "allegro",
+	// "cef", "chromium"
+	Name string `json:"name"`
+	// Empty for LibrarySourceMarker and LibrarySourceExports
+	Version string        `json:"version"`
+	Source  LibrarySource `json:"source"`
+}
+
+type libraryProductName struct {
+	library string
+	// lower-case prefix of the ProductName version property
+	productName string
+}
+
+var libraryProductNames = []libraryProductName{
+	{"openssl", "the openssl toolkit"},
+	{"sdl2", "simple directmedia layer"},
+	{"curl", "the curl library"},
+	{"curl", "libcurl"},
+	{"zlib", "zlib"},
+}
+
+type libraryString struct {
+	library string
+	// the first submatch is the version
+	re *regexp.Regexp
+}
+
+var libraryStrings = []libraryString{
+	// OPENSSL_VERSION_TEXT, for example "OpenSSL 1.1.1k  25 Mar 2021"
+	{"openssl", regexp.MustCompile(`OpenSSL (\d+\.\d+\.\d+[a-z]{0,2})  ?\d{1,2} [A-Z][a-z]{2} \d{4}`)},
+	// SDL_REVISION for 2.0.16 and later, for example "SDL-release-2.26.0-0-g..."
+	{"sdl2", regexp.MustCompile(`SDL-(?:release-)?(2\.\d+\.\d+)`)},
+	// curl_version(), for example "libcurl/7.68.0"
+	{"curl", regexp.MustCompile(`libcurl/(\d+\.\d+\.\d+)`)},
+	// deflate_copyright and inflate_copyright
+	{"zlib", regexp.MustCompile(`(?:de|in)flate (1\.\d+\.\d+(?:\.\d+)?) Copyright`)},
+	// glfwGetVersionString(), for example "3.3.8 Win32 WGL EGL OSMesa MinGW"
+	{"glfw", regexp.MustCompile(`(3\.\d+\.\d+) Win32 WGL`)},
+	// allegro_id (Allegro 4), for example "Allegro 4.4.2, MSVC"
+	{"allegro", regexp.MustCompile(`Allegro (4\.\d+\.\d+), `)},
+}
+
+type libraryMarker struct {
+	library string
+	marker  []byte
+}
+
+// strings that give away statically linked libraries that don't embed
+// their version. They're also looked for as UTF-16.
+var libraryMarkers = []libraryMarker{
+	// a hint, read by SDL 2 and later
+	{"sdl2", []byte("SDL_GAMECONTROLLERCONFIG")},
+	// _GLFW_WNDCLASSNAME, the class of GLFW 3 windows
+	{"glfw", []byte("GLFW30")},
+	// the system-wide configuration file of Allegro 5
+	{"allegro", []byte("allegro5.cfg")},
+}
+
+// functions that are only exported by these libraries
+var libraryExports = map[string]string{
+	"SDL_CreateWindow":  "sdl2",
+	"glfwInit":          "glfw",
+	"al_install_system": "allegro",
+	"install_allegro":   "allegro",
+}
+
+// identifyLibrary returns the library info is, according to its version
+// properties, or nil
+func identifyLibrary(info *PeInfo) *BundledLibrary {
+	productName := strings.ToLower(info.VersionProperties["ProductName"])
+	if productName == "" {
+		return nil
+	}
+
+	for _, lpn := range libraryProductNames {
+		if !strings.HasPrefix(productName, lpn.productName) {
+			continue
+		}
+
+		version := info.VersionProperties["ProductVersion"]
+		if version == "" {
+			version = info.VersionProperties["FileVersion"]
+		}
+		// SDL uses "2, 0, 14, 0"
+		version = strings.Replace(version, ", ", ".", -1)
+		return &BundledLibrary{
+			Name:    lpn.library,
+			Version: version,
+			Source:  LibrarySourceVersionInfo,
+		}
+	}
+	return nil
+}
+
+// libraryScanner collects the version strings of well-known libraries,
+// see scanDataSections
+type libraryScanner struct {
+	libraries []*BundledLibrary
+	seen      map[BundledLibrary]bool
+	markers   map[string]bool
+}
+
+func newLibraryScanner() *libraryScanner {
+	return &libraryScanner{
+		seen:    make(map[BundledLibrary]bool),
+		markers: make(map[string]bool),
+	}
+}
+
+func (ls *libraryScanner) scan(chunk []byte) {
+	for _, lstr := range libraryStrings {
+		for _, m := range lstr.re.FindAllSubmatch(chunk, -1) {
+			bl := BundledLibrary{
+				Name:    lstr.library,
+				Version: string(m[1]),
+				Source:  LibrarySourceString,
+			}
+			if !ls.seen[bl] {
+				ls.seen[bl] = true
+				ls.libraries = append(ls.libraries, &bl)
+			}
+		}
+	}
+
+	var narrow []byte
+	for _, lm := range libraryMarkers {
+		if ls.markers[lm.library] {
+			continue
+		}
+		if narrow == nil {
+			narrow = narrowUTF16(chunk)
+		}
+		if bytes.Contains(chunk, lm.marker) || bytes.Contains(narrow, lm.marker) {
+			ls.markers[lm.library] = true
+		}
+	}
+}
+
+// markerLibraries returns the libraries whose markers were
+// found, but not their version string
+func (ls *libraryScanner) markerLibraries() []*BundledLibrary {
+	var res []*BundledLibrary
+	for _, lm := range libraryMarkers {
+		if ls.markers[lm.library] && !hasLibrary(ls.libraries, lm.library) {
+			res = append(res, &BundledLibrary{
+				Name:   lm.library,
+				Source: LibrarySourceMarker,
+			})
+		}
+	}
+	return res
+}
+
+// exportedLibraries returns the libraries whose API is in the
+// exported names of a binary
+func exportedLibraries(names []string) []*BundledLibrary {
+	var res []*BundledLibrary
+	for _, name := range names {
+		if library, ok := libraryExports[name]; ok {
+			res = appendMissingLibrary(res, &BundledLibrary{
+				Name:   library,
+				Source: LibrarySourceExports,
+			})
+		}
+	}
+	return res
+}
+
+func hasLibrary(libs []*BundledLibrary, name string) bool {
+	for _, bl := range libs {
+		if bl.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
+// appendMissingLibrary appends bl to libs, unless
+// a library of the same name is already in there
+func appendMissingLibrary(libs []*BundledLibrary, bl *BundledLibrary) []*BundledLibrary {
+	if hasLibrary(libs, bl.Name) {
+		return libs
+	}
+	return append(libs, bl)
+}
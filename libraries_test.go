@@ -0,0 +1,59 @@
+package pelican
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_IdentifyLibrary(t *testing.T) {
+	lib := identifyLibrary(&PeInfo{VersionProperties: map[string]string{
+		"ProductName":    "Simple DirectMedia Layer",
+		"ProductVersion": "2, 0, 14, 0",
+	}})
+	assert.EqualValues(t, &BundledLibrary{Name: "sdl2", Version: "2.0.14.0", Source: LibrarySourceVersionInfo}, lib)
+
+	lib = identifyLibrary(&PeInfo{VersionProperties: map[string]string{
+		"ProductName":    "The OpenSSL Toolkit",
+		"ProductVersion": "1.1.1k",
+	}})
+	assert.EqualValues(t, "openssl", lib.Name)
+	assert.EqualValues(t, "1.1.1k", lib.Version)
+
+	assert.Nil(t, identifyLibrary(&PeInfo{VersionProperties: map[string]string{"ProductName": "butler"}}))
+}
+
+func Test_LibraryScanner(t *testing.T) {
+	ls := newLibraryScanner()
+	ls.scan([]byte("\x003.3.8 Win32 WGL EGL OSMesa MinGW\x00Allegro 4.4.2, MSVC\x00"))
+	ls.scan(append([]byte("\x00SDL_GAMECONTROLLERCONFIG\x00"), wide("GLFW30")...))
+	assert.EqualValues(t, []*BundledLibrary{
+		{Name: "glfw", Version: "3.3.8", Source: LibrarySourceString},
+		{Name: "allegro", Version: "4.4.2", Source: LibrarySourceString},
+	}, ls.libraries)
+	// glfw's version is known already
+	assert.EqualValues(t, []*BundledLibrary{
+		{Name: "sdl2", Source: LibrarySourceMarker},
+	}, ls.markerLibraries())
+
+	ls = newLibraryScanner()
+	ls.scan(append([]byte("\x00allegro5.cfg\x00"), wide("GLFW30")...))
+	assert.Empty(t, ls.libraries)
+	assert.EqualValues(t, []*BundledLibrary{
+		{Name: "glfw", Source: LibrarySourceMarker},
+		{Name: "allegro", Source: LibrarySourceMarker},
+	}, ls.markerLibraries())
+}
+
+func Test_ExportedLibraries(t *testing.T) {
+	assert.EqualValues(t, []*BundledLibrary{
+		{Name: "sdl2", Source: LibrarySourceExports},
+	}, exportedLibraries([]string{"SDL_Init", "SDL_CreateWindow", "SDL_Quit"}))
+	assert.EqualValues(t, []*BundledLibrary{
+		{Name: "allegro", Source: LibrarySourceExports},
+	}, exportedLibraries([]string{"al_install_system", "install_allegro"}))
+	assert.Empty(t, exportedLibraries([]string{"CPlApplet"}))
+
+	libs := []*BundledLibrary{{Name: "sdl2", Version: "2.0.14.0", Source: LibrarySourceVersionInfo}}
+	assert.Len(t, appendMissingLibrary(libs, &BundledLibrary{Name: "sdl2", Source: LibrarySourceExports}), 1)
+}
@@ -0,0 +1,254 @@
+package pelican
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/itchio/httpkit/eos"
+	"github.com/itchio/pelican/pe"
+	"github.com/itchio/pelican/resources"
+	"github.com/pkg/errors"
+)
+
+// LicenseFormat is the markup of a LicenseText
+type LicenseFormat string
+
+const (
+	LicenseFormatText LicenseFormat = "text"
+	LicenseFormatRTF  LicenseFormat = "rtf"
+	LicenseFormatHTML LicenseFormat = "html"
+)
+
+// LicenseText is a license agreement (EULA, open-source license, etc.)
+// embedded in a binary, see FindLicenses
+type LicenseText struct {
+	// Where it was found: a resource, named like ExtractResources does
+	// (for example "RcData/EULA/1033"), or an offset in the overlay
+	// (for example "overlay+0x1200")
+	Source string        `json:"source"`
+	Format LicenseFormat `json:"format"`
+	// SPDX identifier of the well-known license the text looks like,
+	// for example "GPL-2.0" or "MIT". Empty for other licenses, like EULAs.
+	License string `json:"license,omitempty"`
+	// Decoded to UTF-8, markup (RTF or HTML) is left as-is
+	Text string `json:"text"`
+}
+
+// resources named like this are assumed to hold license text
+var licenseNameRegexp = regexp.MustCompile(`(?i)licen[cs]e|eula|agreement|copying`)
+
+// text that gives away license agreements, for resources that
+// aren't named after them and for the overlay
+var licenseTextRegexp = regexp.MustCompile(`(?i)end[- ]user license agreement|license agreement|general public license|permission is hereby granted|redistribution and use in source and binary forms|apache license|mozilla public license`)
+
+type spdxSignature struct {
+	id string
+	// all of them must match
+	res []*regexp.Regexp
+}
+
+func spdx(id string, patterns ...string) spdxSignature {
+	sig := spdxSignature{id: id}
+	for _, p := range patterns {
+		sig.res = append(sig.res, regexp.MustCompile(`(?i)`+p))
+	}
+	return sig
+}
+
+// checked in order, the first match wins
+var spdxSignatures = []spdxSignature{
+	spdx("LGPL-2.1", `gnu lesser general public license`, `version 2\.1`),
+	spdx("LGPL-3.0", `gnu lesser general public license`, `version 3`),
+	spdx("GPL-2.0", `gnu general public license`, `version 2`),
+	spdx("GPL-3.0", `gnu general public license`, `version 3`),
+	spdx("Apache-2.0", `apache license`, `version 2\.0`),
+	spdx("MPL-2.0", `mozilla public license`, `version 2\.0`),
+	spdx("MIT", `permission is hereby granted, free of charge`),
+	spdx("BSD-3-Clause", `redistribution and use in source and binary forms`, `neither the name`),
+	spdx("BSD-2-Clause", `redistribution and use in source and binary forms`),
+}
+
+const (
+	// larger resources are unlikely to be license text
+	maxLicenseSize = 1024 * 1024
+	// how much of the overlay is searched, installers usually
+	// keep their license near the start of their data
+	licenseOverlayScanSize = 4 * 1024 * 1024
+	// shorter runs of text in the overlay are ignored
+	minOverlayLicenseSize = 256
+)
+
+// FindLicenses returns the license agreements found in the resources of
+// file (RCDATA, HTML and custom resource types), and in the first
+// few megabytes of its overlay, where installers keep their data.
+// Most installers compress it, so their license can only be found there
+// if it's stored as plain text.
+func FindLicenses(file eos.File, params ProbeParams) ([]*LicenseText, error) {
+	params.setDefaults()
+	stats, err := file.Stat()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	pf, err := pe.NewFile(params.readerAt(file), stats.Size())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var res []*LicenseText
+	if img := findResources(pf); img != nil {
+		err = params.walkResources(nil, img, func(re *resources.Entry) error {
+			if re.Outside || re.Size > maxLicenseSize {
+				return nil
+			}
+			named := licenseNameRegexp.MatchString(re.TypeName) || licenseNameRegexp.MatchString(re.Name)
+			if !named && re.TypeName == "" && re.Type != ResourceTypeRcData && re.Type != ResourceTypeHTML {
+				return nil
+			}
+
+			data := make([]byte, re.Size)
+			_, err := img.ReadAt(data, re.Offset)
+			if err != nil {
+				if params.Strict {
+					return errors.WithMessagef(err, "while reading %s resource %s", re.TypeString(), re.IDString())
+				}
+				params.Consumer.Warnf("Could not read %s resource %s: %+v", re.TypeString(), re.IDString(), err)
+				return nil
+			}
+			text := decodeLicenseText(data)
+			if !named && !licenseTextRegexp.MatchString(text) {
+				return nil
+			}
+			res = append(res, newLicenseText(fmt.Sprintf("%s/%s/%d", re.TypeString(), re.IDString(), re.Language), text))
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	offset, size := overlayRange(pf, stats.Size())
+	if size > 0 {
+		if size > licenseOverlayScanSize {
+			size = licenseOverlayScanSize
+		}
+		found, err := findOverlayLicenses(io.NewSectionReader(params.readerAt(file), offset, size), offset)
+		if err != nil {
+			return nil, errors.WithMessage(err, "while searching overlay")
+		}
+		res = append(res, found...)
+	}
+	return res, nil
+}
+
+// findOverlayLicenses looks for runs of plain text that look like
+// license agreements in r, which starts at offset in the file
+func findOverlayLicenses(r *io.SectionReader, offset int64) ([]*LicenseText, error) {
+	data := make([]byte, r.Size())
+	_, err := io.ReadFull(r, data)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	var res []*LicenseText
+	start := -1
+	flush := func(end int) {
+		if start >= 0 && end-start >= minOverlayLicenseSize {
+			run := bytes.TrimRight(data[start:end], " \t\r\n")
+			text := bytes.TrimLeft(run, " \t\r\n")
+			if licenseTextRegexp.Match(text) {
+				textOffset := offset + int64(start+len(run)-len(text))
+				res = append(res, newLicenseText(fmt.Sprintf("overlay+%#x", textOffset), string(text)))
+			}
+		}
+		start = -1
+	}
+	for i, b := range data {
+		if b == '\t' || b == '\r' || b == '\n' || (b >= 0x20 && b < 0x7f) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		flush(i)
+	}
+	flush(len(data))
+	return res, nil
+}
+
+func newLicenseText(source string, text string) *LicenseText {
+	lt := &LicenseText{
+		Source: source,
+		Format: LicenseFormatText,
+		Text:   text,
+	}
+
+	head := strings.ToLower(strings.TrimSpace(text))
+	if len(head) > 512 {
+		head = head[:512]
+	}
+	switch {
+	case strings.HasPrefix(head, `{\rtf`):
+		lt.Format = LicenseFormatRTF
+	case strings.HasPrefix(head, "<!doctype html"), strings.Contains(head, "<html"):
+		lt.Format = LicenseFormatHTML
+	}
+
+	for _, sig := range spdxSignatures {
+		matched := true
+		for _, re := range sig.res {
+			if !re.MatchString(text) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			lt.License = sig.id
+			break
+		}
+	}
+	return lt
+}
+
+// decodeLicenseText decodes UTF-16 (with a BOM, or that looks like it)
+// and UTF-8 text. Anything else is assumed to be Windows-1252, and
+// only its ASCII subset is kept.
+func decodeLicenseText(data []byte) string {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xff, 0xfe}):
+		return strings.TrimRight(DecodeUTF16(data[2:]), "\x00")
+	case bytes.HasPrefix(data, []byte{0xef, 0xbb, 0xbf}):
+		data = data[3:]
+	case looksLikeUTF16(data):
+		return strings.TrimRight(DecodeUTF16(data), "\x00")
+	}
+
+	data = bytes.TrimRight(data, "\x00")
+	if utf8.Valid(data) {
+		return string(data)
+	}
+	return strings.Map(func(r rune) rune {
+		if r >= utf8.RuneSelf {
+			return -1
+		}
+		return r
+	}, strings.ToValidUTF8(string(data), ""))
+}
+
+// looksLikeUTF16 returns true if the first characters of data
+// are ASCII, encoded as UTF-16LE
+func looksLikeUTF16(data []byte) bool {
+	if len(data) < 8 {
+		return false
+	}
+	for i := 0; i < 8; i += 2 {
+		if data[i] == 0 || data[i+1] != 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,49 @@
+package pelican_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/itchio/httpkit/eos"
+	"github.com/itchio/pelican"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_FindLicenses(t *testing.T) {
+	f, err := eos.Open("./testdata/resourceful/resourceful32-eula.exe")
+	assert.NoError(t, err)
+	defer f.Close()
+
+	licenses, err := pelican.FindLicenses(f, testProbeParams(t))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 4, len(licenses))
+
+	// named resources come first
+	rtf := licenses[0]
+	assert.EqualValues(t, "RTF/LICENSE/1033", rtf.Source)
+	assert.EqualValues(t, pelican.LicenseFormatRTF, rtf.Format)
+	assert.EqualValues(t, "GPL-2.0", rtf.License)
+
+	eula := licenses[1]
+	assert.EqualValues(t, "RcData/EULA/1033", eula.Source)
+	assert.EqualValues(t, pelican.LicenseFormatText, eula.Format)
+	assert.Empty(t, eula.License)
+	assert.EqualValues(t, "END USER LICENSE AGREEMENT\r\n\r\nThis software is licensed, not sold.", eula.Text)
+
+	// not named after a license, but it reads like one
+	html := licenses[2]
+	assert.EqualValues(t, "HTML/7/1033", html.Source)
+	assert.EqualValues(t, pelican.LicenseFormatHTML, html.Format)
+	assert.EqualValues(t, "MIT", html.License)
+
+	offset, size, err := pelican.OverlayRange(f)
+	assert.NoError(t, err)
+	overlay := licenses[3]
+	// after a 4 KiB blob, a newline and some indentation
+	apacheSize := size - 2*4096
+	assert.EqualValues(t, fmt.Sprintf("overlay+%#x", offset+4096+34), overlay.Source)
+	assert.EqualValues(t, "Apache-2.0", overlay.License)
+	assert.True(t, strings.HasPrefix(overlay.Text, "Apache License\n"))
+	assert.True(t, int64(len(overlay.Text)) < apacheSize)
+}
@@ -0,0 +1,61 @@
+package pelican
+
+import (
+	"github.com/itchio/pelican/pe"
+	"github.com/pkg/errors"
+)
+
+// LoadConfig is what the load configuration directory says about the
+// exploit mitigations a binary was built with, see pe.File.LoadConfig.
+// Addresses are virtual addresses, 0 when unset.
+type LoadConfig struct {
+	// Address of the /GS stack cookie
+	SecurityCookie uint64 `json:"securityCookie,omitempty"`
+
+	// x86 only: table of the exception handlers registered
+	// with /SAFESEH, and how many there are
+	SEHandlerTable uint64 `json:"seHandlerTable,omitempty"`
+	SEHandlerCount uint64 `json:"seHandlerCount,omitempty"`
+	// x86 only: set if exceptions can't be hijacked, because handlers
+	// are registered (/SAFESEH), or because the binary has none (NO_SEH)
+	SafeSEH bool `json:"safeSeh,omitempty"`
+
+	// Without the IMAGE_GUARD_ prefix, for example "CF_INSTRUMENTED"
+	GuardFlags []string `json:"guardFlags,omitempty"`
+	// Table of valid indirect call targets, and how many there are
+	GuardCFFunctionTable uint64 `json:"guardCfFunctionTable,omitempty"`
+	GuardCFFunctionCount uint64 `json:"guardCfFunctionCount,omitempty"`
+	// Set if Control Flow Guard is enforced: the code is instrumented,
+	// and the GUARD_CF flag of the optional header is set
+	CFG bool `json:"cfg,omitempty"`
+}
+
+// parseLoadConfig fills info.LoadConfig
+func (params *ProbeParams) parseLoadConfig(info *PeInfo, pf *pe.File) error {
+	lc, err := pf.LoadConfig()
+	if err != nil {
+		if params.Strict {
+			return errors.WithMessage(err, "while parsing load configuration directory")
+		}
+		params.warn(info, WarningDataDirectoryInvalid, err, "Could not parse load configuration directory")
+		return nil
+	}
+	if lc == nil {
+		return nil
+	}
+
+	dc := pe.DllCharacteristics(pf.OptionalHeaderFields().DllCharacteristics)
+	info.LoadConfig = &LoadConfig{
+		SecurityCookie:       lc.SecurityCookie,
+		SEHandlerTable:       lc.SEHandlerTable,
+		SEHandlerCount:       lc.SEHandlerCount,
+		GuardFlags:           lc.GuardFlags.Names(),
+		GuardCFFunctionTable: lc.GuardCFFunctionTable,
+		GuardCFFunctionCount: lc.GuardCFFunctionCount,
+		CFG:                  lc.GuardFlags&pe.IMAGE_GUARD_CF_INSTRUMENTED != 0 && dc&pe.IMAGE_DLLCHARACTERISTICS_GUARD_CF != 0,
+	}
+	if pf.Machine == pe.IMAGE_FILE_MACHINE_I386 {
+		info.LoadConfig.SafeSEH = lc.SEHandlerTable != 0 || dc&pe.IMAGE_DLLCHARACTERISTICS_NO_SEH != 0
+	}
+	return nil
+}
@@ -0,0 +1,274 @@
+package pelican
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// DLLResolution tells where the loader would find an imported DLL
+type DLLResolution string
+
+const (
+	// Through a side-by-side assembly, see SxSRedirection
+	ResolutionSxS DLLResolution = "sxs"
+	// A KnownDLL, always loaded from the system directory
+	ResolutionKnownDLL DLLResolution = "known-dll"
+	// In the application directory
+	ResolutionAppLocal DLLResolution = "app-local"
+	// In the system directory, it ships with Windows
+	ResolutionSystem DLLResolution = "system"
+	// In the system directory, if a redistributable is installed
+	ResolutionRedist DLLResolution = "redist"
+	// Nowhere the loader looks
+	ResolutionMissing DLLResolution = "missing"
+)
+
+// LoadOrderEntry is a DLL imported by an executable, or by one of the
+// app-local DLLs it loads
+type LoadOrderEntry struct {
+	// As imported, for example "KERNEL32.dll"
+	DLL string `json:"dll"`
+	// Slash-separated path of the importing file
+	ImportedBy string        `json:"importedBy"`
+	Resolution DLLResolution `json:"resolution"`
+	// Slash-separated path of the DLL, for app-local ones
+	// and those from private assemblies
+	Path string `json:"path,omitempty"`
+	// Name of the redistributable that installs it, for example
+	// "Visual C++ 2015-2022" or "DirectX"
+	Redist string `json:"redist,omitempty"`
+	// Set for system DLLs that aren't KnownDLLs: a DLL of the same name
+	// in the application directory would be loaded instead. That's how
+	// proxy DLLs (and DLL hijacking) work.
+	Hijackable bool `json:"hijackable,omitempty"`
+	// For missing DLLs, where copies were found in the directory,
+	// although the loader doesn't look there
+	FoundAt []string `json:"foundAt,omitempty"`
+	// Set if it's only delay-loaded: it's loaded when one of its
+	// functions is first called, so the executable starts without it
+	Delayed bool `json:"delayed,omitempty"`
+}
+
+// LoadOrderReport models how the loader would resolve the imports of
+// an executable, following the default search order (SafeDllSearchMode):
+// side-by-side assemblies and KnownDLLs, then the application directory,
+// then the system directory. The current directory and PATH are ignored.
+type LoadOrderReport struct {
+	// Slash-separated path of the executable
+	Path    string            `json:"path"`
+	Entries []*LoadOrderEntry `json:"entries"`
+	// Set if all imports resolve (except delay-loaded ones), and none of
+	// them to a DLL of another architecture. LoadLibrary calls aren't checked.
+	CanStart bool `json:"canStart"`
+}
+
+// System DLLs that aren't KnownDLLs (see shadowableSystemDLLs for
+// those commonly shipped app-local)
+var systemDLLs = map[string]bool{
+	"kernelbase.dll":     true,
+	"bcrypt.dll":         true,
+	"crypt32.dll":        true,
+	"ncrypt.dll":         true,
+	"secur32.dll":        true,
+	"wintrust.dll":       true,
+	"cfgmgr32.dll":       true,
+	"hid.dll":            true,
+	"mpr.dll":            true,
+	"netapi32.dll":       true,
+	"userenv.dll":        true,
+	"powrprof.dll":       true,
+	"wtsapi32.dll":       true,
+	"dnsapi.dll":         true,
+	"mswsock.dll":        true,
+	"wsock32.dll":        true,
+	"urlmon.dll":         true,
+	"dwmapi.dll":         true,
+	"d2d1.dll":           true,
+	"dwrite.dll":         true,
+	"d3dcompiler_47.dll": true,
+	"glu32.dll":          true,
+	"msimg32.dll":        true,
+	"msacm32.dll":        true,
+	"msvfw32.dll":        true,
+	"avifil32.dll":       true,
+	"avrt.dll":           true,
+	"mfplat.dll":         true,
+	"mfreadwrite.dll":    true,
+	"mf.dll":             true,
+	"propsys.dll":        true,
+	"oleacc.dll":         true,
+	"usp10.dll":          true,
+	"xinput9_1_0.dll":    true,
+	"ucrtbase.dll":       true,
+	// installed by graphics drivers
+	"vulkan-1.dll": true,
+}
+
+// DLLs installed by the DirectX End-User Runtime
+var directXRedistRegexp = regexp.MustCompile(`^(d3dx9_\d+|d3dx10(_\d+)?|d3dx11_\d+|d3dcompiler_(3[3-9]|4[0-3])|xinput1_[1-3]|xaudio2_[0-7]|x3daudio1_\d+|xactengine\d_\d+|xapofx1_\d+)\.dll$`)
+
+// redistFor returns the redistributable that installs name, if any
+func redistFor(name string) string {
+	if m := legacyCRTRegexp.FindStringSubmatch(name); m != nil {
+		if redist, ok := legacyCRTRedists[m[1]]; ok {
+			return "Visual C++ " + redist
+		}
+	}
+	if universalCRTRegexp.MatchString(name) {
+		return "Visual C++ 2015-2022"
+	}
+	if directXRedistRegexp.MatchString(name) {
+		return "DirectX"
+	}
+	return ""
+}
+
+func isAPISet(name string) bool {
+	return strings.HasPrefix(name, "api-ms-") || strings.HasPrefix(name, "ext-ms-")
+}
+
+// buildLoadOrder returns a LoadOrderReport for every executable of files
+func buildLoadOrder(files map[string]*PeInfo, redirections []*SxSRedirection, mismatches []*BitnessMismatch) []*LoadOrderReport {
+	byLowerPath := lowerPathIndex(files)
+	sxs := sxsByExe(redirections)
+
+	byBaseName := make(map[string][]string)
+	for p := range files {
+		base := strings.ToLower(path.Base(p))
+		byBaseName[base] = append(byBaseName[base], p)
+	}
+	for _, paths := range byBaseName {
+		sort.Strings(paths)
+	}
+
+	mismatched := make(map[string]bool)
+	for _, bm := range mismatches {
+		mismatched[bm.Path] = true
+	}
+
+	var res []*LoadOrderReport
+	for exe := range files {
+		if strings.ToLower(path.Ext(exe)) != ".exe" {
+			continue
+		}
+		appDir := strings.ToLower(path.Dir(exe))
+
+		report := &LoadOrderReport{
+			Path:     exe,
+			CanStart: !mismatched[exe],
+		}
+		seen := make(map[string]*LoadOrderEntry)
+		queue := []string{exe}
+		for len(queue) > 0 {
+			importer := queue[0]
+			queue = queue[1:]
+
+			for _, imp := range importsOf(files[importer]) {
+				lib := imp.lib
+				name := strings.ToLower(lib)
+				if entry := seen[name]; entry != nil {
+					if entry.Delayed && !imp.delayed {
+						// needed at startup after all
+						entry.Delayed = false
+						if entry.Resolution == ResolutionMissing {
+							report.CanStart = false
+						}
+					}
+					continue
+				}
+
+				entry := &LoadOrderEntry{
+					DLL:        lib,
+					ImportedBy: importer,
+					Delayed:    imp.delayed,
+				}
+				seen[name] = entry
+				report.Entries = append(report.Entries, entry)
+
+				if r := sxs[exe][name]; r != nil {
+					entry.Resolution = ResolutionSxS
+					entry.Path = r.Target
+					continue
+				}
+				if knownDLLs[name] {
+					entry.Resolution = ResolutionKnownDLL
+					continue
+				}
+				if dll, ok := byLowerPath[path.Join(appDir, name)]; ok {
+					entry.Resolution = ResolutionAppLocal
+					entry.Path = dll
+					queue = append(queue, dll)
+					continue
+				}
+				if isAPISet(name) {
+					entry.Resolution = ResolutionSystem
+					continue
+				}
+				if systemDLLs[name] || shadowableSystemDLLs[name] {
+					entry.Resolution = ResolutionSystem
+					entry.Hijackable = true
+					continue
+				}
+				if redist := redistFor(name); redist != "" {
+					entry.Resolution = ResolutionRedist
+					entry.Redist = redist
+					entry.Hijackable = true
+					continue
+				}
+
+				entry.Resolution = ResolutionMissing
+				entry.FoundAt = byBaseName[name]
+				if !entry.Delayed {
+					report.CanStart = false
+				}
+			}
+		}
+		res = append(res, report)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Path < res[j].Path
+	})
+	return res
+}
+
+// missingDLLs returns a message for each missing DLL of r,
+// including delay-loaded ones if delayed is set
+func (r *LoadOrderReport) missingDLLs(delayed bool) []string {
+	var res []string
+	for _, e := range r.Entries {
+		if e.Resolution != ResolutionMissing || (e.Delayed && !delayed) {
+			continue
+		}
+		verb := "imported"
+		if e.Delayed {
+			verb = "delay-loaded"
+		}
+		msg := fmt.Sprintf("%s (%s by %s) can't be found", e.DLL, verb, e.ImportedBy)
+		if len(e.FoundAt) > 0 {
+			msg += fmt.Sprintf(", but there's a copy at %s, which isn't in the search path", strings.Join(e.FoundAt, ", "))
+		}
+		res = append(res, msg)
+	}
+	return res
+}
+
+type dllImport struct {
+	lib     string
+	delayed bool
+}
+
+// importsOf returns the DLLs info imports, then those it delay-loads
+func importsOf(info *PeInfo) []dllImport {
+	var res []dllImport
+	for _, lib := range info.Imports {
+		res = append(res, dllImport{lib: lib})
+	}
+	for _, lib := range info.DelayImports {
+		res = append(res, dllImport{lib: lib, delayed: true})
+	}
+	return res
+}
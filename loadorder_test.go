@@ -0,0 +1,86 @@
+package pelican
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_BuildLoadOrder(t *testing.T) {
+	files := map[string]*PeInfo{
+		"game.exe": {
+			Arch:    ArchAmd64,
+			Imports: []string{"KERNEL32.dll", "COMCTL32.dll", "engine.dll", "VCRUNTIME140.dll", "api-ms-win-crt-runtime-l1-1-0.dll"},
+		},
+		"engine.dll": {
+			Arch:    ArchAmd64,
+			Imports: []string{"KERNEL32.dll", "d3dx9_43.dll", "version.dll", "fmod.dll"},
+		},
+		"lib/fmod.dll": {Arch: ArchAmd64},
+	}
+	redirections := []*SxSRedirection{
+		{Path: "game.exe", DLL: "comctl32.dll", Assembly: "Microsoft.Windows.Common-Controls"},
+	}
+
+	reports := buildLoadOrder(files, redirections, nil)
+	assert.EqualValues(t, 1, len(reports))
+	r := reports[0]
+	assert.EqualValues(t, "game.exe", r.Path)
+	assert.False(t, r.CanStart)
+	assert.EqualValues(t, []*LoadOrderEntry{
+		{DLL: "KERNEL32.dll", ImportedBy: "game.exe", Resolution: ResolutionKnownDLL},
+		{DLL: "COMCTL32.dll", ImportedBy: "game.exe", Resolution: ResolutionSxS},
+		{DLL: "engine.dll", ImportedBy: "game.exe", Resolution: ResolutionAppLocal, Path: "engine.dll"},
+		{DLL: "VCRUNTIME140.dll", ImportedBy: "game.exe", Resolution: ResolutionRedist, Redist: "Visual C++ 2015-2022", Hijackable: true},
+		{DLL: "api-ms-win-crt-runtime-l1-1-0.dll", ImportedBy: "game.exe", Resolution: ResolutionSystem},
+		{DLL: "d3dx9_43.dll", ImportedBy: "engine.dll", Resolution: ResolutionRedist, Redist: "DirectX", Hijackable: true},
+		{DLL: "version.dll", ImportedBy: "engine.dll", Resolution: ResolutionSystem, Hijackable: true},
+		{DLL: "fmod.dll", ImportedBy: "engine.dll", Resolution: ResolutionMissing, FoundAt: []string{"lib/fmod.dll"}},
+	}, r.Entries)
+	assert.EqualValues(t, []string{
+		"fmod.dll (imported by engine.dll) can't be found, but there's a copy at lib/fmod.dll, which isn't in the search path",
+	}, r.missingDLLs(true))
+
+	files["fmod.dll"] = files["lib/fmod.dll"]
+	r = buildLoadOrder(files, redirections, nil)[0]
+	assert.True(t, r.CanStart)
+
+	r = buildLoadOrder(files, redirections, []*BitnessMismatch{{Path: "game.exe"}})[0]
+	assert.False(t, r.CanStart)
+}
+
+func Test_BuildLoadOrderDelayed(t *testing.T) {
+	files := map[string]*PeInfo{
+		"game.exe": {
+			Arch:         ArchAmd64,
+			Imports:      []string{"KERNEL32.dll", "engine.dll"},
+			DelayImports: []string{"fmodex.dll", "steam_api64.dll"},
+		},
+		"engine.dll": {
+			Arch:    ArchAmd64,
+			Imports: []string{"steam_api64.dll"},
+		},
+	}
+
+	r := buildLoadOrder(files, nil, nil)[0]
+	// steam_api64.dll is delay-loaded by the executable,
+	// but engine.dll needs it at startup
+	assert.False(t, r.CanStart)
+	assert.EqualValues(t, []*LoadOrderEntry{
+		{DLL: "KERNEL32.dll", ImportedBy: "game.exe", Resolution: ResolutionKnownDLL},
+		{DLL: "engine.dll", ImportedBy: "game.exe", Resolution: ResolutionAppLocal, Path: "engine.dll"},
+		{DLL: "fmodex.dll", ImportedBy: "game.exe", Resolution: ResolutionMissing, Delayed: true},
+		{DLL: "steam_api64.dll", ImportedBy: "game.exe", Resolution: ResolutionMissing},
+	}, r.Entries)
+	assert.EqualValues(t, []string{
+		"steam_api64.dll (imported by game.exe) can't be found",
+	}, r.missingDLLs(false))
+	assert.EqualValues(t, []string{
+		"fmodex.dll (delay-loaded by game.exe) can't be found",
+		"steam_api64.dll (imported by game.exe) can't be found",
+	}, r.missingDLLs(true))
+
+	files["steam_api64.dll"] = &PeInfo{Arch: ArchAmd64}
+	r = buildLoadOrder(files, nil, nil)[0]
+	assert.True(t, r.CanStart)
+}
@@ -0,0 +1,74 @@
+package pelican
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// DirFS returns a file system for the directory dir, like os.DirFS,
+// except that on Windows, dir is made absolute and given the \\?\ prefix
+// (unless it already has it), so that files whose path is longer than
+// MAX_PATH (260 characters) can be opened. That happens quickly with
+// deep game folders and non-ASCII (Japanese, Korean, etc.) file names.
+func DirFS(dir string) fs.FS {
+	return dirFS(extendedPath(dir))
+}
+
+type dirFS string
+
+func (dir dirFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) || runtime.GOOS == "windows" && strings.ContainsAny(name, `\:`) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	// os.DirFS joins with a forward slash, which \\?\ paths don't accept
+	f, err := os.Open(filepath.Join(string(dir), filepath.FromSlash(name)))
+	if err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// characters Windows doesn't allow in file names, besides control characters
+const windowsReservedChars = `<>:"|?*\`
+
+// device names Windows doesn't allow as file names, with or without extension
+var windowsReservedNames = map[string]bool{
+	"con": true, "prn": true, "aux": true, "nul": true,
+	"com1": true, "com2": true, "com3": true, "com4": true, "com5": true,
+	"com6": true, "com7": true, "com8": true, "com9": true,
+	"lpt1": true, "lpt2": true, "lpt3": true, "lpt4": true, "lpt5": true,
+	"lpt6": true, "lpt7": true, "lpt8": true, "lpt9": true,
+}
+
+// windowsFileName returns the slash-separated name with every element
+// made valid on Windows: reserved characters are replaced with
+// underscores, trailing dots and spaces (which Explorer can't handle)
+// are removed, and device names get an underscore prefix. Resource
+// names, which extracted items are named after, can contain anything.
+func windowsFileName(name string) string {
+	elements := strings.Split(name, "/")
+	for i, e := range elements {
+		e = strings.Map(func(r rune) rune {
+			if r < 0x20 || strings.ContainsRune(windowsReservedChars, r) {
+				return '_'
+			}
+			return r
+		}, e)
+		e = strings.TrimRight(e, ". ")
+		if e == "" {
+			e = "_"
+		}
+		base := strings.ToLower(e)
+		if j := strings.Index(base, "."); j >= 0 {
+			base = base[:j]
+		}
+		if windowsReservedNames[base] {
+			e = "_" + e
+		}
+		elements[i] = e
+	}
+	return strings.Join(elements, "/")
+}
@@ -0,0 +1,15 @@
+//go:build !windows
+// +build !windows
+
+package pelican
+
+// extendedPath returns p as-is, only Windows has a path length limit
+func extendedPath(p string) string {
+	return p
+}
+
+// localFileName returns name as-is, anything but slashes
+// and NUL bytes is allowed in file names
+func localFileName(name string) string {
+	return name
+}
@@ -0,0 +1,15 @@
+package pelican
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_WindowsFileName(t *testing.T) {
+	assert.EqualValues(t, "アイコン/101.ico", windowsFileName("アイコン/101.ico"))
+	assert.EqualValues(t, "What_/a_b_.ico", windowsFileName("What?/a:b*.ico"))
+	assert.EqualValues(t, "tab_name/trailing", windowsFileName("tab\tname/trailing. . "))
+	assert.EqualValues(t, "_CON.ico/_nul/console.ico", windowsFileName("CON.ico/nul/console.ico"))
+	assert.EqualValues(t, "_/x", windowsFileName("../x"))
+}
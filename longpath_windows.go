@@ -0,0 +1,32 @@
+//go:build windows
+// +build windows
+
+package pelican
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// extendedPath returns p as an absolute path with the \\?\ prefix,
+// which lifts the MAX_PATH limit, including for relative paths
+// (which os only extends when they're absolute)
+func extendedPath(p string) string {
+	if strings.HasPrefix(p, `\\?\`) || strings.HasPrefix(p, `\\.\`) {
+		return p
+	}
+	abs, err := filepath.Abs(p)
+	if err != nil {
+		return p
+	}
+	if strings.HasPrefix(abs, `\\`) {
+		// \\server\share\dir
+		return `\\?\UNC\` + abs[2:]
+	}
+	return `\\?\` + abs
+}
+
+// localFileName returns name, slash-separated, as a valid file name
+func localFileName(name string) string {
+	return windowsFileName(name)
+}
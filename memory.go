@@ -0,0 +1,58 @@
+package pelican
+
+import (
+	"fmt"
+	"sync"
+)
+
+// size of pooled buffers, enough for a data scan chunk and its overlap
+const pooledBufferSize = dataScanOverlap + dataScanChunkSize
+
+// buffers are shared across probes, so that batch scans don't
+// allocate (and leave for the GC) one per file
+var bufferPool = sync.Pool{
+	New: func() interface{} {
+		buf := make([]byte, pooledBufferSize)
+		return &buf
+	},
+}
+
+func getBuffer() *[]byte {
+	return bufferPool.Get().(*[]byte)
+}
+
+func putBuffer(buf *[]byte) {
+	bufferPool.Put(buf)
+}
+
+// reserve accounts for n bytes about to be allocated by an optional
+// analysis, and returns false if that would exceed params.MaxMemory,
+// in which case the analysis should be skipped. Reservations last until
+// released, see release.
+func (params *ProbeParams) reserve(n int64) bool {
+	if params.MaxMemory <= 0 {
+		return true
+	}
+	if params.memoryUsed+n > params.MaxMemory {
+		return false
+	}
+	params.memoryUsed += n
+	return true
+}
+
+func (params *ProbeParams) release(n int64) {
+	if params.MaxMemory <= 0 {
+		return
+	}
+	params.memoryUsed -= n
+}
+
+// reserveOrWarn is like reserve, but records a warning if
+// the analysis (described by what) has to be skipped
+func (params *ProbeParams) reserveOrWarn(info *PeInfo, n int64, what string, args ...interface{}) bool {
+	if params.reserve(n) {
+		return true
+	}
+	params.warn(info, WarningMemoryBudgetExceeded, nil, "Skipping %s: needs %d bytes, over the memory budget of %d", fmt.Sprintf(what, args...), n, params.MaxMemory)
+	return false
+}
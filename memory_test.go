@@ -0,0 +1,51 @@
+package pelican
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/itchio/httpkit/eos"
+	"github.com/itchio/pelican/pe"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ProbeEmbeddedMemoryBudget(t *testing.T) {
+	exe, err := ioutil.ReadFile("./testdata/hello/hello32-mingw.exe")
+	assert.NoError(t, err)
+	pf, err := pe.NewFile(bytes.NewReader(exe), int64(len(exe)))
+	assert.NoError(t, err)
+
+	blob := bytes.Repeat(exe, 3)
+	path := filepath.Join(t.TempDir(), "blob.bin")
+	assert.NoError(t, ioutil.WriteFile(path, blob, 0644))
+	f, err := eos.Open(path)
+	assert.NoError(t, err)
+	defer f.Close()
+
+	// enough for one image, and the data scan
+	params := ProbeParams{
+		Strict:    true,
+		MaxMemory: prefetchSize(prefetchGroups(pf)) + pooledBufferSize,
+	}
+	images, err := ProbeEmbedded(f, params)
+	assert.NoError(t, err)
+	if assert.Len(t, images, 3) {
+		for _, ei := range images {
+			assert.Empty(t, ei.Info.Warnings)
+			assert.EqualValues(t, images[0].Info, ei.Info)
+		}
+	}
+}
+
+func Test_MemoryReleased(t *testing.T) {
+	exe, err := ioutil.ReadFile("./testdata/pidgin/pidgin-uninst.exe")
+	assert.NoError(t, err)
+
+	// everything reserved while probing is released after
+	params := &ProbeParams{Strict: true, MaxMemory: 64 * 1024 * 1024}
+	_, err = params.probe(bytes.NewReader(exe), int64(len(exe)))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, params.memoryUsed)
+}
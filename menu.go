@@ -0,0 +1,241 @@
+package pelican
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// MenuTemplate is a parsed RT_MENU resource, either
+// a classic MENUITEMTEMPLATE list or an extended MENUEX_TEMPLATE
+type MenuTemplate struct {
+	ID       uint32 `json:"id"`
+	Language uint32 `json:"language"`
+	Extended bool   `json:"extended"`
+
+	Items []*MenuItem `json:"items"`
+}
+
+// MenuItem is an entry of a menu, which is a submenu if it has items
+type MenuItem struct {
+	// Command sent when the item is chosen, zero for submenus
+	// of classic menus and separators
+	ID   uint32 `json:"id,omitempty"`
+	Text string `json:"text,omitempty"`
+	// MF_* flags for classic menus, MFT_* types for extended menus
+	Flags uint32 `json:"flags,omitempty"`
+	// MFS_* state, only for extended menus
+	State uint32 `json:"state,omitempty"`
+	// Only for submenus of extended menus
+	HelpID uint32 `json:"helpId,omitempty"`
+
+	Items []*MenuItem `json:"items,omitempty"`
+}
+
+// MF_* flags of classic menu items
+const (
+	mfPopup = 0x10
+	mfEnd   = 0x80
+)
+
+// bResInfo flags of extended menu items
+const (
+	menuExPopup = 0x01
+	menuExEnd   = 0x80
+)
+
+// menus can't reasonably be nested deeper than this, and
+// bogus templates shouldn't blow the stack
+const maxMenuDepth = 16
+
+// Strings returns the text of every item of mt, submenus included,
+// in order. Separators and items without text are skipped.
+func (mt *MenuTemplate) Strings() []string {
+	var res []string
+	var visit func(items []*MenuItem)
+	visit = func(items []*MenuItem) {
+		for _, item := range items {
+			if item.Text != "" {
+				res = append(res, item.Text)
+			}
+			visit(item.Items)
+		}
+	}
+	visit(mt.Items)
+	return res
+}
+
+func (params *ProbeParams) parseMenu(info *PeInfo, id uint32, language uint32, rawData []byte) error {
+	consumer := params.Consumer
+	br := bytes.NewReader(rawData)
+
+	read := func(data interface{}) error {
+		return errors.WithStack(binary.Read(br, binary.LittleEndian, data))
+	}
+
+	// cf. https://docs.microsoft.com/en-us/windows/win32/menurc/menuex-template-item
+	// extended items are aligned on a 32-bit boundary
+	alignDword := func() error {
+		offset, err := br.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		mod4 := offset % 4
+		if mod4 > 0 {
+			_, err = br.Seek(4-mod4, io.SeekCurrent)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+		}
+		return nil
+	}
+
+	readString := func() (string, error) {
+		var res []byte
+		buf := make([]byte, 2)
+		for {
+			_, err := io.ReadFull(br, buf)
+			if err != nil {
+				return "", errors.WithStack(err)
+			}
+			if buf[0] == 0 && buf[1] == 0 {
+				break
+			}
+			res = append(res, buf...)
+		}
+		return DecodeUTF16(res), nil
+	}
+
+	mt := &MenuTemplate{
+		ID:       id,
+		Language: language,
+	}
+
+	var header struct {
+		Version uint16
+		Offset  uint16
+	}
+	err := read(&header)
+	if err != nil {
+		return err
+	}
+
+	var readItems func(depth int) ([]*MenuItem, error)
+	switch header.Version {
+	case 0:
+		// cf. https://docs.microsoft.com/en-us/windows/win32/menurc/normalmenuitem
+		readItems = func(depth int) ([]*MenuItem, error) {
+			if depth > maxMenuDepth {
+				return nil, errors.Errorf("menu nested too deeply")
+			}
+
+			var items []*MenuItem
+			for {
+				mi := &MenuItem{}
+				var flags uint16
+				err := read(&flags)
+				if err != nil {
+					return nil, err
+				}
+				mi.Flags = uint32(flags)
+
+				// popups have no ID
+				if flags&mfPopup == 0 {
+					var id16 uint16
+					err = read(&id16)
+					if err != nil {
+						return nil, err
+					}
+					mi.ID = uint32(id16)
+				}
+				mi.Text, err = readString()
+				if err != nil {
+					return nil, err
+				}
+
+				if flags&mfPopup != 0 {
+					mi.Items, err = readItems(depth + 1)
+					if err != nil {
+						return nil, err
+					}
+				}
+				items = append(items, mi)
+				if flags&mfEnd != 0 {
+					return items, nil
+				}
+			}
+		}
+	case 1:
+		mt.Extended = true
+		// skips dwHelpId, and whatever else an unknown version has there
+		_, err = br.Seek(int64(header.Offset), io.SeekCurrent)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		readItems = func(depth int) ([]*MenuItem, error) {
+			if depth > maxMenuDepth {
+				return nil, errors.Errorf("menu nested too deeply")
+			}
+
+			var items []*MenuItem
+			for {
+				var item struct {
+					Type    uint32
+					State   uint32
+					ID      uint32
+					ResInfo uint16
+				}
+				err := read(&item)
+				if err != nil {
+					return nil, err
+				}
+				mi := &MenuItem{
+					ID:    item.ID,
+					Flags: item.Type,
+					State: item.State,
+				}
+				mi.Text, err = readString()
+				if err != nil {
+					return nil, err
+				}
+				err = alignDword()
+				if err != nil {
+					return nil, err
+				}
+
+				if item.ResInfo&menuExPopup != 0 {
+					err = read(&mi.HelpID)
+					if err != nil {
+						return nil, err
+					}
+					mi.Items, err = readItems(depth + 1)
+					if err != nil {
+						return nil, err
+					}
+				}
+				items = append(items, mi)
+				if item.ResInfo&menuExEnd != 0 {
+					return items, nil
+				}
+			}
+		}
+	default:
+		return errors.Errorf("unknown menu template version %d", header.Version)
+	}
+
+	// empty menus have no items at all, not even a terminating one
+	if br.Len() > 0 {
+		mt.Items, err = readItems(0)
+		if err != nil {
+			return err
+		}
+	}
+
+	consumer.Debugf("menu %d: %d items", id, len(mt.Items))
+	info.Menus = append(info.Menus, mt)
+	return nil
+}
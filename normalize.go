@@ -0,0 +1,69 @@
+package pelican
+
+import (
+	"regexp"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// legal symbols, which NFKC would turn into letters ("TM", "SM")
+var trademarkReplacer = strings.NewReplacer(
+	"\u2122", " ", // ™
+	"\u00ae", " ", // ®
+	"\u00a9", " ", // ©
+	"\u2120", " ", // ℠
+)
+
+// the same, spelled out with ASCII
+var trademarkRegexp = regexp.MustCompile(`(?i)\((tm|r|c)\)`)
+
+// NormalizeName cleans up a version property meant for humans, like
+// ProductName or FileDescription: trademark symbols are removed, the text
+// is normalized to NFKC (so full-width letters become ASCII, for example),
+// control characters are dropped and whitespace is collapsed.
+func NormalizeName(s string) string {
+	s = trademarkReplacer.Replace(s)
+	s = trademarkRegexp.ReplaceAllString(s, " ")
+	s = norm.NFKC.String(s)
+	s = strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) {
+			return ' '
+		}
+		if unicode.IsControl(r) || unicode.Is(unicode.Cf, r) {
+			return -1
+		}
+		return r
+	}, s)
+	return strings.Join(strings.Fields(s), " ")
+}
+
+// trailing versions, for example "Game 1.2.3", "Game v1.2 beta" or "Game_1.0"
+var productVersionRegexp = regexp.MustCompile(`(?i)[\s_-]+v?\d+(\.\d+)+(\s.*)?$`)
+
+// trailing qualifiers, for example "Game (64-bit)", "Game x64"
+// or "Game (64-bit, PCD3D_SM5)" as Unreal Engine does
+var productQualifierRegexp = regexp.MustCompile(`(?i)\s*(\([^()]*\b(32|64)[- ]?bits?\b[^()]*\)|\((x86|x64|win32|win64|debug|release|shipping|development)\)|\b(x86|x64|win32|win64)|\b(32|64)[- ]?bits?)$`)
+
+// canonicalProductName returns a name the different builds (versions,
+// architectures, configurations) of the same product have in common,
+// lower-cased, or "" if props has no name. It's derived from ProductName,
+// or from FileDescription if there's no ProductName.
+func canonicalProductName(props map[string]string) string {
+	name := NormalizeName(props["ProductName"])
+	if name == "" {
+		name = NormalizeName(props["FileDescription"])
+	}
+
+	for {
+		trimmed := productVersionRegexp.ReplaceAllString(name, "")
+		trimmed = productQualifierRegexp.ReplaceAllString(trimmed, "")
+		trimmed = strings.TrimRight(trimmed, " -_,:")
+		if trimmed == name || trimmed == "" {
+			break
+		}
+		name = trimmed
+	}
+	return strings.ToLower(name)
+}
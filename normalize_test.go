@@ -0,0 +1,40 @@
+package pelican
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_NormalizeName(t *testing.T) {
+	cases := map[string]string{
+		"  Stockboy™  ":                    "Stockboy",
+		"Pelican® Games (TM)":              "Pelican Games",
+		"\uff27\uff41\uff4d\uff45":         "Game",
+		"Line\r\nbreak\tand\u200bzwsp\x00": "Line break andzwsp",
+		"":                                 "",
+	}
+	for in, out := range cases {
+		assert.EqualValues(t, out, NormalizeName(in), "%q", in)
+	}
+}
+
+func Test_CanonicalProductName(t *testing.T) {
+	cases := []struct {
+		props map[string]string
+		name  string
+	}{
+		{map[string]string{"ProductName": "Stockboy™ 1.2.3"}, "stockboy"},
+		{map[string]string{"ProductName": "Stockboy v2.0 beta (64-bit)"}, "stockboy"},
+		{map[string]string{"ProductName": "STOCKBOY (64-bit, PCD3D_SM5)"}, "stockboy"},
+		{map[string]string{"ProductName": "Stockboy x64"}, "stockboy"},
+		{map[string]string{"ProductName": "Stockboy (Shipping)"}, "stockboy"},
+		{map[string]string{"ProductName": "Half-Life 2"}, "half-life 2"},
+		{map[string]string{"ProductName": "2048"}, "2048"},
+		{map[string]string{"ProductName": " ", "FileDescription": "Stockboy Game"}, "stockboy game"},
+		{map[string]string{}, ""},
+	}
+	for _, c := range cases {
+		assert.EqualValues(t, c.name, canonicalProductName(c.props), "%v", c.props)
+	}
+}
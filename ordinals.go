@@ -0,0 +1,214 @@
+package pelican
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/itchio/pelican/pe"
+)
+
+// OrdinalNames maps the ordinals of the functions DLLs export to their
+// names, keyed by lower-case DLL name. It's used to tell which functions
+// are imported by ordinal, which is how some system DLLs are usually
+// imported, see ProbeParams.OrdinalNames.
+type OrdinalNames map[string]map[uint32]string
+
+// Winsock 1.1 ordinals, shared by ws2_32.dll and wsock32.dll
+var winsockOrdinals = map[uint32]string{
+	1:   "accept",
+	2:   "bind",
+	3:   "closesocket",
+	4:   "connect",
+	5:   "getpeername",
+	6:   "getsockname",
+	7:   "getsockopt",
+	8:   "htonl",
+	9:   "htons",
+	10:  "ioctlsocket",
+	11:  "inet_addr",
+	12:  "inet_ntoa",
+	13:  "listen",
+	14:  "ntohl",
+	15:  "ntohs",
+	16:  "recv",
+	17:  "recvfrom",
+	18:  "select",
+	19:  "send",
+	20:  "sendto",
+	21:  "setsockopt",
+	22:  "shutdown",
+	23:  "socket",
+	51:  "gethostbyaddr",
+	52:  "gethostbyname",
+	53:  "getprotobyname",
+	54:  "getprotobynumber",
+	55:  "getservbyname",
+	56:  "getservbyport",
+	57:  "gethostname",
+	101: "WSAAsyncSelect",
+	102: "WSAAsyncGetHostByAddr",
+	103: "WSAAsyncGetHostByName",
+	104: "WSAAsyncGetProtoByNumber",
+	105: "WSAAsyncGetProtoByName",
+	106: "WSAAsyncGetServByPort",
+	107: "WSAAsyncGetServByName",
+	108: "WSACancelAsyncRequest",
+	109: "WSASetBlockingHook",
+	110: "WSAUnhookBlockingHook",
+	111: "WSAGetLastError",
+	112: "WSASetLastError",
+	113: "WSACancelBlockingCall",
+	114: "WSAIsBlocking",
+	115: "WSAStartup",
+	116: "WSACleanup",
+	151: "__WSAFDIsSet",
+}
+
+// DefaultOrdinalNames covers the system DLLs that are
+// commonly imported by ordinal
+var DefaultOrdinalNames = OrdinalNames{
+	"ws2_32.dll":  winsockOrdinals,
+	"wsock32.dll": winsockOrdinals,
+	"oleaut32.dll": {
+		2:   "SysAllocString",
+		3:   "SysReAllocString",
+		4:   "SysAllocStringLen",
+		5:   "SysReAllocStringLen",
+		6:   "SysFreeString",
+		7:   "SysStringLen",
+		8:   "VariantInit",
+		9:   "VariantClear",
+		10:  "VariantCopy",
+		11:  "VariantCopyInd",
+		12:  "VariantChangeType",
+		13:  "VariantTimeToDosDateTime",
+		14:  "DosDateTimeToVariantTime",
+		15:  "SafeArrayCreate",
+		16:  "SafeArrayDestroy",
+		17:  "SafeArrayGetDim",
+		18:  "SafeArrayGetElemsize",
+		19:  "SafeArrayGetUBound",
+		20:  "SafeArrayGetLBound",
+		21:  "SafeArrayLock",
+		22:  "SafeArrayUnlock",
+		23:  "SafeArrayAccessData",
+		24:  "SafeArrayUnaccessData",
+		25:  "SafeArrayGetElement",
+		26:  "SafeArrayPutElement",
+		27:  "SafeArrayCopy",
+		28:  "DispGetParam",
+		29:  "DispGetIDsOfNames",
+		30:  "DispInvoke",
+		31:  "CreateDispTypeInfo",
+		32:  "CreateStdDispatch",
+		33:  "RegisterActiveObject",
+		34:  "RevokeActiveObject",
+		35:  "GetActiveObject",
+		36:  "SafeArrayAllocDescriptor",
+		37:  "SafeArrayAllocData",
+		38:  "SafeArrayDestroyDescriptor",
+		39:  "SafeArrayDestroyData",
+		40:  "SafeArrayRedim",
+		147: "VariantChangeTypeEx",
+		148: "SafeArrayPtrOfIndex",
+		149: "SysStringByteLen",
+		150: "SysAllocStringByteLen",
+		161: "LoadTypeLib",
+		162: "LoadRegTypeLib",
+		163: "RegisterTypeLib",
+		164: "QueryPathOfRegTypeLib",
+		183: "LoadTypeLibEx",
+		184: "SystemTimeToVariantTime",
+		185: "VariantTimeToSystemTime",
+		186: "UnRegisterTypeLib",
+	},
+	"comctl32.dll": {
+		2:   "MenuHelp",
+		3:   "ShowHideMenuCtl",
+		4:   "GetEffectiveClientRect",
+		5:   "DrawStatusTextA",
+		6:   "CreateStatusWindowA",
+		7:   "CreateToolbar",
+		8:   "CreateMappedBitmap",
+		13:  "MakeDragList",
+		14:  "LBItemFromPt",
+		15:  "DrawInsert",
+		16:  "CreateUpDownControl",
+		17:  "InitCommonControls",
+		410: "SetWindowSubclass",
+		411: "GetWindowSubclass",
+		412: "RemoveWindowSubclass",
+		413: "DefSubclassProc",
+	},
+}
+
+// Lookup returns the name of the function dll exports under
+// ordinal, or an empty string if it's unknown
+func (on OrdinalNames) Lookup(dll string, ordinal uint32) string {
+	return on[strings.ToLower(dll)][ordinal]
+}
+
+// Add records the names of the functions exported by dll, for example
+// read with pe.File.ExportedSymbols. Exports without a name are skipped.
+func (on OrdinalNames) Add(dll string, exports *pe.Exports) {
+	dll = strings.ToLower(dll)
+	for _, es := range exports.Symbols {
+		if es.Name == "" {
+			continue
+		}
+		if on[dll] == nil {
+			on[dll] = make(map[uint32]string)
+		}
+		on[dll][es.Ordinal] = es.Name
+	}
+}
+
+// Digest returns a hex-encoded SHA-256 of the contents of on, which
+// doesn't depend on map order, or an empty string if on is empty
+func (on OrdinalNames) Digest() string {
+	var dlls []string
+	for dll, names := range on {
+		if len(names) > 0 {
+			dlls = append(dlls, dll)
+		}
+	}
+	if len(dlls) == 0 {
+		return ""
+	}
+	sort.Strings(dlls)
+
+	h := sha256.New()
+	for _, dll := range dlls {
+		var ordinals []uint32
+		for ordinal := range on[dll] {
+			ordinals = append(ordinals, ordinal)
+		}
+		sort.Slice(ordinals, func(i, j int) bool {
+			return ordinals[i] < ordinals[j]
+		})
+		for _, ordinal := range ordinals {
+			fmt.Fprintf(h, "%s\t%d\t%s\n", dll, ordinal, on[dll][ordinal])
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// resolveOrdinals fills in the names of the symbols imported by ordinal
+// that are in params.OrdinalNames or DefaultOrdinalNames, so that they're
+// treated like those imported by name. symbols is modified in place.
+func (params *ProbeParams) resolveOrdinals(symbols []pe.ImportedSymbol) []pe.ImportedSymbol {
+	for i, sym := range symbols {
+		if !sym.ByOrdinal() {
+			continue
+		}
+		name := params.OrdinalNames.Lookup(sym.DLL, sym.Ordinal)
+		if name == "" {
+			name = DefaultOrdinalNames.Lookup(sym.DLL, sym.Ordinal)
+		}
+		symbols[i].Name = name
+	}
+	return symbols
+}
@@ -0,0 +1,70 @@
+package pelican
+
+import (
+	"testing"
+
+	"github.com/itchio/pelican/pe"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ResolveOrdinals(t *testing.T) {
+	params := &ProbeParams{
+		OrdinalNames: OrdinalNames{
+			// takes precedence over DefaultOrdinalNames
+			"ws2_32.dll":    {23: "my_socket"},
+			"steam_api.dll": {3: "SteamAPI_Init"},
+		},
+	}
+	symbols := params.resolveOrdinals(importedSymbols(
+		"#115:WS2_32.dll",
+		"#23:WS2_32.dll",
+		"#3:steam_api.dll",
+		"#4:steam_api.dll",
+		"#999:WS2_32.dll",
+		"CreateFileW:KERNEL32.dll",
+	))
+	var names []string
+	for _, sym := range symbols {
+		names = append(names, sym.String())
+	}
+	assert.EqualValues(t, []string{
+		"WSAStartup:WS2_32.dll",
+		"my_socket:WS2_32.dll",
+		"SteamAPI_Init:steam_api.dll",
+		"#4:steam_api.dll",
+		"#999:WS2_32.dll",
+		"CreateFileW:KERNEL32.dll",
+	}, names)
+	assert.EqualValues(t, 115, symbols[0].Ordinal)
+}
+
+func Test_OrdinalNamesAdd(t *testing.T) {
+	on := make(OrdinalNames)
+	on.Add("Engine.DLL", &pe.Exports{Symbols: []pe.ExportedSymbol{
+		{Name: "Alloc", Ordinal: 1},
+		{Ordinal: 2},
+		{Name: "Loop", Ordinal: 3, Forwarder: "core.Loop"},
+	}})
+	assert.EqualValues(t, OrdinalNames{"engine.dll": {1: "Alloc", 3: "Loop"}}, on)
+	assert.EqualValues(t, "Loop", on.Lookup("ENGINE.dll", 3))
+	assert.EqualValues(t, "", on.Lookup("engine.dll", 2))
+	assert.EqualValues(t, "", OrdinalNames(nil).Lookup("engine.dll", 1))
+}
+
+func Test_OrdinalNamesDigest(t *testing.T) {
+	assert.EqualValues(t, "", OrdinalNames(nil).Digest())
+	assert.EqualValues(t, "", OrdinalNames{"engine.dll": {}}.Digest())
+
+	on := OrdinalNames{
+		"engine.dll": {1: "Alloc", 3: "Loop"},
+		"audio.dll":  {7: "Play"},
+	}
+	digest := on.Digest()
+	assert.Len(t, digest, 64)
+	for i := 0; i < 10; i++ {
+		assert.EqualValues(t, digest, on.Digest())
+	}
+
+	on["engine.dll"][3] = "Run"
+	assert.NotEqual(t, digest, on.Digest())
+}
@@ -0,0 +1,77 @@
+package pe
+
+import (
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/binary"
+	"hash"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// offsets of CheckSum and of the certificate table entry
+// of the data directory, from the start of the optional header
+const (
+	checkSumOffset           = 64
+	certificateEntryOffset32 = 128
+	certificateEntryOffset64 = 144
+)
+
+// Authentihash writes to h the parts of f an Authenticode signature
+// covers, and returns the resulting digest. That's the whole file except
+// for the CheckSum field of the optional header, the certificate table
+// entry of the data directory, and the certificate table itself, which
+// are written when signing.
+//
+// If f is signed, the digest matches the one in its signature (see
+// Certificates), as long as h is the digest algorithm the signature uses,
+// and the file wasn't modified since. It doesn't tell whether the
+// signature itself is valid.
+func (f *File) Authentihash(h hash.Hash) ([]byte, error) {
+	if f.OptionalHeader == nil {
+		return nil, errors.Errorf("object files can't be signed")
+	}
+	optionalHeader := f.base + int64(binary.Size(f.FileHeader))
+	certificateEntry := optionalHeader + certificateEntryOffset32
+	if f.OptionalHeaderFields().PE64 {
+		certificateEntry = optionalHeader + certificateEntryOffset64
+	}
+
+	// ranges of the file that are hashed, in order
+	type fileRange struct{ start, end int64 }
+	ranges := []fileRange{
+		{0, optionalHeader + checkSumOffset},
+		{optionalHeader + checkSumOffset + 4, certificateEntry},
+	}
+	if cert := f.dataDirectory(IMAGE_DIRECTORY_ENTRY_SECURITY); cert.VirtualAddress != 0 && cert.Size != 0 {
+		// unlike other data directories, this is a file offset
+		start := int64(cert.VirtualAddress)
+		end := start + int64(cert.Size)
+		if start < certificateEntry+8 || end > f.size {
+			return nil, errors.Errorf("certificate table (%d bytes at 0x%x) is outside of the file", cert.Size, cert.VirtualAddress)
+		}
+		ranges = append(ranges, fileRange{certificateEntry + 8, start}, fileRange{end, f.size})
+	} else {
+		ranges = append(ranges, fileRange{certificateEntry + 8, f.size})
+	}
+
+	for _, r := range ranges {
+		_, err := io.Copy(h, io.NewSectionReader(f.readerAt, r.start, r.end-r.start))
+		if err != nil {
+			return nil, errors.WithMessage(err, "while computing Authentihash")
+		}
+	}
+	return h.Sum(nil), nil
+}
+
+// AuthentihashSHA1 returns the SHA-1 Authentihash of f, which older
+// signatures use, see Authentihash
+func (f *File) AuthentihashSHA1() ([]byte, error) {
+	return f.Authentihash(sha1.New())
+}
+
+// AuthentihashSHA256 returns the SHA-256 Authentihash of f, see Authentihash
+func (f *File) AuthentihashSHA256() ([]byte, error) {
+	return f.Authentihash(sha256.New())
+}
@@ -0,0 +1,39 @@
+package pe_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/itchio/pelican/pe"
+)
+
+func Benchmark_NewFile(b *testing.B) {
+	for _, path := range []string{
+		"../testdata/hello/hello.obj",
+		"../testdata/hello/hello64-msvc.exe",
+		"../testdata/pidgin/pidgin-uninst.exe",
+		"../testdata/wincdemu/WinCDEmu-4.1.exe",
+	} {
+		b.Run(filepath.Base(path), func(b *testing.B) {
+			f, err := os.Open(path)
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer f.Close()
+
+			stats, err := f.Stat()
+			if err != nil {
+				b.Fatal(err)
+			}
+
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				_, err := pe.NewFile(f, stats.Size())
+				if err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
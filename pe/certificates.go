@@ -0,0 +1,85 @@
+package pe
+
+import (
+	"encoding/binary"
+
+	"github.com/pkg/errors"
+)
+
+// size of the WIN_CERTIFICATE header
+const sizeofWinCertificate = 8
+
+// WIN_CERT_REVISION_* values of Certificate.Revision
+const (
+	WIN_CERT_REVISION_1_0 = 0x0100
+	WIN_CERT_REVISION_2_0 = 0x0200
+)
+
+// WIN_CERT_TYPE_* values of Certificate.CertificateType
+const (
+	WIN_CERT_TYPE_X509             = 0x0001
+	WIN_CERT_TYPE_PKCS_SIGNED_DATA = 0x0002
+	WIN_CERT_TYPE_RESERVED_1       = 0x0003
+	WIN_CERT_TYPE_TS_STACK_SIGNED  = 0x0004
+)
+
+// Certificate is an entry of the certificate table (WIN_CERTIFICATE).
+// Authenticode signatures are entries of type
+// WIN_CERT_TYPE_PKCS_SIGNED_DATA, see PKCS7.
+type Certificate struct {
+	// File offset of the entry
+	Offset          int64
+	Revision        uint16
+	CertificateType uint16
+	// The contents of the entry, without its header and padding
+	Data []byte
+}
+
+// PKCS7 returns the DER-encoded PKCS #7 SignedData of c, which crypto
+// libraries take to verify the signature, or nil if c isn't an
+// Authenticode signature.
+func (c *Certificate) PKCS7() []byte {
+	if c.CertificateType != WIN_CERT_TYPE_PKCS_SIGNED_DATA {
+		return nil
+	}
+	return c.Data
+}
+
+// Certificates returns the entries of the certificate table, or nil if
+// f has none (it's not signed). Unlike that of other data directories,
+// the address of the certificate table is a file offset: it's not mapped
+// to memory.
+func (f *File) Certificates() ([]Certificate, error) {
+	dd := f.dataDirectory(IMAGE_DIRECTORY_ENTRY_SECURITY)
+	if dd.VirtualAddress == 0 || dd.Size == 0 {
+		return nil, nil
+	}
+	start := int64(dd.VirtualAddress)
+	end := start + int64(dd.Size)
+	if end > f.size {
+		return nil, errors.Errorf("certificate table (%d bytes at 0x%x) extends past the end of the file", dd.Size, dd.VirtualAddress)
+	}
+
+	table := make([]byte, dd.Size)
+	_, err := f.readerAt.ReadAt(table, start)
+	if err != nil {
+		return nil, errors.WithMessage(err, "while reading certificate table")
+	}
+
+	var res []Certificate
+	for offset := 0; offset+sizeofWinCertificate <= len(table); {
+		length := int(binary.LittleEndian.Uint32(table[offset:]))
+		if length < sizeofWinCertificate || length > len(table)-offset {
+			return nil, errors.Errorf("certificate table entry at 0x%x has invalid length %d", start+int64(offset), length)
+		}
+		res = append(res, Certificate{
+			Offset:          start + int64(offset),
+			Revision:        binary.LittleEndian.Uint16(table[offset+4:]),
+			CertificateType: binary.LittleEndian.Uint16(table[offset+6:]),
+			Data:            table[offset+sizeofWinCertificate : offset+length],
+		})
+		// entries are aligned on 8 bytes
+		offset += (length + 7) &^ 7
+	}
+	return res, nil
+}
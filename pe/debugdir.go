@@ -0,0 +1,187 @@
+package pe
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// size of IMAGE_DEBUG_DIRECTORY
+const sizeofDebugDirectory = 28
+
+// debug directories have a handful of entries, and CodeView records
+// are a header and a path, this bounds what's read from broken files
+const (
+	maxDebugDirectories = 64
+	maxCodeViewSize     = 64 * 1024
+)
+
+// IMAGE_DEBUG_TYPE_* values of DebugDirectory.Type
+const (
+	IMAGE_DEBUG_TYPE_UNKNOWN               = 0
+	IMAGE_DEBUG_TYPE_COFF                  = 1
+	IMAGE_DEBUG_TYPE_CODEVIEW              = 2
+	IMAGE_DEBUG_TYPE_FPO                   = 3
+	IMAGE_DEBUG_TYPE_MISC                  = 4
+	IMAGE_DEBUG_TYPE_EXCEPTION             = 5
+	IMAGE_DEBUG_TYPE_FIXUP                 = 6
+	IMAGE_DEBUG_TYPE_BORLAND               = 9
+	IMAGE_DEBUG_TYPE_CLSID                 = 11
+	IMAGE_DEBUG_TYPE_VC_FEATURE            = 12
+	IMAGE_DEBUG_TYPE_POGO                  = 13
+	IMAGE_DEBUG_TYPE_ILTCG                 = 14
+	IMAGE_DEBUG_TYPE_REPRO                 = 16
+	IMAGE_DEBUG_TYPE_EX_DLLCHARACTERISTICS = 20
+)
+
+// DebugDirectory is an entry of the debug directory
+// (IMAGE_DEBUG_DIRECTORY), which locates debug information
+// of the given Type
+type DebugDirectory struct {
+	Characteristics  uint32
+	TimeDateStamp    uint32
+	MajorVersion     uint16
+	MinorVersion     uint16
+	Type             uint32
+	SizeOfData       uint32
+	AddressOfRawData uint32
+	PointerToRawData uint32
+}
+
+// GUID is a Windows GUID, as stored in binaries (the first three
+// fields are little-endian)
+type GUID [16]byte
+
+// String formats g the usual way, for example
+// "3F2504E0-4F89-11D3-9A0C-0305E82C3301"
+func (g GUID) String() string {
+	return fmt.Sprintf("%08X-%04X-%04X-%X-%X",
+		binary.LittleEndian.Uint32(g[0:4]),
+		binary.LittleEndian.Uint16(g[4:6]),
+		binary.LittleEndian.Uint16(g[6:8]),
+		g[8:10], g[10:16])
+}
+
+// CodeView is the CodeView record of the debug directory, which
+// tells which PDB file holds the debug information of the binary
+type CodeView struct {
+	// "RSDS" for PDB 7.0 files (Visual C++ 7.0 and later),
+	// "NB10" for PDB 2.0 files
+	Signature string
+	// Identifies the PDB, along with Age. Only set for RSDS records.
+	GUID GUID
+	// Identifies the PDB, along with Age. Only set for NB10 records.
+	Timestamp uint32
+	// Incremented each time the PDB is updated
+	Age uint32
+	// Path of the PDB when the binary was linked, for example
+	// "C:\\build\\game\\Release\\game.pdb"
+	PDBPath string
+}
+
+// SymbolServerKey returns the identifier symbol servers file the PDB of
+// cv under: GUID (or timestamp) and age, in upper-case hex without
+// separators. The PDB is at <base name>/<key>/<base name>.
+func (cv *CodeView) SymbolServerKey() string {
+	if cv.Signature == "NB10" {
+		return fmt.Sprintf("%08X%X", cv.Timestamp, cv.Age)
+	}
+	g := cv.GUID
+	return fmt.Sprintf("%08X%04X%04X%X%X",
+		binary.LittleEndian.Uint32(g[0:4]),
+		binary.LittleEndian.Uint16(g[4:6]),
+		binary.LittleEndian.Uint16(g[6:8]),
+		g[8:16], cv.Age)
+}
+
+// DebugDirectories returns the entries of the debug directory,
+// or nil if f has none.
+func (f *File) DebugDirectories() ([]DebugDirectory, error) {
+	dd := f.dataDirectory(IMAGE_DIRECTORY_ENTRY_DEBUG)
+	if dd.VirtualAddress == 0 {
+		return nil, nil
+	}
+
+	n := int64(dd.Size / sizeofDebugDirectory)
+	if n > maxDebugDirectories {
+		n = maxDebugDirectories
+	}
+	rr := newRVAReader(f)
+	data, err := rr.slice(dd.VirtualAddress, n*sizeofDebugDirectory)
+	if err != nil {
+		return nil, errors.WithMessage(err, "while reading debug directory")
+	}
+
+	var res []DebugDirectory
+	for ; len(data) >= sizeofDebugDirectory; data = data[sizeofDebugDirectory:] {
+		res = append(res, DebugDirectory{
+			Characteristics:  binary.LittleEndian.Uint32(data[0:4]),
+			TimeDateStamp:    binary.LittleEndian.Uint32(data[4:8]),
+			MajorVersion:     binary.LittleEndian.Uint16(data[8:10]),
+			MinorVersion:     binary.LittleEndian.Uint16(data[10:12]),
+			Type:             binary.LittleEndian.Uint32(data[12:16]),
+			SizeOfData:       binary.LittleEndian.Uint32(data[16:20]),
+			AddressOfRawData: binary.LittleEndian.Uint32(data[20:24]),
+			PointerToRawData: binary.LittleEndian.Uint32(data[24:28]),
+		})
+	}
+	return res, nil
+}
+
+// CodeView returns the first CodeView record of the debug directory,
+// or nil if there's none, or if it's of an unknown format.
+func (f *File) CodeView() (*CodeView, error) {
+	dds, err := f.DebugDirectories()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, dd := range dds {
+		if dd.Type != IMAGE_DEBUG_TYPE_CODEVIEW || dd.SizeOfData < 4 {
+			continue
+		}
+		size := int64(dd.SizeOfData)
+		if size > maxCodeViewSize {
+			size = maxCodeViewSize
+		}
+		// it's not always mapped (AddressOfRawData can be 0),
+		// but it's always in the file
+		data := make([]byte, size)
+		_, err := f.readerAt.ReadAt(data, int64(dd.PointerToRawData))
+		if err != nil {
+			return nil, errors.WithMessage(err, "while reading CodeView record")
+		}
+		return parseCodeView(data)
+	}
+	return nil, nil
+}
+
+func parseCodeView(data []byte) (*CodeView, error) {
+	cv := &CodeView{Signature: string(data[0:4])}
+	var path []byte
+	switch cv.Signature {
+	case "RSDS":
+		if len(data) < 24 {
+			return nil, errors.Errorf("RSDS record is too short (%d bytes)", len(data))
+		}
+		copy(cv.GUID[:], data[4:20])
+		cv.Age = binary.LittleEndian.Uint32(data[20:24])
+		path = data[24:]
+	case "NB10":
+		if len(data) < 16 {
+			return nil, errors.Errorf("NB10 record is too short (%d bytes)", len(data))
+		}
+		cv.Timestamp = binary.LittleEndian.Uint32(data[8:12])
+		cv.Age = binary.LittleEndian.Uint32(data[12:16])
+		path = data[16:]
+	default:
+		return nil, nil
+	}
+	if i := bytes.IndexByte(path, 0); i >= 0 {
+		path = path[:i]
+	}
+	cv.PDBPath = string(path)
+	return cv, nil
+}
@@ -0,0 +1,126 @@
+package pe
+
+import (
+	"encoding/binary"
+
+	"github.com/pkg/errors"
+)
+
+// size of ImgDelayDescr
+const sizeofDelayImportDescriptor = 32
+
+// DelayImportDescriptor is an entry of the delay-load import directory
+// (ImgDelayDescr). DLLs listed there are only loaded when one of their
+// functions is first called, by the helper linked from delayimp.lib.
+type DelayImportDescriptor struct {
+	// If bit 0 is set, the other fields are RVAs, otherwise they're
+	// virtual addresses (only Visual C++ 6 generated those)
+	Attributes                 uint32
+	DllNameRVA                 uint32
+	ModuleHandleRVA            uint32
+	ImportAddressTableRVA      uint32
+	ImportNameTableRVA         uint32
+	BoundImportAddressTableRVA uint32
+	UnloadInformationTableRVA  uint32
+	TimeDateStamp              uint32
+}
+
+// base returns what to subtract from the addresses of did to get RVAs
+func (did *DelayImportDescriptor) base(f *File) uint64 {
+	if did.Attributes&1 != 0 {
+		return 0
+	}
+	return f.OptionalHeaderFields().ImageBase
+}
+
+// DelayImportDescriptors returns the entries of the delay-load import
+// directory, or nil if the file has none.
+func (f *File) DelayImportDescriptors() ([]DelayImportDescriptor, error) {
+	_, dids, err := f.delayImportDescriptors()
+	return dids, err
+}
+
+func (f *File) delayImportDescriptors() (*rvaReader, []DelayImportDescriptor, error) {
+	dd := f.dataDirectory(IMAGE_DIRECTORY_ENTRY_DELAY_IMPORT)
+	if dd.VirtualAddress == 0 {
+		return nil, nil, nil
+	}
+
+	rr := newRVAReader(f)
+	rva := dd.VirtualAddress
+	_, _, err := rr.locate(rva)
+	if err != nil {
+		return nil, nil, errors.WithMessage(err, "while reading delay-load import directory")
+	}
+
+	var res []DelayImportDescriptor
+	for ; rr.available(rva) >= sizeofDelayImportDescriptor; rva += sizeofDelayImportDescriptor {
+		block, err := rr.slice(rva, sizeofDelayImportDescriptor)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		var did DelayImportDescriptor
+		did.Attributes = binary.LittleEndian.Uint32(block[0:4])
+		did.DllNameRVA = binary.LittleEndian.Uint32(block[4:8])
+		did.ModuleHandleRVA = binary.LittleEndian.Uint32(block[8:12])
+		did.ImportAddressTableRVA = binary.LittleEndian.Uint32(block[12:16])
+		did.ImportNameTableRVA = binary.LittleEndian.Uint32(block[16:20])
+		did.BoundImportAddressTableRVA = binary.LittleEndian.Uint32(block[20:24])
+		did.UnloadInformationTableRVA = binary.LittleEndian.Uint32(block[24:28])
+		did.TimeDateStamp = binary.LittleEndian.Uint32(block[28:32])
+		if did.DllNameRVA == 0 {
+			break
+		}
+		res = append(res, did)
+	}
+	return rr, res, nil
+}
+
+// DelayImportedLibraries returns the names of the libraries
+// the binary f delay-loads, see DelayImportDescriptor. Like
+// ImportedLibraries, it skips names that can't be read.
+func (f *File) DelayImportedLibraries() ([]string, error) {
+	rr, dids, err := f.delayImportDescriptors()
+	if err != nil {
+		return nil, err
+	}
+
+	var ie importErrors
+	var dlls []string
+	for _, did := range dids {
+		dll, err := rr.stringAt(uint32(uint64(did.DllNameRVA) - did.base(f)))
+		if err != nil {
+			ie.add(errors.WithMessage(err, "while reading delay-loaded library name"))
+			continue
+		}
+		dlls = append(dlls, dll)
+	}
+	return dlls, ie.err()
+}
+
+// DelayImportedSymbols returns the symbols the binary f imports
+// from delay-loaded libraries, like ImportedSymbols
+func (f *File) DelayImportedSymbols() ([]ImportedSymbol, error) {
+	rr, dids, err := f.delayImportDescriptors()
+	if err != nil {
+		return nil, err
+	}
+
+	var ie importErrors
+	var allSymbols []ImportedSymbol
+	for _, did := range dids {
+		base := did.base(f)
+		dll, err := rr.stringAt(uint32(uint64(did.DllNameRVA) - base))
+		if err != nil {
+			ie.add(errors.WithMessage(err, "while reading delay-loaded library name"))
+			continue
+		}
+		if did.ImportNameTableRVA == 0 {
+			continue
+		}
+
+		allSymbols = append(allSymbols, f.thunkSymbols(rr, uint32(uint64(did.ImportNameTableRVA)-base), base, dll, &ie)...)
+	}
+	return allSymbols, ie.err()
+}
@@ -0,0 +1,170 @@
+package pe
+
+import (
+	"encoding/binary"
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// size of IMAGE_EXPORT_DIRECTORY
+const sizeofExportDirectory = 40
+
+// Forwarder is an export that's implemented by another DLL.
+// The loader resolves it when the export is imported.
+type Forwarder struct {
+	// Exported name, or "#" followed by the ordinal for exports without a name
+	Name string
+	// For example "NTDLL.RtlAllocateHeap", or "NTDLL.#12" for an ordinal
+	Target string
+}
+
+// ExportedSymbol is an entry of the export address table
+type ExportedSymbol struct {
+	// Empty for exports without a name, which can only be imported by ordinal
+	Name    string
+	Ordinal uint32
+	// Address of the function (or variable), or of the forwarder string
+	RVA uint32
+	// For forwarders, for example "NTDLL.RtlAllocateHeap", see Forwarder
+	Forwarder string
+}
+
+// Exports is the contents of the export directory of a DLL
+type Exports struct {
+	// Name of the DLL when it was linked, for example "steam_api.dll".
+	// It can differ from the file name, when it was renamed.
+	DLLName string
+	// In export address table order, which is that of ordinals
+	Symbols []ExportedSymbol
+}
+
+// ExportedSymbols reads the export directory. It returns nil if f has none,
+// as is the case for most executables.
+func (f *File) ExportedSymbols() (*Exports, error) {
+	dd := f.dataDirectory(IMAGE_DIRECTORY_ENTRY_EXPORT)
+	if dd.VirtualAddress == 0 {
+		return nil, nil
+	}
+
+	rr := newRVAReader(f)
+	ed, err := rr.slice(dd.VirtualAddress, sizeofExportDirectory)
+	if err != nil {
+		return nil, errors.WithMessage(err, "while reading export directory")
+	}
+
+	res := &Exports{}
+	if nameRVA := binary.LittleEndian.Uint32(ed[12:16]); nameRVA != 0 {
+		res.DLLName, err = rr.stringAt(nameRVA)
+		if err != nil {
+			return nil, errors.WithMessage(err, "while reading exporting DLL name")
+		}
+	}
+	res.Symbols, err = readExports(rr, dd, ed)
+	if err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// readExports reads the export address table, given the
+// export directory ed found at dd. It returns nil if it's empty.
+func readExports(rr *rvaReader, dd DataDirectory, ed []byte) ([]ExportedSymbol, error) {
+	base := binary.LittleEndian.Uint32(ed[16:20])
+	numberOfFunctions := binary.LittleEndian.Uint32(ed[20:24])
+	numberOfNames := binary.LittleEndian.Uint32(ed[24:28])
+	addressOfFunctions := binary.LittleEndian.Uint32(ed[28:32])
+	addressOfNames := binary.LittleEndian.Uint32(ed[32:36])
+	addressOfNameOrdinals := binary.LittleEndian.Uint32(ed[36:40])
+
+	if numberOfFunctions == 0 {
+		return nil, nil
+	}
+
+	eat, err := rr.slice(addressOfFunctions, int64(numberOfFunctions)*4)
+	if err != nil {
+		return nil, errors.WithMessage(err, "while reading export address table")
+	}
+
+	names := make(map[uint32]string)
+	if numberOfNames > 0 {
+		npt, err := rr.slice(addressOfNames, int64(numberOfNames)*4)
+		if err != nil {
+			return nil, errors.WithMessage(err, "while reading export name pointer table")
+		}
+		ot, err := rr.slice(addressOfNameOrdinals, int64(numberOfNames)*2)
+		if err != nil {
+			return nil, errors.WithMessage(err, "while reading export ordinal table")
+		}
+
+		for i := uint32(0); i < numberOfNames; i++ {
+			name, err := rr.stringAt(binary.LittleEndian.Uint32(npt[i*4:]))
+			if err != nil {
+				return nil, errors.WithMessagef(err, "while reading name of export %d", i)
+			}
+			names[uint32(binary.LittleEndian.Uint16(ot[i*2:]))] = name
+		}
+	}
+
+	var res []ExportedSymbol
+	for i := uint32(0); i < numberOfFunctions; i++ {
+		rva := binary.LittleEndian.Uint32(eat[i*4:])
+		if rva == 0 {
+			// unused ordinal
+			continue
+		}
+
+		e := ExportedSymbol{
+			Name:    names[i],
+			Ordinal: base + i,
+			RVA:     rva,
+		}
+		if rva >= dd.VirtualAddress && uint64(rva) < uint64(dd.VirtualAddress)+uint64(dd.Size) {
+			// forwarders point to a string in the export directory
+			e.Forwarder, err = rr.stringAt(rva)
+			if err != nil {
+				return nil, errors.WithMessagef(err, "while reading forwarder of ordinal %d", e.Ordinal)
+			}
+		}
+		res = append(res, e)
+	}
+	return res, nil
+}
+
+// ExportedNames returns the names of all exports (including forwarders),
+// in export address table order. Exports without a name are left out.
+func (f *File) ExportedNames() ([]string, error) {
+	exports, err := f.ExportedSymbols()
+	if err != nil || exports == nil {
+		return nil, err
+	}
+
+	var res []string
+	for _, e := range exports.Symbols {
+		if e.Name != "" {
+			res = append(res, e.Name)
+		}
+	}
+	return res, nil
+}
+
+// Forwarders returns all exports that are forwarded to other DLLs
+func (f *File) Forwarders() ([]Forwarder, error) {
+	exports, err := f.ExportedSymbols()
+	if err != nil || exports == nil {
+		return nil, err
+	}
+
+	var res []Forwarder
+	for _, e := range exports.Symbols {
+		if e.Forwarder == "" {
+			continue
+		}
+		name := e.Name
+		if name == "" {
+			name = fmt.Sprintf("#%d", e.Ordinal)
+		}
+		res = append(res, Forwarder{Name: name, Target: e.Forwarder})
+	}
+	return res, nil
+}
@@ -6,10 +6,14 @@
 package pe
 
 import (
+	"bytes"
+	"compress/zlib"
 	"debug/dwarf"
 	"encoding/binary"
 	"fmt"
 	"io"
+	"strings"
+	"sync"
 
 	"github.com/pkg/errors"
 )
@@ -30,6 +34,9 @@ type File struct {
 	readerAt io.ReaderAt
 	base     int64
 	size     int64
+
+	symbolIndexOnce sync.Once
+	symbolIndex     []*Symbol // see NearestSymbol
 }
 
 var (
@@ -73,7 +80,7 @@ func NewFile(r io.ReaderAt, size int64) (*File, error) {
 		return nil, err
 	}
 	switch f.FileHeader.Machine {
-	case IMAGE_FILE_MACHINE_UNKNOWN, IMAGE_FILE_MACHINE_AMD64, IMAGE_FILE_MACHINE_I386:
+	case IMAGE_FILE_MACHINE_UNKNOWN, IMAGE_FILE_MACHINE_AMD64, IMAGE_FILE_MACHINE_I386, IMAGE_FILE_MACHINE_ARM64, IMAGE_FILE_MACHINE_ARMNT:
 	default:
 		return nil, fmt.Errorf("Unrecognised COFF file header machine value of 0x%x.", f.FileHeader.Machine)
 	}
@@ -145,8 +152,22 @@ func NewFile(r io.ReaderAt, size int64) (*File, error) {
 			NumberOfLineNumbers:  sh.NumberOfLineNumbers,
 			Characteristics:      sh.Characteristics,
 		}
+		s.DeclaredOffset = sh.PointerToRawData
+		s.DeclaredSize = sh.SizeOfRawData
+		s.Offset, s.Size = f.rawDataRange(sh)
+		// decided from the header: rawDataRange rounds small offsets
+		// down to 0, and the loader reads those from the file
+		noRawData := sh.PointerToRawData == 0 && !f.LowAlignment()
+		if !noRawData && int64(s.Offset)+int64(s.Size) > size {
+			// truncated file: only what's there can be read
+			available := size - int64(s.Offset)
+			if available < 0 {
+				available = 0
+			}
+			s.Size = uint32(available)
+		}
 		r2 := r
-		if sh.PointerToRawData == 0 { // .bss must have all 0s
+		if noRawData { // .bss must have all 0s
 			r2 = zeroReaderAt{}
 		}
 		s.sr = io.NewSectionReader(r2, int64(s.SectionHeader.Offset), int64(s.SectionHeader.Size))
@@ -164,6 +185,90 @@ func NewFile(r io.ReaderAt, size int64) (*File, error) {
 	return f, nil
 }
 
+// the loader rounds PointerToRawData down to a multiple of this,
+// whatever FileAlignment says
+const loaderFileAlignment = 0x200
+
+// below this SectionAlignment, images are mapped as is, see LowAlignment
+const pageSize = 0x1000
+
+// LowAlignment returns true if the SectionAlignment of f is less than
+// the page size. The loader then maps the file as is, instead of section
+// by section: the raw data of each section is at its RVA, whatever its
+// PointerToRawData says. Packers do that to shrink their output, and
+// often zero FileAlignment and SectionAlignment altogether.
+func (f *File) LowAlignment() bool {
+	return f.OptionalHeader != nil && f.OptionalHeaderFields().SectionAlignment < pageSize
+}
+
+// rawDataRange returns where the loader reads the raw data of the
+// section described by sh from, which isn't always what sh says when the
+// alignment values of the image violate the spec.
+func (f *File) rawDataRange(sh *SectionHeader32) (offset uint32, size uint32) {
+	if f.OptionalHeader == nil {
+		// object files are never loaded
+		return sh.PointerToRawData, sh.SizeOfRawData
+	}
+	if f.LowAlignment() {
+		size = sh.VirtualSize
+		if size < sh.SizeOfRawData {
+			size = sh.SizeOfRawData
+		}
+		return sh.VirtualAddress, size
+	}
+	offset = sh.PointerToRawData
+	// below that, the image is invalid anyway, so there's nothing better
+	// to do than to take the header's word for it
+	if f.OptionalHeaderFields().FileAlignment >= loaderFileAlignment {
+		offset &^= loaderFileAlignment - 1
+	}
+	return offset, sh.SizeOfRawData
+}
+
+// NewFileAt creates a new File for accessing a PE binary that starts at
+// offset in r, and spans at most size bytes, for example one embedded in
+// another file. Offsets in the returned File are relative to the start of
+// the binary.
+func NewFileAt(r io.ReaderAt, offset int64, size int64) (*File, error) {
+	return NewFile(io.NewSectionReader(r, offset, size), size)
+}
+
+// Size returns the size of the underlying file, as passed to NewFile
+func (f *File) Size() int64 {
+	return f.size
+}
+
+// ImageSize returns how many bytes of the underlying file the binary
+// spans: its headers, the raw data of its sections and its certificate
+// table, whichever ends last. It's less than Size when data was appended
+// (overlay), or when the binary is embedded in another file, and never
+// more than Size.
+func (f *File) ImageSize() int64 {
+	oh := f.OptionalHeaderFields()
+	end := int64(oh.SizeOfHeaders)
+	for _, s := range f.Sections {
+		if e := int64(s.Offset) + int64(s.Size); e > end {
+			end = e
+		}
+	}
+	// the only data directory that holds a file offset
+	if cert := oh.Directory(IMAGE_DIRECTORY_ENTRY_SECURITY); cert.Size > 0 {
+		if e := int64(cert.VirtualAddress) + int64(cert.Size); e > end {
+			end = e
+		}
+	}
+	if end > f.size {
+		end = f.size
+	}
+	return end
+}
+
+// Open returns a reader for the whole underlying file, for the parts of
+// it that aren't mapped to memory (overlay, certificate table, etc.)
+func (f *File) Open() *io.SectionReader {
+	return io.NewSectionReader(f.readerAt, 0, f.size)
+}
+
 // zeroReaderAt is ReaderAt that reads 0s.
 type zeroReaderAt struct{}
 
@@ -175,20 +280,6 @@ func (w zeroReaderAt) ReadAt(p []byte, off int64) (n int, err error) {
 	return len(p), nil
 }
 
-// getString extracts a string from symbol string table.
-func getString(section []byte, start int) (string, bool) {
-	if start < 0 || start >= len(section) {
-		return "", false
-	}
-
-	for end := start; end < len(section); end++ {
-		if section[end] == 0 {
-			return string(section[start:end]), true
-		}
-	}
-	return "", false
-}
-
 // Section returns the first section with the given name, or nil if no such
 // section exists.
 func (f *File) Section(name string) *Section {
@@ -200,30 +291,196 @@ func (f *File) Section(name string) *Section {
 	return nil
 }
 
+// dwarfSuffix returns "info" for .debug_info and .zdebug_info,
+// or "" if s is not a DWARF section.
+func dwarfSuffix(s *Section) string {
+	switch {
+	case strings.HasPrefix(s.Name, ".debug_"):
+		return s.Name[7:]
+	case strings.HasPrefix(s.Name, ".zdebug_"):
+		return s.Name[8:]
+	default:
+		return ""
+	}
+}
+
+// dwarfSectionData returns the contents of DWARF section s,
+// decompressed if it's a .zdebug_* section, and relocated
+// if f is an object file.
+func (f *File) dwarfSectionData(s *Section) ([]byte, error) {
+	if int64(s.Size) > MaxSectionDataSize {
+		return nil, errors.Errorf("section %s is too large to read into memory (%d bytes)", s.Name, s.Size)
+	}
+	b, err := s.DataRange(0, int64(s.Size))
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if 0 < s.VirtualSize && s.VirtualSize < s.Size {
+		b = b[:s.VirtualSize]
+	}
+
+	// "ZLIB" followed by the big-endian uncompressed size
+	if len(b) >= 12 && string(b[:4]) == "ZLIB" {
+		dlen := binary.BigEndian.Uint64(b[4:12])
+		if dlen > uint64(MaxSectionDataSize) {
+			return nil, errors.Errorf("section %s is too large to decompress (%d bytes)", s.Name, dlen)
+		}
+
+		r, err := zlib.NewReader(bytes.NewReader(b[12:]))
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		dbuf := make([]byte, dlen)
+		_, err = io.ReadFull(r, dbuf)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "while decompressing section %s", s.Name)
+		}
+		err = r.Close()
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		b = dbuf
+	}
+
+	// in images, debug sections are already relocated by the linker
+	if f.OptionalHeader == nil {
+		err := f.applyRelocations(s, b)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "while relocating section %s", s.Name)
+		}
+	}
+	return b, nil
+}
+
+// applyRelocations applies the relocations of section s to b, its
+// contents. Only the relocation types found in debug sections are
+// supported, others are ignored.
+func (f *File) applyRelocations(s *Section, b []byte) error {
+	for _, r := range s.Relocs {
+		if int(r.SymbolTableIndex) >= len(f.COFFSymbols) {
+			return errors.Errorf("relocation refers to invalid symbol %d", r.SymbolTableIndex)
+		}
+		sym := &f.COFFSymbols[r.SymbolTableIndex]
+
+		var size int
+		switch f.Machine {
+		case IMAGE_FILE_MACHINE_AMD64:
+			switch r.Type {
+			case IMAGE_REL_AMD64_ADDR64:
+				size = 8
+			case IMAGE_REL_AMD64_ADDR32, IMAGE_REL_AMD64_ADDR32NB, IMAGE_REL_AMD64_SECREL:
+				size = 4
+			}
+		case IMAGE_FILE_MACHINE_I386:
+			switch r.Type {
+			case IMAGE_REL_I386_DIR32, IMAGE_REL_I386_DIR32NB, IMAGE_REL_I386_SECREL:
+				size = 4
+			}
+		case IMAGE_FILE_MACHINE_ARM64:
+			switch r.Type {
+			case IMAGE_REL_ARM64_ADDR64:
+				size = 8
+			case IMAGE_REL_ARM64_ADDR32, IMAGE_REL_ARM64_ADDR32NB, IMAGE_REL_ARM64_SECREL:
+				size = 4
+			}
+		case IMAGE_FILE_MACHINE_ARMNT:
+			switch r.Type {
+			case IMAGE_REL_ARM_ADDR32, IMAGE_REL_ARM_ADDR32NB, IMAGE_REL_ARM_SECREL:
+				size = 4
+			}
+		}
+		if size == 0 {
+			continue
+		}
+
+		// the addend is stored in place. In object files, sections start at 0,
+		// so addresses and section-relative offsets are both symbol values.
+		off := uint64(r.VirtualAddress) - uint64(s.VirtualAddress)
+		if off+uint64(size) > uint64(len(b)) {
+			return errors.Errorf("relocation at %x out of bounds", r.VirtualAddress)
+		}
+		switch size {
+		case 8:
+			v := binary.LittleEndian.Uint64(b[off:])
+			binary.LittleEndian.PutUint64(b[off:], v+uint64(sym.Value))
+		case 4:
+			v := binary.LittleEndian.Uint32(b[off:])
+			binary.LittleEndian.PutUint32(b[off:], v+sym.Value)
+		}
+	}
+	return nil
+}
+
+// DWARFSection returns the contents of the DWARF section with the given
+// suffix, for example "frame" for .debug_frame (or .zdebug_frame),
+// decompressed if needed. It returns nil if there is no such section.
+//
+// This gives access to sections debug/dwarf doesn't parse,
+// like .debug_frame and .debug_loc.
+func (f *File) DWARFSection(suffix string) ([]byte, error) {
+	for _, s := range f.Sections {
+		if dwarfSuffix(s) == suffix {
+			return f.dwarfSectionData(s)
+		}
+	}
+	return nil, nil
+}
+
+// DWARF returns the DWARF debug information of f, including DWARF 4
+// type units and DWARF 5 sections (.debug_addr, .debug_line_str,
+// .debug_rnglists, .debug_str_offsets). Compressed .zdebug_* sections,
+// as emitted by the Go linker and some MinGW toolchains, are supported.
 func (f *File) DWARF() (*dwarf.Data, error) {
 	// There are many other DWARF sections, but these
-	// are the ones the debug/dwarf package uses.
-	// Don't bother loading others.
-	var names = [...]string{"abbrev", "info", "line", "ranges", "str"}
-	var dat [len(names)][]byte
-	for i, name := range names {
-		name = ".debug_" + name
-		s := f.Section(name)
-		if s == nil {
+	// are the ones dwarf.New takes. Others are added below.
+	var dat = map[string][]byte{"abbrev": nil, "info": nil, "str": nil, "line": nil, "ranges": nil}
+	for _, s := range f.Sections {
+		suffix := dwarfSuffix(s)
+		if _, ok := dat[suffix]; !ok {
+			continue
+		}
+
+		b, err := f.dwarfSectionData(s)
+		if err != nil {
+			return nil, err
+		}
+		dat[suffix] = b
+	}
+
+	d, err := dwarf.New(dat["abbrev"], nil, nil, dat["info"], dat["line"], nil, dat["ranges"], dat["str"])
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	// Look for DWARF 4 .debug_types sections and DWARF 5 sections.
+	// AddSection ignores the ones debug/dwarf doesn't support yet.
+	for i, s := range f.Sections {
+		suffix := dwarfSuffix(s)
+		if suffix == "" {
+			continue
+		}
+		if _, ok := dat[suffix]; ok {
+			// Already handled.
 			continue
 		}
-		b, err := s.Data()
-		if err != nil && uint32(len(b)) < s.Size {
+
+		b, err := f.dwarfSectionData(s)
+		if err != nil {
 			return nil, err
 		}
-		if 0 < s.VirtualSize && s.VirtualSize < s.Size {
-			b = b[:s.VirtualSize]
+
+		if suffix == "types" {
+			err = d.AddTypes(fmt.Sprintf("types-%d", i), b)
+		} else {
+			err = d.AddSection(".debug_"+suffix, b)
+		}
+		if err != nil {
+			return nil, errors.WithStack(err)
 		}
-		dat[i] = b
 	}
 
-	abbrev, info, line, ranges, str := dat[0], dat[1], dat[2], dat[3], dat[4]
-	return dwarf.New(abbrev, nil, nil, info, line, nil, ranges, str)
+	return d, nil
 }
 
 type ImageImportDescriptor struct {
@@ -234,160 +491,447 @@ type ImageImportDescriptor struct {
 	FirstThunk         uint32
 }
 
-// ImportedSymbols returns the names of all symbols
-// referred to by the binary f that are expected to be
-// satisfied by other libraries at dynamic load time.
-// It does not return weak symbols.
-func (f *File) ImportedSymbols() ([]string, error) {
-	var dd [16]DataDirectory
-	switch oh := f.OptionalHeader.(type) {
-	case *OptionalHeader32:
-		dd = oh.DataDirectory
-	case *OptionalHeader64:
-		dd = oh.DataDirectory
-	}
+// rvaReader reads data by RVA. Sections are read in pages, each at
+// most once, so that large sections (executables over 2 GiB usually
+// embed their assets in one) are never read into memory all at once.
+type rvaReader struct {
+	f     *File
+	pages map[rvaPage][]byte
+}
 
-	importTableAddress := dd[1]
+// rvaPageSize is the granularity at which rvaReader reads sections
+const rvaPageSize = 64 * 1024
 
-	pe64 := f.Machine == IMAGE_FILE_MACHINE_AMD64
+// maxStringLength bounds the search for the terminator of a string
+const maxStringLength = 64 * 1024
 
-	iStart := int64(importTableAddress.VirtualAddress)
-	iEnd := int64(importTableAddress.VirtualAddress) + int64(importTableAddress.Size)
-	var ds *Section
-	for _, s := range f.Sections {
-		sStart := int64(s.VirtualAddress)
-		sEnd := int64(s.VirtualAddress) + int64(s.VirtualSize)
+type rvaPage struct {
+	s     *Section
+	index int64
+}
 
-		if sStart <= iStart && iEnd <= sEnd {
-			ds = s
-			break
+func newRVAReader(f *File) *rvaReader {
+	return &rvaReader{
+		f:     f,
+		pages: make(map[rvaPage][]byte),
+	}
+}
+
+// section returns the section containing rva, or nil
+func (rr *rvaReader) section(rva uint32) *Section {
+	for _, s := range rr.f.Sections {
+		size := s.VirtualSize
+		if size < s.Size {
+			size = s.Size
+		}
+		if s.VirtualAddress <= rva && uint64(rva) < uint64(s.VirtualAddress)+uint64(size) {
+			return s
 		}
 	}
-	if ds == nil {
-		// could not find matching section :(
+	return nil
+}
+
+// locate returns the section containing rva, and the offset
+// of rva in the section's raw data
+func (rr *rvaReader) locate(rva uint32) (*Section, int64, error) {
+	s := rr.section(rva)
+	if s == nil {
+		return nil, 0, errors.Errorf("RVA %x is not in any section", rva)
+	}
+
+	offset := int64(rva - s.VirtualAddress)
+	if offset >= int64(s.Size) {
+		return nil, 0, errors.Errorf("RVA %x is past the raw data of section %s", rva, s.Name)
+	}
+	return s, offset, nil
+}
+
+// available returns the size of the raw data from rva to the end of
+// its section, or 0 if rva isn't backed by raw data
+func (rr *rvaReader) available(rva uint32) int64 {
+	s, offset, err := rr.locate(rva)
+	if err != nil {
+		return 0
+	}
+	return int64(s.Size) - offset
+}
+
+func (rr *rvaReader) page(s *Section, index int64) ([]byte, error) {
+	key := rvaPage{s: s, index: index}
+	if data, ok := rr.pages[key]; ok {
+		return data, nil
+	}
+
+	start := index * rvaPageSize
+	length := int64(s.Size) - start
+	if length > rvaPageSize {
+		length = rvaPageSize
+	}
+	data, err := s.DataRange(start, length)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	rr.pages[key] = data
+	return data, nil
+}
+
+// slice returns length bytes of raw data, starting at rva
+func (rr *rvaReader) slice(rva uint32, length int64) ([]byte, error) {
+	s, offset, err := rr.locate(rva)
+	if err != nil {
+		return nil, err
+	}
+	if length > int64(s.Size)-offset {
+		return nil, errors.Errorf("%d bytes at RVA %x extend past the raw data of section %s", length, rva, s.Name)
+	}
+	if length <= 0 {
 		return nil, nil
 	}
 
-	sectionData, err := ds.Data()
+	index := offset / rvaPageSize
+	if (offset+length-1)/rvaPageSize == index {
+		page, err := rr.page(s, index)
+		if err != nil {
+			return nil, err
+		}
+		start := offset - index*rvaPageSize
+		return page[start : start+length], nil
+	}
+
+	// tables spanning several pages are rare, and read only once
+	data, err := s.DataRange(offset, length)
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
+	return data, nil
+}
+
+// stringAt returns the null-terminated string at rva
+func (rr *rvaReader) stringAt(rva uint32) (string, error) {
+	s, offset, err := rr.locate(rva)
+	if err != nil {
+		return "", err
+	}
+
+	var res []byte
+	for offset < int64(s.Size) && len(res) < maxStringLength {
+		index := offset / rvaPageSize
+		page, err := rr.page(s, index)
+		if err != nil {
+			return "", err
+		}
+		chunk := page[offset-index*rvaPageSize:]
+		if i := bytes.IndexByte(chunk, 0); i >= 0 {
+			return string(append(res, chunk[:i]...)), nil
+		}
+		res = append(res, chunk...)
+		offset += int64(len(chunk))
+	}
+	return "", errors.Errorf("unterminated string at RVA %x", rva)
+}
+
+// OptionalHeaderFields returns the fields of the optional header of f,
+// whether it's an OptionalHeader32 or an OptionalHeader64. They're all
+// zero if f has none (object files).
+func (f *File) OptionalHeaderFields() OptionalHeaderFields {
+	switch oh := f.OptionalHeader.(type) {
+	case *OptionalHeader32:
+		return OptionalHeaderFields{
+			Magic:                       oh.Magic,
+			MajorLinkerVersion:          oh.MajorLinkerVersion,
+			MinorLinkerVersion:          oh.MinorLinkerVersion,
+			SizeOfCode:                  oh.SizeOfCode,
+			SizeOfInitializedData:       oh.SizeOfInitializedData,
+			SizeOfUninitializedData:     oh.SizeOfUninitializedData,
+			AddressOfEntryPoint:         oh.AddressOfEntryPoint,
+			BaseOfCode:                  oh.BaseOfCode,
+			BaseOfData:                  oh.BaseOfData,
+			ImageBase:                   uint64(oh.ImageBase),
+			SectionAlignment:            oh.SectionAlignment,
+			FileAlignment:               oh.FileAlignment,
+			MajorOperatingSystemVersion: oh.MajorOperatingSystemVersion,
+			MinorOperatingSystemVersion: oh.MinorOperatingSystemVersion,
+			MajorImageVersion:           oh.MajorImageVersion,
+			MinorImageVersion:           oh.MinorImageVersion,
+			MajorSubsystemVersion:       oh.MajorSubsystemVersion,
+			MinorSubsystemVersion:       oh.MinorSubsystemVersion,
+			Win32VersionValue:           oh.Win32VersionValue,
+			SizeOfImage:                 oh.SizeOfImage,
+			SizeOfHeaders:               oh.SizeOfHeaders,
+			CheckSum:                    oh.CheckSum,
+			Subsystem:                   oh.Subsystem,
+			DllCharacteristics:          oh.DllCharacteristics,
+			SizeOfStackReserve:          uint64(oh.SizeOfStackReserve),
+			SizeOfStackCommit:           uint64(oh.SizeOfStackCommit),
+			SizeOfHeapReserve:           uint64(oh.SizeOfHeapReserve),
+			SizeOfHeapCommit:            uint64(oh.SizeOfHeapCommit),
+			LoaderFlags:                 oh.LoaderFlags,
+			NumberOfRvaAndSizes:         oh.NumberOfRvaAndSizes,
+			DataDirectory:               oh.DataDirectory,
+		}
+	case *OptionalHeader64:
+		return OptionalHeaderFields{
+			PE64:                        true,
+			Magic:                       oh.Magic,
+			MajorLinkerVersion:          oh.MajorLinkerVersion,
+			MinorLinkerVersion:          oh.MinorLinkerVersion,
+			SizeOfCode:                  oh.SizeOfCode,
+			SizeOfInitializedData:       oh.SizeOfInitializedData,
+			SizeOfUninitializedData:     oh.SizeOfUninitializedData,
+			AddressOfEntryPoint:         oh.AddressOfEntryPoint,
+			BaseOfCode:                  oh.BaseOfCode,
+			ImageBase:                   oh.ImageBase,
+			SectionAlignment:            oh.SectionAlignment,
+			FileAlignment:               oh.FileAlignment,
+			MajorOperatingSystemVersion: oh.MajorOperatingSystemVersion,
+			MinorOperatingSystemVersion: oh.MinorOperatingSystemVersion,
+			MajorImageVersion:           oh.MajorImageVersion,
+			MinorImageVersion:           oh.MinorImageVersion,
+			MajorSubsystemVersion:       oh.MajorSubsystemVersion,
+			MinorSubsystemVersion:       oh.MinorSubsystemVersion,
+			Win32VersionValue:           oh.Win32VersionValue,
+			SizeOfImage:                 oh.SizeOfImage,
+			SizeOfHeaders:               oh.SizeOfHeaders,
+			CheckSum:                    oh.CheckSum,
+			Subsystem:                   oh.Subsystem,
+			DllCharacteristics:          oh.DllCharacteristics,
+			SizeOfStackReserve:          oh.SizeOfStackReserve,
+			SizeOfStackCommit:           oh.SizeOfStackCommit,
+			SizeOfHeapReserve:           oh.SizeOfHeapReserve,
+			SizeOfHeapCommit:            oh.SizeOfHeapCommit,
+			LoaderFlags:                 oh.LoaderFlags,
+			NumberOfRvaAndSizes:         oh.NumberOfRvaAndSizes,
+			DataDirectory:               oh.DataDirectory,
+		}
+	}
+	return OptionalHeaderFields{}
+}
+
+// importDescriptors reads import descriptors until the
+// null descriptor that terminates the import directory table.
+// It returns nil if the file has no import directory.
+func (f *File) importDescriptors() (*rvaReader, []ImageImportDescriptor, error) {
+	importTableAddress := f.dataDirectory(IMAGE_DIRECTORY_ENTRY_IMPORT)
+	if importTableAddress.VirtualAddress == 0 {
+		return nil, nil, nil
+	}
+
+	rr := newRVAReader(f)
+	if rr.section(importTableAddress.VirtualAddress) == nil {
+		// could not find matching section :(
+		return nil, nil, nil
+	}
 
-	sectionData = sectionData[importTableAddress.VirtualAddress-ds.VirtualAddress:]
+	// names and thunks can be anywhere in the image (before the
+	// import table, or in other sections), so they're read by RVA
+	rva := importTableAddress.VirtualAddress
+	_, _, err := rr.locate(rva)
+	if err != nil {
+		return nil, nil, err
+	}
 
 	var importDirectories []ImageImportDescriptor
-	idBlock := sectionData
-	for len(idBlock) > 0 {
+	for ; rr.available(rva) >= 20; rva += 20 {
+		idBlock, err := rr.slice(rva, 20)
+		if err != nil {
+			return nil, nil, err
+		}
+
 		var dt ImageImportDescriptor
 		dt.OriginalFirstThunk = binary.LittleEndian.Uint32(idBlock[0:4])
+		dt.TimeDateStamp = binary.LittleEndian.Uint32(idBlock[4:8])
+		dt.ForwarderChain = binary.LittleEndian.Uint32(idBlock[8:12])
 		dt.Name = binary.LittleEndian.Uint32(idBlock[12:16])
 		dt.FirstThunk = binary.LittleEndian.Uint32(idBlock[16:20])
-		idBlock = idBlock[20:]
-		if dt.OriginalFirstThunk == 0 {
+		// OriginalFirstThunk may legitimately be zero, see ImportedSymbols
+		if dt.OriginalFirstThunk == 0 && dt.Name == 0 && dt.FirstThunk == 0 {
 			break
 		}
 		importDirectories = append(importDirectories, dt)
 	}
+	return rr, importDirectories, nil
+}
 
-	var allSymbols []string
-	for _, dt := range importDirectories {
-		dll, _ := getString(sectionData, int(dt.Name-importTableAddress.VirtualAddress))
-
-		// seek to OriginalFirstThunk
-		thunkDataBlock := sectionData[dt.OriginalFirstThunk-importTableAddress.VirtualAddress:]
-
-		for len(thunkDataBlock) > 0 {
-			if pe64 { // 64bit
-				va := binary.LittleEndian.Uint64(thunkDataBlock[0:8])
-				thunkDataBlock = thunkDataBlock[8:]
-				if va == 0 {
-					break
-				}
-				if va&0x8000000000000000 > 0 { // is Ordinal
-					// TODO add dynimport ordinal support.
-				} else {
-					fn, _ := getString(sectionData, int(uint32(va)-importTableAddress.VirtualAddress+2))
-					allSymbols = append(allSymbols, fn+":"+dll)
-				}
-			} else { // 32bit
-				va := binary.LittleEndian.Uint32(thunkDataBlock[0:4])
-				thunkDataBlock = thunkDataBlock[4:]
-				if va == 0 {
-					break
-				}
-				if va&0x80000000 > 0 { // is Ordinal
-					// TODO add dynimport ordinal support.
-					//ord := va&0x0000FFFF
-				} else {
-					fn, _ := getString(sectionData, int(va-importTableAddress.VirtualAddress+2))
-					allSymbols = append(allSymbols, fn+":"+dll)
-				}
-			}
-		}
+// ImportDescriptors returns the entries of the import directory table,
+// or nil if the file has no import directory.
+func (f *File) ImportDescriptors() ([]ImageImportDescriptor, error) {
+	_, ids, err := f.importDescriptors()
+	return ids, err
+}
+
+// ImportedSymbol is a symbol a binary expects another
+// library to provide at dynamic load time
+type ImportedSymbol struct {
+	// Library it's imported from, as written in the binary,
+	// for example "KERNEL32.dll"
+	DLL string
+	// Empty for symbols imported by ordinal
+	Name string
+	// For symbols imported by ordinal
+	Ordinal uint32
+	// For symbols imported by name, where the linker expected to find
+	// the name in the export name table of DLL
+	Hint uint16
+}
+
+// ByOrdinal returns true if s is imported by ordinal rather than by name
+func (s ImportedSymbol) ByOrdinal() bool {
+	return s.Name == ""
+}
+
+// String returns "name:dll", or "#ordinal:dll" for symbols
+// imported by ordinal, for example "#23:WS2_32.dll"
+func (s ImportedSymbol) String() string {
+	if s.ByOrdinal() {
+		return fmt.Sprintf("#%d:%s", s.Ordinal, s.DLL)
 	}
+	return s.Name + ":" + s.DLL
+}
 
-	return allSymbols, nil
+// importErrors collects the problems found with individual imports,
+// which are skipped, so that one bad entry doesn't hide all the others
+type importErrors struct {
+	first error
+	count int
 }
 
-// ImportedLibraries returns the names of all libraries
-// referred to by the binary f that are expected to be
-// linked with the binary at dynamic link time.
-func (f *File) ImportedLibraries() ([]string, error) {
-	var dd [16]DataDirectory
-	switch oh := f.OptionalHeader.(type) {
-	case *OptionalHeader32:
-		dd = oh.DataDirectory
-	case *OptionalHeader64:
-		dd = oh.DataDirectory
+func (ie *importErrors) add(err error) {
+	if ie.first == nil {
+		ie.first = err
 	}
+	ie.count++
+}
 
-	importTableAddress := dd[1]
+func (ie *importErrors) err() error {
+	if ie.count > 1 {
+		return errors.WithMessagef(ie.first, "%d invalid imports, including", ie.count)
+	}
+	return ie.first
+}
 
-	iStart := int64(importTableAddress.VirtualAddress)
-	iEnd := int64(importTableAddress.VirtualAddress) + int64(importTableAddress.Size)
-	var ds *Section
-	for _, s := range f.Sections {
-		sStart := int64(s.VirtualAddress)
-		sEnd := int64(s.VirtualAddress) + int64(s.VirtualSize)
+// ImportedSymbols returns all symbols referred to by the binary f
+// that are expected to be satisfied by other libraries at dynamic
+// load time, in import table order. It does not return weak symbols.
+//
+// Entries that can't be read are skipped: the symbols that could be
+// read are returned along with an error describing them.
+func (f *File) ImportedSymbols() ([]ImportedSymbol, error) {
+	rr, importDirectories, err := f.importDescriptors()
+	if err != nil {
+		return nil, err
+	}
 
-		if sStart <= iStart && iEnd <= sEnd {
-			ds = s
-			break
+	var ie importErrors
+	var allSymbols []ImportedSymbol
+	for _, dt := range importDirectories {
+		dll, err := rr.stringAt(dt.Name)
+		if err != nil {
+			ie.add(errors.WithMessage(err, "while reading imported library name"))
+			continue
 		}
+
+		// seek to OriginalFirstThunk (the import lookup table), or to
+		// FirstThunk (the import address table) if there's none: some
+		// linkers (Borland) and packers only fill the latter.
+		thunk := dt.OriginalFirstThunk
+		if thunk == 0 {
+			thunk = dt.FirstThunk
+		}
+		allSymbols = append(allSymbols, f.thunkSymbols(rr, thunk, 0, dll, &ie)...)
 	}
-	if ds == nil {
-		// could not find matching section :(
-		return nil, nil
-	}
 
-	sectionData, err := ds.Data()
+	return allSymbols, ie.err()
+}
+
+// thunkSymbols reads the thunks (import lookup table, or delay-load
+// import name table) at the RVA thunk, and returns the symbols they
+// import from dll. base is subtracted from the addresses they hold,
+// for tables of virtual addresses. Problems are added to ie.
+func (f *File) thunkSymbols(rr *rvaReader, thunk uint32, base uint64, dll string, ie *importErrors) []ImportedSymbol {
+	_, _, err := rr.locate(thunk)
 	if err != nil {
-		return nil, errors.WithStack(err)
+		ie.add(errors.WithMessagef(err, "while reading thunks of %s", dll))
+		return nil
 	}
 
-	sectionData = sectionData[importTableAddress.VirtualAddress-ds.VirtualAddress:]
+	// PE32+ files (x64, ARM64) have 64-bit thunks
+	pe64 := f.OptionalHeaderFields().PE64
+	thunkSize := int64(4)
+	if pe64 {
+		thunkSize = 8
+	}
 
-	var importDirectories []ImageImportDescriptor
-	idBlock := sectionData
-	for len(idBlock) > 0 {
-		var dt ImageImportDescriptor
-		dt.OriginalFirstThunk = binary.LittleEndian.Uint32(idBlock[0:4])
-		dt.Name = binary.LittleEndian.Uint32(idBlock[12:16])
-		dt.FirstThunk = binary.LittleEndian.Uint32(idBlock[16:20])
-		idBlock = idBlock[20:]
-		if dt.OriginalFirstThunk == 0 {
+	var symbols []ImportedSymbol
+	for ; rr.available(thunk) >= thunkSize; thunk += uint32(thunkSize) {
+		thunkData, err := rr.slice(thunk, thunkSize)
+		if err != nil {
+			ie.add(errors.WithMessagef(err, "while reading thunks of %s", dll))
 			break
 		}
-		importDirectories = append(importDirectories, dt)
+
+		var va uint64
+		var isOrdinal bool
+		if pe64 { // 64bit
+			va = binary.LittleEndian.Uint64(thunkData)
+			isOrdinal = va&0x8000000000000000 > 0
+		} else { // 32bit
+			va = uint64(binary.LittleEndian.Uint32(thunkData))
+			isOrdinal = va&0x80000000 > 0
+		}
+		if va == 0 {
+			break
+		}
+
+		if isOrdinal {
+			symbols = append(symbols, ImportedSymbol{
+				DLL:     dll,
+				Ordinal: uint32(va & 0xffff),
+			})
+			continue
+		}
+
+		// IMAGE_IMPORT_BY_NAME: a 16-bit hint, then the name
+		hint, err := rr.slice(uint32(va-base), 2)
+		if err != nil {
+			ie.add(errors.WithMessagef(err, "while reading symbol imported from %s", dll))
+			continue
+		}
+		fn, err := rr.stringAt(uint32(va-base) + 2)
+		if err != nil {
+			ie.add(errors.WithMessagef(err, "while reading symbol name imported from %s", dll))
+			continue
+		}
+		symbols = append(symbols, ImportedSymbol{
+			DLL:  dll,
+			Name: fn,
+			Hint: binary.LittleEndian.Uint16(hint),
+		})
 	}
+	return symbols
+}
 
+// ImportedLibraries returns the names of all libraries
+// referred to by the binary f that are expected to be
+// linked with the binary at dynamic link time. Like
+// ImportedSymbols, it skips names that can't be read.
+func (f *File) ImportedLibraries() ([]string, error) {
+	rr, importDirectories, err := f.importDescriptors()
+	if err != nil {
+		return nil, err
+	}
+
+	var ie importErrors
 	var dlls []string
 	for _, dt := range importDirectories {
-		dll, _ := getString(sectionData, int(dt.Name-importTableAddress.VirtualAddress))
+		dll, err := rr.stringAt(dt.Name)
+		if err != nil {
+			ie.add(errors.WithMessage(err, "while reading imported library name"))
+			continue
+		}
 		dlls = append(dlls, dll)
 	}
 
-	return dlls, nil
+	return dlls, ie.err()
 }
 
 // FormatError is unused.
@@ -0,0 +1,755 @@
+package pe_test
+
+import (
+	"bytes"
+	"debug/dwarf"
+	"encoding/binary"
+	"encoding/hex"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/itchio/pelican/pe"
+	"github.com/stretchr/testify/assert"
+)
+
+func openFixture(t *testing.T, path string) *pe.File {
+	f, err := os.Open(path)
+	assert.NoError(t, err)
+	t.Cleanup(func() { f.Close() })
+
+	stats, err := f.Stat()
+	assert.NoError(t, err)
+
+	pf, err := pe.NewFile(f, stats.Size())
+	assert.NoError(t, err)
+	return pf
+}
+
+func Test_DWARF5(t *testing.T) {
+	// see testdata/dwarf/hello.ll and make-zdebug.py
+	for _, path := range []string{
+		"../testdata/dwarf/hello-dwarf5.obj",
+		"../testdata/dwarf/hello-dwarf5-zdebug.obj",
+	} {
+		pf := openFixture(t, path)
+
+		d, err := pf.DWARF()
+		assert.NoError(t, err)
+
+		var names []string
+		r := d.Reader()
+		for {
+			e, err := r.Next()
+			assert.NoError(t, err)
+			if e == nil {
+				break
+			}
+
+			switch e.Tag {
+			case dwarf.TagCompileUnit:
+				// needs .debug_str_offsets
+				assert.EqualValues(t, "hello.c", e.Val(dwarf.AttrName))
+
+				// needs .debug_rnglists
+				ranges, err := d.Ranges(e)
+				assert.NoError(t, err)
+				assert.Len(t, ranges, 2)
+
+				// needs .debug_line_str
+				lr, err := d.LineReader(e)
+				assert.NoError(t, err)
+				var le dwarf.LineEntry
+				assert.NoError(t, lr.Next(&le))
+				assert.EqualValues(t, "/tmp/hello.c", le.File.Name)
+			case dwarf.TagSubprogram:
+				names = append(names, e.Val(dwarf.AttrName).(string))
+			}
+		}
+		assert.EqualValues(t, []string{"hello", "world"}, names)
+
+		names5, err := pf.DWARFSection("names")
+		assert.NoError(t, err)
+		assert.NotEmpty(t, names5)
+
+		frame, err := pf.DWARFSection("frame")
+		assert.NoError(t, err)
+		assert.Nil(t, frame)
+	}
+}
+
+func Test_MaxSectionDataSize(t *testing.T) {
+	defer func(max int64) { pe.MaxSectionDataSize = max }(pe.MaxSectionDataSize)
+	pe.MaxSectionDataSize = 16
+
+	_, err := openFixture(t, "../testdata/dwarf/hello-dwarf5.obj").DWARF()
+	assert.Error(t, err)
+}
+
+func Test_DWARFRelocations(t *testing.T) {
+	// see testdata/dwarf/reloc.s
+	pf := openFixture(t, "../testdata/dwarf/reloc.obj")
+
+	d, err := pf.DWARF()
+	assert.NoError(t, err)
+
+	lowPCs := make(map[string]uint64)
+	r := d.Reader()
+	for {
+		e, err := r.Next()
+		assert.NoError(t, err)
+		if e == nil {
+			break
+		}
+
+		switch e.Tag {
+		case dwarf.TagCompileUnit:
+			assert.EqualValues(t, "reloc.s", e.Val(dwarf.AttrName))
+		case dwarf.TagSubprogram:
+			lowPCs[e.Val(dwarf.AttrName).(string)] = e.Val(dwarf.AttrLowpc).(uint64)
+		}
+	}
+	assert.EqualValues(t, map[string]uint64{"hello": 0, "world": 3}, lowPCs)
+}
+
+func Test_SymbolSection(t *testing.T) {
+	pf := openFixture(t, "../testdata/hello/hello.obj")
+
+	syms := make(map[string]*pe.Symbol)
+	for _, sym := range pf.Symbols {
+		syms[sym.Name] = sym
+	}
+
+	main := syms["_main"]
+	assert.NotNil(t, main)
+	assert.EqualValues(t, ".text$mn", pf.SymbolSection(main).Name)
+	rva, ok := pf.SymbolRVA(main)
+	assert.True(t, ok)
+	assert.EqualValues(t, 0, rva)
+
+	// imported from the CRT, so undefined here
+	_, ok = pf.SymbolRVA(syms["___acrt_iob_func"])
+	assert.False(t, ok)
+	assert.Nil(t, pf.SymbolSection(syms["@comp.id"]))
+}
+
+func Test_NearestSymbol(t *testing.T) {
+	// sections of object files all start at 0, so this looks
+	// across all of them
+	pf := openFixture(t, "../testdata/dwarf/reloc.obj")
+
+	sym, offset := pf.NearestSymbol(3)
+	assert.EqualValues(t, "world", sym.Name)
+	assert.EqualValues(t, 0, offset)
+
+	sym, offset = pf.NearestSymbol(0x10)
+	assert.EqualValues(t, "str_world", sym.Name)
+	assert.EqualValues(t, 2, offset)
+
+	// past the end of .debug_str
+	sym, _ = pf.NearestSymbol(0x100)
+	assert.Nil(t, sym)
+}
+
+func Test_LineNumbers(t *testing.T) {
+	pf := openFixture(t, "../testdata/dwarf/reloc-lines.obj")
+
+	lns, err := pf.LineNumbers(pf.Section(".text"))
+	assert.NoError(t, err)
+	assert.Len(t, lns, 6)
+
+	assert.True(t, lns[3].IsFunction())
+	assert.EqualValues(t, "world", string(pf.COFFSymbols[lns[3].Type].Name[:5]))
+	assert.EqualValues(t, pe.LineNumber{Type: 5, Linenumber: 2}, lns[5])
+
+	lns, err = pf.LineNumbers(pf.Section(".data"))
+	assert.NoError(t, err)
+	assert.Nil(t, lns)
+}
+
+func Test_FunctionStarts(t *testing.T) {
+	pf := openFixture(t, "../testdata/hello/hello64-msvc.exe")
+
+	starts, err := pf.FunctionStarts()
+	assert.NoError(t, err)
+	// the entry point also has unwind info
+	assert.Len(t, starts, 338)
+	assert.EqualValues(t, 0x1000, starts[0])
+	assert.Contains(t, starts, uint32(0x1538))
+
+	// no exception directory, no symbols: only the entry point
+	pf = openFixture(t, "../testdata/hello/hello32-msvc.exe")
+	starts, err = pf.FunctionStarts()
+	assert.NoError(t, err)
+	assert.Len(t, starts, 1)
+}
+
+func Test_Forwarders(t *testing.T) {
+	// see testdata/forwarders/make-dlls.py
+	pf := openFixture(t, "../testdata/forwarders/engine.dll")
+
+	names, err := pf.ExportedNames()
+	assert.NoError(t, err)
+	assert.EqualValues(t, []string{"Alloc", "Free", "Heap", "Init", "Loop", "Render"}, names)
+
+	forwarders, err := pf.Forwarders()
+	assert.NoError(t, err)
+	assert.EqualValues(t, []pe.Forwarder{
+		{Name: "Alloc", Target: "core.Alloc"},
+		{Name: "Free", Target: "core.Free"},
+		{Name: "Heap", Target: "NTDLL.RtlAllocateHeap"},
+		{Name: "Init", Target: "physx.Init"},
+		{Name: "Loop", Target: "core.Loop"},
+	}, forwarders)
+
+	pf = openFixture(t, "../testdata/hello/hello64-msvc.exe")
+	names, err = pf.ExportedNames()
+	assert.NoError(t, err)
+	assert.Empty(t, names)
+}
+
+func Test_Arm64MachineImports(t *testing.T) {
+	// an x64 binary with only the machine patched, see
+	// testdata/resourceful/make-arm64.py: not real ARM64 coverage
+	pf := openFixture(t, "../testdata/resourceful/resourceful-arm64.exe")
+	assert.EqualValues(t, pe.IMAGE_FILE_MACHINE_ARM64, pf.Machine)
+	assert.EqualValues(t, "ARM64", pe.MachineNames[pe.Machine(pf.Machine)])
+
+	syms, err := pf.ImportedSymbols()
+	assert.NoError(t, err)
+	// same binary as resourceful64-mingw, so the same thunks
+	x64Syms, err := openFixture(t, "../testdata/resourceful/resourceful64-mingw.exe").ImportedSymbols()
+	assert.NoError(t, err)
+	assert.NotEmpty(t, syms)
+	assert.EqualValues(t, x64Syms, syms)
+}
+
+func Test_ArmMachineImports(t *testing.T) {
+	// an x86 binary with only the machine patched, see
+	// testdata/resourceful/make-arm.py: not real ARM coverage
+	pf := openFixture(t, "../testdata/resourceful/resourceful-arm.exe")
+	assert.EqualValues(t, "ARMNT", pe.MachineNames[pe.Machine(pf.Machine)])
+
+	syms, err := pf.ImportedSymbols()
+	assert.NoError(t, err)
+	// same binary as resourceful32-mingw, so the same thunks
+	x86Syms, err := openFixture(t, "../testdata/resourceful/resourceful32-mingw.exe").ImportedSymbols()
+	assert.NoError(t, err)
+	assert.NotEmpty(t, syms)
+	assert.EqualValues(t, x86Syms, syms)
+}
+
+func Test_ExportedSymbols(t *testing.T) {
+	// see testdata/forwarders/make-dlls.py
+	pf := openFixture(t, "../testdata/forwarders/core.dll")
+	exports, err := pf.ExportedSymbols()
+	assert.NoError(t, err)
+	assert.EqualValues(t, "core.dll", exports.DLLName)
+	assert.EqualValues(t, 2, len(exports.Symbols))
+	assert.EqualValues(t, pe.ExportedSymbol{Name: "Alloc", Ordinal: 1, RVA: 0x1000}, exports.Symbols[0])
+	loop := exports.Symbols[1]
+	assert.EqualValues(t, "Loop", loop.Name)
+	assert.EqualValues(t, 2, loop.Ordinal)
+	assert.EqualValues(t, "engine.Loop", loop.Forwarder)
+
+	// executables usually export nothing
+	exports, err = openFixture(t, "../testdata/hello/hello32-mingw.exe").ExportedSymbols()
+	assert.NoError(t, err)
+	assert.Nil(t, exports)
+}
+
+func Test_DelayImports(t *testing.T) {
+	// see testdata/delayload/make-delayload.py: the fmodex.dll
+	// descriptor uses virtual addresses in the 32-bit build
+	for _, name := range []string{"delayload32.exe", "delayload64.exe"} {
+		pf := openFixture(t, "../testdata/delayload/"+name)
+
+		libs, err := pf.DelayImportedLibraries()
+		assert.NoError(t, err, name)
+		assert.EqualValues(t, []string{"d3dx9_43.dll", "fmodex.dll"}, libs, name)
+
+		syms, err := pf.DelayImportedSymbols()
+		assert.NoError(t, err, name)
+		assert.EqualValues(t, []pe.ImportedSymbol{
+			{DLL: "d3dx9_43.dll", Name: "D3DXCreateTextureFromFileA"},
+			{DLL: "d3dx9_43.dll", Ordinal: 42},
+			{DLL: "fmodex.dll", Name: "FMOD_System_Create"},
+		}, syms, name)
+
+		// regular imports are unaffected
+		libs, err = pf.ImportedLibraries()
+		assert.NoError(t, err, name)
+		assert.EqualValues(t, []string{"KERNEL32.dll"}, libs, name)
+	}
+
+	dids, err := openFixture(t, "../testdata/hello/hello32-mingw.exe").DelayImportDescriptors()
+	assert.NoError(t, err)
+	assert.Nil(t, dids)
+}
+
+func Test_OrdinalImports(t *testing.T) {
+	pf := openFixture(t, "../testdata/stockboy/stockboy_install_sliced.EXE")
+	syms, err := pf.ImportedSymbols()
+	assert.NoError(t, err)
+
+	var ordinals []string
+	for _, sym := range syms {
+		if sym.ByOrdinal() {
+			ordinals = append(ordinals, sym.String())
+		} else {
+			assert.NotEmpty(t, sym.DLL)
+		}
+	}
+	// InitCommonControls, and OLE Automation functions
+	assert.EqualValues(t, []string{"#17:COMCTL32.dll", "#9:OLEAUT32.dll", "#2:OLEAUT32.dll"}, ordinals)
+
+	sym := pe.ImportedSymbol{DLL: "KERNEL32.dll", Name: "ExitProcess", Hint: 0x11a}
+	assert.False(t, sym.ByOrdinal())
+	assert.EqualValues(t, "ExitProcess:KERNEL32.dll", sym.String())
+}
+
+// rvaOffset returns the file offset of rva in pf
+func rvaOffset(t *testing.T, pf *pe.File, rva uint32) int {
+	for _, s := range pf.Sections {
+		if s.VirtualAddress <= rva && rva < s.VirtualAddress+s.Size {
+			return int(s.Offset + rva - s.VirtualAddress)
+		}
+	}
+	t.Fatalf("RVA %x is not in any section", rva)
+	return 0
+}
+
+func Test_InvalidImports(t *testing.T) {
+	const path = "../testdata/hello/hello32-msvc.exe"
+	data, err := ioutil.ReadFile(path)
+	assert.NoError(t, err)
+	reference := openFixture(t, path)
+	expected, err := reference.ImportedSymbols()
+	assert.NoError(t, err)
+	ids, err := reference.ImportDescriptors()
+	assert.NoError(t, err)
+	assert.True(t, len(ids) >= 2)
+	importTable := reference.OptionalHeaderFields().DataDirectory[pe.IMAGE_DIRECTORY_ENTRY_IMPORT]
+
+	// descriptors are 20 bytes: OriginalFirstThunk, TimeDateStamp,
+	// ForwarderChain, Name, FirstThunk
+	patched := func(patch func(data []byte)) *pe.File {
+		patched := append([]byte(nil), data...)
+		patch(patched)
+		pf, err := pe.NewFile(bytes.NewReader(patched), int64(len(patched)))
+		assert.NoError(t, err)
+		return pf
+	}
+	descriptor := func(i int) int {
+		return rvaOffset(t, reference, importTable.VirtualAddress) + 20*i
+	}
+
+	// MSVC puts the import address table before the import table:
+	// without OriginalFirstThunk, thunks are read from there
+	pf := patched(func(data []byte) {
+		for i, id := range ids {
+			assert.True(t, id.FirstThunk < importTable.VirtualAddress)
+			binary.LittleEndian.PutUint32(data[descriptor(i):], 0)
+		}
+	})
+	symbols, err := pf.ImportedSymbols()
+	assert.NoError(t, err)
+	assert.EqualValues(t, expected, symbols)
+
+	// a name outside of the image only loses that symbol
+	pf = patched(func(data []byte) {
+		binary.LittleEndian.PutUint32(data[rvaOffset(t, reference, ids[0].OriginalFirstThunk):], 0x7ffffff0)
+	})
+	symbols, err = pf.ImportedSymbols()
+	assert.Error(t, err)
+	assert.EqualValues(t, expected[1:], symbols)
+
+	// a library name past the end of its section only loses that library
+	lastSection := reference.Sections[len(reference.Sections)-1]
+	pastEnd := lastSection.VirtualAddress + lastSection.Size + 0x10
+	pf = patched(func(data []byte) {
+		binary.LittleEndian.PutUint32(data[descriptor(0)+12:], pastEnd)
+	})
+	libraries, err := pf.ImportedLibraries()
+	assert.Error(t, err)
+	referenceLibraries, err := reference.ImportedLibraries()
+	assert.NoError(t, err)
+	assert.EqualValues(t, referenceLibraries[1:], libraries)
+	symbols, err = pf.ImportedSymbols()
+	assert.Error(t, err)
+	for _, sym := range symbols {
+		assert.NotEqual(t, referenceLibraries[0], sym.DLL)
+	}
+	assert.NotEmpty(t, symbols)
+
+	// and so does a thunk table outside of the image
+	pf = patched(func(data []byte) {
+		binary.LittleEndian.PutUint32(data[descriptor(0):], 0x7ffffff0)
+		binary.LittleEndian.PutUint32(data[descriptor(1):], 0x7ffffff0)
+	})
+	symbols, err = pf.ImportedSymbols()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "2 invalid imports")
+	for _, sym := range symbols {
+		assert.NotEqual(t, referenceLibraries[0], sym.DLL)
+		assert.NotEqual(t, referenceLibraries[1], sym.DLL)
+	}
+}
+
+func Test_OptionalHeaderFields(t *testing.T) {
+	pf := openFixture(t, "../testdata/delayload/delayload32.exe")
+	oh32 := pf.OptionalHeader.(*pe.OptionalHeader32)
+	oh := pf.OptionalHeaderFields()
+	assert.False(t, oh.PE64)
+	assert.EqualValues(t, 0x400000, oh.ImageBase)
+	assert.EqualValues(t, oh32.AddressOfEntryPoint, oh.AddressOfEntryPoint)
+	assert.EqualValues(t, oh32.Subsystem, oh.Subsystem)
+	assert.EqualValues(t, oh32.DataDirectory, oh.DataDirectory)
+	assert.EqualValues(t, oh32.DataDirectory[pe.IMAGE_DIRECTORY_ENTRY_IMPORT], oh.Directory(pe.IMAGE_DIRECTORY_ENTRY_IMPORT))
+	assert.EqualValues(t, pe.DataDirectory{}, oh.Directory(-1))
+	assert.EqualValues(t, oh.NumberOfRvaAndSizes, len(oh.Directories()))
+
+	pf = openFixture(t, "../testdata/delayload/delayload64.exe")
+	oh64 := pf.OptionalHeader.(*pe.OptionalHeader64)
+	oh = pf.OptionalHeaderFields()
+	assert.True(t, oh.PE64)
+	assert.EqualValues(t, 0x140000000, oh.ImageBase)
+	assert.EqualValues(t, oh64.SizeOfStackReserve, oh.SizeOfStackReserve)
+	assert.EqualValues(t, oh64.DataDirectory, oh.DataDirectory)
+
+	// past NumberOfRvaAndSizes
+	oh.NumberOfRvaAndSizes = pe.IMAGE_DIRECTORY_ENTRY_IMPORT
+	assert.EqualValues(t, pe.DataDirectory{}, oh.Directory(pe.IMAGE_DIRECTORY_ENTRY_IMPORT))
+
+	assert.EqualValues(t, pe.OptionalHeaderFields{}, (&pe.File{}).OptionalHeaderFields())
+}
+
+func Test_RichHeader(t *testing.T) {
+	rh, err := openFixture(t, "../testdata/hello/hello32-msvc.exe").RichHeader()
+	assert.NoError(t, err)
+	if assert.NotNil(t, rh) {
+		assert.EqualValues(t, 0x80, rh.Offset)
+		assert.True(t, rh.ChecksumValid)
+		assert.Len(t, rh.Entries, 10)
+		// imported functions
+		assert.EqualValues(t, pe.RichEntry{ProductID: 1, Build: 0, Count: 85}, rh.Entries[4])
+		// Visual Studio 2015+ linker
+		assert.EqualValues(t, pe.RichEntry{ProductID: 0x102, Build: 23918, Count: 1}, rh.Entries[9])
+	}
+
+	// the DOS stub is part of the checksum
+	data, err := ioutil.ReadFile("../testdata/hello/hello32-msvc.exe")
+	assert.NoError(t, err)
+	data[0x50]++
+	pf, err := pe.NewFile(bytes.NewReader(data), int64(len(data)))
+	assert.NoError(t, err)
+	tampered, err := pf.RichHeader()
+	assert.NoError(t, err)
+	assert.EqualValues(t, rh.Entries, tampered.Entries)
+	assert.False(t, tampered.ChecksumValid)
+
+	rh, err = openFixture(t, "../testdata/hello/hello32-mingw.exe").RichHeader()
+	assert.NoError(t, err)
+	assert.Nil(t, rh)
+}
+
+func Test_TLSDirectory(t *testing.T) {
+	td, err := openFixture(t, "../testdata/hello/hello32-mingw.exe").TLSDirectory()
+	assert.NoError(t, err)
+	if assert.NotNil(t, td) {
+		assert.EqualValues(t, 0x408000, td.StartAddressOfRawData)
+		assert.EqualValues(t, 0x40801c, td.EndAddressOfRawData)
+		assert.EqualValues(t, 0x405390, td.AddressOfIndex)
+		// __dyn_tls_init and __dyn_tls_dtor of the MinGW runtime
+		assert.EqualValues(t, []uint32{0x18d0, 0x1880}, td.Callbacks)
+	}
+
+	pf := openFixture(t, "../testdata/hello/hello64-mingw.exe")
+	td, err = pf.TLSDirectory()
+	assert.NoError(t, err)
+	if assert.NotNil(t, td) {
+		assert.EqualValues(t, 0x40a000, td.StartAddressOfRawData)
+		assert.EqualValues(t, []uint32{0x1930, 0x1900}, td.Callbacks)
+	}
+	starts, err := pf.FunctionStarts()
+	assert.NoError(t, err)
+	assert.Contains(t, starts, uint32(0x1930))
+
+	td, err = openFixture(t, "../testdata/hello/hello32-msvc.exe").TLSDirectory()
+	assert.NoError(t, err)
+	assert.Nil(t, td)
+}
+
+func Test_CodeView(t *testing.T) {
+	data, err := ioutil.ReadFile("../testdata/hello/hello32-msvc.exe")
+	assert.NoError(t, err)
+
+	pf, err := pe.NewFile(bytes.NewReader(data), int64(len(data)))
+	assert.NoError(t, err)
+	dds, err := pf.DebugDirectories()
+	assert.NoError(t, err)
+	if assert.Len(t, dds, 1) {
+		assert.EqualValues(t, pe.IMAGE_DEBUG_TYPE_POGO, dds[0].Type)
+	}
+	cv, err := pf.CodeView()
+	assert.NoError(t, err)
+	assert.Nil(t, cv)
+
+	// turn the POGO entry into a CodeView one, and
+	// write a record over the POGO data
+	entry := make([]byte, 16)
+	binary.LittleEndian.PutUint32(entry[0:], dds[0].Type)
+	binary.LittleEndian.PutUint32(entry[4:], dds[0].SizeOfData)
+	binary.LittleEndian.PutUint32(entry[8:], dds[0].AddressOfRawData)
+	binary.LittleEndian.PutUint32(entry[12:], dds[0].PointerToRawData)
+	i := bytes.Index(data, entry)
+	assert.True(t, i > 0)
+	binary.LittleEndian.PutUint32(data[i:], pe.IMAGE_DEBUG_TYPE_CODEVIEW)
+
+	record := []byte("RSDS")
+	record = append(record, 0xe0, 0x04, 0x25, 0x3f, 0x89, 0x4f, 0xd3, 0x11, 0x9a, 0x0c, 0x03, 0x05, 0xe8, 0x2c, 0x33, 0x01)
+	record = append(record, 0x2a, 0, 0, 0)
+	record = append(record, "C:\\build\\hello\\Release\\hello.pdb\x00"...)
+	copy(data[dds[0].PointerToRawData:], record)
+
+	pf, err = pe.NewFile(bytes.NewReader(data), int64(len(data)))
+	assert.NoError(t, err)
+	cv, err = pf.CodeView()
+	assert.NoError(t, err)
+	if assert.NotNil(t, cv) {
+		assert.EqualValues(t, "RSDS", cv.Signature)
+		assert.EqualValues(t, "3F2504E0-4F89-11D3-9A0C-0305E82C3301", cv.GUID.String())
+		assert.EqualValues(t, 42, cv.Age)
+		assert.EqualValues(t, `C:\build\hello\Release\hello.pdb`, cv.PDBPath)
+		assert.EqualValues(t, "3F2504E04F8911D39A0C0305E82C33012A", cv.SymbolServerKey())
+	}
+
+	record = []byte("NB10\x00\x00\x00\x00\x78\x56\x34\x12\x02\x00\x00\x00hello.pdb\x00")
+	copy(data[dds[0].PointerToRawData:], record)
+	pf, err = pe.NewFile(bytes.NewReader(data), int64(len(data)))
+	assert.NoError(t, err)
+	cv, err = pf.CodeView()
+	assert.NoError(t, err)
+	if assert.NotNil(t, cv) {
+		assert.EqualValues(t, "NB10", cv.Signature)
+		assert.EqualValues(t, "hello.pdb", cv.PDBPath)
+		assert.EqualValues(t, "123456782", cv.SymbolServerKey())
+	}
+}
+
+func Test_TruncatedSections(t *testing.T) {
+	data, err := ioutil.ReadFile("../testdata/hello/hello32-msvc.exe")
+	assert.NoError(t, err)
+	data = data[:len(data)/2]
+
+	pf, err := pe.NewFile(bytes.NewReader(data), int64(len(data)))
+	assert.NoError(t, err)
+	text := pf.Section(".text")
+	assert.True(t, text.Truncated())
+	assert.EqualValues(t, 65024, text.DeclaredSize)
+	assert.EqualValues(t, len(data)-int(text.Offset), text.Size)
+	// what's there can still be read
+	_, err = text.DataRange(0, int64(text.Size))
+	assert.NoError(t, err)
+
+	rdata := pf.Section(".rdata")
+	assert.True(t, rdata.Truncated())
+	assert.EqualValues(t, 0, rdata.Size)
+
+	pf = openFixture(t, "../testdata/hello/hello32-msvc.exe")
+	for _, s := range pf.Sections {
+		assert.False(t, s.Truncated())
+		assert.EqualValues(t, s.DeclaredSize, s.Size)
+	}
+}
+
+func Test_LoadConfig(t *testing.T) {
+	lc, err := openFixture(t, "../testdata/hello/hello32-msvc.exe").LoadConfig()
+	assert.NoError(t, err)
+	if assert.NotNil(t, lc) {
+		assert.EqualValues(t, 92, lc.Size)
+		assert.EqualValues(t, 0x418038, lc.SecurityCookie)
+		assert.EqualValues(t, 0x416560, lc.SEHandlerTable)
+		assert.EqualValues(t, 3, lc.SEHandlerCount)
+		assert.EqualValues(t, 0x411114, lc.GuardCFCheckFunctionPointer)
+		assert.EqualValues(t, "CF_INSTRUMENTED", lc.GuardFlags.String())
+	}
+
+	lc, err = openFixture(t, "../testdata/hello/hello64-msvc.exe").LoadConfig()
+	assert.NoError(t, err)
+	if assert.NotNil(t, lc) {
+		assert.EqualValues(t, 148, lc.Size)
+		assert.EqualValues(t, 0x14001c038, lc.SecurityCookie)
+		// no SafeSEH on x64, exception handlers are in the exception directory
+		assert.EqualValues(t, 0, lc.SEHandlerCount)
+		assert.EqualValues(t, 0x140012240, lc.GuardCFDispatchFunctionPointer)
+	}
+
+	// Visual Studio 2008 era: the directory ends before the CFG fields
+	lc, err = openFixture(t, "../testdata/wincdemu/WinCDEmu-4.1.exe").LoadConfig()
+	assert.NoError(t, err)
+	if assert.NotNil(t, lc) {
+		assert.EqualValues(t, 72, lc.Size)
+		assert.EqualValues(t, 66, lc.SEHandlerCount)
+		assert.EqualValues(t, 0, lc.GuardFlags)
+	}
+
+	lc, err = openFixture(t, "../testdata/hello/hello32-mingw.exe").LoadConfig()
+	assert.NoError(t, err)
+	assert.Nil(t, lc)
+
+	assert.EqualValues(t, []string{"CF_INSTRUMENTED", "CF_FUNCTION_TABLE_PRESENT"}, pe.GuardFlags(0x10000500).Names())
+}
+
+// patchSectionHeader adds delta to the 32-bit field at fieldOffset
+// in the header of the named section
+func patchSectionHeader(t *testing.T, data []byte, name string, fieldOffset int, delta uint32) {
+	lfanew := int(binary.LittleEndian.Uint32(data[0x3c:]))
+	numSections := int(binary.LittleEndian.Uint16(data[lfanew+6:]))
+	sizeOfOptionalHeader := int(binary.LittleEndian.Uint16(data[lfanew+20:]))
+	table := lfanew + 24 + sizeOfOptionalHeader
+	for i := 0; i < numSections; i++ {
+		header := data[table+i*40:]
+		if string(bytes.TrimRight(header[:8], "\x00")) == name {
+			field := header[fieldOffset:]
+			binary.LittleEndian.PutUint32(field, binary.LittleEndian.Uint32(field)+delta)
+			return
+		}
+	}
+	t.Fatalf("no section %s", name)
+}
+
+func Test_Alignment(t *testing.T) {
+	data, err := ioutil.ReadFile("../testdata/hello/hello32-msvc.exe")
+	assert.NoError(t, err)
+	reference := openFixture(t, "../testdata/hello/hello32-msvc.exe")
+	for _, s := range reference.Sections {
+		assert.EqualValues(t, s.DeclaredOffset, s.Offset)
+	}
+	assert.False(t, reference.LowAlignment())
+
+	// the loader ignores the low bits of PointerToRawData
+	unaligned := append([]byte(nil), data...)
+	patchSectionHeader(t, unaligned, ".rdata", 20, 0x1ff)
+	pf, err := pe.NewFile(bytes.NewReader(unaligned), int64(len(unaligned)))
+	assert.NoError(t, err)
+	rdata := pf.Section(".rdata")
+	assert.EqualValues(t, reference.Section(".rdata").Offset+0x1ff, rdata.DeclaredOffset)
+	assert.EqualValues(t, reference.Section(".rdata").Offset, rdata.Offset)
+	assert.False(t, rdata.Truncated())
+	imports, err := pf.ImportedSymbols()
+	assert.NoError(t, err)
+	referenceImports, err := reference.ImportedSymbols()
+	assert.NoError(t, err)
+	assert.EqualValues(t, referenceImports, imports)
+
+	// that includes offsets below 0x200, which are read from the start
+	// of the file, not treated as uninitialized data
+	low := append([]byte(nil), data...)
+	patchSectionHeader(t, low, ".rdata", 20, 0x100-reference.Section(".rdata").Offset)
+	pf, err = pe.NewFile(bytes.NewReader(low), int64(len(low)))
+	assert.NoError(t, err)
+	rdata = pf.Section(".rdata")
+	assert.EqualValues(t, 0x100, rdata.DeclaredOffset)
+	assert.EqualValues(t, 0, rdata.Offset)
+	head := make([]byte, 2)
+	_, err = rdata.ReadAt(head, 0)
+	assert.NoError(t, err)
+	assert.EqualValues(t, "MZ", string(head))
+
+	// below the page size, the file is mapped as is
+	lowAlignment := append([]byte(nil), data...)
+	lfanew := int(binary.LittleEndian.Uint32(lowAlignment[0x3c:]))
+	binary.LittleEndian.PutUint32(lowAlignment[lfanew+24+32:], 0x200) // SectionAlignment
+	pf, err = pe.NewFile(bytes.NewReader(lowAlignment), int64(len(lowAlignment)))
+	assert.NoError(t, err)
+	assert.True(t, pf.LowAlignment())
+	text := pf.Section(".text")
+	assert.EqualValues(t, text.VirtualAddress, text.Offset)
+	assert.EqualValues(t, reference.Section(".text").Offset, text.DeclaredOffset)
+	// the larger of VirtualSize and SizeOfRawData is mapped
+	assert.EqualValues(t, text.DeclaredSize, text.Size)
+}
+
+func Test_Certificates(t *testing.T) {
+	certs, err := openFixture(t, "../testdata/wincdemu/WinCDEmu-4.1.exe").Certificates()
+	assert.NoError(t, err)
+	if assert.Len(t, certs, 1) {
+		c := certs[0]
+		assert.EqualValues(t, 0x19ccb8, c.Offset)
+		assert.EqualValues(t, pe.WIN_CERT_REVISION_2_0, c.Revision)
+		assert.EqualValues(t, pe.WIN_CERT_TYPE_PKCS_SIGNED_DATA, c.CertificateType)
+		assert.Len(t, c.Data, 7000-8)
+		// a DER SEQUENCE, the PKCS #7 ContentInfo
+		assert.EqualValues(t, []byte{0x30, 0x82, 0x1b, 0x4b}, c.PKCS7()[:4])
+
+		c.CertificateType = pe.WIN_CERT_TYPE_X509
+		assert.Nil(t, c.PKCS7())
+	}
+
+	certs, err = openFixture(t, "../testdata/hello/hello32-msvc.exe").Certificates()
+	assert.NoError(t, err)
+	assert.Nil(t, certs)
+
+	data, err := ioutil.ReadFile("../testdata/wincdemu/WinCDEmu-4.1.exe")
+	assert.NoError(t, err)
+	// the entry claims to be larger than the table
+	binary.LittleEndian.PutUint32(data[0x19ccb8:], 7001)
+	pf, err := pe.NewFile(bytes.NewReader(data), int64(len(data)))
+	assert.NoError(t, err)
+	_, err = pf.Certificates()
+	assert.Error(t, err)
+
+	// the table is past the end of the file
+	data = data[:0x19ccb8+100]
+	pf, err = pe.NewFile(bytes.NewReader(data), int64(len(data)))
+	assert.NoError(t, err)
+	_, err = pf.Certificates()
+	assert.Error(t, err)
+}
+
+func Test_Authentihash(t *testing.T) {
+	pf := openFixture(t, "../testdata/wincdemu/WinCDEmu-4.1.exe")
+	// the digest in its signature (in SpcIndirectDataContent), which is SHA-1
+	sum, err := pf.AuthentihashSHA1()
+	assert.NoError(t, err)
+	assert.EqualValues(t, "bf9d4f49564d77387f5d12483c294c90dea80bb0", hex.EncodeToString(sum))
+	certs, err := pf.Certificates()
+	assert.NoError(t, err)
+	assert.True(t, bytes.Contains(certs[0].PKCS7(), sum))
+
+	sum, err = pf.AuthentihashSHA256()
+	assert.NoError(t, err)
+	assert.EqualValues(t, "5bfc4ff921500797b2483073910f7b16941ae2f4fb9377e51ee9dfdc73d8aad1", hex.EncodeToString(sum))
+
+	// unsigned: the checksum is still left out, but nothing else
+	data, err := ioutil.ReadFile("../testdata/hello/hello32-msvc.exe")
+	assert.NoError(t, err)
+	authentihash := func(data []byte) string {
+		pf, err := pe.NewFile(bytes.NewReader(data), int64(len(data)))
+		assert.NoError(t, err)
+		sum, err := pf.AuthentihashSHA256()
+		assert.NoError(t, err)
+		return hex.EncodeToString(sum)
+	}
+	reference := authentihash(data)
+	optionalHeader := int(binary.LittleEndian.Uint32(data[0x3c:])) + 24
+
+	patched := append([]byte(nil), data...)
+	binary.LittleEndian.PutUint32(patched[optionalHeader+64:], 0x12345678)
+	assert.EqualValues(t, reference, authentihash(patched))
+
+	// the certificate table entry points past the end of the file
+	binary.LittleEndian.PutUint64(patched[optionalHeader+128:], 0x1234567812345678)
+	pf, err = pe.NewFile(bytes.NewReader(patched), int64(len(patched)))
+	assert.NoError(t, err)
+	_, err = pf.AuthentihashSHA256()
+	assert.Error(t, err)
+
+	patched = append([]byte(nil), data...)
+	patched[len(patched)-1]++
+	assert.NotEqual(t, reference, authentihash(patched))
+
+	_, err = openFixture(t, "../testdata/dwarf/reloc.obj").AuthentihashSHA256()
+	assert.Error(t, err)
+}
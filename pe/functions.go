@@ -0,0 +1,127 @@
+package pe
+
+import (
+	"encoding/binary"
+	"sort"
+
+	"github.com/pkg/errors"
+)
+
+// Symbol base and complex types (Symbol.Type)
+const (
+	IMAGE_SYM_DTYPE_FUNCTION = 2
+)
+
+// size of a RUNTIME_FUNCTION entry of the exception directory: x64 ones
+// have begin and end addresses and unwind info, ARM and ARM64 ones don't
+// have an end address
+const (
+	sizeofRuntimeFunction    = 12
+	sizeofArmRuntimeFunction = 8
+)
+
+// dataDirectory returns the data directory at index, or a zero
+// DataDirectory if f has none (object files, truncated headers).
+func (f *File) dataDirectory(index int) DataDirectory {
+	oh := f.OptionalHeaderFields()
+	return oh.Directory(index)
+}
+
+// FunctionStarts returns the sorted, deduplicated RVAs of all functions
+// pelican knows of: the entry point, TLS callbacks, the begin addresses of
+// the x64, ARM and ARM64 exception directories, function symbols and
+// exported functions (except forwarders).
+//
+// None of these sources is exhaustive: x86 binaries have no exception
+// directory, leaf functions don't need unwind info, and release builds
+// are usually stripped.
+func (f *File) FunctionStarts() ([]uint32, error) {
+	seen := make(map[uint32]bool)
+	add := func(rva uint32) {
+		if rva != 0 {
+			seen[rva] = true
+		}
+	}
+
+	add(f.OptionalHeaderFields().AddressOfEntryPoint)
+
+	td, err := f.TLSDirectory()
+	if err != nil {
+		return nil, err
+	}
+	if td != nil {
+		for _, rva := range td.Callbacks {
+			add(rva)
+		}
+	}
+
+	for _, sym := range f.Symbols {
+		if (sym.Type>>4)&0xf != IMAGE_SYM_DTYPE_FUNCTION {
+			continue
+		}
+		if rva, ok := f.SymbolRVA(sym); ok {
+			add(rva)
+		}
+	}
+
+	rr := newRVAReader(f)
+
+	entrySize := 0
+	switch f.Machine {
+	case IMAGE_FILE_MACHINE_AMD64:
+		entrySize = sizeofRuntimeFunction
+	case IMAGE_FILE_MACHINE_ARM64, IMAGE_FILE_MACHINE_ARMNT:
+		entrySize = sizeofArmRuntimeFunction
+	}
+	if entrySize > 0 {
+		dd := f.dataDirectory(IMAGE_DIRECTORY_ENTRY_EXCEPTION)
+		if dd.VirtualAddress != 0 {
+			size := int64(dd.Size)
+			if available := rr.available(dd.VirtualAddress); size > available {
+				size = available
+			}
+			data, err := rr.slice(dd.VirtualAddress, size)
+			if err != nil {
+				return nil, errors.WithMessage(err, "while reading exception directory")
+			}
+			for ; len(data) >= entrySize; data = data[entrySize:] {
+				begin := binary.LittleEndian.Uint32(data[0:4])
+				if f.Machine == IMAGE_FILE_MACHINE_ARMNT {
+					// the low bit is set for Thumb code
+					begin &^= 1
+				}
+				add(begin)
+			}
+		}
+	}
+
+	dd := f.dataDirectory(IMAGE_DIRECTORY_ENTRY_EXPORT)
+	if dd.VirtualAddress != 0 {
+		ed, err := rr.slice(dd.VirtualAddress, sizeofExportDirectory)
+		if err != nil {
+			return nil, errors.WithMessage(err, "while reading export directory")
+		}
+		numberOfFunctions := binary.LittleEndian.Uint32(ed[20:24])
+		addressOfFunctions := binary.LittleEndian.Uint32(ed[28:32])
+
+		eat, err := rr.slice(addressOfFunctions, int64(numberOfFunctions)*4)
+		if err != nil {
+			return nil, errors.WithMessage(err, "while reading export address table")
+		}
+		for i := uint32(0); i < numberOfFunctions; i++ {
+			rva := binary.LittleEndian.Uint32(eat[i*4:])
+			if rva >= dd.VirtualAddress && uint64(rva) < uint64(dd.VirtualAddress)+uint64(dd.Size) {
+				// forwarders point to a string in the export directory
+				continue
+			}
+			add(rva)
+		}
+	}
+
+	res := make([]uint32, 0, len(seen))
+	for rva := range seen {
+		res = append(res, rva)
+	}
+	sort.Slice(res, func(i, j int) bool { return res[i] < res[j] })
+	return res, nil
+}
@@ -0,0 +1,162 @@
+package pe
+
+import (
+	"encoding/binary"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// IMAGE_GUARD_* flags of LoadConfig.GuardFlags
+const (
+	IMAGE_GUARD_CF_INSTRUMENTED                    = 0x00000100
+	IMAGE_GUARD_CFW_INSTRUMENTED                   = 0x00000200
+	IMAGE_GUARD_CF_FUNCTION_TABLE_PRESENT          = 0x00000400
+	IMAGE_GUARD_SECURITY_COOKIE_UNUSED             = 0x00000800
+	IMAGE_GUARD_PROTECT_DELAYLOAD_IAT              = 0x00001000
+	IMAGE_GUARD_DELAYLOAD_IAT_IN_ITS_OWN_SECTION   = 0x00002000
+	IMAGE_GUARD_CF_EXPORT_SUPPRESSION_INFO_PRESENT = 0x00004000
+	IMAGE_GUARD_CF_ENABLE_EXPORT_SUPPRESSION       = 0x00008000
+	IMAGE_GUARD_CF_LONGJUMP_TABLE_PRESENT          = 0x00010000
+	IMAGE_GUARD_EH_CONTINUATION_TABLE_PRESENT      = 0x00400000
+	// the high bits are the size of the extra data
+	// of each entry of the CFG function table
+	IMAGE_GUARD_CF_FUNCTION_TABLE_SIZE_MASK = 0xf0000000
+)
+
+// GuardFlags is the type of LoadConfig.GuardFlags
+type GuardFlags uint32
+
+// GuardFlagsNames maps IMAGE_GUARD_* flags to their names
+var GuardFlagsNames = map[GuardFlags]string{
+	IMAGE_GUARD_CF_INSTRUMENTED:                    "CF_INSTRUMENTED",
+	IMAGE_GUARD_CFW_INSTRUMENTED:                   "CFW_INSTRUMENTED",
+	IMAGE_GUARD_CF_FUNCTION_TABLE_PRESENT:          "CF_FUNCTION_TABLE_PRESENT",
+	IMAGE_GUARD_SECURITY_COOKIE_UNUSED:             "SECURITY_COOKIE_UNUSED",
+	IMAGE_GUARD_PROTECT_DELAYLOAD_IAT:              "PROTECT_DELAYLOAD_IAT",
+	IMAGE_GUARD_DELAYLOAD_IAT_IN_ITS_OWN_SECTION:   "DELAYLOAD_IAT_IN_ITS_OWN_SECTION",
+	IMAGE_GUARD_CF_EXPORT_SUPPRESSION_INFO_PRESENT: "CF_EXPORT_SUPPRESSION_INFO_PRESENT",
+	IMAGE_GUARD_CF_ENABLE_EXPORT_SUPPRESSION:       "CF_ENABLE_EXPORT_SUPPRESSION",
+	IMAGE_GUARD_CF_LONGJUMP_TABLE_PRESENT:          "CF_LONGJUMP_TABLE_PRESENT",
+	IMAGE_GUARD_EH_CONTINUATION_TABLE_PRESENT:      "EH_CONTINUATION_TABLE_PRESENT",
+}
+
+// Names returns the names of all flags set in gf, sorted by value.
+// The function table entry size bits are left out.
+func (gf GuardFlags) Names() []string {
+	return flagNames(uint64(gf&^IMAGE_GUARD_CF_FUNCTION_TABLE_SIZE_MASK), func(flag uint64) (string, bool) {
+		name, ok := GuardFlagsNames[GuardFlags(flag)]
+		return name, ok
+	})
+}
+
+func (gf GuardFlags) String() string {
+	return strings.Join(gf.Names(), "|")
+}
+
+// LoadConfig holds the fields of the load configuration directory
+// (IMAGE_LOAD_CONFIG_DIRECTORY32 or IMAGE_LOAD_CONFIG_DIRECTORY64,
+// widened) that tell how a binary was hardened. The directory grew with
+// each Windows release: fields past Size weren't written by the linker,
+// and are zero.
+//
+// Addresses are virtual addresses, not RVAs.
+type LoadConfig struct {
+	// Size of the directory, as written by the linker
+	Size          uint32
+	TimeDateStamp uint32
+	MajorVersion  uint16
+	MinorVersion  uint16
+
+	// Address of the /GS stack cookie, 0 if it's not used
+	SecurityCookie uint64
+
+	// x86 only: table of the RVAs of the exception handlers
+	// registered with /SAFESEH, and how many there are
+	SEHandlerTable uint64
+	SEHandlerCount uint64
+
+	// Control Flow Guard: address of the pointer to the function that
+	// checks indirect call targets, and of that which dispatches them
+	GuardCFCheckFunctionPointer    uint64
+	GuardCFDispatchFunctionPointer uint64
+	// Address and size of the table of valid indirect call targets
+	GuardCFFunctionTable uint64
+	GuardCFFunctionCount uint64
+	GuardFlags           GuardFlags
+}
+
+// offsets of the fields of LoadConfig in the 32 and 64-bit structures
+type loadConfigLayout struct {
+	securityCookie, seHandlerTable, seHandlerCount int
+	guardCFCheckFunctionPointer                    int
+	guardCFDispatchFunctionPointer                 int
+	guardCFFunctionTable, guardCFFunctionCount     int
+	guardFlags                                     int
+	end                                            int
+}
+
+var (
+	loadConfigLayout32 = loadConfigLayout{60, 64, 68, 72, 76, 80, 84, 88, 92}
+	loadConfigLayout64 = loadConfigLayout{88, 96, 104, 112, 120, 128, 136, 144, 148}
+)
+
+// LoadConfig reads the load configuration directory,
+// or returns nil if f has none.
+func (f *File) LoadConfig() (*LoadConfig, error) {
+	dd := f.dataDirectory(IMAGE_DIRECTORY_ENTRY_LOAD_CONFIG)
+	if dd.VirtualAddress == 0 {
+		return nil, nil
+	}
+
+	oh := f.OptionalHeaderFields()
+	layout, ptrSize := loadConfigLayout32, 4
+	if oh.PE64 {
+		layout, ptrSize = loadConfigLayout64, 8
+	}
+
+	rr := newRVAReader(f)
+	header, err := rr.slice(dd.VirtualAddress, 4)
+	if err != nil {
+		return nil, errors.WithMessage(err, "while reading load configuration directory")
+	}
+	// the loader goes by the size in the structure, older linkers
+	// wrote the wrong one in the data directory
+	size := int(binary.LittleEndian.Uint32(header))
+	n := size
+	if n > layout.end {
+		n = layout.end
+	}
+	if avail := rr.available(dd.VirtualAddress); int64(n) > avail {
+		n = int(avail)
+	}
+	data, err := rr.slice(dd.VirtualAddress, int64(n))
+	if err != nil {
+		return nil, errors.WithMessage(err, "while reading load configuration directory")
+	}
+
+	// fields past the end of the structure are zero
+	full := make([]byte, layout.end)
+	copy(full, data)
+	readPtr := func(offset int) uint64 {
+		if ptrSize == 8 {
+			return binary.LittleEndian.Uint64(full[offset:])
+		}
+		return uint64(binary.LittleEndian.Uint32(full[offset:]))
+	}
+
+	return &LoadConfig{
+		Size:                           uint32(size),
+		TimeDateStamp:                  binary.LittleEndian.Uint32(full[4:]),
+		MajorVersion:                   binary.LittleEndian.Uint16(full[8:]),
+		MinorVersion:                   binary.LittleEndian.Uint16(full[10:]),
+		SecurityCookie:                 readPtr(layout.securityCookie),
+		SEHandlerTable:                 readPtr(layout.seHandlerTable),
+		SEHandlerCount:                 readPtr(layout.seHandlerCount),
+		GuardCFCheckFunctionPointer:    readPtr(layout.guardCFCheckFunctionPointer),
+		GuardCFDispatchFunctionPointer: readPtr(layout.guardCFDispatchFunctionPointer),
+		GuardCFFunctionTable:           readPtr(layout.guardCFFunctionTable),
+		GuardCFFunctionCount:           readPtr(layout.guardCFFunctionCount),
+		GuardFlags:                     GuardFlags(binary.LittleEndian.Uint32(full[layout.guardFlags:])),
+	}, nil
+}
@@ -0,0 +1,204 @@
+package pe
+
+import (
+	"fmt"
+	"strings"
+)
+
+// The types below give symbolic names to the values of FileHeader
+// and OptionalHeader fields, for example:
+//
+//	pe.Machine(f.Machine).String() // "I386"
+//
+// They implement encoding.TextMarshaler, so they show up as names in
+// JSON too, flags being joined with "|".
+//
+// The IMAGE_* constants are untyped so they can still be compared
+// with the raw header fields.
+
+// Machine is the type of FileHeader.Machine
+type Machine uint16
+
+// MachineNames maps IMAGE_FILE_MACHINE_* values to their names
+var MachineNames = map[Machine]string{
+	IMAGE_FILE_MACHINE_UNKNOWN:   "UNKNOWN",
+	IMAGE_FILE_MACHINE_AM33:      "AM33",
+	IMAGE_FILE_MACHINE_AMD64:     "AMD64",
+	IMAGE_FILE_MACHINE_ARM:       "ARM",
+	IMAGE_FILE_MACHINE_ARM64:     "ARM64",
+	IMAGE_FILE_MACHINE_ARMNT:     "ARMNT",
+	IMAGE_FILE_MACHINE_EBC:       "EBC",
+	IMAGE_FILE_MACHINE_I386:      "I386",
+	IMAGE_FILE_MACHINE_IA64:      "IA64",
+	IMAGE_FILE_MACHINE_M32R:      "M32R",
+	IMAGE_FILE_MACHINE_MIPS16:    "MIPS16",
+	IMAGE_FILE_MACHINE_MIPSFPU:   "MIPSFPU",
+	IMAGE_FILE_MACHINE_MIPSFPU16: "MIPSFPU16",
+	IMAGE_FILE_MACHINE_POWERPC:   "POWERPC",
+	IMAGE_FILE_MACHINE_POWERPCFP: "POWERPCFP",
+	IMAGE_FILE_MACHINE_R4000:     "R4000",
+	IMAGE_FILE_MACHINE_SH3:       "SH3",
+	IMAGE_FILE_MACHINE_SH3DSP:    "SH3DSP",
+	IMAGE_FILE_MACHINE_SH4:       "SH4",
+	IMAGE_FILE_MACHINE_SH5:       "SH5",
+	IMAGE_FILE_MACHINE_THUMB:     "THUMB",
+	IMAGE_FILE_MACHINE_WCEMIPSV2: "WCEMIPSV2",
+}
+
+func (m Machine) String() string {
+	if name, ok := MachineNames[m]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%x", uint16(m))
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (m Machine) MarshalText() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+// Subsystem is the type of OptionalHeader.Subsystem
+type Subsystem uint16
+
+// SubsystemNames maps IMAGE_SUBSYSTEM_* values to their names
+var SubsystemNames = map[Subsystem]string{
+	IMAGE_SUBSYSTEM_UNKNOWN:                  "UNKNOWN",
+	IMAGE_SUBSYSTEM_NATIVE:                   "NATIVE",
+	IMAGE_SUBSYSTEM_WINDOWS_GUI:              "WINDOWS_GUI",
+	IMAGE_SUBSYSTEM_WINDOWS_CUI:              "WINDOWS_CUI",
+	IMAGE_SUBSYSTEM_OS2_CUI:                  "OS2_CUI",
+	IMAGE_SUBSYSTEM_POSIX_CUI:                "POSIX_CUI",
+	IMAGE_SUBSYSTEM_NATIVE_WINDOWS:           "NATIVE_WINDOWS",
+	IMAGE_SUBSYSTEM_WINDOWS_CE_GUI:           "WINDOWS_CE_GUI",
+	IMAGE_SUBSYSTEM_EFI_APPLICATION:          "EFI_APPLICATION",
+	IMAGE_SUBSYSTEM_EFI_BOOT_SERVICE_DRIVER:  "EFI_BOOT_SERVICE_DRIVER",
+	IMAGE_SUBSYSTEM_EFI_RUNTIME_DRIVER:       "EFI_RUNTIME_DRIVER",
+	IMAGE_SUBSYSTEM_EFI_ROM:                  "EFI_ROM",
+	IMAGE_SUBSYSTEM_XBOX:                     "XBOX",
+	IMAGE_SUBSYSTEM_WINDOWS_BOOT_APPLICATION: "WINDOWS_BOOT_APPLICATION",
+}
+
+func (s Subsystem) String() string {
+	if name, ok := SubsystemNames[s]; ok {
+		return name
+	}
+	return fmt.Sprintf("0x%x", uint16(s))
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (s Subsystem) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// SectionCharacteristics is the type of SectionHeader.Characteristics
+type SectionCharacteristics uint32
+
+// SectionCharacteristicsNames maps IMAGE_SCN_* flags to their names.
+// The alignment bits (IMAGE_SCN_ALIGN_MASK) are a number, not flags.
+var SectionCharacteristicsNames = map[SectionCharacteristics]string{
+	IMAGE_SCN_TYPE_NO_PAD:            "TYPE_NO_PAD",
+	IMAGE_SCN_CNT_CODE:               "CNT_CODE",
+	IMAGE_SCN_CNT_INITIALIZED_DATA:   "CNT_INITIALIZED_DATA",
+	IMAGE_SCN_CNT_UNINITIALIZED_DATA: "CNT_UNINITIALIZED_DATA",
+	IMAGE_SCN_LNK_OTHER:              "LNK_OTHER",
+	IMAGE_SCN_LNK_INFO:               "LNK_INFO",
+	IMAGE_SCN_LNK_REMOVE:             "LNK_REMOVE",
+	IMAGE_SCN_LNK_COMDAT:             "LNK_COMDAT",
+	IMAGE_SCN_GPREL:                  "GPREL",
+	IMAGE_SCN_LNK_NRELOC_OVFL:        "LNK_NRELOC_OVFL",
+	IMAGE_SCN_MEM_DISCARDABLE:        "MEM_DISCARDABLE",
+	IMAGE_SCN_MEM_NOT_CACHED:         "MEM_NOT_CACHED",
+	IMAGE_SCN_MEM_NOT_PAGED:          "MEM_NOT_PAGED",
+	IMAGE_SCN_MEM_SHARED:             "MEM_SHARED",
+	IMAGE_SCN_MEM_EXECUTE:            "MEM_EXECUTE",
+	IMAGE_SCN_MEM_READ:               "MEM_READ",
+	IMAGE_SCN_MEM_WRITE:              "MEM_WRITE",
+}
+
+// Alignment returns the alignment of the section's data in object
+// files (IMAGE_SCN_ALIGN_*), or 0 if unspecified.
+func (sc SectionCharacteristics) Alignment() uint32 {
+	n := (uint32(sc) & IMAGE_SCN_ALIGN_MASK) >> 20
+	if n == 0 {
+		return 0
+	}
+	return 1 << (n - 1)
+}
+
+// Names returns the names of all flags set in sc, sorted by value
+func (sc SectionCharacteristics) Names() []string {
+	names := flagNames(uint64(sc&^IMAGE_SCN_ALIGN_MASK), func(flag uint64) (string, bool) {
+		name, ok := SectionCharacteristicsNames[SectionCharacteristics(flag)]
+		return name, ok
+	})
+	if align := sc.Alignment(); align > 0 {
+		names = append(names, fmt.Sprintf("ALIGN_%dBYTES", align))
+	}
+	return names
+}
+
+func (sc SectionCharacteristics) String() string {
+	return strings.Join(sc.Names(), "|")
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (sc SectionCharacteristics) MarshalText() ([]byte, error) {
+	return []byte(sc.String()), nil
+}
+
+// DllCharacteristics is the type of OptionalHeader.DllCharacteristics
+type DllCharacteristics uint16
+
+// DllCharacteristicsNames maps IMAGE_DLLCHARACTERISTICS_* flags to their names
+var DllCharacteristicsNames = map[DllCharacteristics]string{
+	IMAGE_DLLCHARACTERISTICS_HIGH_ENTROPY_VA:       "HIGH_ENTROPY_VA",
+	IMAGE_DLLCHARACTERISTICS_DYNAMIC_BASE:          "DYNAMIC_BASE",
+	IMAGE_DLLCHARACTERISTICS_FORCE_INTEGRITY:       "FORCE_INTEGRITY",
+	IMAGE_DLLCHARACTERISTICS_NX_COMPAT:             "NX_COMPAT",
+	IMAGE_DLLCHARACTERISTICS_NO_ISOLATION:          "NO_ISOLATION",
+	IMAGE_DLLCHARACTERISTICS_NO_SEH:                "NO_SEH",
+	IMAGE_DLLCHARACTERISTICS_NO_BIND:               "NO_BIND",
+	IMAGE_DLLCHARACTERISTICS_APPCONTAINER:          "APPCONTAINER",
+	IMAGE_DLLCHARACTERISTICS_WDM_DRIVER:            "WDM_DRIVER",
+	IMAGE_DLLCHARACTERISTICS_GUARD_CF:              "GUARD_CF",
+	IMAGE_DLLCHARACTERISTICS_TERMINAL_SERVER_AWARE: "TERMINAL_SERVER_AWARE",
+}
+
+// Names returns the names of all flags set in dc, sorted by value
+func (dc DllCharacteristics) Names() []string {
+	return flagNames(uint64(dc), func(flag uint64) (string, bool) {
+		name, ok := DllCharacteristicsNames[DllCharacteristics(flag)]
+		return name, ok
+	})
+}
+
+func (dc DllCharacteristics) String() string {
+	return strings.Join(dc.Names(), "|")
+}
+
+// MarshalText implements encoding.TextMarshaler
+func (dc DllCharacteristics) MarshalText() ([]byte, error) {
+	return []byte(dc.String()), nil
+}
+
+// flagNames returns the names of the bits set in value, unknown
+// bits being formatted as hexadecimal.
+func flagNames(value uint64, lookup func(flag uint64) (string, bool)) []string {
+	var names []string
+	var unknown uint64
+	for bit := uint(0); bit < 64; bit++ {
+		flag := uint64(1) << bit
+		if value&flag == 0 {
+			continue
+		}
+		if name, ok := lookup(flag); ok {
+			names = append(names, name)
+		} else {
+			unknown |= flag
+		}
+	}
+	if unknown != 0 {
+		names = append(names, fmt.Sprintf("0x%x", unknown))
+	}
+	return names
+}
@@ -0,0 +1,94 @@
+package pe_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/itchio/pelican/pe"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_MachineNames(t *testing.T) {
+	cases := []struct {
+		value pe.Machine
+		name  string
+	}{
+		{pe.IMAGE_FILE_MACHINE_I386, "I386"},
+		{pe.IMAGE_FILE_MACHINE_AMD64, "AMD64"},
+		{pe.IMAGE_FILE_MACHINE_ARM64, "ARM64"},
+		{pe.IMAGE_FILE_MACHINE_UNKNOWN, "UNKNOWN"},
+		{0x1234, "0x1234"},
+	}
+	for _, c := range cases {
+		assert.EqualValues(t, c.name, c.value.String())
+	}
+}
+
+func Test_SubsystemNames(t *testing.T) {
+	cases := []struct {
+		value pe.Subsystem
+		name  string
+	}{
+		{pe.IMAGE_SUBSYSTEM_WINDOWS_GUI, "WINDOWS_GUI"},
+		{pe.IMAGE_SUBSYSTEM_WINDOWS_CUI, "WINDOWS_CUI"},
+		{pe.IMAGE_SUBSYSTEM_EFI_APPLICATION, "EFI_APPLICATION"},
+		{0xff, "0xff"},
+	}
+	for _, c := range cases {
+		assert.EqualValues(t, c.name, c.value.String())
+	}
+}
+
+func Test_SectionCharacteristicsNames(t *testing.T) {
+	cases := []struct {
+		value     pe.SectionCharacteristics
+		names     []string
+		alignment uint32
+	}{
+		{0, nil, 0},
+		{pe.IMAGE_SCN_CNT_CODE | pe.IMAGE_SCN_MEM_EXECUTE | pe.IMAGE_SCN_MEM_READ, []string{"CNT_CODE", "MEM_EXECUTE", "MEM_READ"}, 0},
+		{pe.IMAGE_SCN_CNT_INITIALIZED_DATA | 0x00500000, []string{"CNT_INITIALIZED_DATA", "ALIGN_16BYTES"}, 16},
+		{0x00100000, []string{"ALIGN_1BYTES"}, 1},
+		{0x00e00000, []string{"ALIGN_8192BYTES"}, 8192},
+		// unknown bits come last
+		{pe.IMAGE_SCN_MEM_READ | 0x1, []string{"MEM_READ", "0x1"}, 0},
+	}
+	for _, c := range cases {
+		assert.EqualValues(t, c.names, c.value.Names(), "%#x", uint32(c.value))
+		assert.EqualValues(t, c.alignment, c.value.Alignment(), "%#x", uint32(c.value))
+	}
+	assert.EqualValues(t, "CNT_CODE|MEM_READ", pe.SectionCharacteristics(pe.IMAGE_SCN_CNT_CODE|pe.IMAGE_SCN_MEM_READ).String())
+}
+
+func Test_DllCharacteristicsNames(t *testing.T) {
+	cases := []struct {
+		value pe.DllCharacteristics
+		names []string
+	}{
+		{0, nil},
+		{pe.IMAGE_DLLCHARACTERISTICS_DYNAMIC_BASE | pe.IMAGE_DLLCHARACTERISTICS_NX_COMPAT, []string{"DYNAMIC_BASE", "NX_COMPAT"}},
+		{pe.IMAGE_DLLCHARACTERISTICS_TERMINAL_SERVER_AWARE, []string{"TERMINAL_SERVER_AWARE"}},
+		{pe.IMAGE_DLLCHARACTERISTICS_GUARD_CF | 0x3, []string{"GUARD_CF", "0x3"}},
+	}
+	for _, c := range cases {
+		assert.EqualValues(t, c.names, c.value.Names(), "%#x", uint16(c.value))
+	}
+	assert.EqualValues(t, "DYNAMIC_BASE|NX_COMPAT", pe.DllCharacteristics(0x140).String())
+}
+
+func Test_NamesJSON(t *testing.T) {
+	type header struct {
+		Machine            pe.Machine
+		Subsystem          pe.Subsystem
+		Characteristics    pe.SectionCharacteristics
+		DllCharacteristics pe.DllCharacteristics
+	}
+	bs, err := json.Marshal(header{
+		Machine:            pe.IMAGE_FILE_MACHINE_AMD64,
+		Subsystem:          0xff,
+		Characteristics:    pe.IMAGE_SCN_CNT_CODE | pe.IMAGE_SCN_MEM_READ,
+		DllCharacteristics: pe.IMAGE_DLLCHARACTERISTICS_NX_COMPAT,
+	})
+	assert.NoError(t, err)
+	assert.EqualValues(t, `{"Machine":"AMD64","Subsystem":"0xff","Characteristics":"CNT_CODE|MEM_READ","DllCharacteristics":"NX_COMPAT"}`, string(bs))
+}
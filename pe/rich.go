@@ -0,0 +1,107 @@
+package pe
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math/bits"
+
+	"github.com/pkg/errors"
+)
+
+// the Rich header is usually a few hundred bytes, this
+// bounds how much of the DOS stub is read to find it
+const maxRichHeaderOffset = 4096
+
+// RichEntry is a line of the Rich header: how many object files
+// (or imported functions, for ProductID 1) a tool version produced
+type RichEntry struct {
+	// Identifies the tool and its Visual Studio version, for example
+	// 0x105 for the C++ compiler of Visual Studio 2015 and later
+	ProductID uint16
+	// Build number of the tool, for example 30159
+	Build uint16
+	Count uint32
+}
+
+// RichHeader is the undocumented header the Microsoft linker writes
+// between the DOS stub and the PE signature. It lists the tools that
+// produced the object files that were linked, and is XOR-ed with a
+// checksum of itself and of the DOS header.
+type RichHeader struct {
+	// File offset of the header (where "DanS" is, once decoded)
+	Offset int64
+	// XOR key, which is also the checksum
+	Key     uint32
+	Entries []RichEntry
+	// Set if Key matches the checksum, which it doesn't
+	// if the header or the DOS header were tampered with
+	ChecksumValid bool
+}
+
+// RichHeader returns the Rich header of f, or nil if it has none,
+// which is the case of binaries built by other linkers than Microsoft's.
+func (f *File) RichHeader() (*RichHeader, error) {
+	// base is 0 for object files, otherwise it's after the PE signature
+	end := f.base - 4
+	if end <= 0 {
+		return nil, nil
+	}
+	if end > maxRichHeaderOffset {
+		end = maxRichHeaderOffset
+	}
+	stub := make([]byte, end)
+	_, err := f.readerAt.ReadAt(stub, 0)
+	if err != nil {
+		return nil, errors.WithMessage(err, "while reading DOS stub")
+	}
+
+	richOffset := bytes.LastIndex(stub, []byte("Rich"))
+	if richOffset < 0 || richOffset+8 > len(stub) {
+		return nil, nil
+	}
+	key := binary.LittleEndian.Uint32(stub[richOffset+4:])
+
+	// walk back to "DanS", which is followed by 3 padding dwords
+	dansOffset := -1
+	for off := richOffset - 4; off >= 0; off -= 4 {
+		if binary.LittleEndian.Uint32(stub[off:])^key == 0x536e6144 {
+			dansOffset = off
+			break
+		}
+	}
+	if dansOffset < 0 || dansOffset+16 > richOffset {
+		return nil, errors.Errorf("Rich header at 0x%x has no start marker", richOffset)
+	}
+
+	rh := &RichHeader{
+		Offset: int64(dansOffset),
+		Key:    key,
+	}
+	for off := dansOffset + 16; off+8 <= richOffset; off += 8 {
+		compID := binary.LittleEndian.Uint32(stub[off:]) ^ key
+		rh.Entries = append(rh.Entries, RichEntry{
+			ProductID: uint16(compID >> 16),
+			Build:     uint16(compID),
+			Count:     binary.LittleEndian.Uint32(stub[off+4:]) ^ key,
+		})
+	}
+	rh.ChecksumValid = richChecksum(stub[:dansOffset], rh.Entries) == key
+	return rh, nil
+}
+
+// richChecksum computes the checksum the Microsoft linker uses as the
+// key of the Rich header, from the bytes that precede it, minus e_lfanew
+func richChecksum(dosStub []byte, entries []RichEntry) uint32 {
+	sum := uint32(len(dosStub))
+	for i, b := range dosStub {
+		if i >= 0x3c && i < 0x40 {
+			continue
+		}
+		sum += bits.RotateLeft32(uint32(b), i)
+	}
+	for _, e := range entries {
+		compID := uint32(e.ProductID)<<16 | uint32(e.Build)
+		sum += bits.RotateLeft32(compID, int(e.Count%32))
+	}
+	return sum
+}
@@ -39,7 +39,23 @@ func (sh *SectionHeader32) fullName(st StringTable) (string, error) {
 	return st.String(uint32(i))
 }
 
-// TODO(brainman): copy all IMAGE_REL_* consts from ldpe.go here
+// Relocation types (Reloc.Type) that can appear in debug sections
+const (
+	IMAGE_REL_I386_DIR32     = 0x0006
+	IMAGE_REL_I386_DIR32NB   = 0x0007
+	IMAGE_REL_I386_SECREL    = 0x000B
+	IMAGE_REL_AMD64_ADDR64   = 0x0001
+	IMAGE_REL_AMD64_ADDR32   = 0x0002
+	IMAGE_REL_AMD64_ADDR32NB = 0x0003
+	IMAGE_REL_AMD64_SECREL   = 0x000B
+	IMAGE_REL_ARM64_ADDR32   = 0x0001
+	IMAGE_REL_ARM64_ADDR32NB = 0x0002
+	IMAGE_REL_ARM64_SECREL   = 0x0008
+	IMAGE_REL_ARM64_ADDR64   = 0x000E
+	IMAGE_REL_ARM_ADDR32     = 0x0001
+	IMAGE_REL_ARM_ADDR32NB   = 0x0002
+	IMAGE_REL_ARM_SECREL     = 0x000F
+)
 
 // Reloc represents a PE COFF relocation.
 // Each section contains its own relocation list.
@@ -55,16 +71,56 @@ func readRelocs(sh *SectionHeader, r io.ReadSeeker) ([]Reloc, error) {
 	}
 	_, err := r.Seek(int64(sh.PointerToRelocations), seekStart)
 	if err != nil {
-		return nil, fmt.Errorf("fail to seek to %q section relocations: %v", sh.Name, err)
+		return nil, fmt.Errorf("fail to seek to %q section relocations: %w", sh.Name, err)
 	}
 	relocs := make([]Reloc, sh.NumberOfRelocations)
 	err = binary.Read(r, binary.LittleEndian, relocs)
 	if err != nil {
-		return nil, fmt.Errorf("fail to read section relocations: %v", err)
+		return nil, fmt.Errorf("fail to read section relocations: %w", err)
 	}
 	return relocs, nil
 }
 
+// LineNumber represents a COFF line number record (IMAGE_LINENUMBER).
+// Records are grouped by function: the first record of each group has
+// a Linenumber of 0 and a Type holding the symbol table index of the
+// function. In the following ones, Type is a virtual address and
+// Linenumber is relative to the start of the function, starting at 1.
+//
+// Line numbers are deprecated, and only older toolchains emit them.
+type LineNumber struct {
+	Type       uint32
+	Linenumber uint16
+}
+
+const sizeofLineNumber = 6
+
+// IsFunction returns true if ln starts the records of a function,
+// in which case ln.Type is a symbol table index.
+func (ln LineNumber) IsFunction() bool {
+	return ln.Linenumber == 0
+}
+
+// LineNumbers reads and returns the COFF line number records of s,
+// or nil if it has none.
+func (f *File) LineNumbers(s *Section) ([]LineNumber, error) {
+	if s.NumberOfLineNumbers == 0 || s.PointerToLineNumbers == 0 {
+		return nil, nil
+	}
+	offset := int64(s.PointerToLineNumbers)
+	size := int64(s.NumberOfLineNumbers) * sizeofLineNumber
+	if offset+size > f.size {
+		return nil, fmt.Errorf("line numbers of section %s are out of bounds (%d bytes at %d)", s.Name, size, offset)
+	}
+
+	lns := make([]LineNumber, s.NumberOfLineNumbers)
+	err := binary.Read(io.NewSectionReader(f.readerAt, offset, size), binary.LittleEndian, lns)
+	if err != nil {
+		return nil, fmt.Errorf("fail to read %q section line numbers: %w", s.Name, err)
+	}
+	return lns, nil
+}
+
 // SectionHeader is similar to SectionHeader32 with Name
 // field replaced by Go string.
 type SectionHeader struct {
@@ -84,6 +140,15 @@ type SectionHeader struct {
 type Section struct {
 	SectionHeader
 	Relocs []Reloc
+	// PointerToRawData, as written in the section header. SectionHeader.Offset
+	// is where the loader actually reads the raw data from, which differs
+	// when the image doesn't follow the alignment rules, see
+	// File.LowAlignment.
+	DeclaredOffset uint32
+	// SizeOfRawData, as written in the section header. SectionHeader.Size
+	// is less when the raw data extends past the end of the file, see
+	// Truncated.
+	DeclaredSize uint32
 
 	// Embed ReaderAt for ReadAt method.
 	// Do not embed SectionReader directly
@@ -95,7 +160,15 @@ type Section struct {
 	sr *io.SectionReader
 }
 
+// MaxSectionDataSize is the largest section (or decompressed section)
+// the DWARF functions read into memory. Sections of large game
+// executables can be hundreds of megabytes. Data doesn't enforce it.
+var MaxSectionDataSize int64 = 256 * 1024 * 1024
+
 // Data reads and returns the contents of the PE section s.
+//
+// Deprecated: Data reads the whole section into memory, which sections of
+// large executables don't fit in. Use Open or DataRange instead.
 func (s *Section) Data() ([]byte, error) {
 	dat := make([]byte, s.sr.Size())
 	n, err := s.sr.ReadAt(dat, 0)
@@ -105,7 +178,30 @@ func (s *Section) Data() ([]byte, error) {
 	return dat[0:n], err
 }
 
-// Open returns a new ReadSeeker reading the PE section s.
+// DataRange reads and returns length bytes of the PE section s,
+// starting at offset (relative to the start of the section).
+func (s *Section) DataRange(offset int64, length int64) ([]byte, error) {
+	if offset < 0 || length < 0 || offset+length > s.sr.Size() {
+		return nil, fmt.Errorf("range [%d, %d) out of bounds of section %s (%d bytes)", offset, offset+length, s.Name, s.sr.Size())
+	}
+
+	dat := make([]byte, length)
+	_, err := s.sr.ReadAt(dat, offset)
+	if err != nil {
+		return nil, err
+	}
+	return dat, nil
+}
+
+// Truncated returns true if the raw data of s extends past the end of
+// the file (interrupted download, sparse upload, etc.). Only the part
+// that's in the file can be read, Size is clamped to it.
+func (s *Section) Truncated() bool {
+	return s.Size < s.DeclaredSize
+}
+
+// Open returns a new ReadSeeker reading the PE section s,
+// without reading it all into memory.
 func (s *Section) Open() io.ReadSeeker {
-	return io.NewSectionReader(s.sr, 0, 1<<63-1)
+	return io.NewSectionReader(s.sr, 0, s.sr.Size())
 }
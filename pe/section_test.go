@@ -0,0 +1,43 @@
+package pe_test
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/itchio/pelican/pe"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_SectionData(t *testing.T) {
+	f, err := os.Open("../testdata/hello/hello32-msvc.exe")
+	assert.NoError(t, err)
+	defer f.Close()
+	stats, err := f.Stat()
+	assert.NoError(t, err)
+	pf, err := pe.NewFile(f, stats.Size())
+	assert.NoError(t, err)
+
+	text := pf.Section(".text")
+	data, err := text.Data()
+	assert.NoError(t, err)
+	assert.Len(t, data, int(text.Size))
+
+	// Open and DataRange read the same bytes, without going past the section
+	opened, err := ioutil.ReadAll(text.Open())
+	assert.NoError(t, err)
+	assert.EqualValues(t, data, opened)
+	end, err := text.Open().Seek(0, io.SeekEnd)
+	assert.NoError(t, err)
+	assert.EqualValues(t, text.Size, end)
+
+	chunk, err := text.DataRange(16, 32)
+	assert.NoError(t, err)
+	assert.EqualValues(t, data[16:48], chunk)
+
+	_, err = text.DataRange(int64(text.Size)-8, 16)
+	assert.Error(t, err)
+	_, err = text.DataRange(-1, 8)
+	assert.Error(t, err)
+}
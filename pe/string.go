@@ -32,12 +32,12 @@ func readStringTable(f *File, fh *FileHeader, r io.ReadSeeker) (StringTable, err
 	offset := fh.PointerToSymbolTable + COFFSymbolSize*fh.NumberOfSymbols
 	_, err := r.Seek(int64(offset), io.SeekStart)
 	if err != nil {
-		return nil, fmt.Errorf("fail to seek to string table: %v", err)
+		return nil, fmt.Errorf("fail to seek to string table: %w", err)
 	}
 	var l uint32
 	err = binary.Read(r, binary.LittleEndian, &l)
 	if err != nil {
-		return nil, fmt.Errorf("fail to read string table length: %v", err)
+		return nil, fmt.Errorf("fail to read string table length: %w", err)
 	}
 
 	var end int64 = int64(offset) + int64(l)
@@ -54,7 +54,7 @@ func readStringTable(f *File, fh *FileHeader, r io.ReadSeeker) (StringTable, err
 	buf := make([]byte, l)
 	_, err = io.ReadFull(r, buf)
 	if err != nil {
-		return nil, fmt.Errorf("fail to read string table: %v", err)
+		return nil, fmt.Errorf("fail to read string table: %w", err)
 	}
 	return StringTable(buf), nil
 }
@@ -31,12 +31,12 @@ func readCOFFSymbols(f *File, fh *FileHeader, r io.ReadSeeker) ([]COFFSymbol, er
 	}
 	_, err := r.Seek(int64(fh.PointerToSymbolTable), seekStart)
 	if err != nil {
-		return nil, fmt.Errorf("fail to seek to symbol table: %v", err)
+		return nil, fmt.Errorf("fail to seek to symbol table: %w", err)
 	}
 	syms := make([]COFFSymbol, fh.NumberOfSymbols)
 	err = binary.Read(r, binary.LittleEndian, syms)
 	if err != nil {
-		return nil, fmt.Errorf("fail to read symbol table: %v", err)
+		return nil, fmt.Errorf("fail to read symbol table: %w", err)
 	}
 	return syms, nil
 }
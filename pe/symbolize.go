@@ -0,0 +1,97 @@
+package pe
+
+import "sort"
+
+// Symbol storage classes (Symbol.StorageClass)
+const (
+	IMAGE_SYM_CLASS_EXTERNAL = 2
+	IMAGE_SYM_CLASS_STATIC   = 3
+	IMAGE_SYM_CLASS_LABEL    = 6
+	IMAGE_SYM_CLASS_FUNCTION = 101
+	IMAGE_SYM_CLASS_FILE     = 103
+)
+
+// SymbolSection returns the section sym is defined in, or nil for
+// undefined, absolute and debugging symbols.
+func (f *File) SymbolSection(sym *Symbol) *Section {
+	if sym.SectionNumber <= 0 || int(sym.SectionNumber) > len(f.Sections) {
+		return nil
+	}
+	return f.Sections[sym.SectionNumber-1]
+}
+
+// SymbolRVA returns the relative virtual address of sym, and false if
+// it's not defined in a section. In object files, sections all start at 0.
+func (f *File) SymbolRVA(sym *Symbol) (uint32, bool) {
+	s := f.SymbolSection(sym)
+	if s == nil {
+		return 0, false
+	}
+	return s.VirtualAddress + sym.Value, true
+}
+
+// isAddressSymbol returns true for symbols that name a location in code
+// or data, as opposed to section definitions, .bf/.ef markers, files, etc.
+func (f *File) isAddressSymbol(sym *Symbol) bool {
+	s := f.SymbolSection(sym)
+	if s == nil {
+		return false
+	}
+
+	switch sym.StorageClass {
+	case IMAGE_SYM_CLASS_EXTERNAL, IMAGE_SYM_CLASS_LABEL:
+		return true
+	case IMAGE_SYM_CLASS_STATIC:
+		// section definitions are static symbols named after their section
+		return !(sym.Value == 0 && sym.Name == s.Name)
+	default:
+		return false
+	}
+}
+
+// NearestSymbol returns the symbol at or immediately before rva in the
+// same section, and the offset of rva from it, for example to symbolize
+// a crash address as "main+0x1c". It returns nil if there is none, for
+// instance if the file is stripped.
+//
+// In object files, where all sections start at 0, rva is ambiguous.
+func (f *File) NearestSymbol(rva uint32) (*Symbol, uint32) {
+	f.symbolIndexOnce.Do(func() {
+		for _, sym := range f.Symbols {
+			if f.isAddressSymbol(sym) {
+				f.symbolIndex = append(f.symbolIndex, sym)
+			}
+		}
+		sort.SliceStable(f.symbolIndex, func(i, j int) bool {
+			ri, _ := f.SymbolRVA(f.symbolIndex[i])
+			rj, _ := f.SymbolRVA(f.symbolIndex[j])
+			return ri < rj
+		})
+	})
+
+	// index of the first symbol after rva
+	i := sort.Search(len(f.symbolIndex), func(i int) bool {
+		r, _ := f.SymbolRVA(f.symbolIndex[i])
+		return r > rva
+	})
+
+	if i == 0 {
+		return nil, 0
+	}
+
+	sym := f.symbolIndex[i-1]
+	s := f.SymbolSection(sym)
+	if uint64(rva) >= uint64(s.VirtualAddress)+uint64(max32(s.VirtualSize, s.Size)) {
+		// rva is past the end of that symbol's section
+		return nil, 0
+	}
+	symRVA, _ := f.SymbolRVA(sym)
+	return sym, rva - symRVA
+}
+
+func max32(a, b uint32) uint32 {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,108 @@
+package pe
+
+import (
+	"encoding/binary"
+
+	"github.com/pkg/errors"
+)
+
+// size of IMAGE_TLS_DIRECTORY32 and IMAGE_TLS_DIRECTORY64
+const (
+	sizeofTLSDirectory32 = 24
+	sizeofTLSDirectory64 = 40
+)
+
+// callback arrays are short, this bounds how much is
+// read when the terminating null is missing
+const maxTLSCallbacks = 1024
+
+// TLSDirectory is the thread-local storage directory
+// (IMAGE_TLS_DIRECTORY32 or IMAGE_TLS_DIRECTORY64, widened)
+type TLSDirectory struct {
+	// Virtual addresses of the template the TLS of each thread is
+	// initialized with
+	StartAddressOfRawData uint64
+	EndAddressOfRawData   uint64
+	// Virtual address of the variable the loader stores the TLS index in
+	AddressOfIndex uint64
+	// Virtual address of the null-terminated array of callbacks
+	AddressOfCallBacks uint64
+	SizeOfZeroFill     uint32
+	Characteristics    uint32
+
+	// RVAs of the functions in the AddressOfCallBacks array. The loader
+	// calls them before the entry point, which packers and anti-debugging
+	// code take advantage of. They can also be added at runtime, by
+	// writing to the array.
+	Callbacks []uint32
+}
+
+// TLSDirectory reads the thread-local storage directory, and the
+// callbacks it lists. It returns nil if f has none.
+func (f *File) TLSDirectory() (*TLSDirectory, error) {
+	dd := f.dataDirectory(IMAGE_DIRECTORY_ENTRY_TLS)
+	if dd.VirtualAddress == 0 {
+		return nil, nil
+	}
+
+	oh := f.OptionalHeaderFields()
+	size := int64(sizeofTLSDirectory32)
+	ptrSize := int64(4)
+	if oh.PE64 {
+		size = sizeofTLSDirectory64
+		ptrSize = 8
+	}
+	readPtr := func(b []byte) uint64 {
+		if ptrSize == 8 {
+			return binary.LittleEndian.Uint64(b)
+		}
+		return uint64(binary.LittleEndian.Uint32(b))
+	}
+
+	rr := newRVAReader(f)
+	data, err := rr.slice(dd.VirtualAddress, size)
+	if err != nil {
+		return nil, errors.WithMessage(err, "while reading TLS directory")
+	}
+
+	td := &TLSDirectory{
+		StartAddressOfRawData: readPtr(data[0:]),
+		EndAddressOfRawData:   readPtr(data[ptrSize:]),
+		AddressOfIndex:        readPtr(data[2*ptrSize:]),
+		AddressOfCallBacks:    readPtr(data[3*ptrSize:]),
+		SizeOfZeroFill:        binary.LittleEndian.Uint32(data[4*ptrSize:]),
+		Characteristics:       binary.LittleEndian.Uint32(data[4*ptrSize+4:]),
+	}
+	if td.AddressOfCallBacks == 0 {
+		return td, nil
+	}
+
+	toRVA := func(va uint64) (uint32, error) {
+		if va < oh.ImageBase || va-oh.ImageBase >= uint64(oh.SizeOfImage) {
+			return 0, errors.Errorf("address 0x%x is outside of the image", va)
+		}
+		return uint32(va - oh.ImageBase), nil
+	}
+
+	rva, err := toRVA(td.AddressOfCallBacks)
+	if err != nil {
+		return nil, errors.WithMessage(err, "while reading TLS callbacks")
+	}
+	for len(td.Callbacks) < maxTLSCallbacks && rr.available(rva) >= ptrSize {
+		b, err := rr.slice(rva, ptrSize)
+		if err != nil {
+			return nil, errors.WithMessage(err, "while reading TLS callbacks")
+		}
+		va := readPtr(b)
+		if va == 0 {
+			break
+		}
+		callback, err := toRVA(va)
+		if err != nil {
+			return nil, errors.WithMessage(err, "while reading TLS callbacks")
+		}
+		td.Callbacks = append(td.Callbacks, callback)
+		rva += uint32(ptrSize)
+	}
+	return td, nil
+}
@@ -0,0 +1,165 @@
+package pelican
+
+import (
+	"io"
+	"sort"
+
+	"github.com/itchio/pelican/pe"
+)
+
+// PE headers (DOS stub, COFF header, optional header and section table)
+// almost always fit in this, see IMAGE_OPTIONAL_HEADER.SizeOfHeaders
+const prefetchHeadersSize = 4 * 1024
+
+// the resource directory's size covers the resource data as well, but
+// only the tree structure at the start is needed before parsing
+const prefetchResourceSize = 64 * 1024
+
+// if all regions fit in a span this large, they're fetched with a
+// single read, otherwise with one read per group of nearby regions
+const maxPrefetchSpan = 1024 * 1024
+
+// regions less than this far apart are fetched together
+const prefetchGap = 64 * 1024
+
+// data directories read early on, in that order, by probe
+var prefetchedDataDirectories = []int{
+	pe.IMAGE_DIRECTORY_ENTRY_IMPORT,
+	pe.IMAGE_DIRECTORY_ENTRY_IAT,
+	pe.IMAGE_DIRECTORY_ENTRY_RESOURCE,
+	pe.IMAGE_DIRECTORY_ENTRY_DEBUG,
+}
+
+type prefetchBlock struct {
+	offset int64
+	data   []byte
+}
+
+// prefetchReaderAt serves reads from blocks fetched in advance when
+// they're fully contained in one, and from r otherwise. Parsing issues
+// lots of small reads, which is slow on high-latency storage (HTTP).
+type prefetchReaderAt struct {
+	r      io.ReaderAt
+	size   int64
+	blocks []prefetchBlock
+}
+
+func newPrefetchReaderAt(r io.ReaderAt, size int64) *prefetchReaderAt {
+	pr := &prefetchReaderAt{r: r, size: size}
+	pr.fetch(0, prefetchHeadersSize)
+	return pr
+}
+
+func (pr *prefetchReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	for _, b := range pr.blocks {
+		if off >= b.offset && off+int64(len(p)) <= b.offset+int64(len(b.data)) {
+			return copy(p, b.data[off-b.offset:]), nil
+		}
+	}
+	return pr.r.ReadAt(p, off)
+}
+
+// fetch reads a region into a block. Errors are ignored: the region
+// will be read again (and the error reported) when it's actually needed.
+func (pr *prefetchReaderAt) fetch(offset int64, length int64) {
+	if offset+length > pr.size {
+		length = pr.size - offset
+	}
+	if length <= 0 {
+		return
+	}
+
+	data := make([]byte, length)
+	n, _ := pr.r.ReadAt(data, offset)
+	if n > 0 {
+		pr.blocks = append(pr.blocks, prefetchBlock{offset: offset, data: data[:n]})
+	}
+}
+
+type fileRange struct {
+	start int64
+	end   int64
+}
+
+// prefetchGroups returns the regions of pf that are parsed first, as
+// read by prefetch: a single one if they all fit in maxPrefetchSpan,
+// otherwise one per group of nearby regions
+func prefetchGroups(pf *pe.File) []fileRange {
+	ranges := prefetchPlan(pf)
+	if len(ranges) == 0 {
+		return nil
+	}
+
+	if ranges[len(ranges)-1].end-ranges[0].start <= maxPrefetchSpan {
+		return []fileRange{{start: ranges[0].start, end: ranges[len(ranges)-1].end}}
+	}
+
+	var groups []fileRange
+	group := ranges[0]
+	for _, fr := range ranges[1:] {
+		if fr.start-group.end <= prefetchGap {
+			if fr.end > group.end {
+				group.end = fr.end
+			}
+			continue
+		}
+		groups = append(groups, group)
+		group = fr
+	}
+	return append(groups, group)
+}
+
+// prefetchSize returns how many bytes prefetching groups allocates, at most
+func prefetchSize(groups []fileRange) int64 {
+	var size int64
+	for _, g := range groups {
+		size += g.end - g.start
+	}
+	return size
+}
+
+// prefetch fetches groups, see prefetchGroups
+func (pr *prefetchReaderAt) prefetch(groups []fileRange) {
+	for _, g := range groups {
+		pr.fetch(g.start, g.end-g.start)
+	}
+}
+
+// prefetchPlan returns the file ranges of prefetchedDataDirectories,
+// sorted by offset
+func prefetchPlan(pf *pe.File) []fileRange {
+	dirs := dataDirectories(pf)
+
+	var ranges []fileRange
+	for _, index := range prefetchedDataDirectories {
+		if index >= len(dirs) || dirs[index].Size == 0 {
+			continue
+		}
+		dd := dirs[index]
+
+		size := int64(dd.Size)
+		if index == pe.IMAGE_DIRECTORY_ENTRY_RESOURCE && size > prefetchResourceSize {
+			size = prefetchResourceSize
+		}
+
+		for _, s := range pf.Sections {
+			start := uint64(s.VirtualAddress)
+			end := start + uint64(s.Size)
+			if uint64(dd.VirtualAddress) < start || uint64(dd.VirtualAddress) >= end {
+				continue
+			}
+
+			offset := int64(s.Offset) + int64(uint64(dd.VirtualAddress)-start)
+			if max := int64(s.Offset) + int64(s.Size); offset+size > max {
+				size = max - offset
+			}
+			ranges = append(ranges, fileRange{start: offset, end: offset + size})
+			break
+		}
+	}
+
+	sort.Slice(ranges, func(i, j int) bool {
+		return ranges[i].start < ranges[j].start
+	})
+	return ranges
+}
@@ -0,0 +1,60 @@
+package pelican
+
+import (
+	"os"
+	"testing"
+
+	"github.com/itchio/pelican/pe"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Prefetch(t *testing.T) {
+	f, err := os.Open("./testdata/pidgin/pidgin-uninst.exe")
+	assert.NoError(t, err)
+	defer f.Close()
+
+	stats, err := f.Stat()
+	assert.NoError(t, err)
+
+	reads := 0
+	r := readerAtFunc(func(p []byte, off int64) (int, error) {
+		reads++
+		return f.ReadAt(p, off)
+	})
+
+	params := &ProbeParams{Strict: true}
+	info, err := params.probe(r, stats.Size())
+	assert.NoError(t, err)
+	assert.EqualValues(t, "Pidgin", info.VersionProperties["ProductName"])
+
+	// headers, one batch for the directories, then
+	// whole sections for imports, resources and data scans
+	assert.True(t, reads <= 10, "%d reads", reads)
+}
+
+func Test_PrefetchSize(t *testing.T) {
+	for _, path := range []string{
+		"./testdata/pidgin/pidgin-uninst.exe",
+		"./testdata/resourceful/resourceful64-mingw.exe",
+	} {
+		f, err := os.Open(path)
+		assert.NoError(t, err)
+		defer f.Close()
+		stats, err := f.Stat()
+		assert.NoError(t, err)
+
+		pf, err := pe.NewFile(f, stats.Size())
+		assert.NoError(t, err)
+
+		// what's reserved covers what's allocated
+		pr := &prefetchReaderAt{r: f, size: stats.Size()}
+		groups := prefetchGroups(pf)
+		assert.NotEmpty(t, groups, path)
+		pr.prefetch(groups)
+		var allocated int64
+		for _, b := range pr.blocks {
+			allocated += int64(cap(b.data))
+		}
+		assert.EqualValues(t, prefetchSize(groups), allocated, path)
+	}
+}
@@ -1,6 +1,10 @@
 package pelican
 
 import (
+	"context"
+	"io"
+	"strings"
+
 	"github.com/itchio/pelican/pe"
 
 	"github.com/itchio/headway/state"
@@ -13,52 +17,419 @@ type ProbeParams struct {
 	// Return errors instead of printing warnings when
 	// we can't parse some parts of the file
 	Strict bool
+	// Guess whether Windows will elevate the executable even
+	// though its manifest doesn't ask for it, see PeInfo.RequiresElevationHeuristic
+	ElevationHeuristics bool
+	// If set, called with the raw contents of every non-empty data directory
+	// pelican doesn't parse itself (COM+ descriptor, architecture-specific, etc.).
+	// Not called for results returned from Cache.
+	OnUnknownDataDirectory DataDirectoryFunc
+	// If set, results are looked up in (and stored into) this cache,
+	// keyed by the SHA-256 of the file and the parameters that change
+	// results, see CacheKey. Note that hashing requires reading the
+	// whole file.
+	Cache Cache
+	// If set, failed reads are retried according to this policy,
+	// see DefaultRetryPolicy
+	RetryPolicy *RetryPolicy
+	// If positive, an approximate limit (in bytes) on the memory a probe
+	// allocates for optional analyses (data section scans, large resources,
+	// data directories, prefetching). Those that would exceed it are skipped
+	// with a warning, even in strict mode, and the result isn't stored in
+	// Cache. Useful for batch scans.
+	MaxMemory int64
+	// Compute PeInfo.Entropy, which requires reading the whole file,
+	// unless EntropySampleSize is set
+	Entropy bool
+	// If positive, the entropy of files larger than this is estimated by
+	// only reading about this many bytes, in fixed-size windows spread
+	// across the file. Useful for batch scans of multi-GB files.
+	EntropySampleSize int64
+	// Replace values that may be sensitive with hashes, see PeInfo.Redacted.
+	// Results are stored in Cache unredacted.
+	Redact bool
+	// If set, ProbeDir and StreamDir stop before the next file once it's
+	// done, and return its error. A file being probed is always finished.
+	Context context.Context
+	// If set, StreamDir skips the files it records as processed (unless
+	// they changed since), and records those it processes, see OpenCheckpoint.
+	// Files that can't be probed aren't recorded, so they're retried.
+	Checkpoint *Checkpoint
+	// Custom analyses, keyed by the name their results are stored under
+	// in PeInfo.Extensions. Names should be namespaced, like
+	// "example.com/drm", so they don't clash. Those missing from results
+	// returned from Cache are run, but results aren't refreshed otherwise.
+	Extensions map[string]ExtensionFunc
+	// If set, icons and manifests are extracted to this directory, named
+	// after their SHA-256 (so identical files are only written once), and
+	// listed in PeInfo.Assets. Useful to ingest a catalog: the directory
+	// can be uploaded to a CDN as-is. Assets are never cached.
+	AssetDir string
+	// Names of functions imported by ordinal, checked before
+	// DefaultOrdinalNames. Results are cached separately for each
+	// set of names, see CacheKey.
+	OrdinalNames OrdinalNames
+
+	// see reserve
+	memoryUsed int64
+	// first I/O error tolerated as a warning, see noteIOError
+	ioErr error
+}
+
+// DefaultConsumer receives messages when ProbeParams.Consumer is nil.
+// It's nil itself by default, which discards them. See SlogConsumer.
+var DefaultConsumer *state.Consumer
+
+func (params *ProbeParams) setDefaults() {
+	if params.Consumer == nil {
+		params.Consumer = DefaultConsumer
+	}
+	if params.Context == nil {
+		params.Context = context.Background()
+	}
 }
 
 // Probe retrieves information about an PE file
 func Probe(file eos.File, params ProbeParams) (*PeInfo, error) {
+	params.setDefaults()
 	consumer := params.Consumer
 
 	stats, err := file.Stat()
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
+	r := params.readerAt(file)
+
+	var info *PeInfo
+	var cacheKey CacheKey
+	if params.Cache != nil {
+		cacheKey, err = params.cacheKeyFor(r, stats.Size())
+		if err != nil {
+			return nil, errors.WithMessage(err, "while hashing file")
+		}
+
+		info, err = params.Cache.Get(cacheKey)
+		if err != nil {
+			consumer.Warnf("Could not read cached probe result: %+v", err)
+		} else if info != nil {
+			consumer.Debugf("Using cached probe result (%s)", cacheKey)
+		}
+	}
+
+	if info == nil {
+		info, err = params.probe(r, stats.Size())
+		if err != nil {
+			return nil, err
+		}
+		if params.ioErr != nil {
+			return nil, errors.WithMessage(params.ioErr, "while reading file")
+		}
+
+		if params.Cache != nil {
+			if info.hasWarning(WarningMemoryBudgetExceeded) {
+				// some analyses were skipped, probing again with a larger
+				// budget must not return this result
+				consumer.Debugf("Not caching probe result, it exceeded the memory budget")
+			} else {
+				err = params.Cache.Put(cacheKey, info)
+				if err != nil {
+					consumer.Warnf("Could not store probe result in cache: %+v", err)
+				}
+			}
+		}
+	} else if (params.Entropy && info.Entropy == nil) || params.missingExtensions(info) {
+		// the cached result was computed without them
+		pf, err := pe.NewFile(r, stats.Size())
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+		if params.Entropy && info.Entropy == nil {
+			err = params.probeEntropy(info, r, stats.Size(), pf)
+			if err != nil {
+				return nil, err
+			}
+		}
+		err = params.runExtensions(info, pf, true)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// these depend on the file name, so they're never cached
+	info.Kind = refineKind(info.Kind, stats.Name())
+	info.ElevationReasons = nil
+	if params.ElevationHeuristics {
+		info.ElevationReasons = guessElevation(info, stats.Name())
+	}
+
+	info.Assets = nil
+	if params.AssetDir != "" {
+		info.Assets, err = params.writeAssets(file)
+		if err != nil {
+			if params.Strict {
+				return nil, err
+			}
+			consumer.Warnf("Could not write assets: %+v", err)
+		}
+	}
+
+	if params.Redact {
+		info = info.Redacted()
+	}
 
-	pf, err := pe.NewFile(file, stats.Size())
+	return info, nil
+}
+
+func (params *ProbeParams) probe(r io.ReaderAt, size int64) (*PeInfo, error) {
+	file := newPrefetchReaderAt(r, size)
+	pf, err := pe.NewFile(file, size)
 	if err != nil {
 		return nil, errors.WithStack(err)
 	}
+	prefetched := prefetchGroups(pf)
+	if size := prefetchSize(prefetched); params.reserve(size) {
+		file.prefetch(prefetched)
+		defer params.release(size)
+	}
 
 	info := &PeInfo{
+		Size:              size,
 		VersionProperties: make(map[string]string),
 	}
 
+	info.HeadersSHA256, err = headersHash(file, pf, size)
+	if err != nil {
+		return nil, errors.WithMessage(err, "while hashing headers")
+	}
+
+	err = params.checkTruncatedSections(info, pf)
+	if err != nil {
+		return nil, err
+	}
+	params.checkAlignment(info, pf)
+
 	switch pf.Machine {
 	case pe.IMAGE_FILE_MACHINE_I386:
-		info.Arch = "386"
+		info.Arch = Arch386
 	case pe.IMAGE_FILE_MACHINE_AMD64:
-		info.Arch = "amd64"
+		info.Arch = ArchAmd64
+	case pe.IMAGE_FILE_MACHINE_ARM64:
+		info.Arch = ArchArm64
+	case pe.IMAGE_FILE_MACHINE_ARMNT:
+		info.Arch = ArchArm
+	}
+
+	switch pf.OptionalHeaderFields().Subsystem {
+	case pe.IMAGE_SUBSYSTEM_UNKNOWN:
+		info.Subsystem = SubsystemUnknown
+	case pe.IMAGE_SUBSYSTEM_WINDOWS_GUI:
+		info.Subsystem = SubsystemGUI
+	case pe.IMAGE_SUBSYSTEM_WINDOWS_CUI:
+		info.Subsystem = SubsystemConsole
+	case pe.IMAGE_SUBSYSTEM_NATIVE:
+		info.Subsystem = SubsystemNative
+	case pe.IMAGE_SUBSYSTEM_EFI_APPLICATION, pe.IMAGE_SUBSYSTEM_EFI_BOOT_SERVICE_DRIVER,
+		pe.IMAGE_SUBSYSTEM_EFI_RUNTIME_DRIVER, pe.IMAGE_SUBSYSTEM_EFI_ROM:
+		info.Subsystem = SubsystemEFI
+	default:
+		info.Subsystem = SubsystemOther
+	}
+
+	info.EntryPointStub = classifyEntryPoint(info, pf)
+
+	err = params.parseRichHeader(info, pf)
+	if err != nil {
+		return nil, err
+	}
+
+	err = params.parseTLS(info, pf)
+	if err != nil {
+		return nil, err
+	}
+
+	err = params.parseCodeView(info, pf)
+	if err != nil {
+		return nil, err
+	}
+
+	err = params.parseLoadConfig(info, pf)
+	if err != nil {
+		return nil, err
+	}
+
+	exportedNames, err := params.classifyKind(info, pf)
+	if err != nil {
+		return nil, err
 	}
 
+	if params.OnUnknownDataDirectory != nil {
+		err = params.visitUnknownDataDirectories(info, file, size, pf)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	symbols, err := params.parseImports(info, pf)
+	if err != nil {
+		return nil, err
+	}
+
+	info.Protections = detectProtections(pf.Sections, pf.OptionalHeaderFields().AddressOfEntryPoint, info.TLSCallbacks, info.Imports, symbols)
+
+	err = params.probeResources(info, pf, ResourceTypeNone)
+	if err != nil {
+		return nil, err
+	}
+
+	info.CanonicalProductName = canonicalProductName(info.VersionProperties)
+	if lib := identifyLibrary(info); lib != nil {
+		info.BundledLibraries = append(info.BundledLibraries, lib)
+	}
+	detectCEF(info)
+	detectInstaller(info, pf.Sections)
+	if params.reserveOrWarn(info, pooledBufferSize, "data section scan") {
+		ls := newLibraryScanner()
+		is := newIndicatorScanner()
+		lns := newLauncherScanner()
+		err = scanDataSections(pf, ls.scan, is.scan, lns.scan)
+		params.release(pooledBufferSize)
+		if err != nil {
+			if params.Strict {
+				return nil, errors.WithMessage(err, "while scanning data sections")
+			}
+			params.warn(info, WarningSectionUnreadable, err, "Could not scan data sections")
+		}
+		info.BundledLibraries = append(info.BundledLibraries, ls.libraries...)
+		for _, bl := range ls.markerLibraries() {
+			info.BundledLibraries = appendMissingLibrary(info.BundledLibraries, bl)
+		}
+		info.Indicators = is.result()
+		if info.Indicators != nil {
+			info.Provenance = analyzeBuildPaths(info.Indicators.BuildPaths)
+		}
+		info.Launcher = detectLauncher(info, symbols, lns)
+	}
+	for _, bl := range exportedLibraries(exportedNames) {
+		info.BundledLibraries = appendMissingLibrary(info.BundledLibraries, bl)
+	}
+
+	detectDelphi(info, pf)
+	info.Compatibility = computeCompatibility(info, pf, symbols)
+	info.CRT = classifyCRT(info)
+	info.WineNotes = findWineNotes(info, symbols)
+	info.ArmEmulation = armEmulation(info)
+	info.Console = detectConsoleBehavior(info, pf, symbols)
+
+	err = ParseSignature(info, pf, *params)
+	if err != nil {
+		return nil, err
+	}
+
+	if params.Entropy {
+		err = params.probeEntropy(info, file, size, pf)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	err = params.runExtensions(info, pf, false)
+	if err != nil {
+		return nil, err
+	}
+
+	return info, nil
+}
+
+// checkTruncatedSections warns about sections whose raw data extends
+// past the end of the file. pe.File only reads what's there, so later
+// stages fail to parse what's missing, rather than the whole probe.
+func (params *ProbeParams) checkTruncatedSections(info *PeInfo, pf *pe.File) error {
+	for _, s := range pf.Sections {
+		if !s.Truncated() {
+			continue
+		}
+		if params.Strict {
+			return errors.Errorf("section %s is truncated: only %d of its %d bytes are in the file", s.Name, s.Size, s.DeclaredSize)
+		}
+		params.warn(info, WarningSectionTruncated, nil, "Section %s is truncated: only %d of its %d bytes are in the file", s.Name, s.Size, s.DeclaredSize)
+	}
+	return nil
+}
+
+// checkAlignment notes images whose sections aren't where their headers
+// say. pe.File locates them like the loader does, so that's not an error.
+func (params *ProbeParams) checkAlignment(info *PeInfo, pf *pe.File) {
+	if pf.LowAlignment() {
+		oh := pf.OptionalHeaderFields()
+		params.warn(info, WarningAlignmentNonstandard, nil, "Section alignment (0x%x) is less than a page, sections were read as mapped", oh.SectionAlignment)
+		return
+	}
+	for _, s := range pf.Sections {
+		if s.Offset != s.DeclaredOffset {
+			params.warn(info, WarningAlignmentNonstandard, nil, "Raw data of section %s is not aligned (0x%x), it was read from 0x%x", s.Name, s.DeclaredOffset, s.Offset)
+		}
+	}
+}
+
+// parseImports fills info.Imports and info.ImportMetrics, and returns the imported symbols
+// for the stages that need them
+func (params *ProbeParams) parseImports(info *PeInfo, pf *pe.File) ([]pe.ImportedSymbol, error) {
 	imports, err := pf.ImportedLibraries()
 	if err != nil {
 		if params.Strict {
 			return nil, errors.WithMessage(err, "while parsing imported libraries")
 		}
-		consumer.Warnf("Could not parse imported libraries: %+v", err)
+		params.warn(info, WarningImportsInvalid, err, "Could not parse imported libraries")
 	}
 	info.Imports = imports
+	info.IsDebugBuild = importsDebugCRT(imports)
+
+	delayImports, err := pf.DelayImportedLibraries()
+	if err != nil {
+		if params.Strict {
+			return nil, errors.WithMessage(err, "while parsing delay-loaded libraries")
+		}
+		params.warn(info, WarningImportsInvalid, err, "Could not parse delay-loaded libraries")
+	}
+	info.DelayImports = delayImports
+
+	symbols, err := pf.ImportedSymbols()
+	if err != nil {
+		if params.Strict {
+			return nil, errors.WithMessage(err, "while parsing imported symbols")
+		}
+		params.warn(info, WarningImportsInvalid, err, "Could not parse imported symbols")
+	}
+	symbols = params.resolveOrdinals(symbols)
+
+	// ordinals don't tell which functions are used without the
+	// exports of the library, so only names count
+	importedByName := make(map[string]bool)
+	for _, sym := range symbols {
+		if !sym.ByOrdinal() {
+			importedByName[strings.ToLower(sym.DLL)] = true
+		}
+	}
+	for _, lib := range imports {
+		if !importedByName[strings.ToLower(lib)] {
+			params.warn(info, WarningImportOrdinalOnly, nil, "%s is only imported by ordinal", lib)
+		}
+	}
+	info.ImportMetrics = importMetrics(info, pf, symbols)
 
-	sect := pf.Section(".rsrc")
-	if sect != nil {
-		err = params.parseResources(info, sect)
+	return symbols, nil
+}
+
+func (params *ProbeParams) probeResources(info *PeInfo, pf *pe.File, only ResourceType) error {
+	img := findResources(pf)
+	if img != nil {
+		err := params.parseResources(info, img, only)
 		if err != nil {
 			if params.Strict {
-				return nil, errors.WithMessage(err, "while parsing resources")
+				return errors.WithMessage(err, "while parsing resources")
 			}
-			consumer.Warnf("Could not parse resources: %+v", err)
+			params.warn(info, WarningResourceDirectoryInvalid, err, "Could not parse resources")
 		}
 	}
-
-	return info, nil
+	return nil
 }
@@ -1,11 +1,19 @@
 package pelican_test
 
 import (
+	"bytes"
+	"encoding/binary"
+	"encoding/gob"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
 	"testing"
+	"testing/fstest"
 
 	"github.com/itchio/headway/state"
 	"github.com/itchio/httpkit/eos"
 	"github.com/itchio/pelican"
+	"github.com/itchio/pelican/pe"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -40,6 +48,27 @@ func Test_Hello32Mingw(t *testing.T) {
 	info, err := pelican.Probe(f, testProbeParams(t))
 	assert.NoError(t, err)
 	assert.EqualValues(t, pelican.Arch386, info.Arch)
+	assert.EqualValues(t, pelican.StubMinGW, info.EntryPointStub)
+	assert.EqualValues(t, &pelican.CRTInfo{Linkage: pelican.CRTSystem, Libraries: []string{"msvcrt.dll"}}, info.CRT)
+	assert.False(t, info.Arch.Is64())
+	assert.EqualValues(t, 32, info.Arch.Bits())
+	assert.Nil(t, info.Delphi)
+}
+
+func Test_FakeDelphi(t *testing.T) {
+	f, err := eos.Open("./testdata/delphi/hello32-fake-delphi.exe")
+	assert.NoError(t, err)
+	defer f.Close()
+
+	info, err := pelican.Probe(f, testProbeParams(t))
+	assert.NoError(t, err)
+	// imports are still found through the import address table
+	assert.Contains(t, info.Imports, "msvcrt.dll")
+
+	d := info.Delphi
+	assert.NotNil(t, d)
+	assert.EqualValues(t, "2007 or earlier", d.Version)
+	assert.EqualValues(t, []string{"CODE/DATA/BSS sections", "imports without OriginalFirstThunk"}, d.Evidence)
 }
 
 func Test_Hello32Msvc(t *testing.T) {
@@ -50,6 +79,14 @@ func Test_Hello32Msvc(t *testing.T) {
 	info, err := pelican.Probe(f, testProbeParams(t))
 	assert.NoError(t, err)
 	assert.EqualValues(t, pelican.Arch386, info.Arch)
+	assert.EqualValues(t, pelican.StubMSVC, info.EntryPointStub)
+	assert.EqualValues(t, &pelican.CRTInfo{Linkage: pelican.CRTStatic}, info.CRT)
+
+	compat := info.Compatibility
+	assert.EqualValues(t, pelican.WindowsVista, compat.DeclaredMinVersion())
+	assert.EqualValues(t, pelican.WindowsXP, compat.ImportsMinVersion)
+	assert.Contains(t, compat.ImportsRequiring, "kernel32.dll!GetModuleHandleExW")
+	assert.EqualValues(t, "Windows Vista+ (declared)", compat.Summary)
 }
 
 func Test_Hello64Mingw(t *testing.T) {
@@ -60,6 +97,10 @@ func Test_Hello64Mingw(t *testing.T) {
 	info, err := pelican.Probe(f, testProbeParams(t))
 	assert.NoError(t, err)
 	assert.EqualValues(t, pelican.ArchAmd64, info.Arch)
+	assert.EqualValues(t, pelican.StubMinGW, info.EntryPointStub)
+	assert.True(t, info.Arch.Is64())
+	assert.EqualValues(t, 64, info.Arch.Bits())
+	assert.EqualValues(t, "amd64", info.Arch.String())
 }
 
 func Test_Hello64Msvc(t *testing.T) {
@@ -67,9 +108,24 @@ func Test_Hello64Msvc(t *testing.T) {
 	assert.NoError(t, err)
 	defer f.Close()
 
-	info, err := pelican.Probe(f, testProbeParams(t))
+	directories := make(map[int][]byte)
+	params := testProbeParams(t)
+	params.OnUnknownDataDirectory = func(index int, dir pe.DataDirectory, data []byte) {
+		assert.EqualValues(t, dir.Size, len(data))
+		directories[index] = data
+	}
+
+	info, err := pelican.Probe(f, params)
 	assert.NoError(t, err)
 	assert.EqualValues(t, pelican.ArchAmd64, info.Arch)
+	assert.EqualValues(t, pelican.StubMSVC, info.EntryPointStub)
+
+	assert.NotContains(t, directories, pe.IMAGE_DIRECTORY_ENTRY_IMPORT)
+	assert.NotContains(t, directories, pe.IMAGE_DIRECTORY_ENTRY_DEBUG)
+	assert.NotContains(t, directories, pe.IMAGE_DIRECTORY_ENTRY_LOAD_CONFIG)
+	// RUNTIME_FUNCTION entries are 12 bytes each
+	assert.Contains(t, directories, pe.IMAGE_DIRECTORY_ENTRY_EXCEPTION)
+	assert.Zero(t, len(directories[pe.IMAGE_DIRECTORY_ENTRY_EXCEPTION])%12)
 }
 
 func assertResources(t *testing.T, info *pelican.PeInfo) {
@@ -97,10 +153,97 @@ func Test_Resourceful32Mingw(t *testing.T) {
 	info, err := pelican.Probe(f, testProbeParams(t))
 	assert.NoError(t, err)
 	assert.EqualValues(t, pelican.Arch386, info.Arch)
+	assert.False(t, info.IsDebugBuild)
+	assert.False(t, info.IsPrerelease)
+
+	assertResources(t, info)
+}
+
+func Test_DebugBuild(t *testing.T) {
+	f, err := eos.Open("./testdata/resourceful/resourceful32-debug.exe")
+	assert.NoError(t, err)
+	defer f.Close()
+
+	info, err := pelican.Probe(f, testProbeParams(t))
+	assert.NoError(t, err)
+	assert.True(t, info.IsDebugBuild)
+	assert.True(t, info.IsPrerelease)
+}
+
+func Test_RenamedResourceSection(t *testing.T) {
+	data, err := ioutil.ReadFile("./testdata/resourceful/resourceful32-mingw.exe")
+	assert.NoError(t, err)
+
+	// packers and some linkers don't call it .rsrc
+	name := []byte(".rsrc\x00\x00\x00")
+	assert.EqualValues(t, 1, bytes.Count(data, name))
+	data = bytes.Replace(data, name, []byte("UPX2\x00\x00\x00\x00"), 1)
+
+	path := filepath.Join(t.TempDir(), "renamed.exe")
+	assert.NoError(t, ioutil.WriteFile(path, data, 0644))
+
+	f, err := eos.Open(path)
+	assert.NoError(t, err)
+	defer f.Close()
 
+	info, err := pelican.Probe(f, testProbeParams(t))
+	assert.NoError(t, err)
 	assertResources(t, info)
 }
 
+func Test_EmbeddedLibraryVersions(t *testing.T) {
+	data, err := ioutil.ReadFile("./testdata/hello/hello32-mingw.exe")
+	assert.NoError(t, err)
+
+	// pretend OpenSSL and zlib are linked statically
+	replace := func(old string, new string) {
+		assert.EqualValues(t, 1, bytes.Count(data, []byte(old)))
+		padded := append([]byte(new), make([]byte, len(old)-len(new))...)
+		data = bytes.Replace(data, []byte(old), padded, 1)
+	}
+	replace("The result is too small to be represented (UNDERFLOW)", "OpenSSL 1.1.1k  25 Mar 2021")
+	replace("Partial loss of significance (PLOSS)", " inflate 1.2.11 Copyright 1995")
+
+	path := filepath.Join(t.TempDir(), "static.exe")
+	assert.NoError(t, ioutil.WriteFile(path, data, 0644))
+
+	f, err := eos.Open(path)
+	assert.NoError(t, err)
+	defer f.Close()
+
+	info, err := pelican.Probe(f, testProbeParams(t))
+	assert.NoError(t, err)
+	assert.EqualValues(t, []*pelican.BundledLibrary{
+		{Name: "openssl", Version: "1.1.1k", Source: pelican.LibrarySourceString},
+		{Name: "zlib", Version: "1.2.11", Source: pelican.LibrarySourceString},
+	}, info.BundledLibraries)
+}
+
+func Test_PackedResources(t *testing.T) {
+	// see testdata/resourceful/make-packed.py
+	f, err := eos.Open("./testdata/resourceful/resourceful32-packed.exe")
+	assert.NoError(t, err)
+	defer f.Close()
+
+	_, err = pelican.Probe(f, testProbeParams(t))
+	assert.Error(t, err)
+
+	params := testProbeParams(t)
+	params.Strict = false
+	info, err := pelican.Probe(f, params)
+	assert.NoError(t, err)
+	assertResources(t, info)
+
+	var codes []pelican.WarningCode
+	for _, w := range info.Warnings {
+		codes = append(codes, w.Code)
+	}
+	assert.EqualValues(t, []pelican.WarningCode{
+		pelican.WarningResourceSubtreeUnreadable,
+		pelican.WarningResourcePacked,
+	}, codes)
+}
+
 func Test_Resourceful64Mingw(t *testing.T) {
 	f, err := eos.Open("./testdata/resourceful/resourceful64-mingw.exe")
 	assert.NoError(t, err)
@@ -113,6 +256,42 @@ func Test_Resourceful64Mingw(t *testing.T) {
 	assertResources(t, info)
 }
 
+func Test_ResourcefulArm64Machine(t *testing.T) {
+	// an x64 binary with only the machine patched, see
+	// testdata/resourceful/make-arm64.py. This checks how the machine
+	// is reported, not that real ARM64 binaries parse.
+	f, err := eos.Open("./testdata/resourceful/resourceful-arm64.exe")
+	assert.NoError(t, err)
+	defer f.Close()
+
+	info, err := pelican.Probe(f, testProbeParams(t))
+	assert.NoError(t, err)
+	assert.EqualValues(t, pelican.ArchArm64, info.Arch)
+	assert.True(t, info.Arch.Is64())
+	assert.EqualValues(t, "arm64", info.Arch.String())
+	assert.EqualValues(t, []string{"KERNEL32.dll", "msvcrt.dll"}, info.Imports)
+
+	assertResources(t, info)
+}
+
+func Test_ResourcefulArmMachine(t *testing.T) {
+	// an x86 binary with only the machine patched, see
+	// testdata/resourceful/make-arm.py. This checks how the machine
+	// is reported, not that real ARM binaries parse.
+	f, err := eos.Open("./testdata/resourceful/resourceful-arm.exe")
+	assert.NoError(t, err)
+	defer f.Close()
+
+	info, err := pelican.Probe(f, testProbeParams(t))
+	assert.NoError(t, err)
+	assert.EqualValues(t, pelican.ArchArm, info.Arch)
+	assert.EqualValues(t, 32, info.Arch.Bits())
+	assert.EqualValues(t, "arm", info.Arch.String())
+	assert.EqualValues(t, []string{"KERNEL32.dll", "msvcrt.dll"}, info.Imports)
+
+	assertResources(t, info)
+}
+
 func Test_WinCDEmuInstaller(t *testing.T) {
 	f, err := eos.Open("./testdata/wincdemu/WinCDEmu-4.1.exe")
 	assert.NoError(t, err)
@@ -121,6 +300,13 @@ func Test_WinCDEmuInstaller(t *testing.T) {
 	info, err := pelican.Probe(f, testProbeParams(t))
 	assert.NoError(t, err)
 	assert.EqualValues(t, pelican.Arch386, info.Arch)
+	assert.EqualValues(t, pelican.StubUPX, info.EntryPointStub)
+	assert.EqualValues(t, &pelican.SignatureInfo{
+		Offset:             1690808,
+		Size:               7000,
+		CertificateSHA256:  "1177fc00c11106759ea87c909173f74ed295e9517ed11180a6898fdba043058b",
+		CertificateSubject: "CN=Sysprogs OU,O=Sysprogs OU,L=Maardu,C=EE",
+	}, info.Signature)
 
 	vp := info.VersionProperties
 	assert.EqualValues(t, "Sysprogs OU", vp["CompanyName"])
@@ -140,6 +326,136 @@ func Test_WinCDEmuInstaller(t *testing.T) {
 	assert.EqualValues(t, "*", da.Language)
 	assert.EqualValues(t, "*", da.ProcessorArchitecture)
 	assert.EqualValues(t, "6595b64144ccf1df", da.PublicKeyToken)
+
+	compat := info.Compatibility
+	assert.NotNil(t, compat)
+	assert.EqualValues(t, pelican.WindowsXP, compat.DeclaredMinVersion())
+	assert.EqualValues(t, []pelican.WindowsVersion{pelican.WindowsVista, pelican.Windows7}, compat.SupportedOS)
+	assert.EqualValues(t, "Windows XP+ (declared), Windows Vista to Windows 7 (manifest)", compat.Summary)
+
+	// UPX leaves OriginalFirstThunk empty
+	assert.EqualValues(t, []string{"KERNEL32.DLL", "ADVAPI32.dll", "COMCTL32.dll", "GDI32.dll", "ole32.dll", "SHELL32.dll", "USER32.dll"}, info.Imports)
+
+	// UPX also leaves most resources compressed, outside of .rsrc
+	// COMCTL32.dll is only imported by ordinal, but it's in DefaultOrdinalNames
+	assert.NotEmpty(t, info.Warnings)
+	for _, w := range info.Warnings {
+		assert.EqualValues(t, pelican.WarningResourcePacked, w.Code)
+	}
+	assert.Empty(t, info.WarningsAtLeast(pelican.SeverityWarn))
+}
+
+func Test_FirstThunkOnly(t *testing.T) {
+	open := func(path string) *pe.File {
+		f, err := eos.Open(path)
+		assert.NoError(t, err)
+		t.Cleanup(func() { f.Close() })
+		stats, err := f.Stat()
+		assert.NoError(t, err)
+		pf, err := pe.NewFile(f, stats.Size())
+		assert.NoError(t, err)
+		return pf
+	}
+
+	// same as hello32-mingw.exe, without OriginalFirstThunk
+	expected, err := open("./testdata/hello/hello32-mingw.exe").ImportedSymbols()
+	assert.NoError(t, err)
+	assert.NotEmpty(t, expected)
+	symbols, err := open("./testdata/borland/hello32-firstthunk.exe").ImportedSymbols()
+	assert.NoError(t, err)
+	assert.EqualValues(t, expected, symbols)
+
+	f, err := eos.Open("./testdata/borland/hello32-firstthunk.exe")
+	assert.NoError(t, err)
+	defer f.Close()
+
+	info, err := pelican.Probe(f, testProbeParams(t))
+	assert.NoError(t, err)
+	assert.EqualValues(t, []string{"KERNEL32.dll", "msvcrt.dll"}, info.Imports)
+}
+
+func Test_InvalidSignature(t *testing.T) {
+	data, err := ioutil.ReadFile("./testdata/wincdemu/WinCDEmu-4.1.exe")
+	assert.NoError(t, err)
+	// mangle the PKCS #7 blob, after the WIN_CERTIFICATE header
+	for i := 1690808 + 8; i < 1690808+64; i++ {
+		data[i] = 0xff
+	}
+	fsys := fstest.MapFS{"setup.exe": {Data: data}}
+
+	f, err := fsys.Open("setup.exe")
+	assert.NoError(t, err)
+	defer f.Close()
+
+	params := testProbeParams(t)
+	_, err = pelican.Probe(f.(eos.File), params)
+	assert.Error(t, err)
+
+	params.Strict = false
+	info, err := pelican.Probe(f.(eos.File), params)
+	assert.NoError(t, err)
+	assert.EqualValues(t, &pelican.SignatureInfo{Offset: 1690808, Size: 7000}, info.Signature)
+	assert.EqualValues(t, pelican.WarningSignatureInvalid, info.Warnings[len(info.Warnings)-1].Code)
+}
+
+func Test_TruncatedDownload(t *testing.T) {
+	data, err := ioutil.ReadFile("./testdata/hello/hello64-mingw.exe")
+	assert.NoError(t, err)
+	fsys := fstest.MapFS{"hello.exe": {Data: data[:len(data)*3/4]}}
+
+	f, err := fsys.Open("hello.exe")
+	assert.NoError(t, err)
+	defer f.Close()
+
+	params := testProbeParams(t)
+	_, err = pelican.Probe(f.(eos.File), params)
+	assert.Error(t, err)
+
+	params.Strict = false
+	info, err := pelican.Probe(f.(eos.File), params)
+	assert.NoError(t, err)
+	assert.EqualValues(t, pelican.ArchAmd64, info.Arch)
+	var truncated []string
+	for _, w := range info.WarningsAtLeast(pelican.SeverityWarn) {
+		if w.Code == pelican.WarningSectionTruncated {
+			truncated = append(truncated, w.Message)
+		}
+	}
+	assert.Contains(t, truncated, "Section .idata is truncated: only 0 of its 2048 bytes are in the file")
+	// the imports are missing, not the whole result
+	assert.Nil(t, info.Imports)
+	assert.NotEmpty(t, info.WarningsAtLeast(pelican.SeverityError))
+}
+
+func Test_UnalignedSections(t *testing.T) {
+	data, err := ioutil.ReadFile("./testdata/hello/hello32-msvc.exe")
+	assert.NoError(t, err)
+	// point the raw data of .rdata, which holds the imports, a bit
+	// further: the loader rounds that down to a multiple of 0x200
+	lfanew := int(binary.LittleEndian.Uint32(data[0x3c:]))
+	sizeOfOptionalHeader := int(binary.LittleEndian.Uint16(data[lfanew+20:]))
+	header := data[lfanew+24+sizeOfOptionalHeader+40:]
+	assert.EqualValues(t, ".rdata\x00\x00", string(header[:8]))
+	binary.LittleEndian.PutUint32(header[20:], binary.LittleEndian.Uint32(header[20:])+0x100)
+	fsys := fstest.MapFS{"hello.exe": {Data: data}}
+
+	f, err := fsys.Open("hello.exe")
+	assert.NoError(t, err)
+	defer f.Close()
+
+	info, err := pelican.Probe(f.(eos.File), testProbeParams(t))
+	assert.NoError(t, err)
+	assert.NotEmpty(t, info.Imports)
+	if assert.Len(t, info.Warnings, 1) {
+		assert.EqualValues(t, pelican.WarningAlignmentNonstandard, info.Warnings[0].Code)
+	}
+
+	reference, err := eos.Open("./testdata/hello/hello32-msvc.exe")
+	assert.NoError(t, err)
+	defer reference.Close()
+	referenceInfo, err := pelican.Probe(reference, testProbeParams(t))
+	assert.NoError(t, err)
+	assert.EqualValues(t, referenceInfo.Imports, info.Imports)
 }
 
 func Test_PidginUninstaller(t *testing.T) {
@@ -150,6 +466,11 @@ func Test_PidginUninstaller(t *testing.T) {
 	info, err := pelican.Probe(f, testProbeParams(t))
 	assert.NoError(t, err)
 	assert.EqualValues(t, pelican.Arch386, info.Arch)
+	assert.EqualValues(t, pelican.StubNSIS, info.EntryPointStub)
+	assert.EqualValues(t, &pelican.Indicators{
+		URLs:         []string{"http://nsis.sf.net/NSIS_Error"},
+		RegistryKeys: []string{`Software\Microsoft\Windows\CurrentVersion`},
+	}, info.Indicators)
 
 	vp := info.VersionProperties
 	assert.EqualValues(t, "Pidgin Installer", vp["FileDescription"])
@@ -159,6 +480,14 @@ func Test_PidginUninstaller(t *testing.T) {
 
 	assert.NotNil(t, info.AssemblyInfo)
 	assert.EqualValues(t, "highestAvailable", info.AssemblyInfo.RequestedExecutionLevel)
+	assert.EqualValues(t, "Nullsoft Install System v2.46", info.AssemblyInfo.Description)
+
+	id := info.AssemblyInfo.Identity
+	assert.NotNil(t, id)
+	assert.EqualValues(t, "Nullsoft.NSIS.exehead", id.Name)
+	assert.EqualValues(t, "1.0.0.0", id.Version)
+	assert.EqualValues(t, "X86", id.ProcessorArchitecture)
+	assert.EqualValues(t, "win32", id.Type)
 	assert.True(t, info.RequiresElevation())
 
 	assert.EqualValues(t, 1, len(info.DependentAssemblies))
@@ -167,6 +496,38 @@ func Test_PidginUninstaller(t *testing.T) {
 	assert.EqualValues(t, "*", da.Language)
 	assert.EqualValues(t, "X86", da.ProcessorArchitecture)
 	assert.EqualValues(t, "6595b64144ccf1df", da.PublicKeyToken)
+
+	assert.NotEmpty(t, info.Dialogs)
+	dt := info.Dialogs[0]
+	assert.True(t, dt.Extended)
+	assert.EqualValues(t, "MS Shell Dlg", dt.FontName)
+	assert.NotEmpty(t, dt.Controls)
+}
+
+func Test_MemoryBudget(t *testing.T) {
+	f, err := eos.Open("./testdata/pidgin/pidgin-uninst.exe")
+	assert.NoError(t, err)
+	defer f.Close()
+
+	params := testProbeParams(t)
+	params.MaxMemory = 4096
+	info, err := pelican.Probe(f, params)
+	assert.NoError(t, err)
+
+	// the data scan doesn't fit, small resources do
+	assert.Nil(t, info.Indicators)
+	assert.EqualValues(t, "Pidgin", info.VersionProperties["ProductName"])
+	assert.NotNil(t, info.AssemblyInfo)
+
+	var skipped []string
+	for _, w := range info.Warnings {
+		if w.Code == pelican.WarningMemoryBudgetExceeded {
+			skipped = append(skipped, w.Message)
+		}
+	}
+	assert.EqualValues(t, []string{
+		"Skipping data section scan: needs 1048832 bytes, over the memory budget of 4096",
+	}, skipped)
 }
 
 func Test_Stockboy(t *testing.T) {
@@ -174,7 +535,245 @@ func Test_Stockboy(t *testing.T) {
 	assert.NoError(t, err)
 	defer f.Close()
 
-	info, err := pelican.Probe(f, testProbeParams(t))
+	params := testProbeParams(t)
+	params.ElevationHeuristics = true
+	info, err := pelican.Probe(f, params)
 	assert.NoError(t, err)
 	assert.EqualValues(t, pelican.Arch386, info.Arch)
+	// Visual C++ 6
+	assert.EqualValues(t, pelican.StubMSVC, info.EntryPointStub)
+	assert.EqualValues(t, pelican.SubsystemGUI, info.Subsystem)
+
+	assert.False(t, info.RequiresElevation())
+	elevate, reasons := info.RequiresElevationHeuristic()
+	assert.True(t, elevate)
+	assert.Contains(t, reasons, `file name contains "install"`)
+	assert.Contains(t, reasons, "GUI executable has no manifest")
+
+	assert.EqualValues(t, 1, len(info.Dialogs))
+	dt := info.Dialogs[0]
+	assert.EqualValues(t, 500, dt.ID)
+	assert.False(t, dt.Extended)
+	assert.EqualValues(t, "Progress", dt.Title)
+	assert.EqualValues(t, 2, dt.Controls[0].ID)
+	assert.EqualValues(t, "Button", dt.Controls[0].Class)
+	assert.EqualValues(t, "Cancel", dt.Controls[0].Text)
+
+	// COMCTL32.dll and OLEAUT32.dll are only imported
+	// by ordinal, but they're in DefaultOrdinalNames
+	assert.Empty(t, info.Warnings)
+}
+
+func Test_Reprobe(t *testing.T) {
+	probe := func(path string, previous *pelican.PeInfo) *pelican.PeInfo {
+		f, err := eos.Open(path)
+		assert.NoError(t, err)
+		defer f.Close()
+
+		info, err := pelican.Reprobe(f, previous, testProbeParams(t))
+		assert.NoError(t, err)
+		return info
+	}
+
+	previous := probe("./testdata/pidgin/pidgin-uninst.exe", nil)
+	assert.NotEmpty(t, previous.HeadersSHA256)
+
+	info := probe("./testdata/pidgin/pidgin-uninst.exe", previous)
+	assert.EqualValues(t, previous, info)
+	assert.False(t, previous == info)
+
+	// headers differ, so nothing is reused
+	info = probe("./testdata/resourceful/resourceful32-mingw.exe", previous)
+	assert.NotEqual(t, previous.HeadersSHA256, info.HeadersSHA256)
+	assert.Nil(t, info.AssemblyInfo)
+	assertResources(t, info)
+
+	// what's reused and what's recomputed add up to a full probe
+	for _, path := range []string{
+		"./testdata/hello/hello64-msvc.exe",
+		"./testdata/wincdemu/WinCDEmu-4.1.exe",
+	} {
+		previous := probe(path, nil)
+		assert.EqualValues(t, previous, probe(path, previous), path)
+	}
+}
+
+func Test_ReprobeSignature(t *testing.T) {
+	original, err := eos.Open("./testdata/wincdemu/WinCDEmu-4.1.exe")
+	assert.NoError(t, err)
+	defer original.Close()
+	previous, err := pelican.Probe(original, testProbeParams(t))
+	assert.NoError(t, err)
+	assert.NotNil(t, previous.Signature)
+
+	// the certificate table is past the headers, so
+	// changing it doesn't force a full probe
+	data, err := ioutil.ReadFile("./testdata/wincdemu/WinCDEmu-4.1.exe")
+	assert.NoError(t, err)
+	for i := 1690808 + 8; i < 1690808+64; i++ {
+		data[i] = 0xff
+	}
+	fsys := fstest.MapFS{"setup.exe": {Data: data}}
+	f, err := fsys.Open("setup.exe")
+	assert.NoError(t, err)
+	defer f.Close()
+
+	params := testProbeParams(t)
+	params.Strict = false
+	info, err := pelican.Reprobe(f.(eos.File), previous, params)
+	assert.NoError(t, err)
+	assert.EqualValues(t, previous.HeadersSHA256, info.HeadersSHA256)
+	full, err := pelican.Probe(f.(eos.File), params)
+	assert.NoError(t, err)
+	assert.EqualValues(t, full, info)
+	assert.NotEqual(t, previous.Signature, info.Signature)
+}
+
+func Test_ReprobeLauncher(t *testing.T) {
+	f, err := eos.Open("./testdata/stockboy/stockboy_install_sliced.EXE")
+	assert.NoError(t, err)
+	defer f.Close()
+
+	full, err := pelican.Probe(f, testProbeParams(t))
+	assert.NoError(t, err)
+	assert.NotNil(t, full.Launcher)
+
+	// it depends on the version info, so it's never reused
+	stale := *full
+	stale.Launcher = nil
+	info, err := pelican.Reprobe(f, &stale, testProbeParams(t))
+	assert.NoError(t, err)
+	assert.EqualValues(t, full, info)
+
+	stale.Launcher = &pelican.LauncherInfo{Targets: []string{"Old.exe"}, Evidence: "imports WinExec"}
+	info, err = pelican.Reprobe(f, &stale, testProbeParams(t))
+	assert.NoError(t, err)
+	assert.EqualValues(t, full.Launcher, info.Launcher)
+}
+
+func Test_ReprobeMemoryBudget(t *testing.T) {
+	previous, err := eos.Open("./testdata/hello/hello32-msvc.exe")
+	assert.NoError(t, err)
+	defer previous.Close()
+	previousInfo, err := pelican.Probe(previous, testProbeParams(t))
+	assert.NoError(t, err)
+
+	f, err := eos.Open("./testdata/pidgin/pidgin-uninst.exe")
+	assert.NoError(t, err)
+	defer f.Close()
+
+	// a full probe gets the whole budget, even though
+	// Reprobe had to read the headers first
+	params := testProbeParams(t)
+	params.Strict = false
+	params.MaxMemory = 2 * 1024 * 1024
+	info, err := pelican.Reprobe(f, previousInfo, params)
+	assert.NoError(t, err)
+	full, err := pelican.Probe(f, params)
+	assert.NoError(t, err)
+	assert.EqualValues(t, full, info)
+}
+
+func Test_GobRoundtrip(t *testing.T) {
+	f, err := eos.Open("./testdata/pidgin/pidgin-uninst.exe")
+	assert.NoError(t, err)
+	defer f.Close()
+
+	info, err := pelican.Probe(f, testProbeParams(t))
+	assert.NoError(t, err)
+
+	var buf bytes.Buffer
+	assert.NoError(t, gob.NewEncoder(&buf).Encode(info))
+
+	decoded := &pelican.PeInfo{}
+	assert.NoError(t, gob.NewDecoder(&buf).Decode(decoded))
+	assert.EqualValues(t, info, decoded)
+}
+
+func Test_Stages(t *testing.T) {
+	f, err := eos.Open("./testdata/pidgin/pidgin-uninst.exe")
+	assert.NoError(t, err)
+	defer f.Close()
+
+	stats, err := f.Stat()
+	assert.NoError(t, err)
+	pf, err := pe.NewFile(f, stats.Size())
+	assert.NoError(t, err)
+
+	params := testProbeParams(t)
+
+	info := &pelican.PeInfo{}
+	assert.NoError(t, pelican.ParseManifest(info, pf, params))
+	assert.EqualValues(t, "Nullsoft.NSIS.exehead", info.AssemblyInfo.Identity.Name)
+	assert.Nil(t, info.VersionProperties)
+	assert.Nil(t, info.Imports)
+
+	assert.NoError(t, pelican.ParseImports(info, pf, params))
+	assert.Contains(t, info.Imports, "KERNEL32.dll")
+
+	// the uninstaller was written with the installer's headers,
+	// certificate table included
+	assert.NoError(t, pelican.ParseSignature(info, pf, params))
+	assert.Nil(t, info.Signature)
+	assert.EqualValues(t, pelican.WarningSignatureOutsideFile, info.Warnings[len(info.Warnings)-1].Code)
+
+	full, err := pelican.Probe(f, params)
+	assert.NoError(t, err)
+
+	info = &pelican.PeInfo{}
+	assert.NoError(t, pelican.ParseResources(info, pf, params))
+	assert.EqualValues(t, full.VersionProperties, info.VersionProperties)
+	assert.EqualValues(t, full.AssemblyInfo, info.AssemblyInfo)
+}
+
+func Test_MenusAndAccelerators(t *testing.T) {
+	f, err := eos.Open("./testdata/resourceful/resourceful32-menus.exe")
+	assert.NoError(t, err)
+	defer f.Close()
+
+	info, err := pelican.Probe(f, testProbeParams(t))
+	assert.NoError(t, err)
+	// still has everything else
+	assert.EqualValues(t, "butler", info.VersionProperties["ProductName"])
+
+	if assert.Len(t, info.Menus, 2) {
+		mt := info.Menus[0]
+		assert.EqualValues(t, 1, mt.ID)
+		assert.EqualValues(t, 1033, mt.Language)
+		assert.False(t, mt.Extended)
+		assert.EqualValues(t, []string{"&File", "&Open...\tCtrl+O", "E&xit", "&Help", "&About"}, mt.Strings())
+		if assert.Len(t, mt.Items, 2) {
+			file := mt.Items[0]
+			assert.Len(t, file.Items, 3)
+			assert.EqualValues(t, 100, file.Items[0].ID)
+			// separator
+			assert.EqualValues(t, 0, file.Items[1].ID)
+			assert.EqualValues(t, 0x800, file.Items[1].Flags)
+			assert.EqualValues(t, 101, file.Items[2].ID)
+			assert.EqualValues(t, 200, mt.Items[1].Items[0].ID)
+		}
+
+		mt = info.Menus[1]
+		assert.EqualValues(t, 2, mt.ID)
+		assert.True(t, mt.Extended)
+		assert.EqualValues(t, []*pelican.MenuItem{
+			{
+				Text:   "&Edit",
+				HelpID: 42,
+				Items: []*pelican.MenuItem{
+					{ID: 300, Text: "&Copy", State: 0x1000},
+				},
+			},
+		}, mt.Items)
+	}
+
+	if assert.Len(t, info.Accelerators, 1) {
+		at := info.Accelerators[0]
+		assert.EqualValues(t, 1, at.ID)
+		var shortcuts []string
+		for _, a := range at.Accelerators {
+			shortcuts = append(shortcuts, fmt.Sprintf("%s => %d", a, a.Command))
+		}
+		assert.EqualValues(t, []string{"Ctrl+O => 100", "F1 => 200", "Alt+? => 200"}, shortcuts)
+	}
 }
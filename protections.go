@@ -0,0 +1,156 @@
+package pelican
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/itchio/pelican/pe"
+)
+
+// ProtectionKind tells what a Protection is for
+type ProtectionKind string
+
+const (
+	// Copy protection, may require activation or a client to run
+	ProtectionDRM ProtectionKind = "drm"
+	// Anti-cheat, requires a service to be installed (and often running)
+	ProtectionAntiCheat ProtectionKind = "anticheat"
+)
+
+// Protection is a DRM or anti-cheat component detected in a binary.
+// Binaries that have them usually won't run without extra services.
+type Protection struct {
+	// For example "steam-stub", "denuvo", "easyanticheat"
+	Name string         `json:"name"`
+	Kind ProtectionKind `json:"kind"`
+	// What gave it away, for example "section .bind"
+	Evidence string `json:"evidence"`
+}
+
+type protectionSignature struct {
+	name string
+	kind ProtectionKind
+}
+
+// keyed by section name
+var protectionSections = map[string]protectionSignature{
+	// SteamStub, added by Steam's DRM wrapper
+	".bind": {"steam-stub", ProtectionDRM},
+	// SecuROM
+	".securom": {"securom", ProtectionDRM},
+	".cms_t":   {"securom", ProtectionDRM},
+	".cms_d":   {"securom", ProtectionDRM},
+	// StarForce
+	".sforce3": {"starforce", ProtectionDRM},
+	// SafeDisc
+	"stxt371": {"safedisc", ProtectionDRM},
+	"stxt774": {"safedisc", ProtectionDRM},
+	// Solidshield
+	".solid": {"solidshield", ProtectionDRM},
+}
+
+// keyed by lower-case library name
+var protectionImports = map[string]protectionSignature{
+	"easyanticheat_x86.dll": {"easyanticheat", ProtectionAntiCheat},
+	"easyanticheat_x64.dll": {"easyanticheat", ProtectionAntiCheat},
+	"beclient.dll":          {"battleye", ProtectionAntiCheat},
+	"beclient_x64.dll":      {"battleye", ProtectionAntiCheat},
+	"xigncode3.dll":         {"xigncode", ProtectionAntiCheat},
+	"npggnt.des":            {"gameguard", ProtectionAntiCheat},
+}
+
+// keyed by "symbol:library", see pe.ImportedSymbol.String
+// (library is lower-cased)
+var protectionSymbols = map[string]protectionSignature{
+	// relaunches the game through the Steam client if needed
+	"SteamAPI_RestartAppIfNecessary:steam_api.dll":   {"steamworks", ProtectionDRM},
+	"SteamAPI_RestartAppIfNecessary:steam_api64.dll": {"steamworks", ProtectionDRM},
+}
+
+// Denuvo adds one huge executable section, with a random or empty name,
+// and runs its own code from there before the game's: the entry point
+// or a TLS callback points into it. Size alone isn't enough, some
+// toolchains emit large code sections with unusual names.
+const denuvoMinSectionSize = 16 * 1024 * 1024
+
+// sections that can't be Denuvo's: those of toolchains, and those
+// of packers, which also take over the entry point
+var standardSectionNames = map[string]bool{
+	".text":    true,
+	".itext":   true,
+	"CODE":     true,
+	".textbss": true,
+	"UPX0":     true,
+	"UPX1":     true,
+	".vmp0":    true,
+	".vmp1":    true,
+	".themida": true,
+	".MPRESS1": true,
+	".enigma1": true,
+}
+
+// detectProtections looks for DRM and anti-cheat components
+// in section names, imported libraries and imported symbols.
+// entryPoint and tlsCallbacks are RVAs.
+func detectProtections(sections []*pe.Section, entryPoint uint32, tlsCallbacks []uint32, imports []string, symbols []pe.ImportedSymbol) []*Protection {
+	var res []*Protection
+	seen := make(map[string]bool)
+	add := func(sig protectionSignature, evidence string, args ...interface{}) {
+		if seen[sig.name] {
+			return
+		}
+		seen[sig.name] = true
+		res = append(res, &Protection{
+			Name:     sig.name,
+			Kind:     sig.kind,
+			Evidence: fmt.Sprintf(evidence, args...),
+		})
+	}
+
+	for _, s := range sections {
+		if sig, ok := protectionSections[s.Name]; ok {
+			add(sig, "section %s", s.Name)
+			continue
+		}
+
+		if s.Characteristics&pe.IMAGE_SCN_MEM_EXECUTE != 0 && !standardSectionNames[s.Name] {
+			size := s.VirtualSize
+			if s.Size > size {
+				size = s.Size
+			}
+			contains := func(rva uint32) bool {
+				return rva >= s.VirtualAddress && rva-s.VirtualAddress < size
+			}
+			if size >= denuvoMinSectionSize {
+				if contains(entryPoint) {
+					add(protectionSignature{"denuvo", ProtectionDRM}, "entry point in executable section %q of %d MiB", s.Name, size/1024/1024)
+					continue
+				}
+				for _, rva := range tlsCallbacks {
+					if contains(rva) {
+						add(protectionSignature{"denuvo", ProtectionDRM}, "TLS callback in executable section %q of %d MiB", s.Name, size/1024/1024)
+						break
+					}
+				}
+			}
+		}
+	}
+
+	for _, lib := range imports {
+		if sig, ok := protectionImports[strings.ToLower(lib)]; ok {
+			add(sig, "imports %s", lib)
+		}
+	}
+
+	for _, sym := range symbols {
+		if sym.ByOrdinal() {
+			continue
+		}
+		key := sym.Name + ":" + strings.ToLower(sym.DLL)
+		if sig, ok := protectionSymbols[key]; ok {
+			add(sig, "imports %s from %s", sym.Name, sym.DLL)
+		}
+	}
+
+	return res
+}
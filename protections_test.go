@@ -0,0 +1,71 @@
+package pelican
+
+import (
+	"testing"
+
+	"github.com/itchio/pelican/pe"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_DetectProtections(t *testing.T) {
+	const MiB = 1024 * 1024
+	section := func(name string, address, size uint32, characteristics uint32) *pe.Section {
+		return &pe.Section{SectionHeader: pe.SectionHeader{
+			Name:            name,
+			VirtualAddress:  address,
+			VirtualSize:     size,
+			Characteristics: characteristics,
+		}}
+	}
+	code := uint32(pe.IMAGE_SCN_CNT_CODE | pe.IMAGE_SCN_MEM_EXECUTE | pe.IMAGE_SCN_MEM_READ)
+
+	ps := detectProtections(
+		[]*pe.Section{
+			section(".text", 0x1000, 64*MiB, code),
+			section(".bind", 0x1000+64*MiB, 4096, code),
+			section(".x9qr", 0x2000+64*MiB, 80*MiB, code),
+			section(".rdata", 0x2000+144*MiB, 32*MiB, pe.IMAGE_SCN_CNT_INITIALIZED_DATA),
+		},
+		0x2000+64*MiB+0x1234,
+		nil,
+		[]string{"KERNEL32.dll", "steam_api64.dll", "EasyAntiCheat_x64.dll"},
+		importedSymbols("SteamAPI_Init:steam_api64.dll", "#3:steam_api64.dll", "SteamAPI_RestartAppIfNecessary:steam_api64.dll"),
+	)
+	assert.EqualValues(t, []*Protection{
+		{Name: "steam-stub", Kind: ProtectionDRM, Evidence: "section .bind"},
+		{Name: "denuvo", Kind: ProtectionDRM, Evidence: `entry point in executable section ".x9qr" of 80 MiB`},
+		{Name: "easyanticheat", Kind: ProtectionAntiCheat, Evidence: "imports EasyAntiCheat_x64.dll"},
+		{Name: "steamworks", Kind: ProtectionDRM, Evidence: "imports SteamAPI_RestartAppIfNecessary from steam_api64.dll"},
+	}, ps)
+
+	// Denuvo's TLS callbacks run before the entry point
+	ps = detectProtections(
+		[]*pe.Section{
+			section(".text", 0x1000, 4*MiB, code),
+			section("", 0x1000+4*MiB, 40*MiB, code),
+		},
+		0x1000,
+		[]uint32{0x2000, 0x1000 + 20*MiB},
+		[]string{"KERNEL32.dll"},
+		nil,
+	)
+	assert.EqualValues(t, []*Protection{
+		{Name: "denuvo", Kind: ProtectionDRM, Evidence: `TLS callback in executable section "" of 40 MiB`},
+	}, ps)
+
+	assert.Nil(t, detectProtections([]*pe.Section{section(".text", 0x1000, 4096, code)}, 0x1000, nil, []string{"KERNEL32.dll"}, nil))
+
+	// large code sections with unusual names aren't enough...
+	sections := []*pe.Section{
+		section(".text", 0x1000, 4*MiB, code),
+		section(".ltext", 0x1000+4*MiB, 80*MiB, code),
+	}
+	assert.Nil(t, detectProtections(sections, 0x1000, []uint32{0x2000}, []string{"KERNEL32.dll"}, nil))
+
+	// ...and neither are packers, which take over the entry point too
+	sections = []*pe.Section{
+		section("UPX0", 0x1000, 60*MiB, code),
+		section("UPX1", 0x1000+60*MiB, 20*MiB, code),
+	}
+	assert.Nil(t, detectProtections(sections, 0x1000+70*MiB, nil, []string{"KERNEL32.dll"}, nil))
+}
@@ -0,0 +1,128 @@
+package pelican_test
+
+import (
+	"bufio"
+	"os"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/itchio/pelican"
+	"github.com/stretchr/testify/assert"
+)
+
+type protoField struct {
+	typ      string
+	repeated bool
+}
+
+var (
+	protoMessageRegexp = regexp.MustCompile(`^message (\w+) \{$`)
+	protoFieldRegexp   = regexp.MustCompile(`^\s+(repeated )?(map<[^>]+>|\w+) (\w+) = (\d+);$`)
+)
+
+// parseProto returns the fields of the messages of a .proto file,
+// keyed by message name then by JSON name
+func parseProto(t *testing.T, path string) map[string]map[string]protoField {
+	f, err := os.Open(path)
+	assert.NoError(t, err)
+	defer f.Close()
+
+	res := make(map[string]map[string]protoField)
+	var fields map[string]protoField
+	numbers := make(map[string]bool)
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := s.Text()
+		if m := protoMessageRegexp.FindStringSubmatch(line); m != nil {
+			fields = make(map[string]protoField)
+			numbers = make(map[string]bool)
+			res[m[1]] = fields
+			continue
+		}
+		if m := protoFieldRegexp.FindStringSubmatch(line); m != nil && fields != nil {
+			assert.False(t, numbers[m[4]], "duplicate field number in %q", line)
+			numbers[m[4]] = true
+			fields[protoJSONName(m[3])] = protoField{typ: m[2], repeated: m[1] != ""}
+		}
+	}
+	assert.NoError(t, s.Err())
+	return res
+}
+
+// protoJSONName converts a field name to lowerCamelCase, as protoc does
+func protoJSONName(name string) string {
+	parts := strings.Split(name, "_")
+	for i := 1; i < len(parts); i++ {
+		parts[i] = strings.ToUpper(parts[i][:1]) + parts[i][1:]
+	}
+	return strings.Join(parts, "")
+}
+
+// protoType returns the type of the proto field that maps to t
+func protoType(t reflect.Type) string {
+	switch t.Kind() {
+	case reflect.String:
+		return "string"
+	case reflect.Bool:
+		return "bool"
+	case reflect.Int32, reflect.Int64, reflect.Uint32, reflect.Uint64:
+		return t.Kind().String()
+	case reflect.Float64:
+		return "double"
+	case reflect.Map:
+		// extensions are JSON documents
+		return "map<string, string>"
+	case reflect.Ptr:
+		return t.Elem().Name()
+	case reflect.Struct:
+		return t.Name()
+	}
+	return "unsupported " + t.String()
+}
+
+func Test_ProtoMatchesPeInfo(t *testing.T) {
+	messages := parseProto(t, "./proto/pelican.proto")
+
+	seen := make(map[reflect.Type]bool)
+	queue := []reflect.Type{reflect.TypeOf(pelican.PeInfo{})}
+	for len(queue) > 0 {
+		ty := queue[0]
+		queue = queue[1:]
+		if seen[ty] {
+			continue
+		}
+		seen[ty] = true
+
+		fields, ok := messages[ty.Name()]
+		if !assert.True(t, ok, "missing message %s", ty.Name()) {
+			continue
+		}
+		for i := 0; i < ty.NumField(); i++ {
+			f := ty.Field(i)
+			name := strings.Split(f.Tag.Get("json"), ",")[0]
+			pf, ok := fields[name]
+			if !assert.True(t, ok, "missing field %s.%s", ty.Name(), name) {
+				continue
+			}
+			delete(fields, name)
+
+			ft := f.Type
+			repeated := ft.Kind() == reflect.Slice
+			if repeated {
+				ft = ft.Elem()
+			}
+			assert.EqualValues(t, repeated, pf.repeated, "%s.%s", ty.Name(), name)
+			assert.EqualValues(t, protoType(ft), pf.typ, "%s.%s", ty.Name(), name)
+
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				queue = append(queue, ft)
+			}
+		}
+		assert.Empty(t, fields, "fields of %s that PeInfo doesn't have", ty.Name())
+	}
+}
@@ -0,0 +1,128 @@
+package pelican
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ProvenanceKind tells what a ProvenanceFinding is about
+type ProvenanceKind string
+
+const (
+	// Built from a user's home directory, Detail is the user name
+	ProvenanceUserHome ProvenanceKind = "user-home"
+	// Built on a CI runner, Detail is the CI service
+	ProvenanceCI ProvenanceKind = "ci"
+	// A path component is a word commonly found in malware and
+	// cheat projects, Detail is the word
+	ProvenanceSuspiciousName ProvenanceKind = "suspicious-name"
+)
+
+// ProvenanceFinding is something a build path reveals about where,
+// and by whom, a binary was built. They're informational: a binary
+// built by "jdoe" uploaded by someone else isn't necessarily an
+// impersonation, but it's worth a look.
+type ProvenanceFinding struct {
+	Kind ProvenanceKind `json:"kind"`
+	// The build path, see Indicators.BuildPaths
+	Path   string `json:"path"`
+	Detail string `json:"detail"`
+}
+
+var userHomeRegexps = []*regexp.Regexp{
+	regexp.MustCompile(`(?i)^[a-z]:\\(?:Users|Documents and Settings)\\([^\\]+)\\`),
+	regexp.MustCompile(`^/(?:home|Users)/([^/]+)/`),
+}
+
+// home directories that don't belong to a person
+var nonPersonalHomes = map[string]bool{
+	"public":  true,
+	"default": true,
+	"shared":  true,
+}
+
+var ciRunners = []struct {
+	name string
+	re   *regexp.Regexp
+}{
+	{"GitHub Actions", regexp.MustCompile(`(?i)^(?:[a-z]:\\a\\[^\\]+\\[^\\]+\\|[a-z]:\\actions-runner\\|/home/runner/work/|/Users/runner/work/)`)},
+	{"Azure Pipelines", regexp.MustCompile(`(?i)^(?:[a-z]:\\a\\\d+\\s\\|[a-z]:\\agent\\_work\\|/home/vsts/work/)`)},
+	{"GitLab CI", regexp.MustCompile(`(?i)^(?:[a-z]:\\GitLab-Runner\\builds\\|/builds/)`)},
+	{"Jenkins", regexp.MustCompile(`(?i)[\\/]jenkins[^\\/]*[\\/](?:.*[\\/])?workspace[\\/]`)},
+	{"TeamCity", regexp.MustCompile(`(?i)^[a-z]:\\BuildAgent\\work\\`)},
+	{"AppVeyor", regexp.MustCompile(`(?i)^[a-z]:\\projects\\`)},
+	{"Travis CI", regexp.MustCompile(`(?i)^(?:/home/travis/build/|[a-z]:\\Users\\travis\\build\\)`)},
+	{"CircleCI", regexp.MustCompile(`^/home/circleci/`)},
+}
+
+// whole path components (or words of them) only, so that "CheatManager.cpp"
+// and "ResourceLoader.cpp" don't count
+var suspiciousWords = map[string]bool{
+	"crack":       true,
+	"cracked":     true,
+	"keygen":      true,
+	"inject":      true,
+	"injector":    true,
+	"stealer":     true,
+	"grabber":     true,
+	"rat":         true,
+	"miner":       true,
+	"cryptominer": true,
+	"spoofer":     true,
+	"ransomware":  true,
+	"trojan":      true,
+	"botnet":      true,
+	"payload":     true,
+	"dropper":     true,
+	"bypass":      true,
+	"exploit":     true,
+	"cheat":       true,
+	"hack":        true,
+	"aimbot":      true,
+	"wallhack":    true,
+}
+
+var pathWordSplitter = regexp.MustCompile(`[^A-Za-z0-9]+`)
+
+// analyzeBuildPaths looks for user names, CI runners and suspicious
+// project names in build paths
+func analyzeBuildPaths(paths []string) []*ProvenanceFinding {
+	var res []*ProvenanceFinding
+	seen := make(map[ProvenanceFinding]bool)
+	add := func(kind ProvenanceKind, p string, detail string) {
+		f := ProvenanceFinding{Kind: kind, Path: p, Detail: detail}
+		if seen[f] {
+			return
+		}
+		seen[f] = true
+		res = append(res, &f)
+	}
+
+	for _, p := range paths {
+		ci := ""
+		for _, runner := range ciRunners {
+			if runner.re.MatchString(p) {
+				ci = runner.name
+				add(ProvenanceCI, p, ci)
+				break
+			}
+		}
+
+		// CI runners build from the home directory of a service account
+		if ci == "" {
+			for _, re := range userHomeRegexps {
+				if m := re.FindStringSubmatch(p); m != nil && !nonPersonalHomes[strings.ToLower(m[1])] {
+					add(ProvenanceUserHome, p, m[1])
+					break
+				}
+			}
+		}
+
+		for _, word := range pathWordSplitter.Split(p, -1) {
+			if w := strings.ToLower(word); suspiciousWords[w] {
+				add(ProvenanceSuspiciousName, p, w)
+			}
+		}
+	}
+	return res
+}
@@ -0,0 +1,31 @@
+package pelican
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_AnalyzeBuildPaths(t *testing.T) {
+	findings := analyzeBuildPaths([]string{
+		`C:\Users\Jane Doe\source\repos\Game\x64\Release\Game.pdb`,
+		`C:\Users\Public\Game\main.cpp`,
+		`D:\a\game\game\src\main.cpp`,
+		`/home/runner/work/game/game/src/main.c`,
+		`/Users/jdoe/dev/aimbot/src/ESP.cpp`,
+		`C:\BuildAgent\work\4f3a\Source\CheatManager.cpp`,
+		`C:\dev\Game\Source\Cheat\Console.cpp`,
+	})
+
+	assert.EqualValues(t, []*ProvenanceFinding{
+		{Kind: ProvenanceUserHome, Path: `C:\Users\Jane Doe\source\repos\Game\x64\Release\Game.pdb`, Detail: "Jane Doe"},
+		{Kind: ProvenanceCI, Path: `D:\a\game\game\src\main.cpp`, Detail: "GitHub Actions"},
+		{Kind: ProvenanceCI, Path: `/home/runner/work/game/game/src/main.c`, Detail: "GitHub Actions"},
+		{Kind: ProvenanceUserHome, Path: `/Users/jdoe/dev/aimbot/src/ESP.cpp`, Detail: "jdoe"},
+		{Kind: ProvenanceSuspiciousName, Path: `/Users/jdoe/dev/aimbot/src/ESP.cpp`, Detail: "aimbot"},
+		{Kind: ProvenanceCI, Path: `C:\BuildAgent\work\4f3a\Source\CheatManager.cpp`, Detail: "TeamCity"},
+		{Kind: ProvenanceSuspiciousName, Path: `C:\dev\Game\Source\Cheat\Console.cpp`, Detail: "cheat"},
+	}, findings)
+
+	assert.Nil(t, analyzeBuildPaths(nil))
+}
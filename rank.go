@@ -0,0 +1,223 @@
+package pelican
+
+import (
+	"path"
+	"sort"
+	"strings"
+)
+
+// LaunchCandidate is an executable ranked by Rank
+type LaunchCandidate struct {
+	// Slash-separated path, as passed to Rank
+	Path string `json:"path"`
+	// The higher, the more likely it's what should be launched.
+	// Only meaningful relative to the other candidates.
+	Score int `json:"score"`
+	// What contributed to the score, for example "GUI executable"
+	Reasons []string `json:"reasons"`
+	// For launchers (see PeInfo.Launcher), the executables they start,
+	// transitively. Those are left out of the ranking, and the launcher
+	// gets the best of their scores.
+	Launches []string `json:"launches,omitempty"`
+}
+
+type nameHint struct {
+	// lower-case substring of the file name
+	substring string
+	score     int
+	reason    string
+}
+
+// checked in order, only the first that matches counts
+var launchNameHints = []nameHint{
+	{"unins", -80, "uninstaller"},
+	{"vcredist", -80, "redistributable installer"},
+	{"vc_redist", -80, "redistributable installer"},
+	{"dxsetup", -80, "redistributable installer"},
+	{"dotnetfx", -80, "redistributable installer"},
+	{"ndp4", -80, "redistributable installer"},
+	{"physx", -80, "redistributable installer"},
+	{"oalinst", -80, "redistributable installer"},
+	{"ue4prereq", -80, "redistributable installer"},
+	{"prereq", -60, "redistributable installer"},
+	{"crashhandler", -60, "crash handler"},
+	{"crashpad", -60, "crash handler"},
+	{"crashreport", -60, "crash handler"},
+	{"bugreport", -60, "crash handler"},
+	{"errorreport", -60, "crash handler"},
+	{"setup", -50, "installer"},
+	{"install", -50, "installer"},
+	{"update", -30, "updater"},
+	{"patch", -30, "updater"},
+	{"server", -20, "server"},
+	{"config", -10, "configuration tool"},
+	{"settings", -10, "configuration tool"},
+	{"editor", -10, "editor"},
+	{"helper", -10, "helper"},
+}
+
+// used for installers that don't say so in their name, see PeInfo.Installer
+var installerHint = nameHint{score: -50, reason: "installer"}
+
+// Rank orders the executables of files (keyed by slash-separated path,
+// like DirInfo.Files) by how likely they are to be what a user wants to
+// launch, most likely first. Libraries, screensavers and control panel
+// applets are left out.
+//
+// It's a heuristic, based on the subsystem, icon, version info, size,
+// location and name of each executable, and whether it looks like an
+// installer, a crash handler, a launcher, etc.
+func Rank(files map[string]*PeInfo) []*LaunchCandidate {
+	var largest int64
+	for p, info := range files {
+		if isLaunchCandidate(p, info) && info.Size > largest {
+			largest = info.Size
+		}
+	}
+
+	byPath := make(map[string]*LaunchCandidate)
+	// paths of candidates whose name or kind gives them away
+	// as something else than a game (installers, etc.)
+	hinted := make(map[string]bool)
+	for p, info := range files {
+		if !isLaunchCandidate(p, info) {
+			continue
+		}
+
+		lc := &LaunchCandidate{Path: p}
+		add := func(score int, reason string) {
+			lc.Score += score
+			lc.Reasons = append(lc.Reasons, reason)
+		}
+
+		switch info.Subsystem {
+		case SubsystemGUI:
+			add(30, "GUI executable")
+		case SubsystemConsole:
+			add(-10, "console executable")
+		case SubsystemUnknown:
+		default:
+			add(-100, "not a Windows application")
+		}
+
+		if info.HasIcon {
+			add(20, "has an icon")
+		}
+		if info.VersionProperties["ProductName"] != "" || info.VersionProperties["FileDescription"] != "" {
+			add(10, "has version info")
+		}
+		if info.IsDebugBuild {
+			add(-10, "debug build")
+		}
+
+		if largest > 0 && info.Size == largest {
+			add(10, "largest executable")
+		}
+
+		// games are rarely buried deep in their folder
+		depth := strings.Count(p, "/")
+		if depth == 0 {
+			add(10, "at the top of the folder")
+		} else if depth > 1 {
+			add(-5*(depth-1), "deep in the folder")
+		}
+
+		name := strings.ToLower(path.Base(p))
+		var match *nameHint
+		for i, hint := range launchNameHints {
+			if strings.Contains(name, hint.substring) {
+				match = &launchNameHints[i]
+				break
+			}
+		}
+		// detection is more telling than milder name hints
+		if info.Installer != nil && (match == nil || match.score > installerHint.score) {
+			match = &installerHint
+		}
+		if match != nil {
+			add(match.score, match.reason)
+			hinted[p] = true
+		}
+
+		byPath[p] = lc
+	}
+
+	absorbLaunchTargets(files, byPath, hinted)
+
+	var res []*LaunchCandidate
+	for _, lc := range byPath {
+		res = append(res, lc)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Score != res[j].Score {
+			return res[i].Score > res[j].Score
+		}
+		return res[i].Path < res[j].Path
+	})
+	return res
+}
+
+// absorbLaunchTargets removes the executables started by launchers from
+// byPath, and records them in the LaunchCandidate of the launcher, so
+// that a game isn't listed twice. Installers and such that launchers
+// start (see hinted) are left alone.
+func absorbLaunchTargets(files map[string]*PeInfo, byPath map[string]*LaunchCandidate, hinted map[string]bool) {
+	targets := make(map[string][]string)
+	targeted := make(map[string]bool)
+	for p := range byPath {
+		li := files[p].Launcher
+		if li == nil || hinted[p] {
+			continue
+		}
+		for _, t := range launcherTargets(p, li, byPath) {
+			if !hinted[t] {
+				targets[p] = append(targets[p], t)
+				targeted[t] = true
+			}
+		}
+	}
+
+	var launchers []string
+	for p := range targets {
+		// launchers started by other launchers are absorbed with their targets
+		if !targeted[p] {
+			launchers = append(launchers, p)
+		}
+	}
+	sort.Strings(launchers)
+
+	absorbed := make(map[string]bool)
+	for _, p := range launchers {
+		lc := byPath[p]
+		visited := map[string]bool{p: true}
+		queue := targets[p]
+		for len(queue) > 0 {
+			t := queue[0]
+			queue = queue[1:]
+			if visited[t] {
+				continue
+			}
+			visited[t] = true
+			absorbed[t] = true
+
+			lc.Launches = append(lc.Launches, t)
+			lc.Reasons = append(lc.Reasons, "launches "+t)
+			if target := byPath[t]; target.Score > lc.Score {
+				lc.Score = target.Score
+			}
+			queue = append(queue, targets[t]...)
+		}
+	}
+
+	for t := range absorbed {
+		delete(byPath, t)
+	}
+}
+
+func isLaunchCandidate(p string, info *PeInfo) bool {
+	if strings.ToLower(path.Ext(p)) != ".exe" {
+		return false
+	}
+	return info.Kind == ImageKindUnknown || info.Kind.IsLaunchable()
+}
@@ -0,0 +1,114 @@
+package pelican
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Rank(t *testing.T) {
+	game := &PeInfo{
+		Subsystem:         SubsystemGUI,
+		Size:              650 * 1024,
+		HasIcon:           true,
+		VersionProperties: map[string]string{"ProductName": "Stockboy"},
+	}
+	files := map[string]*PeInfo{
+		"Stockboy.exe": game,
+		"UnityCrashHandler64.exe": {
+			Subsystem:         SubsystemGUI,
+			Size:              1500 * 1024,
+			HasIcon:           true,
+			VersionProperties: map[string]string{"ProductName": "Unity Player"},
+		},
+		"UnityPlayer.dll": {Subsystem: SubsystemGUI, Size: 20 << 20},
+		"unins000.exe": {
+			Subsystem: SubsystemGUI,
+			Size:      700 * 1024,
+			HasIcon:   true,
+		},
+		"tools/server.exe": {Subsystem: SubsystemConsole, Size: 100 * 1024},
+		"redist/setup_helper.exe": {
+			Subsystem: SubsystemGUI,
+			Size:      100 * 1024,
+			Installer: &InstallerInfo{Type: InstallerNSIS},
+		},
+		"bin/x64/drivers/efi.exe": {Subsystem: SubsystemEFI},
+	}
+
+	ranked := Rank(files)
+	var paths []string
+	for _, lc := range ranked {
+		paths = append(paths, lc.Path)
+	}
+	assert.EqualValues(t, []string{
+		"Stockboy.exe",
+		"UnityCrashHandler64.exe",
+		"redist/setup_helper.exe",
+		"unins000.exe",
+		"tools/server.exe",
+		"bin/x64/drivers/efi.exe",
+	}, paths)
+
+	assert.EqualValues(t, &LaunchCandidate{
+		Path:    "Stockboy.exe",
+		Score:   70,
+		Reasons: []string{"GUI executable", "has an icon", "has version info", "at the top of the folder"},
+	}, ranked[0])
+	assert.EqualValues(t, []string{"GUI executable", "has an icon", "has version info", "largest executable", "at the top of the folder", "crash handler"}, ranked[1].Reasons)
+
+	// detected installers are recognized too, which wins
+	// over milder name hints
+	files["redist/helper.exe"] = files["redist/setup_helper.exe"]
+	delete(files, "redist/setup_helper.exe")
+	ranked = Rank(files)
+	assert.EqualValues(t, "redist/helper.exe", ranked[2].Path)
+	assert.EqualValues(t, []string{"GUI executable", "installer"}, ranked[2].Reasons)
+}
+
+func Test_RankKinds(t *testing.T) {
+	ranked := Rank(map[string]*PeInfo{
+		"game.exe":     {Kind: ImageKindExecutable, Subsystem: SubsystemGUI, Size: 100},
+		"stars.exe":    {Kind: ImageKindScreensaver, Subsystem: SubsystemGUI, Size: 200},
+		"settings.exe": {Kind: ImageKindControlPanel, Subsystem: SubsystemGUI, Size: 200},
+	})
+	assert.EqualValues(t, 1, len(ranked))
+	assert.EqualValues(t, "game.exe", ranked[0].Path)
+	assert.Contains(t, ranked[0].Reasons, "largest executable")
+}
+
+func Test_RankLaunchers(t *testing.T) {
+	launcher := &LauncherInfo{
+		Targets:  []string{`Game\Binaries\Win64\Game-Win64-Shipping.exe`, "UnityCrashHandler64.exe"},
+		Evidence: "imports CreateProcessW",
+	}
+	files := map[string]*PeInfo{
+		"Game.exe": {Subsystem: SubsystemGUI, Size: 200 * 1024, HasIcon: true, Launcher: launcher},
+		"Game/Binaries/Win64/Game-Win64-Shipping.exe": {
+			Subsystem:         SubsystemGUI,
+			Size:              80 << 20,
+			HasIcon:           true,
+			VersionProperties: map[string]string{"ProductName": "Game"},
+		},
+		"UnityCrashHandler64.exe": {Subsystem: SubsystemGUI, Size: 100 * 1024},
+		"Engine/Tools/editor.exe": {Subsystem: SubsystemGUI, Size: 100 * 1024},
+	}
+
+	ranked := Rank(files)
+	assert.EqualValues(t, 3, len(ranked))
+	// the shipping executable is left out, crash handlers
+	// aren't what launchers are for
+	assert.EqualValues(t, &LaunchCandidate{
+		Path:     "Game.exe",
+		Score:    60,
+		Reasons:  []string{"GUI executable", "has an icon", "at the top of the folder", "launches Game/Binaries/Win64/Game-Win64-Shipping.exe"},
+		Launches: []string{"Game/Binaries/Win64/Game-Win64-Shipping.exe"},
+	}, ranked[0])
+
+	// launchers of launchers, targets found by name
+	files["Splash.exe"] = &PeInfo{Subsystem: SubsystemGUI, Size: 100 * 1024, Launcher: &LauncherInfo{Targets: []string{"GAME.EXE"}}}
+	ranked = Rank(files)
+	assert.EqualValues(t, "Splash.exe", ranked[0].Path)
+	assert.EqualValues(t, 60, ranked[0].Score)
+	assert.EqualValues(t, []string{"Game.exe", "Game/Binaries/Win64/Game-Win64-Shipping.exe"}, ranked[0].Launches)
+}
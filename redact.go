@@ -0,0 +1,66 @@
+package pelican
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+)
+
+// redacted values are replaced with this prefix, followed by the
+// hex-encoded SHA-256 of the original value, so that they can still
+// be compared across binaries (and against known values)
+const redactedPrefix = "redacted:sha256:"
+
+func redact(s string) string {
+	if strings.HasPrefix(s, redactedPrefix) {
+		return s
+	}
+	h := sha256.Sum256([]byte(s))
+	return redactedPrefix + hex.EncodeToString(h[:])
+}
+
+func redactAll(values []string) []string {
+	if values == nil {
+		return nil
+	}
+	res := make([]string, len(values))
+	for i, s := range values {
+		res[i] = redact(s)
+	}
+	return res
+}
+
+// Redacted returns a copy of pi without values that may be sensitive,
+// like build paths (which often contain user names), for reports that
+// are shared publicly. They're replaced with hashes, see ProbeParams.Redact.
+// pi itself is not modified.
+func (pi *PeInfo) Redacted() *PeInfo {
+	res := new(PeInfo)
+	*res = *pi
+
+	if pi.Indicators != nil {
+		ind := *pi.Indicators
+		ind.BuildPaths = redactAll(ind.BuildPaths)
+		res.Indicators = &ind
+	}
+
+	if pi.Provenance != nil {
+		res.Provenance = make([]*ProvenanceFinding, len(pi.Provenance))
+		for i, pf := range pi.Provenance {
+			f := *pf
+			f.Path = redact(f.Path)
+			if f.Kind == ProvenanceUserHome {
+				f.Detail = redact(f.Detail)
+			}
+			res.Provenance[i] = &f
+		}
+	}
+
+	if pi.PDB != nil {
+		pdb := *pi.PDB
+		pdb.Path = redact(pdb.Path)
+		res.PDB = &pdb
+	}
+
+	return res
+}
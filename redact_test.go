@@ -0,0 +1,43 @@
+package pelican
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Redacted(t *testing.T) {
+	info := &PeInfo{
+		Arch: ArchAmd64,
+		Indicators: &Indicators{
+			URLs:       []string{"https://example.org"},
+			BuildPaths: []string{`C:\Users\jane\game\main.cpp`},
+		},
+		Provenance: []*ProvenanceFinding{
+			{Kind: ProvenanceUserHome, Path: `C:\Users\jane\game\main.cpp`, Detail: "jane"},
+			{Kind: ProvenanceCI, Path: `D:\a\game\game\main.cpp`, Detail: "GitHub Actions"},
+		},
+		PDB: &PDBInfo{Path: `C:\Users\jane\game\game.pdb`, SymbolServerKey: "3F2504E04F8911D39A0C0305E82C33012A"},
+	}
+
+	redacted := info.Redacted()
+	assert.EqualValues(t, ArchAmd64, redacted.Arch)
+	assert.EqualValues(t, []string{"https://example.org"}, redacted.Indicators.URLs)
+	assert.EqualValues(t, []string{
+		"redacted:sha256:8d5434ed51f598b3b6cfe5e717948b1464fe2fdc19f4623acf17a7285a7b3c11",
+	}, redacted.Indicators.BuildPaths)
+
+	assert.EqualValues(t, redact(`C:\Users\jane\game\main.cpp`), redacted.Provenance[0].Path)
+	assert.EqualValues(t, redact("jane"), redacted.Provenance[0].Detail)
+	assert.EqualValues(t, "GitHub Actions", redacted.Provenance[1].Detail)
+	// symbol servers are still usable
+	assert.EqualValues(t, redact(`C:\Users\jane\game\game.pdb`), redacted.PDB.Path)
+	assert.EqualValues(t, info.PDB.SymbolServerKey, redacted.PDB.SymbolServerKey)
+
+	// the original is left alone, and redacting twice changes nothing
+	assert.EqualValues(t, `C:\Users\jane\game\main.cpp`, info.Indicators.BuildPaths[0])
+	assert.EqualValues(t, "jane", info.Provenance[0].Detail)
+	assert.EqualValues(t, redacted, redacted.Redacted())
+
+	assert.Nil(t, (&PeInfo{}).Redacted().Indicators)
+}
@@ -0,0 +1,209 @@
+package pelican
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+
+	"github.com/itchio/httpkit/eos"
+	"github.com/itchio/pelican/pe"
+	"github.com/pkg/errors"
+)
+
+// headersHash hashes everything up to SizeOfHeaders, which covers
+// the DOS header and stub, the PE headers and the section table.
+func headersHash(r io.ReaderAt, pf *pe.File, size int64) (string, error) {
+	sizeOfHeaders := int64(pf.OptionalHeaderFields().SizeOfHeaders)
+	if sizeOfHeaders <= 0 || sizeOfHeaders > size {
+		sizeOfHeaders = size
+	}
+
+	h := sha256.New()
+	_, err := io.Copy(h, io.NewSectionReader(r, 0, sizeOfHeaders))
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Reprobe is like Probe, but when the headers and section table of file
+// are identical to those previous was computed from, it only re-parses
+// the parts that are likely to have changed (resources and the
+// signature), and reuses everything else from previous.
+//
+// previous is never modified, so Reprobe is safe to call concurrently
+// with the same previous result.
+func Reprobe(file eos.File, previous *PeInfo, params ProbeParams) (*PeInfo, error) {
+	params.setDefaults()
+	consumer := params.Consumer
+	// before any of the memory budget is used
+	probeParams := params
+
+	if previous == nil || previous.HeadersSHA256 == "" {
+		return Probe(file, probeParams)
+	}
+
+	stats, err := file.Stat()
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	r := newPrefetchReaderAt(params.readerAt(file), stats.Size())
+	pf, err := pe.NewFile(r, stats.Size())
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	prefetched := prefetchGroups(pf)
+	if size := prefetchSize(prefetched); params.reserve(size) {
+		r.prefetch(prefetched)
+		defer params.release(size)
+	}
+
+	hash, err := headersHash(r, pf, stats.Size())
+	if err != nil {
+		return nil, errors.WithMessage(err, "while hashing headers")
+	}
+	if hash != previous.HeadersSHA256 {
+		consumer.Debugf("Headers changed, doing a full probe")
+		return Probe(file, probeParams)
+	}
+	consumer.Debugf("Headers unchanged, only re-parsing resources")
+
+	// everything is reused, except for what's computed again below,
+	// which is reset here, so that fields added to PeInfo are kept
+	// unless they depend on resources
+	reused := *previous
+	info := &reused
+	info.Size = stats.Size()
+	info.VersionProperties = make(map[string]string)
+	info.AssemblyInfo = nil
+	info.DependentAssemblies = nil
+	info.Dialogs = nil
+	info.Menus = nil
+	info.Accelerators = nil
+	info.Compatibility = nil
+	info.HasIcon = false
+	info.Delphi = nil
+	info.CEF = nil
+	info.Installer = nil
+	info.Launcher = nil
+	// also set from the version info, see applyFileFlags
+	info.IsDebugBuild = importsDebugCRT(previous.Imports)
+	info.IsPrerelease = false
+	info.CanonicalProductName = ""
+	info.BundledLibraries = nil
+	info.Entropy = nil
+	info.Signature = nil
+	info.ElevationReasons = nil
+	info.Assets = nil
+	info.Extensions = nil
+	info.Warnings = nil
+	for _, w := range previous.Warnings {
+		if !w.Code.isResource() && !w.Code.isSignature() {
+			info.Warnings = append(info.Warnings, w)
+		}
+	}
+
+	err = params.probeResources(info, pf, ResourceTypeNone)
+	if err != nil {
+		return nil, err
+	}
+	if params.ioErr != nil {
+		return nil, errors.WithMessage(params.ioErr, "while reading file")
+	}
+	detectDelphi(info, pf)
+	info.CanonicalProductName = canonicalProductName(info.VersionProperties)
+
+	if params.Entropy {
+		err = params.probeEntropy(info, r, stats.Size(), pf)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	// embedded version strings are outside of resources
+	if lib := identifyLibrary(info); lib != nil {
+		info.BundledLibraries = append(info.BundledLibraries, lib)
+	}
+	detectCEF(info)
+	// previous.Kind may depend on its file name
+	_, err = params.classifyKind(info, pf)
+	if err != nil {
+		return nil, err
+	}
+	detectInstaller(info, pf.Sections)
+	// the version info names the executable itself, and installers
+	// aren't launchers. Launchers are small, so scanning them again
+	// is cheap. Problems with imports and sections were already
+	// reported in previous.
+	if mayBeLauncher(info) && params.reserve(pooledBufferSize) {
+		lns := newLauncherScanner()
+		err = scanDataSections(pf, lns.scan)
+		params.release(pooledBufferSize)
+		if err == nil {
+			symbols, _ := pf.ImportedSymbols()
+			info.Launcher = detectLauncher(info, params.resolveOrdinals(symbols), lns)
+		}
+	}
+	for _, bl := range previous.BundledLibraries {
+		if bl.Source != LibrarySourceVersionInfo {
+			info.BundledLibraries = append(info.BundledLibraries, bl)
+		}
+	}
+
+	// imports live outside of resources, so they're kept as-is,
+	// but the manifest part of compatibility has to be refreshed
+	if previous.Compatibility != nil {
+		c := computeCompatibility(info, pf, nil)
+		c.ImportsMinVersion = previous.Compatibility.ImportsMinVersion
+		c.ImportsRequiring = previous.Compatibility.ImportsRequiring
+		c.summarize()
+		info.Compatibility = c
+	}
+
+	// the certificate table is outside of the headers, so it may
+	// have changed, for example if the file was signed again
+	err = ParseSignature(info, pf, params)
+	if err != nil {
+		return nil, err
+	}
+
+	// extensions may look at resources, so they're all run again,
+	// but the results of those not in params are kept
+	for name, data := range previous.Extensions {
+		if _, ok := params.Extensions[name]; ok {
+			continue
+		}
+		if info.Extensions == nil {
+			info.Extensions = make(map[string]json.RawMessage)
+		}
+		info.Extensions[name] = data
+	}
+	err = params.runExtensions(info, pf, false)
+	if err != nil {
+		return nil, err
+	}
+	info.Kind = refineKind(info.Kind, stats.Name())
+
+	if params.ElevationHeuristics {
+		info.ElevationReasons = guessElevation(info, stats.Name())
+	}
+
+	// icons and manifests are resources, so they're extracted again
+	if params.AssetDir != "" {
+		info.Assets, err = params.writeAssets(file)
+		if err != nil {
+			if params.Strict {
+				return nil, err
+			}
+			consumer.Warnf("Could not write assets: %+v", err)
+		}
+	}
+
+	if params.Redact {
+		info = info.Redacted()
+	}
+
+	return info, nil
+}
@@ -1,7 +1,6 @@
 package pelican
 
 import (
-	"encoding/binary"
 	"fmt"
 	"io"
 	"io/ioutil"
@@ -9,207 +8,264 @@ import (
 
 	"github.com/itchio/headway/united"
 	"github.com/itchio/pelican/pe"
-
-	xj "github.com/basgys/goxml2json"
+	"github.com/itchio/pelican/resources"
 	"github.com/pkg/errors"
 )
 
-type imageResourceDirectory struct {
-	Characteristics      uint32
-	TimeDateStamp        uint32
-	MajorVersion         uint16
-	MinorVersion         uint16
-	NumberOfNamedEntries uint16
-	NumberOfIdEntries    uint16
+// The resource parser lives in the resources package,
+// these are kept for compatibility.
+
+type ResourceType = resources.Type
+
+const (
+	ResourceTypeNone = resources.TypeNone
+
+	ResourceTypeCursor       = resources.TypeCursor
+	ResourceTypeBitmap       = resources.TypeBitmap
+	ResourceTypeIcon         = resources.TypeIcon
+	ResourceTypeMenu         = resources.TypeMenu
+	ResourceTypeDialog       = resources.TypeDialog
+	ResourceTypeString       = resources.TypeString
+	ResourceTypeFontDir      = resources.TypeFontDir
+	ResourceTypeFont         = resources.TypeFont
+	ResourceTypeAccelerator  = resources.TypeAccelerator
+	ResourceTypeRcData       = resources.TypeRcData
+	ResourceTypeMessageTable = resources.TypeMessageTable
+
+	ResourceTypeGroupCursor = resources.TypeGroupCursor
+	ResourceTypeGroupIcon   = resources.TypeGroupIcon
+
+	ResourceTypeVersion    = resources.TypeVersion
+	ResourceTypeDlgInclude = resources.TypeDlgInclude
+	ResourceTypePlugPlay   = resources.TypePlugPlay
+	ResourceTypeVXD        = resources.TypeVXD
+	ResourceTypeAniCursor  = resources.TypeAniCursor
+	ResourceTypeAniIcon    = resources.TypeAniIcon
+	ResourceTypeHTML       = resources.TypeHTML
+	ResourceTypeManifest   = resources.TypeManifest
+)
+
+var ResourceTypeNames = resources.TypeNames
+
+type (
+	VsBlock         = resources.VsBlock
+	VsFixedFileInfo = resources.VsFixedFileInfo
+	ReadSeekerAt    = resources.ReadSeekerAt
+)
+
+// Convert a UTF-16 string (as a byte slice) to unicode
+func DecodeUTF16(bs []byte) string {
+	return resources.DecodeUTF16(bs)
 }
 
-type imageResourceDirectoryEntry struct {
-	NameId uint32
-	Data   uint32
+// findResources locates the resource table through the resource data
+// directory, since packers and some linkers rename the .rsrc section.
+// The .rsrc section is used as a fallback if the directory is bogus.
+// It returns nil if there are no resources.
+func findResources(pf *pe.File) *resources.Image {
+	dirs := dataDirectories(pf)
+	if len(dirs) > pe.IMAGE_DIRECTORY_ENTRY_RESOURCE {
+		dd := dirs[pe.IMAGE_DIRECTORY_ENTRY_RESOURCE]
+		for _, s := range pf.Sections {
+			if dd.VirtualAddress == 0 {
+				break
+			}
+			if s.VirtualAddress <= dd.VirtualAddress && uint64(dd.VirtualAddress) < uint64(s.VirtualAddress)+uint64(s.Size) {
+				offset := int64(dd.VirtualAddress - s.VirtualAddress)
+				return newResourceImage(pf, io.NewSectionReader(s, offset, int64(s.Size)-offset), int64(s.Size)-offset, dd.VirtualAddress)
+			}
+		}
+	}
+
+	if s := pf.Section(".rsrc"); s != nil {
+		return newResourceImage(pf, s, int64(s.Size), s.VirtualAddress)
+	}
+	return nil
 }
 
-type imageResourceDataEntry struct {
-	Data     uint32
-	Size     uint32
-	CodePage uint32
-	Reserved uint32
+// newResourceImage returns an image for the resource table at the start
+// of r, which looks up data outside of it in the other sections of pf
+func newResourceImage(pf *pe.File, r io.ReaderAt, size int64, rva uint32) *resources.Image {
+	img := resources.NewImage(r, size, rva)
+	img.ReadOutside = func(re *resources.Entry) (*io.SectionReader, error) {
+		for _, s := range pf.Sections {
+			if s.VirtualAddress <= re.RVA && uint64(re.RVA)+uint64(re.Size) <= uint64(s.VirtualAddress)+uint64(s.Size) {
+				return io.NewSectionReader(s, int64(re.RVA-s.VirtualAddress), int64(re.Size)), nil
+			}
+		}
+		return nil, errors.Errorf("%s resource %s (at RVA %x) is not backed by file data", re.TypeString(), re.IDString(), re.RVA)
+	}
+	return img
 }
 
-type ResourceType uint32
+// walkResources calls cb for every resource, including those whose data
+// lies outside of the resource section (see resources.Entry.Outside).
+// Unreadable subtrees, and resources that extend past the end of the
+// resource section, are skipped with a warning, recorded in info if non-nil.
+func (params *ProbeParams) walkResources(info *PeInfo, img *resources.Image, cb func(re *resources.Entry) error) error {
+	consumer := params.Consumer
+	consumer.Debugf("Found resource table at %x (%s)", img.RVA, united.FormatBytes(img.Size()))
 
-// https://msdn.microsoft.com/fr-fr/library/windows/desktop/ms648009(v=vs.85).aspx
-const (
-	ResourceTypeNone ResourceType = 0
-
-	ResourceTypeCursor       ResourceType = 1
-	ResourceTypeBitmap       ResourceType = 2
-	ResourceTypeIcon         ResourceType = 3
-	ResourceTypeMenu         ResourceType = 4
-	ResourceTypeDialog       ResourceType = 5
-	ResourceTypeString       ResourceType = 6
-	ResourceTypeFontDir      ResourceType = 7
-	ResourceTypeFont         ResourceType = 8
-	ResourceTypeAccelerator  ResourceType = 9
-	ResourceTypeRcData       ResourceType = 10
-	ResourceTypeMessageTable ResourceType = 11
-
-	ResourceTypeGroupCursor ResourceType = ResourceTypeCursor + 11 // 12
-	ResourceTypeGroupIcon   ResourceType = ResourceTypeIcon + 11   // 14
-
-	ResourceTypeVersion    ResourceType = 16
-	ResourceTypeDlgInclude ResourceType = 17
-	ResourceTypePlugPlay   ResourceType = 19
-	ResourceTypeVXD        ResourceType = 20 // vxd = virtual device
-	ResourceTypeAniCursor  ResourceType = 21
-	ResourceTypeAniIcon    ResourceType = 22
-	ResourceTypeHTML       ResourceType = 23
-	ResourceTypeManifest   ResourceType = 24
-)
+	// a subtree that can't be read (corrupt, or mangled by a packer)
+	// doesn't prevent reading its siblings, except in strict mode
+	tolerate := func(err error, entry *resources.Entry, level int) error {
+		if params.Strict {
+			return err
+		}
+		what := "resource directory"
+		switch level {
+		case 1:
+			what = fmt.Sprintf("%s resources", entry.TypeString())
+		case 2:
+			what = fmt.Sprintf("%s resource %s", entry.TypeString(), entry.IDString())
+		}
+		params.warn(info, WarningResourceSubtreeUnreadable, err, "Could not read %s", what)
+		return nil
+	}
 
-var ResourceTypeNames = map[ResourceType]string{
-	ResourceTypeCursor:       "Cursor",
-	ResourceTypeBitmap:       "Bitmap",
-	ResourceTypeIcon:         "Icon",
-	ResourceTypeMenu:         "Menu",
-	ResourceTypeDialog:       "Dialog",
-	ResourceTypeString:       "String",
-	ResourceTypeFontDir:      "FontDir",
-	ResourceTypeFont:         "Font",
-	ResourceTypeAccelerator:  "Accelerator",
-	ResourceTypeRcData:       "RcData",
-	ResourceTypeMessageTable: "MessageTable",
-	ResourceTypeGroupCursor:  "GroupCursor",
-	ResourceTypeGroupIcon:    "GroupIcon",
-	ResourceTypeVersion:      "Version",
-	ResourceTypeDlgInclude:   "DlgInclude",
-	ResourceTypePlugPlay:     "PlugPlay",
-	ResourceTypeVXD:          "VXD",
-	ResourceTypeAniCursor:    "AniCursor",
-	ResourceTypeAniIcon:      "AniIcon",
-	ResourceTypeHTML:         "HTML",
-	ResourceTypeManifest:     "Manifest",
+	err := img.Walk(func(re *resources.Entry) error {
+		consumer.Debugf("%s/%s/%d @ %x (%s, %d bytes)", re.TypeString(), re.IDString(), re.Language, re.RVA, united.FormatBytes(int64(re.Size)), re.Size)
+		if re.Truncated {
+			params.warn(info, WarningResourceTruncated, nil, "%s resource %s extends past the end of the resource section, skipping", re.TypeString(), re.IDString())
+			return nil
+		}
+		return cb(re)
+	}, tolerate)
+	if err != nil {
+		return errors.WithStack(err)
+	}
+
+	return nil
 }
 
-func (params *ProbeParams) parseResources(info *PeInfo, sect *pe.Section) error {
+// parseResources parses the resources of img that pelican is interested in,
+// or only those of type only, if it's not ResourceTypeNone
+func (params *ProbeParams) parseResources(info *PeInfo, img *resources.Image, only ResourceType) error {
 	consumer := params.Consumer
-	consumer.Debugf("Found resource section (%s)", united.FormatBytes(int64(sect.Size)))
 
-	var readDirectory func(offset uint32, level int, resourceType ResourceType) error
-	readDirectory = func(offset uint32, level int, resourceType ResourceType) error {
-		prefix := strings.Repeat("  ", level)
-		log := func(msg string, args ...interface{}) {
-			consumer.Debugf("%s%s", prefix, fmt.Sprintf(msg, args...))
+	return params.walkResources(info, img, func(re *resources.Entry) error {
+		if only == ResourceTypeNone && re.Type == ResourceTypeRcData && re.TypeName == "" && re.Name != "" {
+			noteDelphiResource(info, re.Name)
+		}
+		if only == ResourceTypeNone && re.Type == ResourceTypeGroupIcon && re.TypeName == "" {
+			info.HasIcon = true
 		}
 
-		br := io.NewSectionReader(sect, int64(offset), int64(sect.Size)-int64(offset))
-		ird := new(imageResourceDirectory)
-		err := binary.Read(br, binary.LittleEndian, ird)
-		if err != nil {
-			return errors.WithStack(err)
+		// other named types and resources are not of interest here
+		if re.TypeName != "" || re.Name != "" {
+			return nil
 		}
 
-		for i := uint16(0); i < ird.NumberOfNamedEntries+ird.NumberOfIdEntries; i++ {
-			irde := new(imageResourceDirectoryEntry)
-			err = binary.Read(br, binary.LittleEndian, irde)
-			if err != nil {
-				return errors.WithStack(err)
+		switch re.Type {
+		case ResourceTypeManifest, ResourceTypeVersion, ResourceTypeDialog,
+			ResourceTypeMenu, ResourceTypeAccelerator:
+		default:
+			return nil
+		}
+		if only != ResourceTypeNone && re.Type != only {
+			return nil
+		}
+
+		// some packers leave version info uncompressed outside of the
+		// resource section, so it's worth a try
+		strict := params.Strict
+		if re.Outside {
+			if re.Type != ResourceTypeVersion {
+				params.warn(info, WarningResourcePacked, nil, "%s resource %s lies outside the resource section (packed executable?), skipping", re.TypeString(), re.IDString())
+				return nil
 			}
+			params.warn(info, WarningResourcePacked, nil, "%s resource %s lies outside the resource section (packed executable?), trying anyway", re.TypeString(), re.IDString())
+			strict = false
+		}
+
+		if !params.reserveOrWarn(info, int64(re.Size), "%s resource %s", re.TypeString(), re.IDString()) {
+			return nil
+		}
+		defer params.release(int64(re.Size))
 
-			if irde.NameId&0x80000000 > 0 {
-				continue
+		sr, err := img.Open(re)
+		if err != nil {
+			params.warn(info, WarningResourceVersionInvalid, err, "Could not read version block")
+			return nil
+		}
+
+		rawData, err := ioutil.ReadAll(sr)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		switch re.Type {
+		case ResourceTypeManifest:
+			// actually not utf-16,
+			// but TODO: figure out
+			// codepage
+			stringData := string(rawData)
+			consumer.Debugf("=========================")
+			for _, l := range strings.Split(stringData, "\n") {
+				consumer.Debugf("%s", l)
 			}
+			consumer.Debugf("=========================")
 
-			id := irde.NameId & 0xffff
-			if level == 0 {
-				typ := ResourceType(id)
-				if name, ok := ResourceTypeNames[typ]; ok {
-					log("=> %s", name)
-				} else {
-					log("=> type #%d (unknown)", id)
+			m, err := resources.ParseManifest(rawData)
+			if err != nil {
+				if params.Strict {
+					return errors.WithMessage(err, "while parsing manifest")
 				}
+				params.warn(info, WarningResourceManifestInvalid, err, "Could not parse manifest")
 			} else {
-				log("=> %d", id)
+				info.AssemblyInfo = m.Assembly
+				info.DependentAssemblies = append(info.DependentAssemblies, m.DependentAssemblies...)
 			}
-
-			if irde.Data&0x80000000 > 0 {
-				offset := irde.Data & 0x7fffffff
-				recResourceType := resourceType
-				if level == 0 {
-					recResourceType = ResourceType(id)
-				}
-
-				err := readDirectory(offset, level+1, recResourceType)
-				if err != nil {
-					return errors.WithStack(err)
+		case ResourceTypeVersion:
+			err := params.parseVersion(info, rawData)
+			if err != nil {
+				if strict {
+					return errors.WithMessage(err, "while parsing version block")
 				}
-				continue
+				params.warn(info, WarningResourceVersionInvalid, err, "Could not parse version block")
 			}
-
-			dbr := io.NewSectionReader(sect, int64(irde.Data), int64(sect.Size)-int64(irde.Data))
-
-			irda := new(imageResourceDataEntry)
-			err = binary.Read(dbr, binary.LittleEndian, irda)
+		case ResourceTypeDialog:
+			err := params.parseDialog(info, re.ID, re.Language, rawData)
 			if err != nil {
-				return errors.WithStack(err)
+				if params.Strict {
+					return errors.WithMessage(err, "while parsing dialog template")
+				}
+				params.warn(info, WarningResourceDialogInvalid, err, "Could not parse dialog template %d", re.ID)
 			}
-
-			if resourceType == ResourceTypeManifest || resourceType == ResourceTypeVersion {
-				log("@ %x (%s, %d bytes)", irda.Data, united.FormatBytes(int64(irda.Size)), irda.Size)
-
-				dataStart := int64(irda.Data - sect.VirtualAddress)
-				log("is dataStart 32-bit aligned? %v", dataStart%4 == 0)
-				sr := io.NewSectionReader(sect, dataStart, int64(irda.Size))
-
-				rawData, err := ioutil.ReadAll(sr)
-				if err != nil {
-					return errors.WithStack(err)
+		case ResourceTypeMenu:
+			err := params.parseMenu(info, re.ID, re.Language, rawData)
+			if err != nil {
+				if params.Strict {
+					return errors.WithMessage(err, "while parsing menu template")
 				}
-
-				switch resourceType {
-				case ResourceTypeManifest:
-					// actually not utf-16,
-					// but TODO: figure out
-					// codepage
-					stringData := string(rawData)
-					log("=========================")
-					for _, l := range strings.Split(stringData, "\n") {
-						log("%s", l)
-					}
-					log("=========================")
-
-					js, err := xj.Convert(strings.NewReader(stringData))
-					if err != nil {
-						if params.Strict {
-							return errors.WithMessage(err, "while converting manifest to json")
-						}
-						consumer.Warnf("Could not convert manifest to json: %+v", err)
-					} else {
-						err := interpretManifest(info, js.Bytes())
-						if err != nil {
-							if params.Strict {
-								return errors.WithMessage(err, "while intepreting manifest")
-							}
-							consumer.Warnf("Could not interpret manifest: %+v", err)
-						}
-					}
-				case ResourceTypeVersion:
-					err := params.parseVersion(info, rawData)
-					if err != nil {
-						if params.Strict {
-							return errors.WithMessage(err, "while parsing version block")
-						}
-						consumer.Warnf("Could not parse resources: %+v", err)
-					}
+				params.warn(info, WarningResourceMenuInvalid, err, "Could not parse menu template %d", re.ID)
+			}
+		case ResourceTypeAccelerator:
+			err := params.parseAccelerators(info, re.ID, re.Language, rawData)
+			if err != nil {
+				if params.Strict {
+					return errors.WithMessage(err, "while parsing accelerator table")
 				}
+				params.warn(info, WarningResourceAcceleratorInvalid, err, "Could not parse accelerator table %d", re.ID)
 			}
 		}
 		return nil
-	}
+	})
+}
 
-	err := readDirectory(0, 0, 0)
-	if err != nil {
-		return errors.WithStack(err)
+func (params *ProbeParams) parseVersion(info *PeInfo, rawData []byte) error {
+	vi, err := resources.ParseVersionInfo(rawData)
+	if vi == nil {
+		return err
 	}
 
-	return nil
+	// what could be parsed is kept, even if the rest couldn't
+	if vi.FixedFileInfo != nil {
+		applyFileFlags(info, vi.FixedFileInfo)
+	}
+	for key, value := range vi.Properties() {
+		params.Consumer.Debugf("%s: %s", key, value)
+		info.VersionProperties[key] = value
+	}
+	return err
 }
@@ -0,0 +1,70 @@
+package resources
+
+import (
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/japanese"
+	"golang.org/x/text/encoding/korean"
+	"golang.org/x/text/encoding/simplifiedchinese"
+	"golang.org/x/text/encoding/traditionalchinese"
+)
+
+const codePageUTF16 = 1200
+
+// legacy (ANSI) Windows code pages, see
+// https://docs.microsoft.com/en-us/windows/win32/intl/code-page-identifiers
+var codePageEncodings = map[uint16]encoding.Encoding{
+	874:  charmap.Windows874,
+	932:  japanese.ShiftJIS,
+	936:  simplifiedchinese.GBK,
+	949:  korean.EUCKR,
+	950:  traditionalchinese.Big5,
+	1250: charmap.Windows1250,
+	1251: charmap.Windows1251,
+	1252: charmap.Windows1252,
+	1253: charmap.Windows1253,
+	1254: charmap.Windows1254,
+	1255: charmap.Windows1255,
+	1256: charmap.Windows1256,
+	1257: charmap.Windows1257,
+	1258: charmap.Windows1258,
+}
+
+// DecodeVersionString decodes the value of a version string.
+// They're stored as UTF-16, but some resource compilers widen each byte
+// of the ANSI string to a UTF-16 code unit instead of converting it,
+// which turns "Игра" (CP1251) into "Èãðà". If the string table specifies
+// a legacy code page and every code unit fits in a byte, the bytes are
+// decoded with that code page, unless they're not valid in it.
+func DecodeVersionString(raw []byte, codePage uint16) string {
+	s := DecodeUTF16(raw)
+	if enc, ok := codePageEncodings[codePage]; ok {
+		if narrow, ok := widenedBytes(raw); ok {
+			decoded, err := enc.NewDecoder().Bytes(narrow)
+			if err == nil && utf8.Valid(decoded) && !strings.ContainsRune(string(decoded), utf8.RuneError) {
+				s = string(decoded)
+			}
+		}
+	}
+	return strings.TrimSpace(s)
+}
+
+// widenedBytes returns the low byte of each code unit of raw, if none of
+// them has a high byte, and at least one of them isn't ASCII
+func widenedBytes(raw []byte) ([]byte, bool) {
+	res := make([]byte, len(raw)/2)
+	hasHigh := false
+	for i := range res {
+		if raw[i*2+1] != 0 {
+			return nil, false
+		}
+		res[i] = raw[i*2]
+		if res[i] >= utf8.RuneSelf {
+			hasHigh = true
+		}
+	}
+	return res, hasHigh
+}
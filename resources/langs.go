@@ -0,0 +1,80 @@
+package resources
+
+import "strconv"
+
+// see
+// https://msdn.microsoft.com/en-us/library/windows/desktop/dd318693(v=vs.85).aspx
+func isLanguageWhitelisted(key string) bool {
+	if len(key) < 4 {
+		return false
+	}
+	localeID := key[:4]
+	primaryLangID := localeID[2:]
+
+	switch primaryLangID {
+	// neutral
+	case "00":
+		return true
+	// english
+	case "09":
+		return true
+	}
+	return false
+}
+
+// parseStringTableKey splits the key of a StringTable block, like
+// "041103a4", into a language ID (0x0411) and a code page (932)
+func parseStringTableKey(key string) (langID uint16, codePage uint16, ok bool) {
+	if len(key) != 8 {
+		return 0, 0, false
+	}
+	v, err := strconv.ParseUint(key, 16, 32)
+	if err != nil {
+		return 0, 0, false
+	}
+	return uint16(v >> 16), uint16(v), true
+}
+
+// ANSI code pages of languages that don't use Windows-1252,
+// keyed by primary language ID
+var languageCodePages = map[uint16]uint16{
+	0x01: 1256, // arabic
+	0x02: 1251, // bulgarian
+	0x05: 1250, // czech
+	0x08: 1253, // greek
+	0x0d: 1255, // hebrew
+	0x0e: 1250, // hungarian
+	0x11: 932,  // japanese
+	0x12: 949,  // korean
+	0x15: 1250, // polish
+	0x18: 1250, // romanian
+	0x19: 1251, // russian
+	0x1a: 1250, // croatian, serbian (latin)
+	0x1b: 1250, // slovak
+	0x1e: 874,  // thai
+	0x1f: 1254, // turkish
+	0x22: 1251, // ukrainian
+	0x23: 1251, // belarusian
+	0x24: 1250, // slovenian
+	0x25: 1257, // estonian
+	0x26: 1257, // latvian
+	0x27: 1257, // lithuanian
+	0x2a: 1258, // vietnamese
+}
+
+// languageCodePage returns the ANSI code page of a language ID
+func languageCodePage(langID uint16) uint16 {
+	primary := langID & 0x3ff
+	if primary == 0x04 {
+		// chinese: traditional in Taiwan, Hong Kong and Macao
+		switch langID >> 10 {
+		case 0x01, 0x03, 0x05:
+			return 950
+		}
+		return 936
+	}
+	if cp, ok := languageCodePages[primary]; ok {
+		return cp
+	}
+	return 1252
+}
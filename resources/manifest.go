@@ -0,0 +1,269 @@
+package resources
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	xj "github.com/basgys/goxml2json"
+	"github.com/pkg/errors"
+)
+
+// Manifest is what pelican gets out of an application manifest
+// (RT_MANIFEST resource, or .manifest file), see ParseManifest
+type Manifest struct {
+	Assembly *AssemblyInfo
+	// Side-by-side assemblies it depends on
+	DependentAssemblies []*AssemblyIdentity
+	// Names of all the <file> elements, which list the files
+	// of an assembly (relative to the manifest)
+	Files []string
+}
+
+type AssemblyInfo struct {
+	// Identity of the binary itself, as declared by the manifest's
+	// top-level <assemblyIdentity> element. Some games only
+	// version themselves there.
+	Identity    *AssemblyIdentity `json:"identity"`
+	Description string            `json:"description"`
+
+	RequestedExecutionLevel string `json:"requestedExecutionLevel,omitempty"`
+
+	WindowsSettings *WindowsSettings `json:"windowsSettings,omitempty"`
+
+	// GUIDs of the <compatibility><application><supportedOS> elements
+	SupportedOS []string `json:"supportedOs,omitempty"`
+
+	// Files declaring registration-free COM classes or type libraries,
+	// which don't need to be registered at install time
+	ComServers []*ComServer `json:"comServers,omitempty"`
+}
+
+// ComServer is a <file> element of a manifest that declares
+// registration-free COM classes or type libraries
+//
+// See https://docs.microsoft.com/en-us/windows/win32/sbscs/manifest-file-schema
+type ComServer struct {
+	// Name of the file, relative to the manifest
+	File     string      `json:"file"`
+	Classes  []*ComClass `json:"classes,omitempty"`
+	TypeLibs []*TypeLib  `json:"typeLibs,omitempty"`
+}
+
+// ComClass is a <comClass> element
+type ComClass struct {
+	CLSID          string `json:"clsid"`
+	ProgID         string `json:"progId,omitempty"`
+	ThreadingModel string `json:"threadingModel,omitempty"`
+	// GUID of the type library that describes the class
+	TypeLibID   string `json:"tlbid,omitempty"`
+	Description string `json:"description,omitempty"`
+}
+
+// TypeLib is a <typelib> element
+type TypeLib struct {
+	TypeLibID string `json:"tlbid"`
+	Version   string `json:"version,omitempty"`
+	HelpDir   string `json:"helpDir,omitempty"`
+	// For example "HASDISKIMAGE"
+	Flags string `json:"flags,omitempty"`
+}
+
+// WindowsSettings contains the <application><windowsSettings> elements
+// of a manifest, which affect runtime behavior
+//
+// See https://docs.microsoft.com/en-us/windows/win32/sbscs/application-manifests
+type WindowsSettings struct {
+	DpiAware       string `json:"dpiAware,omitempty"`
+	DpiAwareness   string `json:"dpiAwareness,omitempty"`
+	LongPathAware  bool   `json:"longPathAware,omitempty"`
+	GdiScaling     bool   `json:"gdiScaling,omitempty"`
+	ActiveCodePage string `json:"activeCodePage,omitempty"`
+
+	// "SegmentHeap" opts into the segment heap on Windows 10 2004+
+	HeapType string `json:"heapType,omitempty"`
+	// Architectures the binary natively supports (ARM64X / ARM64EC),
+	// for example ["amd64", "arm64"]
+	SupportedArchitectures []string `json:"supportedArchitectures,omitempty"`
+}
+
+type AssemblyIdentity struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	Type    string `json:"type"`
+
+	ProcessorArchitecture string `json:"processorArchitecture,omitempty"`
+	Language              string `json:"language,omitempty"`
+	PublicKeyToken        string `json:"publicKeyToken,omitempty"`
+}
+
+type node = map[string]interface{}
+
+func visit(n node, key string, f func(c node)) {
+	if c, ok := n[key].(node); ok {
+		f(c)
+	}
+}
+
+func visitMany(n node, key string, f func(c node)) {
+	if cs, ok := n[key].([]interface{}); ok {
+		for _, c := range cs {
+			if c, ok := c.(node); ok {
+				f(c)
+			}
+		}
+	}
+	if c, ok := n[key].(node); ok {
+		f(c)
+	}
+}
+
+func getString(n node, key string, f func(s string)) {
+	if s, ok := n[key].(string); ok {
+		f(s)
+	}
+}
+
+// getText is like getString, but also handles elements that carry
+// attributes (typically xmlns), which goxml2json turns into nodes
+func getText(n node, key string, f func(s string)) {
+	getString(n, key, f)
+	visit(n, key, func(c node) {
+		getString(c, "#content", f)
+	})
+}
+
+// ParseManifest parses an application or assembly manifest
+func ParseManifest(manifest []byte) (*Manifest, error) {
+	js, err := xj.Convert(bytes.NewReader(manifest))
+	if err != nil {
+		return nil, errors.WithMessage(err, "while converting manifest to json")
+	}
+
+	intermediate := make(node)
+	err = json.Unmarshal(js.Bytes(), &intermediate)
+	if err != nil {
+		return nil, errors.WithMessage(err, "while interpreting manifest")
+	}
+
+	m := &Manifest{}
+	assInfo := &AssemblyInfo{}
+
+	interpretIdentity := func(id node, f func(id *AssemblyIdentity)) {
+		ai := &AssemblyIdentity{}
+		getString(id, "-name", func(s string) { ai.Name = s })
+		getString(id, "-version", func(s string) { ai.Version = s })
+		getString(id, "-type", func(s string) { ai.Type = s })
+
+		getString(id, "-processorArchitecture", func(s string) { ai.ProcessorArchitecture = s })
+		getString(id, "-publicKeyToken", func(s string) { ai.PublicKeyToken = s })
+		getString(id, "-language", func(s string) { ai.Language = s })
+		f(ai)
+	}
+
+	visit(intermediate, "assembly", func(assembly node) {
+		// some manifests (wrongly) repeat it, the loader only honors the first one
+		visitMany(assembly, "assemblyIdentity", func(id node) {
+			if assInfo.Identity != nil {
+				return
+			}
+			interpretIdentity(id, func(ai *AssemblyIdentity) {
+				assInfo.Identity = ai
+			})
+		})
+
+		getString(assembly, "description", func(s string) { assInfo.Description = s })
+
+		visit(assembly, "trustInfo", func(ti node) {
+			visit(ti, "security", func(sec node) {
+				visit(sec, "requestedPrivileges", func(rp node) {
+					visit(rp, "requestedExecutionLevel", func(rel node) {
+						getString(rel, "-level", func(s string) {
+							assInfo.RequestedExecutionLevel = s
+						})
+					})
+				})
+			})
+		})
+
+		visitMany(assembly, "application", func(app node) {
+			// settings are split across several elements when they
+			// belong to different namespaces (2005, 2016, 2017 etc.)
+			visitMany(app, "windowsSettings", func(ws node) {
+				if assInfo.WindowsSettings == nil {
+					assInfo.WindowsSettings = &WindowsSettings{}
+				}
+				settings := assInfo.WindowsSettings
+
+				getText(ws, "dpiAware", func(s string) { settings.DpiAware = strings.TrimSpace(s) })
+				getText(ws, "dpiAwareness", func(s string) { settings.DpiAwareness = strings.TrimSpace(s) })
+				getText(ws, "longPathAware", func(s string) { settings.LongPathAware = isManifestTrue(s) })
+				getText(ws, "gdiScaling", func(s string) { settings.GdiScaling = isManifestTrue(s) })
+				getText(ws, "activeCodePage", func(s string) { settings.ActiveCodePage = strings.TrimSpace(s) })
+				getText(ws, "heapType", func(s string) { settings.HeapType = strings.TrimSpace(s) })
+				getText(ws, "supportedArchitectures", func(s string) {
+					settings.SupportedArchitectures = strings.Fields(s)
+				})
+			})
+		})
+
+		visit(assembly, "compatibility", func(compat node) {
+			visit(compat, "application", func(app node) {
+				visitMany(app, "supportedOS", func(os node) {
+					getString(os, "-Id", func(s string) {
+						assInfo.SupportedOS = append(assInfo.SupportedOS, s)
+					})
+				})
+			})
+		})
+
+		visitMany(assembly, "file", func(file node) {
+			cs := &ComServer{}
+			getString(file, "-name", func(s string) {
+				cs.File = s
+				m.Files = append(m.Files, s)
+			})
+			visitMany(file, "comClass", func(cc node) {
+				class := &ComClass{}
+				getString(cc, "-clsid", func(s string) { class.CLSID = s })
+				getString(cc, "-progid", func(s string) { class.ProgID = s })
+				getString(cc, "-threadingModel", func(s string) { class.ThreadingModel = s })
+				getString(cc, "-tlbid", func(s string) { class.TypeLibID = s })
+				getString(cc, "-description", func(s string) { class.Description = s })
+				cs.Classes = append(cs.Classes, class)
+			})
+			visitMany(file, "typelib", func(tl node) {
+				lib := &TypeLib{}
+				getString(tl, "-tlbid", func(s string) { lib.TypeLibID = s })
+				getString(tl, "-version", func(s string) { lib.Version = s })
+				getString(tl, "-helpdir", func(s string) { lib.HelpDir = s })
+				getString(tl, "-flags", func(s string) { lib.Flags = s })
+				cs.TypeLibs = append(cs.TypeLibs, lib)
+			})
+
+			// plain <file> elements just list the assembly's files
+			if len(cs.Classes) > 0 || len(cs.TypeLibs) > 0 {
+				assInfo.ComServers = append(assInfo.ComServers, cs)
+			}
+		})
+
+		// usually one <dependency> per dependent assembly
+		visitMany(assembly, "dependency", func(dep node) {
+			visitMany(dep, "dependentAssembly", func(da node) {
+				visit(da, "assemblyIdentity", func(id node) {
+					interpretIdentity(id, func(ai *AssemblyIdentity) {
+						m.DependentAssemblies = append(m.DependentAssemblies, ai)
+					})
+				})
+			})
+		})
+	})
+
+	m.Assembly = assInfo
+
+	return m, nil
+}
+
+func isManifestTrue(s string) bool {
+	return strings.EqualFold(strings.TrimSpace(s), "true")
+}
@@ -0,0 +1,122 @@
+package resources
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func parseTestManifest(t *testing.T, manifest string) *Manifest {
+	m, err := ParseManifest([]byte(manifest))
+	assert.NoError(t, err)
+	return m
+}
+
+const modernManifest = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<assembly xmlns="urn:schemas-microsoft-com:asm.v1" manifestVersion="1.0" xmlns:asmv3="urn:schemas-microsoft-com:asm.v3">
+  <assemblyIdentity type="win32" name="Itch.Game" version="1.2.3.4" processorArchitecture="amd64"/>
+  <asmv3:application>
+    <asmv3:windowsSettings xmlns="http://schemas.microsoft.com/SMI/2005/WindowsSettings">
+      <dpiAware>true/pm</dpiAware>
+    </asmv3:windowsSettings>
+    <asmv3:windowsSettings xmlns="http://schemas.microsoft.com/SMI/2016/WindowsSettings">
+      <dpiAwareness>PerMonitorV2, PerMonitor</dpiAwareness>
+      <longPathAware>true</longPathAware>
+    </asmv3:windowsSettings>
+    <asmv3:windowsSettings xmlns="http://schemas.microsoft.com/SMI/2020/WindowsSettings">
+      <heapType>SegmentHeap</heapType>
+    </asmv3:windowsSettings>
+    <asmv3:windowsSettings xmlns="http://schemas.microsoft.com/SMI/2024/WindowsSettings">
+      <supportedArchitectures>amd64 arm64</supportedArchitectures>
+    </asmv3:windowsSettings>
+  </asmv3:application>
+</assembly>`
+
+func Test_WindowsSettings(t *testing.T) {
+	m := parseTestManifest(t, modernManifest)
+
+	ai := m.Assembly
+	assert.NotNil(t, ai.Identity)
+	assert.EqualValues(t, "Itch.Game", ai.Identity.Name)
+	assert.EqualValues(t, "1.2.3.4", ai.Identity.Version)
+
+	ws := ai.WindowsSettings
+	assert.NotNil(t, ws)
+	assert.EqualValues(t, "true/pm", ws.DpiAware)
+	assert.EqualValues(t, "PerMonitorV2, PerMonitor", ws.DpiAwareness)
+	assert.True(t, ws.LongPathAware)
+	assert.EqualValues(t, "SegmentHeap", ws.HeapType)
+	assert.EqualValues(t, []string{"amd64", "arm64"}, ws.SupportedArchitectures)
+}
+
+const comManifest = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<assembly xmlns="urn:schemas-microsoft-com:asm.v1" manifestVersion="1.0">
+  <assemblyIdentity type="win32" name="Itch.Launcher" version="1.0.0.0"/>
+  <file name="readme.txt"/>
+  <file name="bink2w32.dll" hashalg="SHA1">
+    <comClass clsid="{5B1C3B0E-0A0E-4A1A-9E2B-3C6F0C0C3A01}" threadingModel="Apartment" progid="Bink.Player.1" tlbid="{5B1C3B0E-0A0E-4A1A-9E2B-3C6F0C0C3A02}" description="Bink player"/>
+    <comClass clsid="{5B1C3B0E-0A0E-4A1A-9E2B-3C6F0C0C3A03}" threadingModel="Both"/>
+    <typelib tlbid="{5B1C3B0E-0A0E-4A1A-9E2B-3C6F0C0C3A02}" version="1.0" helpdir="" flags="HASDISKIMAGE"/>
+  </file>
+  <file name="speech\sapi.dll">
+    <comClass clsid="{96749377-3391-11D2-9EE3-00C04F797396}"/>
+  </file>
+</assembly>`
+
+func Test_ComServers(t *testing.T) {
+	m := parseTestManifest(t, comManifest)
+
+	assert.EqualValues(t, []*ComServer{
+		{
+			File: "bink2w32.dll",
+			Classes: []*ComClass{
+				{
+					CLSID:          "{5B1C3B0E-0A0E-4A1A-9E2B-3C6F0C0C3A01}",
+					ProgID:         "Bink.Player.1",
+					ThreadingModel: "Apartment",
+					TypeLibID:      "{5B1C3B0E-0A0E-4A1A-9E2B-3C6F0C0C3A02}",
+					Description:    "Bink player",
+				},
+				{
+					CLSID:          "{5B1C3B0E-0A0E-4A1A-9E2B-3C6F0C0C3A03}",
+					ThreadingModel: "Both",
+				},
+			},
+			TypeLibs: []*TypeLib{
+				{
+					TypeLibID: "{5B1C3B0E-0A0E-4A1A-9E2B-3C6F0C0C3A02}",
+					Version:   "1.0",
+					Flags:     "HASDISKIMAGE",
+				},
+			},
+		},
+		{
+			File: `speech\sapi.dll`,
+			Classes: []*ComClass{
+				{CLSID: "{96749377-3391-11D2-9EE3-00C04F797396}"},
+			},
+		},
+	}, m.Assembly.ComServers)
+	assert.EqualValues(t, []string{"readme.txt", "bink2w32.dll", `speech\sapi.dll`}, m.Files)
+
+	m = parseTestManifest(t, modernManifest)
+	assert.Empty(t, m.Assembly.ComServers)
+	assert.Empty(t, m.Files)
+}
+
+const repeatedIdentityManifest = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<assembly xmlns="urn:schemas-microsoft-com:asm.v1" manifestVersion="1.0">
+  <assemblyIdentity type="win32" name="Itch.Game" version="1.2.3.4"/>
+  <assemblyIdentity type="win32" name="Itch.Game.Copy" version="0.0.0.1"/>
+</assembly>`
+
+func Test_RepeatedIdentity(t *testing.T) {
+	m := parseTestManifest(t, repeatedIdentityManifest)
+
+	// the loader only honors the first one
+	id := m.Assembly.Identity
+	if assert.NotNil(t, id) {
+		assert.EqualValues(t, "Itch.Game", id.Name)
+		assert.EqualValues(t, "1.2.3.4", id.Version)
+	}
+}
@@ -0,0 +1,298 @@
+// Package resources parses the resource table of PE files (what's usually
+// found in their .rsrc section): the resource directory, version info and
+// manifests. It works on any io.ReaderAt holding a resource table, so it
+// doesn't need the rest of the file.
+package resources
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+type imageResourceDirectory struct {
+	Characteristics      uint32
+	TimeDateStamp        uint32
+	MajorVersion         uint16
+	MinorVersion         uint16
+	NumberOfNamedEntries uint16
+	NumberOfIdEntries    uint16
+}
+
+type imageResourceDirectoryEntry struct {
+	NameId uint32
+	Data   uint32
+}
+
+type imageResourceDataEntry struct {
+	Data     uint32
+	Size     uint32
+	CodePage uint32
+	Reserved uint32
+}
+
+type Type uint32
+
+// https://msdn.microsoft.com/fr-fr/library/windows/desktop/ms648009(v=vs.85).aspx
+const (
+	TypeNone Type = 0
+
+	TypeCursor       Type = 1
+	TypeBitmap       Type = 2
+	TypeIcon         Type = 3
+	TypeMenu         Type = 4
+	TypeDialog       Type = 5
+	TypeString       Type = 6
+	TypeFontDir      Type = 7
+	TypeFont         Type = 8
+	TypeAccelerator  Type = 9
+	TypeRcData       Type = 10
+	TypeMessageTable Type = 11
+
+	TypeGroupCursor Type = TypeCursor + 11 // 12
+	TypeGroupIcon   Type = TypeIcon + 11   // 14
+
+	TypeVersion    Type = 16
+	TypeDlgInclude Type = 17
+	TypePlugPlay   Type = 19
+	TypeVXD        Type = 20 // vxd = virtual device
+	TypeAniCursor  Type = 21
+	TypeAniIcon    Type = 22
+	TypeHTML       Type = 23
+	TypeManifest   Type = 24
+)
+
+var TypeNames = map[Type]string{
+	TypeCursor:       "Cursor",
+	TypeBitmap:       "Bitmap",
+	TypeIcon:         "Icon",
+	TypeMenu:         "Menu",
+	TypeDialog:       "Dialog",
+	TypeString:       "String",
+	TypeFontDir:      "FontDir",
+	TypeFont:         "Font",
+	TypeAccelerator:  "Accelerator",
+	TypeRcData:       "RcData",
+	TypeMessageTable: "MessageTable",
+	TypeGroupCursor:  "GroupCursor",
+	TypeGroupIcon:    "GroupIcon",
+	TypeVersion:      "Version",
+	TypeDlgInclude:   "DlgInclude",
+	TypePlugPlay:     "PlugPlay",
+	TypeVXD:          "VXD",
+	TypeAniCursor:    "AniCursor",
+	TypeAniIcon:      "AniIcon",
+	TypeHTML:         "HTML",
+	TypeManifest:     "Manifest",
+}
+
+// Entry is a leaf of the resource directory tree
+type Entry struct {
+	Type Type
+	// set instead of Type for types identified by name
+	TypeName string
+	ID       uint32
+	// set instead of ID for resources identified by name
+	Name     string
+	Language uint32
+
+	RVA      uint32
+	Size     uint32
+	CodePage uint32
+	// data location, relative to the start of the resource table,
+	// unless the data lies outside of the resource table
+	Offset  int64
+	Outside bool
+	// set if the data starts in the resource table, but extends past its end
+	Truncated bool
+}
+
+// TypeString returns the name of the type of e, for example "Version",
+// "#42" for unknown types, or the name of types identified by name
+func (e *Entry) TypeString() string {
+	if e.TypeName != "" {
+		return e.TypeName
+	}
+	if name, ok := TypeNames[e.Type]; ok {
+		return name
+	}
+	return fmt.Sprintf("#%d", e.Type)
+}
+
+// IDString returns the name of e, or its ID formatted as a number
+func (e *Entry) IDString() string {
+	if e.Name != "" {
+		return e.Name
+	}
+	return fmt.Sprintf("%d", e.ID)
+}
+
+// Image gives access to a resource table, and to whatever
+// follows it in the same section
+type Image struct {
+	*io.SectionReader
+	// RVA of the resource table, which data entries are relative to
+	RVA uint32
+	// If set, used to read data that lies outside of the resource
+	// table (see Entry.Outside), which packers leave behind
+	ReadOutside func(e *Entry) (*io.SectionReader, error)
+}
+
+// NewImage returns an Image for the resource table that
+// starts at the beginning of r and is mapped at rva
+func NewImage(r io.ReaderAt, size int64, rva uint32) *Image {
+	return &Image{
+		SectionReader: io.NewSectionReader(r, 0, size),
+		RVA:           rva,
+	}
+}
+
+// Open returns a reader for the data of e. Data outside of the resource
+// table can only be read with ReadOutside, and it's often compressed.
+func (img *Image) Open(e *Entry) (*io.SectionReader, error) {
+	if e.Outside {
+		if img.ReadOutside == nil {
+			return nil, errors.Errorf("%s resource %s (at RVA %x) lies outside of the resource table", e.TypeString(), e.IDString(), e.RVA)
+		}
+		return img.ReadOutside(e)
+	}
+	if e.Truncated || e.Offset+int64(e.Size) > img.Size() {
+		return nil, errors.Errorf("%s resource %s extends past the end of the resource section", e.TypeString(), e.IDString())
+	}
+	return io.NewSectionReader(img, e.Offset, int64(e.Size)), nil
+}
+
+// readName reads the length-prefixed UTF-16 name of a resource
+// directory entry, at offset from the start of the resource table
+func (img *Image) readName(offset uint32) (string, error) {
+	br := io.NewSectionReader(img, int64(offset), img.Size()-int64(offset))
+	var length uint16
+	err := binary.Read(br, binary.LittleEndian, &length)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	buf := make([]byte, int(length)*2)
+	_, err = io.ReadFull(br, buf)
+	if err != nil {
+		return "", errors.WithStack(err)
+	}
+	return DecodeUTF16(buf), nil
+}
+
+// readDataEntry reads the data entry at offset from the start of the
+// resource table, and completes e with it
+func (img *Image) readDataEntry(offset uint32, e *Entry) (*imageResourceDataEntry, error) {
+	dbr := io.NewSectionReader(img, int64(offset), img.Size()-int64(offset))
+	irda := new(imageResourceDataEntry)
+	err := binary.Read(dbr, binary.LittleEndian, irda)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	e.RVA = irda.Data
+	e.Size = irda.Size
+	e.CodePage = irda.CodePage
+
+	// packers (UPX, etc.) compress most resources and leave
+	// entries pointing outside of the resource section
+	sectEnd := uint64(img.RVA) + uint64(img.Size())
+	if irda.Data < img.RVA || uint64(irda.Data) >= sectEnd {
+		e.Outside = true
+		return irda, nil
+	}
+	e.Offset = int64(irda.Data - img.RVA)
+	e.Truncated = uint64(irda.Data)+uint64(irda.Size) > sectEnd
+	return irda, nil
+}
+
+// ErrorFunc is called when a subdirectory of the resource directory
+// can't be read (corrupt, or mangled by a packer). parent has the type
+// (depth 1) and ID or name (depth 2) leading to it. If it returns nil,
+// the subdirectory is skipped, otherwise reading stops with that error.
+type ErrorFunc func(err error, parent *Entry, depth int) error
+
+// Walk calls cb for every resource, including those whose data lies
+// outside of the resource table (see Entry.Outside) or extends past
+// its end (see Entry.Truncated). If onError is nil, unreadable
+// subdirectories stop the walk.
+func (img *Image) Walk(cb func(e *Entry) error, onError ErrorFunc) error {
+	var readDirectory func(offset uint32, level int, parent Entry) error
+	readDirectory = func(offset uint32, level int, parent Entry) error {
+		// type, ID, language: anything deeper is bogus (or a loop)
+		if level > 2 {
+			return errors.Errorf("resource directory nested too deeply")
+		}
+
+		br := io.NewSectionReader(img, int64(offset), img.Size()-int64(offset))
+		ird := new(imageResourceDirectory)
+		err := binary.Read(br, binary.LittleEndian, ird)
+		if err != nil {
+			return errors.WithStack(err)
+		}
+
+		for i := uint16(0); i < ird.NumberOfNamedEntries+ird.NumberOfIdEntries; i++ {
+			irde := new(imageResourceDirectoryEntry)
+			err = binary.Read(br, binary.LittleEndian, irde)
+			if err != nil {
+				return errors.WithStack(err)
+			}
+
+			entry := parent
+			id, name, err := img.readID(irde)
+			if err != nil {
+				return err
+			}
+			entry.setLevel(level, id, name)
+
+			if irde.Data&0x80000000 > 0 {
+				err := readDirectory(irde.Data&0x7fffffff, level+1, entry)
+				if err != nil {
+					if onError == nil {
+						return err
+					}
+					err = onError(err, &entry, level+1)
+					if err != nil {
+						return err
+					}
+				}
+				continue
+			}
+
+			_, err = img.readDataEntry(irde.Data, &entry)
+			if err != nil {
+				return err
+			}
+			err = cb(&entry)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	return readDirectory(0, 0, Entry{})
+}
+
+// readID returns the ID or name of a directory entry
+func (img *Image) readID(irde *imageResourceDirectoryEntry) (uint32, string, error) {
+	if irde.NameId&0x80000000 > 0 {
+		name, err := img.readName(irde.NameId & 0x7fffffff)
+		return 0, name, err
+	}
+	return irde.NameId & 0xffff, "", nil
+}
+
+// setLevel sets the type, ID or language of e, depending on the
+// level of the directory an entry was found in
+func (e *Entry) setLevel(level int, id uint32, name string) {
+	switch level {
+	case 0:
+		e.Type, e.TypeName = Type(id), name
+	case 1:
+		e.ID, e.Name = id, name
+	default:
+		e.Language = id
+	}
+}
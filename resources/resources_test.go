@@ -0,0 +1,97 @@
+package resources_test
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/itchio/pelican/pe"
+	"github.com/itchio/pelican/resources"
+	"github.com/stretchr/testify/assert"
+)
+
+// rsrcImage returns the .rsrc section of a fixture, which
+// is all the resources package needs
+func rsrcImage(t *testing.T, path string) *resources.Image {
+	f, err := os.Open(path)
+	assert.NoError(t, err)
+	t.Cleanup(func() { f.Close() })
+
+	stats, err := f.Stat()
+	assert.NoError(t, err)
+	pf, err := pe.NewFile(f, stats.Size())
+	assert.NoError(t, err)
+
+	s := pf.Section(".rsrc")
+	if !assert.NotNil(t, s) {
+		t.FailNow()
+	}
+	return resources.NewImage(s, int64(s.Size), s.VirtualAddress)
+}
+
+func Test_WalkVersionInfo(t *testing.T) {
+	img := rsrcImage(t, "../testdata/resourceful/resourceful32-mingw.exe")
+
+	var versions []*resources.Entry
+	err := img.Walk(func(e *resources.Entry) error {
+		if e.Type == resources.TypeVersion {
+			versions = append(versions, e)
+		}
+		return nil
+	}, nil)
+	assert.NoError(t, err)
+	if !assert.Len(t, versions, 1) {
+		return
+	}
+	assert.EqualValues(t, "Version", versions[0].TypeString())
+	assert.EqualValues(t, "1", versions[0].IDString())
+	assert.EqualValues(t, 1033, versions[0].Language)
+
+	r, err := img.Open(versions[0])
+	assert.NoError(t, err)
+	data, err := ioutil.ReadAll(r)
+	assert.NoError(t, err)
+
+	vi, err := resources.ParseVersionInfo(data)
+	assert.NoError(t, err)
+	assert.NotNil(t, vi.FixedFileInfo)
+	if assert.Len(t, vi.StringTables, 1) {
+		assert.EqualValues(t, "080904E4", vi.StringTables[0].Key)
+		assert.EqualValues(t, 1252, vi.StringTables[0].CodePage(vi.Translations))
+	}
+	props := vi.Properties()
+	assert.EqualValues(t, "itch corp.", props["CompanyName"])
+	assert.EqualValues(t, "3.14", props["FileVersion"])
+
+	tree, err := resources.ReadTree(img, nil)
+	assert.NoError(t, err)
+	vd := tree.Root.Find(uint32(resources.TypeVersion)).Directory.Find(1).Directory.Find(1033).Data
+	assert.EqualValues(t, versions[0].Size, vd.Size)
+	assert.EqualValues(t, *versions[0], vd.Entry())
+
+	// resources outside of the table can't be read without ReadOutside
+	_, err = img.Open(&resources.Entry{Type: resources.TypeIcon, ID: 1, Outside: true, RVA: 0x1000, Size: 16})
+	assert.Error(t, err)
+}
+
+func Test_VersionStringTables(t *testing.T) {
+	vi := &resources.VersionInfo{
+		StringTables: []*resources.StringTable{
+			{Key: "040704e4"},
+			{Key: "04190000"},
+		},
+		Translations: []resources.Translation{{LangID: 0x419, CodePage: 1251}},
+	}
+
+	selected := vi.SelectStringTables()
+	if assert.Len(t, selected, 1) {
+		assert.EqualValues(t, "04190000", selected[0].Key)
+		assert.EqualValues(t, 1251, selected[0].CodePage(vi.Translations))
+		assert.EqualValues(t, 1251, selected[0].CodePage(nil))
+	}
+
+	// widened Windows-1251 bytes
+	widened := []byte{0xc8, 0, 0xe3, 0, 0xf0, 0, 0xe0, 0}
+	assert.EqualValues(t, "Игра", resources.DecodeVersionString(widened, 1251))
+	assert.EqualValues(t, "Èãðà", resources.DecodeVersionString(widened, 1200))
+}
@@ -0,0 +1,369 @@
+package resources
+
+import (
+	"encoding/binary"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Tree is the resource directory of a PE file, as stored in it:
+// entries are kept in order, along with the fields Windows ignores
+// (timestamps, versions, reserved fields), so that it can be written
+// back exactly.
+type Tree struct {
+	Root *Directory
+
+	img *Image
+}
+
+// Directory is a node of a Tree. The entries of the root are
+// types, theirs are IDs (or names), and theirs are languages.
+type Directory struct {
+	Characteristics uint32
+	// Usually zero, but some resource compilers set it to the build time
+	TimeDateStamp uint32
+	MajorVersion  uint16
+	MinorVersion  uint16
+	// Entries identified by name come first, as they do in the file
+	Entries []*DirectoryEntry
+}
+
+// DirectoryEntry is either a subdirectory, or the data of a resource.
+// Both are nil if the subdirectory could not be read (see ReadTree).
+type DirectoryEntry struct {
+	ID uint32
+	// Set instead of ID for entries identified by name
+	Name string
+
+	Directory *Directory
+	Data      *Data
+}
+
+// Data describes where the data of a resource lies, see Tree.Open
+type Data struct {
+	RVA      uint32
+	Size     uint32
+	CodePage uint32
+	Reserved uint32
+
+	entry Entry
+}
+
+// Entry returns the type, ID, language and location of d
+func (d *Data) Entry() Entry {
+	return d.entry
+}
+
+// ReadTree reads the whole resource directory of img, which must stay
+// readable for as long as the tree is used. Subdirectories that can't
+// be read are left out if onError returns nil, see Image.Walk.
+func ReadTree(img *Image, onError ErrorFunc) (*Tree, error) {
+	root, err := readDirectory(img, 0, 0, Entry{}, onError)
+	if err != nil {
+		return nil, err
+	}
+	return &Tree{Root: root, img: img}, nil
+}
+
+func readDirectory(img *Image, offset uint32, level int, parent Entry, onError ErrorFunc) (*Directory, error) {
+	// type, ID, language: anything deeper is bogus (or a loop)
+	if level > 2 {
+		return nil, errors.Errorf("resource directory nested too deeply")
+	}
+
+	br := io.NewSectionReader(img, int64(offset), img.Size()-int64(offset))
+	var ird imageResourceDirectory
+	err := binary.Read(br, binary.LittleEndian, &ird)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	rd := &Directory{
+		Characteristics: ird.Characteristics,
+		TimeDateStamp:   ird.TimeDateStamp,
+		MajorVersion:    ird.MajorVersion,
+		MinorVersion:    ird.MinorVersion,
+	}
+
+	for i := uint16(0); i < ird.NumberOfNamedEntries+ird.NumberOfIdEntries; i++ {
+		var irde imageResourceDirectoryEntry
+		err = binary.Read(br, binary.LittleEndian, &irde)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		rde := new(DirectoryEntry)
+		rde.ID, rde.Name, err = img.readID(&irde)
+		if err != nil {
+			return nil, err
+		}
+		rd.Entries = append(rd.Entries, rde)
+
+		entry := parent
+		entry.setLevel(level, rde.ID, rde.Name)
+
+		if irde.Data&0x80000000 > 0 {
+			rde.Directory, err = readDirectory(img, irde.Data&0x7fffffff, level+1, entry, onError)
+			if err != nil {
+				if onError == nil {
+					return nil, err
+				}
+				err = onError(err, &entry, level+1)
+				if err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+
+		irda, err := img.readDataEntry(irde.Data, &entry)
+		if err != nil {
+			return nil, err
+		}
+		rde.Data = &Data{
+			RVA:      irda.Data,
+			Size:     irda.Size,
+			CodePage: irda.CodePage,
+			Reserved: irda.Reserved,
+			entry:    entry,
+		}
+	}
+	return rd, nil
+}
+
+// Open returns a reader for the data of rd, which must belong to t,
+// see Image.Open
+func (t *Tree) Open(rd *Data) (*io.SectionReader, error) {
+	return t.img.Open(&rd.entry)
+}
+
+// Find returns the entry of rd with the given ID, or nil
+func (rd *Directory) Find(id uint32) *DirectoryEntry {
+	for _, e := range rd.Entries {
+		if e.Name == "" && e.ID == id {
+			return e
+		}
+	}
+	return nil
+}
+
+// FindName returns the entry of rd with the given name, or nil. Like
+// FindResource, it's case-insensitive (resource compilers upper-case
+// names anyway), and "#123" stands for ID 123.
+func (rd *Directory) FindName(name string) *DirectoryEntry {
+	if strings.HasPrefix(name, "#") {
+		id, err := strconv.ParseUint(name[1:], 10, 16)
+		if err == nil {
+			return rd.Find(uint32(id))
+		}
+	}
+	for _, e := range rd.Entries {
+		if e.Name != "" && strings.EqualFold(e.Name, name) {
+			return e
+		}
+	}
+	return nil
+}
+
+// Names returns the names of all resources of type typ, with
+// resources identified by ID written as "#123", so they can be
+// passed to Lookup.
+func (t *Tree) Names(typ Type) []string {
+	te := t.Root.Find(uint32(typ))
+	if te == nil || te.Directory == nil {
+		return nil
+	}
+
+	var names []string
+	for _, e := range te.Directory.Entries {
+		if e.Name != "" {
+			names = append(names, e.Name)
+		} else {
+			names = append(names, fmt.Sprintf("#%d", e.ID))
+		}
+	}
+	return names
+}
+
+// Lookup returns the data of the resource of type typ with the given
+// name (see Directory.FindName), or nil if there's none. If
+// there are several languages, the first one is picked, which is the
+// neutral language if present.
+func (t *Tree) Lookup(typ Type, name string) *Data {
+	te := t.Root.Find(uint32(typ))
+	if te == nil || te.Directory == nil {
+		return nil
+	}
+	ie := te.Directory.FindName(name)
+	if ie == nil || ie.Directory == nil {
+		return nil
+	}
+	for _, le := range ie.Directory.Entries {
+		if le.Data != nil {
+			return le.Data
+		}
+	}
+	return nil
+}
+
+// ReadAll reads the data of rd, which must belong to t
+func (t *Tree) ReadAll(rd *Data) ([]byte, error) {
+	r, err := t.Open(rd)
+	if err != nil {
+		return nil, err
+	}
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	return data, nil
+}
+
+// RcData returns the contents of the RT_RCDATA resource with the given
+// name, where installers and launchers often keep their configuration.
+// It returns nil if there's no such resource.
+func (t *Tree) RcData(name string) ([]byte, error) {
+	return t.readNamed(TypeRcData, name)
+}
+
+// HTML returns the contents of the RT_HTML resource with the given name,
+// or nil if there's no such resource.
+func (t *Tree) HTML(name string) ([]byte, error) {
+	return t.readNamed(TypeHTML, name)
+}
+
+func (t *Tree) readNamed(typ Type, name string) ([]byte, error) {
+	rd := t.Lookup(typ, name)
+	if rd == nil {
+		return nil, nil
+	}
+	return t.ReadAll(rd)
+}
+
+// walk calls cb for the data of every resource of type typ
+func (t *Tree) walk(typ Type, cb func(rd *Data) error) error {
+	for _, te := range t.Root.Entries {
+		if te.Name != "" || Type(te.ID) != typ || te.Directory == nil {
+			continue
+		}
+		for _, ie := range te.Directory.Entries {
+			if ie.Directory == nil {
+				continue
+			}
+			for _, le := range ie.Directory.Entries {
+				if le.Data == nil {
+					continue
+				}
+				err := cb(le.Data)
+				if err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// GroupIconDir is the header of RT_GROUP_ICON and RT_GROUP_CURSOR
+// resources (GRPICONDIR, also known as NEWHEADER)
+type GroupIconDir struct {
+	Reserved uint16
+	Type     uint16
+	Count    uint16
+}
+
+// GroupIconDirEntry is an icon image of an RT_GROUP_ICON resource
+// (GRPICONDIRENTRY)
+type GroupIconDirEntry struct {
+	Width      uint8
+	Height     uint8
+	ColorCount uint8
+	Reserved   uint8
+	Planes     uint16
+	BitCount   uint16
+	BytesInRes uint32
+	// ID of the RT_ICON resource holding the image
+	ID uint16
+}
+
+// CursorGroup is an RT_GROUP_CURSOR resource, which lists the images
+// available for a cursor, each stored as an RT_CURSOR resource
+type CursorGroup struct {
+	ID uint32
+	// Set instead of ID for groups identified by name
+	Name     string
+	Language uint32
+	Cursors  []*GroupCursorEntry
+}
+
+// GroupCursorEntry is a cursor image of a CursorGroup (RESDIR)
+type GroupCursorEntry struct {
+	Width uint16
+	// Twice the height of the cursor, since it includes the AND mask
+	Height     uint16
+	Planes     uint16
+	BitCount   uint16
+	BytesInRes uint32
+	// ID of the RT_CURSOR resource holding the image
+	ID uint16
+}
+
+// groupCursorType is NEWHEADER.ResType for cursors (1 for icons)
+const groupCursorType = 2
+
+// CursorGroups parses all the RT_GROUP_CURSOR resources of t
+func (t *Tree) CursorGroups() ([]*CursorGroup, error) {
+	var groups []*CursorGroup
+	err := t.walk(TypeGroupCursor, func(rd *Data) error {
+		r, err := t.Open(rd)
+		if err != nil {
+			return err
+		}
+		cursors, err := readCursorGroup(r)
+		if err != nil {
+			return errors.WithMessagef(err, "while parsing cursor group %s", rd.entry.IDString())
+		}
+		groups = append(groups, &CursorGroup{
+			ID:       rd.entry.ID,
+			Name:     rd.entry.Name,
+			Language: rd.entry.Language,
+			Cursors:  cursors,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return groups, nil
+}
+
+func readCursorGroup(r *io.SectionReader) ([]*GroupCursorEntry, error) {
+	var dir GroupIconDir
+	err := binary.Read(r, binary.LittleEndian, &dir)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+	if dir.Type != groupCursorType {
+		return nil, errors.Errorf("unexpected group type %d", dir.Type)
+	}
+
+	entrySize := int64(binary.Size(GroupCursorEntry{}))
+	if int64(dir.Count)*entrySize > r.Size()-int64(binary.Size(dir)) {
+		return nil, errors.Errorf("%d cursors don't fit in %d bytes", dir.Count, r.Size())
+	}
+
+	cursors := make([]*GroupCursorEntry, dir.Count)
+	for i := range cursors {
+		cursors[i] = new(GroupCursorEntry)
+		err = binary.Read(r, binary.LittleEndian, cursors[i])
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+	}
+	return cursors, nil
+}
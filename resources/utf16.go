@@ -1,4 +1,4 @@
-package pelican
+package resources
 
 import (
 	"encoding/binary"
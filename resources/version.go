@@ -0,0 +1,348 @@
+package resources
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// PE version block utilities
+
+type ReadSeekerAt interface {
+	io.ReadSeeker
+	io.ReaderAt
+}
+
+type VsBlock struct {
+	Length      uint16
+	ValueLength uint16
+	Type        uint16
+	Key         []byte
+	EndOffset   int64
+
+	ReadSeekerAt
+}
+
+func (vb *VsBlock) KeyString() string {
+	return DecodeUTF16(vb.Key)
+}
+
+type VsFixedFileInfo struct {
+	DwSignature        uint32
+	DwStrucVersion     uint32
+	DwFileVersionMS    uint32
+	DwFileVersionLS    uint32
+	DwProductVersionMS uint32
+	DwProductVersionLS uint32
+	DwFileFlagsMask    uint32
+	DwFileFlags        uint32
+	DwFileOS           uint32
+	DwFileType         uint32
+	DwFileSubtype      uint32
+	DwFileDateMS       uint32
+	DwFileDateLS       uint32
+}
+
+// VersionInfo is the contents of an RT_VERSION resource
+// (VS_VERSIONINFO), see ParseVersionInfo
+type VersionInfo struct {
+	// nil if the block doesn't have one, in which case it has nothing else
+	FixedFileInfo *VsFixedFileInfo
+	// The StringTable blocks of StringFileInfo, in order
+	StringTables []*StringTable
+	// The Translation entries of VarFileInfo
+	Translations []Translation
+}
+
+// StringTable holds the version strings of a language
+type StringTable struct {
+	// Language ID and code page, in hex, for example "040904b0"
+	Key     string
+	Strings []*VersionString
+}
+
+// VersionString is an entry of a StringTable
+type VersionString struct {
+	Key string
+	// UTF-16, without the NUL terminator, see DecodeVersionString
+	Value []byte
+}
+
+// Translation is an entry of VarFileInfo's Translation,
+// which lists the languages the version info is available in
+type Translation struct {
+	LangID   uint16
+	CodePage uint16
+}
+
+// CodePage returns the code page the values of t are encoded with. If its
+// key doesn't specify one, it's looked up in translations, and failing that,
+// derived from its language.
+func (t *StringTable) CodePage(translations []Translation) uint16 {
+	langID, codePage, ok := parseStringTableKey(t.Key)
+	if !ok {
+		return codePageUTF16
+	}
+	if codePage != 0 {
+		return codePage
+	}
+	for _, tr := range translations {
+		if tr.LangID == langID && tr.CodePage != 0 {
+			return tr.CodePage
+		}
+	}
+	return languageCodePage(langID)
+}
+
+// SelectStringTables returns the neutral and English string tables.
+// If there are none, it returns the first one listed in Translations,
+// or the first one.
+func (vi *VersionInfo) SelectStringTables() []*StringTable {
+	var res []*StringTable
+	for _, t := range vi.StringTables {
+		if isLanguageWhitelisted(t.Key) {
+			res = append(res, t)
+		}
+	}
+	if len(res) > 0 || len(vi.StringTables) == 0 {
+		return res
+	}
+
+	for _, tr := range vi.Translations {
+		for _, t := range vi.StringTables {
+			langID, codePage, ok := parseStringTableKey(t.Key)
+			if ok && langID == tr.LangID && (codePage == tr.CodePage || codePage == 0) {
+				return []*StringTable{t}
+			}
+		}
+	}
+	return vi.StringTables[:1]
+}
+
+// Properties returns the version strings of the tables picked by
+// SelectStringTables, decoded with their code page. If several
+// tables have the same string, the last one wins.
+func (vi *VersionInfo) Properties() map[string]string {
+	props := make(map[string]string)
+	for _, table := range vi.SelectStringTables() {
+		codePage := table.CodePage(vi.Translations)
+		for _, vs := range table.Strings {
+			props[vs.Key] = DecodeVersionString(vs.Value, codePage)
+		}
+	}
+	return props
+}
+
+// ParseVersionInfo parses the data of an RT_VERSION resource. If the
+// string tables can't be parsed, it returns what it could parse
+// of them, along with the error.
+func ParseVersionInfo(rawData []byte) (*VersionInfo, error) {
+	vi := &VersionInfo{}
+	br := bytes.NewReader(rawData)
+	buf := make([]byte, 2)
+
+	// cf. https://msdn.microsoft.com/en-us/library/windows/desktop/ms647001%28v=vs.85%29.aspx?f=255&MSPPError=-2147217396
+	// Padding "contains as many zero words as necessary to align the Value member on a 32-bit boundary."
+	skipPadding := func(r ReadSeekerAt) error {
+		offset, err := r.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return err
+		}
+
+		mod4 := offset % 4
+		if mod4 > 0 {
+			_, err = r.Seek(4-mod4, io.SeekCurrent)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	parseNullTerminatedString := func(r ReadSeekerAt) ([]byte, error) {
+		var res []byte
+
+		for {
+			_, err := r.Read(buf)
+			if err != nil {
+				if errors.Cause(err) == io.EOF {
+					return res, nil
+				}
+				return nil, errors.WithStack(err)
+			}
+
+			if buf[0] == 0 && buf[1] == 0 {
+				break
+			} else {
+				res = append(res, buf...)
+			}
+		}
+		return res, nil
+	}
+
+	parseVSBlock := func(r ReadSeekerAt) (*VsBlock, error) {
+		startOffset, err := r.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		var wLength uint16
+		err = binary.Read(r, binary.LittleEndian, &wLength)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		endOffset := startOffset + int64(wLength)
+		sr := io.NewSectionReader(r, startOffset, int64(wLength) /* we already read the wLength uint16 */)
+
+		sr.Seek(2, io.SeekCurrent)
+
+		var wValueLength uint16
+		err = binary.Read(sr, binary.LittleEndian, &wValueLength)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		var wType uint16
+		err = binary.Read(sr, binary.LittleEndian, &wType)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		szKey, err := parseNullTerminatedString(sr)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		err = skipPadding(sr)
+		if err != nil {
+			return nil, errors.WithStack(err)
+		}
+
+		return &VsBlock{
+			Length:       wLength,
+			ValueLength:  wValueLength,
+			Type:         wType,
+			Key:          szKey,
+			EndOffset:    endOffset,
+			ReadSeekerAt: sr,
+		}, nil
+	}
+
+	vsVersionInfo, err := parseVSBlock(br)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if vsVersionInfo.ValueLength == 0 {
+		return vi, nil
+	}
+
+	ffi := new(VsFixedFileInfo)
+	err = binary.Read(vsVersionInfo, binary.LittleEndian, ffi)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	if ffi.DwSignature != 0xFEEF04BD {
+		return nil, errors.Errorf("invalid version block signature (%08x)", ffi.DwSignature)
+	}
+	vi.FixedFileInfo = ffi
+
+	err = skipPadding(vsVersionInfo)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	for {
+		fileInfo, err := parseVSBlock(vsVersionInfo)
+		if err != nil {
+			if errors.Cause(err) == io.EOF {
+				break
+			}
+			return vi, errors.WithStack(err)
+		}
+
+		switch fileInfo.KeyString() {
+		case "StringFileInfo":
+			for {
+				stable, err := parseVSBlock(fileInfo)
+				if err != nil {
+					if errors.Cause(err) == io.EOF {
+						break
+					}
+					return vi, errors.WithStack(err)
+				}
+
+				table := &StringTable{Key: stable.KeyString()}
+				vi.StringTables = append(vi.StringTables, table)
+				for {
+					str, err := parseVSBlock(stable)
+					if err != nil {
+						if errors.Cause(err) == io.EOF {
+							break
+						}
+						return vi, errors.WithStack(err)
+					}
+
+					val, err := parseNullTerminatedString(str)
+					if err != nil {
+						return vi, errors.WithStack(err)
+					}
+					table.Strings = append(table.Strings, &VersionString{Key: str.KeyString(), Value: val})
+
+					_, err = stable.Seek(str.EndOffset, io.SeekStart)
+					if err != nil {
+						return vi, errors.WithStack(err)
+					}
+
+					err = skipPadding(stable)
+					if err != nil {
+						return vi, errors.WithStack(err)
+					}
+				}
+
+				_, err = fileInfo.Seek(stable.EndOffset, io.SeekStart)
+				if err != nil {
+					return vi, errors.WithStack(err)
+				}
+			}
+		case "VarFileInfo":
+			for {
+				v, err := parseVSBlock(fileInfo)
+				if err != nil {
+					if errors.Cause(err) == io.EOF {
+						break
+					}
+					return vi, errors.WithStack(err)
+				}
+
+				if v.KeyString() == "Translation" {
+					// pairs of language ID and code page
+					pairs := make([]uint16, v.ValueLength/4*2)
+					err = binary.Read(v, binary.LittleEndian, pairs)
+					if err != nil {
+						return vi, errors.WithStack(err)
+					}
+					for i := 0; i < len(pairs); i += 2 {
+						vi.Translations = append(vi.Translations, Translation{LangID: pairs[i], CodePage: pairs[i+1]})
+					}
+				}
+
+				_, err = fileInfo.Seek(v.EndOffset, io.SeekStart)
+				if err != nil {
+					return vi, errors.WithStack(err)
+				}
+			}
+		}
+
+		_, err = vsVersionInfo.Seek(fileInfo.EndOffset, io.SeekStart)
+		if err != nil {
+			return vi, errors.WithStack(err)
+		}
+	}
+
+	return vi, nil
+}
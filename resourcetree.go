@@ -0,0 +1,44 @@
+package pelican
+
+import (
+	"github.com/itchio/httpkit/eos"
+	"github.com/itchio/pelican/resources"
+	"github.com/pkg/errors"
+)
+
+// The resource tree lives in the resources package,
+// these are kept for compatibility.
+type (
+	ResourceTree           = resources.Tree
+	ResourceDirectory      = resources.Directory
+	ResourceDirectoryEntry = resources.DirectoryEntry
+	ResourceData           = resources.Data
+	CursorGroup            = resources.CursorGroup
+	GroupCursorEntry       = resources.GroupCursorEntry
+)
+
+// ReadResourceTree reads the whole resource directory of file, which must
+// stay open for as long as the tree is used. It returns nil if file has
+// no resources.
+func ReadResourceTree(file eos.File, params ProbeParams) (*ResourceTree, error) {
+	params.setDefaults()
+	img, err := params.resourceImageOf(file)
+	if err != nil {
+		return nil, err
+	}
+	if img == nil {
+		return nil, nil
+	}
+
+	tree, err := resources.ReadTree(img, func(err error, entry *resources.Entry, depth int) error {
+		if params.Strict {
+			return err
+		}
+		params.warn(nil, WarningResourceSubtreeUnreadable, err, "Could not read %s resource directory %s", entry.TypeString(), entry.IDString())
+		return nil
+	})
+	if err != nil {
+		return nil, errors.WithMessage(err, "while reading resource tree")
+	}
+	return tree, nil
+}
@@ -0,0 +1,114 @@
+package pelican_test
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/itchio/httpkit/eos"
+	"github.com/itchio/pelican"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_ResourceTree(t *testing.T) {
+	readTree := func(path string) *pelican.ResourceTree {
+		f, err := eos.Open(path)
+		assert.NoError(t, err)
+		t.Cleanup(func() { f.Close() })
+
+		tree, err := pelican.ReadResourceTree(f, testProbeParams(t))
+		assert.NoError(t, err)
+		return tree
+	}
+
+	tree := readTree("./testdata/resourceful/resourceful32-mingw.exe")
+	assert.EqualValues(t, 0, tree.Root.TimeDateStamp)
+	var types []uint32
+	for _, e := range tree.Root.Entries {
+		types = append(types, e.ID)
+	}
+	assert.EqualValues(t, []uint32{3, 14, 16}, types)
+
+	version := tree.Root.Entries[2].Directory.Entries[0]
+	assert.EqualValues(t, 1, version.ID)
+	lang := version.Directory.Entries[0]
+	assert.EqualValues(t, 1033, lang.ID)
+	assert.Nil(t, lang.Directory)
+	r, err := tree.Open(lang.Data)
+	assert.NoError(t, err)
+	data, err := ioutil.ReadAll(r)
+	assert.NoError(t, err)
+	assert.Len(t, data, int(lang.Data.Size))
+
+	groups, err := tree.CursorGroups()
+	assert.NoError(t, err)
+	assert.Empty(t, groups)
+
+	tree = readTree("./testdata/resourceful/resourceful32-cursor.exe")
+	assert.EqualValues(t, 1536000000, tree.Root.TimeDateStamp)
+	assert.EqualValues(t, 4, tree.Root.MajorVersion)
+	assert.EqualValues(t, 2, tree.Root.MinorVersion)
+
+	groups, err = tree.CursorGroups()
+	assert.NoError(t, err)
+	if assert.Len(t, groups, 1) {
+		g := groups[0]
+		assert.EqualValues(t, 101, g.ID)
+		assert.EqualValues(t, 1033, g.Language)
+		if assert.Len(t, g.Cursors, 5) {
+			c := g.Cursors[0]
+			assert.EqualValues(t, 16, c.Width)
+			assert.EqualValues(t, 32, c.Height)
+			assert.EqualValues(t, 32, c.BitCount)
+			assert.EqualValues(t, 1128, c.BytesInRes)
+			assert.EqualValues(t, 1, c.ID)
+
+			// stored as 0 in icon groups
+			assert.EqualValues(t, 256, g.Cursors[4].Width)
+		}
+	}
+
+	// no resources at all
+	tree = readTree("./testdata/hello/hello64-mingw.exe")
+	assert.Nil(t, tree)
+}
+
+func Test_ResourceTreeLookup(t *testing.T) {
+	f, err := eos.Open("./testdata/resourceful/resourceful32-blobs.exe")
+	assert.NoError(t, err)
+	defer f.Close()
+
+	tree, err := pelican.ReadResourceTree(f, testProbeParams(t))
+	assert.NoError(t, err)
+
+	assert.EqualValues(t, []string{"CONFIG", "#7"}, tree.Names(pelican.ResourceTypeRcData))
+	assert.EqualValues(t, []string{"README.HTM"}, tree.Names(pelican.ResourceTypeHTML))
+	assert.Empty(t, tree.Names(pelican.ResourceTypeMenu))
+
+	// names are case-insensitive, the first language wins
+	config, err := tree.RcData("config")
+	assert.NoError(t, err)
+	assert.EqualValues(t, `{"launch": "game.exe", "args": ["--fullscreen"]}`, string(config))
+
+	settings, err := tree.RcData("#7")
+	assert.NoError(t, err)
+	assert.Contains(t, string(settings), "<vsync>true</vsync>")
+
+	readme, err := tree.HTML("readme.htm")
+	assert.NoError(t, err)
+	assert.Contains(t, string(readme), "<h1>Read me</h1>")
+
+	missing, err := tree.RcData("README.HTM")
+	assert.NoError(t, err)
+	assert.Nil(t, missing)
+
+	// other languages are still reachable through the tree
+	te := tree.Root.Find(uint32(pelican.ResourceTypeRcData))
+	ie := te.Directory.FindName("CONFIG")
+	if assert.Len(t, ie.Directory.Entries, 2) {
+		fr := ie.Directory.Find(1036)
+		assert.NotNil(t, fr)
+		data, err := tree.ReadAll(fr.Data)
+		assert.NoError(t, err)
+		assert.EqualValues(t, `{"launch": "jeu.exe"}`, string(data))
+	}
+}
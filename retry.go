@@ -0,0 +1,80 @@
+package pelican
+
+import (
+	"io"
+	"time"
+
+	"github.com/itchio/headway/state"
+)
+
+// RetryPolicy controls how failed reads are retried. Only errors
+// for which IsRetriable returns true are retried (network errors,
+// server errors, throttling), so it's mostly useful for remote files
+// opened with eos.Open, where a CDN hiccup would otherwise fail the
+// whole probe.
+type RetryPolicy struct {
+	// Number of attempts for each read, including the first one
+	Attempts int
+	// Delay before the first retry, doubled on each subsequent retry
+	Backoff time.Duration
+	// If non-zero, the delay never exceeds this
+	MaxBackoff time.Duration
+}
+
+// DefaultRetryPolicy is a reasonable policy for files served over HTTP
+var DefaultRetryPolicy = &RetryPolicy{
+	Attempts:   5,
+	Backoff:    250 * time.Millisecond,
+	MaxBackoff: 5 * time.Second,
+}
+
+// overridden in tests
+var sleep = time.Sleep
+
+func (rp *RetryPolicy) delay(retry int) time.Duration {
+	d := rp.Backoff
+	for i := 0; i < retry; i++ {
+		d *= 2
+		if rp.MaxBackoff > 0 && d >= rp.MaxBackoff {
+			return rp.MaxBackoff
+		}
+	}
+	if rp.MaxBackoff > 0 && d > rp.MaxBackoff {
+		return rp.MaxBackoff
+	}
+	return d
+}
+
+// retryingReaderAt retries reads that fail with a retriable IOError
+type retryingReaderAt struct {
+	r        io.ReaderAt
+	policy   *RetryPolicy
+	consumer *state.Consumer
+}
+
+func (rr retryingReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	for retry := 0; ; retry++ {
+		n, err := rr.r.ReadAt(p, off)
+		if err == nil || retry+1 >= rr.policy.Attempts || !IsRetriable(err) {
+			return n, err
+		}
+
+		d := rr.policy.delay(retry)
+		rr.consumer.Debugf("Read failed (%v), retrying in %s (%d/%d)", err, d, retry+1, rr.policy.Attempts-1)
+		sleep(d)
+	}
+}
+
+// readerAt returns r with read errors wrapped in IOError,
+// and retried according to params.RetryPolicy
+func (params *ProbeParams) readerAt(r io.ReaderAt) io.ReaderAt {
+	var res io.ReaderAt = ioErrorReaderAt{r}
+	if params.RetryPolicy != nil && params.RetryPolicy.Attempts > 1 {
+		res = retryingReaderAt{
+			r:        res,
+			policy:   params.RetryPolicy,
+			consumer: params.Consumer,
+		}
+	}
+	return res
+}
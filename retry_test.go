@@ -0,0 +1,56 @@
+package pelican
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_RetryPolicyDelay(t *testing.T) {
+	rp := &RetryPolicy{
+		Attempts:   10,
+		Backoff:    100 * time.Millisecond,
+		MaxBackoff: time.Second,
+	}
+	assert.EqualValues(t, 100*time.Millisecond, rp.delay(0))
+	assert.EqualValues(t, 200*time.Millisecond, rp.delay(1))
+	assert.EqualValues(t, 800*time.Millisecond, rp.delay(3))
+	assert.EqualValues(t, time.Second, rp.delay(4))
+	assert.EqualValues(t, time.Second, rp.delay(100))
+
+	rp.MaxBackoff = 0
+	assert.EqualValues(t, 1600*time.Millisecond, rp.delay(4))
+}
+
+func Test_RetryingReaderAt(t *testing.T) {
+	var slept []time.Duration
+	sleep = func(d time.Duration) { slept = append(slept, d) }
+	defer func() { sleep = time.Sleep }()
+
+	params := &ProbeParams{
+		RetryPolicy: &RetryPolicy{
+			Attempts: 4,
+			Backoff:  time.Second,
+		},
+	}
+
+	calls := 0
+	r := params.readerAt(readerAtFunc(func(p []byte, off int64) (int, error) {
+		calls++
+		if calls < 3 {
+			return 0, assert.AnError
+		}
+		return len(p), nil
+	}))
+
+	n, err := r.ReadAt(make([]byte, 4), 0)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 4, n)
+	assert.EqualValues(t, 3, calls)
+	assert.EqualValues(t, []time.Duration{time.Second, 2 * time.Second}, slept)
+}
+
+type readerAtFunc func(p []byte, off int64) (int, error)
+
+func (f readerAtFunc) ReadAt(p []byte, off int64) (int, error) { return f(p, off) }
@@ -0,0 +1,59 @@
+package pelican
+
+import (
+	"github.com/itchio/pelican/pe"
+	"github.com/pkg/errors"
+)
+
+// RichHeader lists the Microsoft tools (compiler, assembler, linker,
+// etc.) that built a binary, and how many object files each produced,
+// see pe.RichHeader. Binaries built by other linkers don't have one.
+type RichHeader struct {
+	// XOR key the header is encoded with, which is a checksum of
+	// the DOS header and of the entries. Identical binaries built on
+	// different machines usually have the same key.
+	Key uint32 `json:"key"`
+	// Not set if the key doesn't match the checksum, which means the
+	// header was edited (or forged) after linking
+	ChecksumValid bool         `json:"checksumValid"`
+	Entries       []*RichEntry `json:"entries,omitempty"`
+}
+
+// RichEntry is a line of the Rich header
+type RichEntry struct {
+	// Identifies the tool and the Visual Studio release it
+	// comes with, for example 0x105 is the C++ compiler of
+	// Visual Studio 2015 and later. 1 counts imported functions.
+	ProductID uint32 `json:"productId"`
+	// Build number of the tool, for example 30159
+	Build uint32 `json:"build"`
+	Count uint32 `json:"count"`
+}
+
+// parseRichHeader fills info.RichHeader
+func (params *ProbeParams) parseRichHeader(info *PeInfo, pf *pe.File) error {
+	rh, err := pf.RichHeader()
+	if err != nil {
+		if params.Strict {
+			return errors.WithMessage(err, "while parsing Rich header")
+		}
+		params.warn(info, WarningRichHeaderInvalid, err, "Could not parse Rich header")
+		return nil
+	}
+	if rh == nil {
+		return nil
+	}
+
+	info.RichHeader = &RichHeader{
+		Key:           rh.Key,
+		ChecksumValid: rh.ChecksumValid,
+	}
+	for _, e := range rh.Entries {
+		info.RichHeader.Entries = append(info.RichHeader.Entries, &RichEntry{
+			ProductID: uint32(e.ProductID),
+			Build:     uint32(e.Build),
+			Count:     e.Count,
+		})
+	}
+	return nil
+}
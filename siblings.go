@@ -0,0 +1,129 @@
+package pelican
+
+import (
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// SiblingBuilds are builds of the same executable for different
+// architectures, like game.exe and game64.exe, or x86/game.exe and
+// x64/game.exe. Launchers can use Pick to run the right one.
+type SiblingBuilds struct {
+	// Base name shared by the builds, for example "game"
+	Name string `json:"name"`
+	// Sorted by path
+	Builds []*SiblingBuild `json:"builds"`
+}
+
+// SiblingBuild is one of the executables of SiblingBuilds
+type SiblingBuild struct {
+	// Slash-separated path relative to the directory
+	Path string `json:"path"`
+	Arch Arch   `json:"arch"`
+}
+
+// Pick returns the build that runs best on a system of the given
+// architecture, or nil if none of them can run there.
+// 64-bit Windows runs 32-bit binaries too, but native ones are preferred.
+func (sb *SiblingBuilds) Pick(arch Arch) *SiblingBuild {
+	var fallback *SiblingBuild
+	for _, b := range sb.Builds {
+		if b.Arch == arch {
+			return b
+		}
+		if fallback == nil && arch == ArchAmd64 && b.Arch == Arch386 {
+			fallback = b
+		}
+	}
+	return fallback
+}
+
+// suffixes that tell architectures apart in executable names,
+// for example "game64", "game_x64", "game-win32"
+var archSuffixRegexp = regexp.MustCompile(`(?i)[-_ .]?(x86_64|x86|x64|win32|win64|amd64|i386|32|64)(bits?)?$`)
+
+// directories that tell architectures apart, for example
+// "bin/x64" or "Binaries/Win64"
+var archDirs = map[string]bool{
+	"x86":    true,
+	"x64":    true,
+	"x86_64": true,
+	"amd64":  true,
+	"i386":   true,
+	"win32":  true,
+	"win64":  true,
+	"bin32":  true,
+	"bin64":  true,
+	"32":     true,
+	"64":     true,
+	"32bit":  true,
+	"64bit":  true,
+}
+
+// siblingName returns the base name of p without its extension
+// or architecture suffix
+func siblingName(p string) string {
+	base := path.Base(p)
+	name := strings.TrimSuffix(base, path.Ext(base))
+	if trimmed := archSuffixRegexp.ReplaceAllString(name, ""); trimmed != "" {
+		name = trimmed
+	}
+	return name
+}
+
+// siblingKey returns what p has in common with its builds
+// for other architectures
+func siblingKey(p string) string {
+	dir := path.Dir(p)
+	var parts []string
+	for _, part := range strings.Split(strings.ToLower(dir), "/") {
+		if archDirs[part] {
+			part = "*"
+		}
+		parts = append(parts, part)
+	}
+	return path.Join(append(parts, strings.ToLower(siblingName(p)))...)
+}
+
+// findSiblingBuilds groups the executables of files that only differ
+// in architecture, judging by their paths
+func findSiblingBuilds(files map[string]*PeInfo) []*SiblingBuilds {
+	groups := make(map[string]*SiblingBuilds)
+	for p, info := range files {
+		if strings.ToLower(path.Ext(p)) != ".exe" || info.Arch == ArchUnknown {
+			continue
+		}
+
+		key := siblingKey(p)
+		sb := groups[key]
+		if sb == nil {
+			sb = &SiblingBuilds{}
+			groups[key] = sb
+		}
+		sb.Builds = append(sb.Builds, &SiblingBuild{Path: p, Arch: info.Arch})
+	}
+
+	var res []*SiblingBuilds
+	for _, sb := range groups {
+		arches := make(map[Arch]bool)
+		for _, b := range sb.Builds {
+			arches[b.Arch] = true
+		}
+		if len(arches) < 2 {
+			continue
+		}
+
+		sort.Slice(sb.Builds, func(i, j int) bool {
+			return sb.Builds[i].Path < sb.Builds[j].Path
+		})
+		sb.Name = siblingName(sb.Builds[0].Path)
+		res = append(res, sb)
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Builds[0].Path < res[j].Builds[0].Path
+	})
+	return res
+}
@@ -0,0 +1,54 @@
+package pelican
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_FindSiblingBuilds(t *testing.T) {
+	files := map[string]*PeInfo{
+		"Game.exe":                                  {Arch: Arch386},
+		"Game64.exe":                                {Arch: ArchAmd64},
+		"tools/editor_x86.exe":                      {Arch: Arch386},
+		"tools/editor_x64.exe":                      {Arch: ArchAmd64},
+		"Binaries/Win32/Shooter.exe":                {Arch: Arch386},
+		"Binaries/Win64/Shooter.exe":                {Arch: ArchAmd64},
+		"Binaries/Win64/Shooter-Win64-Shipping.exe": {Arch: ArchAmd64},
+		// same architecture, not siblings
+		"setup32.exe": {Arch: Arch386},
+		"setup.exe":   {Arch: Arch386},
+		// not executables
+		"engine.dll":   {Arch: Arch386},
+		"engine64.dll": {Arch: ArchAmd64},
+		// unknown architecture
+		"launcher.exe":   {Arch: Arch386},
+		"launcher64.exe": {},
+	}
+
+	res := findSiblingBuilds(files)
+	assert.EqualValues(t, []*SiblingBuilds{
+		{Name: "Shooter", Builds: []*SiblingBuild{
+			{Path: "Binaries/Win32/Shooter.exe", Arch: Arch386},
+			{Path: "Binaries/Win64/Shooter.exe", Arch: ArchAmd64},
+		}},
+		{Name: "Game", Builds: []*SiblingBuild{
+			{Path: "Game.exe", Arch: Arch386},
+			{Path: "Game64.exe", Arch: ArchAmd64},
+		}},
+		{Name: "editor", Builds: []*SiblingBuild{
+			{Path: "tools/editor_x64.exe", Arch: ArchAmd64},
+			{Path: "tools/editor_x86.exe", Arch: Arch386},
+		}},
+	}, res)
+
+	game := res[1]
+	assert.EqualValues(t, "Game64.exe", game.Pick(ArchAmd64).Path)
+	assert.EqualValues(t, "Game.exe", game.Pick(Arch386).Path)
+	assert.Nil(t, game.Pick(ArchUnknown))
+
+	only32 := &SiblingBuilds{Builds: []*SiblingBuild{{Path: "game.exe", Arch: Arch386}}}
+	assert.EqualValues(t, "game.exe", only32.Pick(ArchAmd64).Path)
+	only64 := &SiblingBuilds{Builds: []*SiblingBuild{{Path: "game64.exe", Arch: ArchAmd64}}}
+	assert.Nil(t, only64.Pick(Arch386))
+}
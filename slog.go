@@ -0,0 +1,33 @@
+//go:build go1.21
+// +build go1.21
+
+package pelican
+
+import (
+	"context"
+	"log/slog"
+
+	"github.com/itchio/headway/state"
+)
+
+var slogLevels = map[string]slog.Level{
+	"debug":   slog.LevelDebug,
+	"info":    slog.LevelInfo,
+	"warning": slog.LevelWarn,
+	"error":   slog.LevelError,
+}
+
+// SlogConsumer returns a consumer that forwards messages to logger,
+// to be used as ProbeParams.Consumer or DefaultConsumer by services
+// that use structured logging.
+func SlogConsumer(logger *slog.Logger) *state.Consumer {
+	return &state.Consumer{
+		OnMessage: func(level string, msg string) {
+			lvl, ok := slogLevels[level]
+			if !ok {
+				lvl = slog.LevelInfo
+			}
+			logger.Log(context.Background(), lvl, msg)
+		},
+	}
+}
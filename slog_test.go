@@ -0,0 +1,32 @@
+//go:build go1.21
+// +build go1.21
+
+package pelican_test
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+
+	"github.com/itchio/httpkit/eos"
+	"github.com/itchio/pelican"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_SlogConsumer(t *testing.T) {
+	f, err := eos.Open("./testdata/resourceful/resourceful32-packed.exe")
+	assert.NoError(t, err)
+	defer f.Close()
+
+	buf := new(bytes.Buffer)
+	logger := slog.New(slog.NewTextHandler(buf, &slog.HandlerOptions{Level: slog.LevelWarn}))
+
+	pelican.DefaultConsumer = pelican.SlogConsumer(logger)
+	defer func() { pelican.DefaultConsumer = nil }()
+
+	_, err = pelican.Probe(f, pelican.ProbeParams{})
+	assert.NoError(t, err)
+	assert.Contains(t, buf.String(), "level=WARN")
+	assert.Contains(t, buf.String(), "lies outside the resource section")
+	assert.NotContains(t, buf.String(), "level=DEBUG")
+}
@@ -0,0 +1,91 @@
+package pelican
+
+import (
+	"github.com/itchio/pelican/pe"
+	"github.com/pkg/errors"
+)
+
+// The functions below are the stages Probe is made of. They can be used
+// on a *pe.File opened by the caller, to only compute the parts of a PeInfo
+// that are needed. Each stage fills the relevant fields of info, and records
+// warnings in it, unless params.Strict is set, in which case it returns
+// errors instead. params.Cache is ignored.
+
+// ParseImports fills info.Imports, info.DelayImports, info.ImportMetrics
+// and info.IsDebugBuild
+func ParseImports(info *PeInfo, pf *pe.File, params ProbeParams) error {
+	params.setDefaults()
+	_, err := params.parseImports(info, pf)
+	return err
+}
+
+// ParseResources fills the fields that come from resources:
+// info.VersionProperties, info.IsPrerelease, info.AssemblyInfo,
+// info.DependentAssemblies, info.Dialogs, info.Menus, info.Accelerators
+// and info.HasIcon
+func ParseResources(info *PeInfo, pf *pe.File, params ProbeParams) error {
+	params.setDefaults()
+	if info.VersionProperties == nil {
+		info.VersionProperties = make(map[string]string)
+	}
+	return params.probeResources(info, pf, ResourceTypeNone)
+}
+
+// ParseManifest only fills info.AssemblyInfo and info.DependentAssemblies,
+// which is faster than ParseResources for binaries with many resources
+func ParseManifest(info *PeInfo, pf *pe.File, params ProbeParams) error {
+	params.setDefaults()
+	return params.probeResources(info, pf, ResourceTypeManifest)
+}
+
+// ParseSignature fills info.Signature, if pf has a certificate table,
+// including the certificate it was signed with
+func ParseSignature(info *PeInfo, pf *pe.File, params ProbeParams) error {
+	params.setDefaults()
+	info.Signature = nil
+
+	dirs := dataDirectories(pf)
+	if len(dirs) <= pe.IMAGE_DIRECTORY_ENTRY_SECURITY {
+		return nil
+	}
+	dd := dirs[pe.IMAGE_DIRECTORY_ENTRY_SECURITY]
+	if dd.VirtualAddress == 0 || dd.Size == 0 {
+		return nil
+	}
+
+	// unlike other data directories, this is a file offset
+	if int64(dd.VirtualAddress)+int64(dd.Size) > pf.Size() {
+		// NSIS uninstallers, for example, are written with the installer's headers
+		params.warn(info, WarningSignatureOutsideFile, nil, "Certificate table (%d bytes at %x) lies past the end of the file, ignoring", dd.Size, dd.VirtualAddress)
+		return nil
+	}
+	info.Signature = &SignatureInfo{
+		Offset: int64(dd.VirtualAddress),
+		Size:   int64(dd.Size),
+	}
+
+	if !params.reserveOrWarn(info, int64(dd.Size), "certificate table") {
+		return nil
+	}
+	defer params.release(int64(dd.Size))
+
+	certs, err := pf.Certificates()
+	if err != nil {
+		if params.Strict {
+			return errors.WithMessage(err, "while reading certificate table")
+		}
+		params.warn(info, WarningSignatureInvalid, err, "Could not read certificate table")
+		return nil
+	}
+	cert, err := signingCertificate(certs)
+	if err != nil {
+		if params.Strict {
+			return errors.WithMessage(err, "while parsing signature")
+		}
+		params.warn(info, WarningSignatureInvalid, err, "Could not parse signature")
+		return nil
+	}
+	info.Signature.CertificateSHA256 = certificateSHA256(cert)
+	info.Signature.CertificateSubject = cert.Subject.String()
+	return nil
+}
@@ -0,0 +1,123 @@
+package pelican
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/itchio/pelican/pe"
+)
+
+// EntryPointStub identifies the code found at the entry point of a binary,
+// which is usually generated by the toolchain or by a packer
+type EntryPointStub string
+
+const (
+	StubUnknown EntryPointStub = ""
+	// Visual C++ CRT startup (mainCRTStartup, WinMainCRTStartup, etc.)
+	StubMSVC EntryPointStub = "msvc"
+	// MinGW / MinGW-w64 crt startup
+	StubMinGW EntryPointStub = "mingw"
+	// UPX decompression stub
+	StubUPX EntryPointStub = "upx"
+	// Borland / Embarcadero Delphi or C++ Builder
+	StubDelphi EntryPointStub = "delphi"
+	// Nullsoft Scriptable Install System
+	StubNSIS EntryPointStub = "nsis"
+)
+
+type stubSignature struct {
+	stub EntryPointStub
+	arch Arch
+	// -1 matches any byte
+	pattern []int
+}
+
+// parseStubPattern parses space-separated hex bytes, with "??" as a wildcard
+func parseStubPattern(s string) []int {
+	var res []int
+	for _, tok := range strings.Fields(s) {
+		if tok == "??" {
+			res = append(res, -1)
+			continue
+		}
+		b, err := strconv.ParseUint(tok, 16, 8)
+		if err != nil {
+			panic(err)
+		}
+		res = append(res, int(b))
+	}
+	return res
+}
+
+// Signatures are checked in order, the first match wins.
+var stubSignatures = []stubSignature{
+	// pushad; mov esi, <packed>; lea edi, [esi-<offset>]; push edi
+	{StubUPX, Arch386, parseStubPattern("60 BE ?? ?? ?? ?? 8D BE ?? ?? ?? ?? 57")},
+	// push rbx; push rsi; push rdi; push rbp; lea rsi, <packed>; lea rdi, [rsi-<offset>]
+	{StubUPX, ArchAmd64, parseStubPattern("53 56 57 55 48 8D 35 ?? ?? ?? ?? 48 8D BE")},
+
+	{StubNSIS, Arch386, parseStubPattern("81 EC ?? ?? 00 00 53 55 56 33 DB 57")},
+
+	{StubDelphi, Arch386, parseStubPattern("55 8B EC 83 C4 F0 B8 ?? ?? ?? ?? E8")},
+	{StubDelphi, Arch386, parseStubPattern("55 8B EC 83 C4 F0 53 B8 ?? ?? ?? ?? E8")},
+
+	// mov dword ptr [__mingw_app_type], 0 or 1
+	{StubMinGW, Arch386, parseStubPattern("83 EC 0C C7 05 ?? ?? ?? ?? ?? 00 00 00 E8")},
+	{StubMinGW, ArchAmd64, parseStubPattern("48 83 EC 28 48 8B 05 ?? ?? ?? ?? C7 00 ?? 00 00 00 E8")},
+
+	// call __security_init_cookie; jmp __scrt_common_main_seh
+	{StubMSVC, Arch386, parseStubPattern("E8 ?? ?? ?? ?? E9 ?? ?? ?? ??")},
+	{StubMSVC, ArchAmd64, parseStubPattern("48 83 EC 28 E8 ?? ?? ?? ?? 48 83 C4 28 E9")},
+	// Visual C++ 6 to 2003: SEH frame setup
+	{StubMSVC, Arch386, parseStubPattern("55 8B EC 6A FF 68 ?? ?? ?? ?? 68 ?? ?? ?? ?? 64 A1 00 00 00 00")},
+}
+
+// the longest signature
+const stubPeekSize = 32
+
+func (sig stubSignature) matches(code []byte) bool {
+	if len(code) < len(sig.pattern) {
+		return false
+	}
+	for i, b := range sig.pattern {
+		if b >= 0 && int(code[i]) != b {
+			return false
+		}
+	}
+	return true
+}
+
+// classifyEntryPoint compares the first bytes at the entry point
+// against known stubs. It's a heuristic: unreadable entry points
+// are simply reported as unknown.
+func classifyEntryPoint(info *PeInfo, pf *pe.File) EntryPointStub {
+	entry := pf.OptionalHeaderFields().AddressOfEntryPoint
+	if entry == 0 {
+		// DLLs may have no entry point
+		return StubUnknown
+	}
+
+	for _, s := range pf.Sections {
+		if entry < s.VirtualAddress || uint64(entry) >= uint64(s.VirtualAddress)+uint64(s.Size) {
+			continue
+		}
+
+		offset := int64(entry - s.VirtualAddress)
+		length := int64(stubPeekSize)
+		if offset+length > int64(s.Size) {
+			length = int64(s.Size) - offset
+		}
+		code, err := s.DataRange(offset, length)
+		if err != nil {
+			return StubUnknown
+		}
+
+		for _, sig := range stubSignatures {
+			if sig.arch == info.Arch && sig.matches(code) {
+				return sig.stub
+			}
+		}
+		return StubUnknown
+	}
+	return StubUnknown
+}
@@ -0,0 +1,179 @@
+package pelican
+
+import (
+	"io/fs"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/itchio/pelican/resources"
+	"github.com/pkg/errors"
+)
+
+// SxSRedirection is a DLL an executable binds to through a side-by-side
+// assembly listed in its manifest, rather than through the usual search
+// order. The typical example is comctl32.dll, which resolves to version 6
+// in WinSxS when depending on Microsoft.Windows.Common-Controls, even if
+// there's a copy next to the executable.
+type SxSRedirection struct {
+	// Slash-separated path (relative to the directory) of the executable
+	Path string `json:"path"`
+	// Lower-case DLL name, for example "comctl32.dll"
+	DLL      string `json:"dll"`
+	Assembly string `json:"assembly"`
+	// Slash-separated path of the DLL for private assemblies (deployed
+	// in the directory), empty for system assemblies (deployed in WinSxS)
+	Target string `json:"target,omitempty"`
+}
+
+// system assemblies and the DLLs they contain, keyed by lower-case name
+var sxsSystemAssemblies = map[string][]string{
+	"microsoft.windows.common-controls": {"comctl32.dll"},
+	"microsoft.windows.gdiplus":         {"gdiplus.dll"},
+	"microsoft.vc80.crt":                {"msvcr80.dll", "msvcp80.dll", "msvcm80.dll"},
+	"microsoft.vc90.crt":                {"msvcr90.dll", "msvcp90.dll", "msvcm90.dll"},
+	"microsoft.vc80.mfc":                {"mfc80.dll", "mfc80u.dll", "mfcm80.dll", "mfcm80u.dll"},
+	"microsoft.vc90.mfc":                {"mfc90.dll", "mfc90u.dll", "mfcm90.dll", "mfcm90u.dll"},
+	"microsoft.vc80.openmp":             {"vcomp.dll"},
+	"microsoft.vc90.openmp":             {"vcomp90.dll"},
+}
+
+// findSxSRedirections resolves the dependent assemblies of every executable.
+// Private assemblies (a manifest next to the executable, or in a folder named
+// after the assembly) take precedence over system ones, like they do for the
+// loader when the exact version isn't in WinSxS. Assemblies that can't be found
+// either way are left out.
+func findSxSRedirections(fsys fs.FS, files map[string]*PeInfo) ([]*SxSRedirection, error) {
+	var res []*SxSRedirection
+	for p, info := range files {
+		if strings.ToLower(path.Ext(p)) != ".exe" {
+			continue
+		}
+
+		for _, da := range info.DependentAssemblies {
+			if da.Name == "" {
+				continue
+			}
+
+			am, manifestPath, err := findPrivateAssembly(fsys, path.Dir(p), da.Name)
+			if err != nil {
+				return nil, errors.WithMessagef(err, "while reading manifest of assembly %s", da.Name)
+			}
+			if am != nil {
+				for _, dll := range am.files {
+					res = append(res, &SxSRedirection{
+						Path:     p,
+						DLL:      strings.ToLower(path.Base(dll)),
+						Assembly: da.Name,
+						Target:   path.Join(path.Dir(manifestPath), dll),
+					})
+				}
+				continue
+			}
+
+			for _, dll := range sxsSystemAssemblies[strings.ToLower(da.Name)] {
+				res = append(res, &SxSRedirection{
+					Path:     p,
+					DLL:      dll,
+					Assembly: da.Name,
+				})
+			}
+		}
+	}
+
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Path != res[j].Path {
+			return res[i].Path < res[j].Path
+		}
+		return res[i].DLL < res[j].DLL
+	})
+	return res, nil
+}
+
+// assemblyManifest is what matters about the manifest of a private assembly
+type assemblyManifest struct {
+	files        []string
+	dependencies []*AssemblyIdentity
+}
+
+// findPrivateAssembly looks for the manifest of a private assembly the
+// way the loader does, and parses it. am is nil if there's none.
+func findPrivateAssembly(fsys fs.FS, dir string, name string) (am *assemblyManifest, manifestPath string, err error) {
+	for _, candidate := range []string{
+		path.Join(dir, name+".manifest"),
+		path.Join(dir, name, name+".manifest"),
+	} {
+		data, err := fs.ReadFile(fsys, candidate)
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, "", errors.WithStack(err)
+		}
+
+		am, err := readAssemblyManifest(data)
+		if err != nil {
+			return nil, "", err
+		}
+		return am, candidate, nil
+	}
+	return nil, "", nil
+}
+
+// readAssemblyManifest returns the files an assembly manifest lists,
+// and the assemblies it depends on
+func readAssemblyManifest(manifest []byte) (*assemblyManifest, error) {
+	m, err := resources.ParseManifest(manifest)
+	if err != nil {
+		return nil, err
+	}
+	return &assemblyManifest{
+		files: m.Files,
+		// dependencies are declared the same way as in executables
+		dependencies: m.DependentAssemblies,
+	}, nil
+}
+
+// sxsByDir returns, for each directory with executables, the redirections
+// that apply to all of them, keyed by DLL name
+func sxsByDir(files map[string]*PeInfo, redirections []*SxSRedirection) map[string]map[string]*SxSRedirection {
+	exeCounts := make(map[string]int)
+	for p := range files {
+		if strings.ToLower(path.Ext(p)) == ".exe" {
+			exeCounts[path.Dir(p)]++
+		}
+	}
+
+	counts := make(map[string]map[string]int)
+	res := make(map[string]map[string]*SxSRedirection)
+	for _, r := range redirections {
+		dir := path.Dir(r.Path)
+		if counts[dir] == nil {
+			counts[dir] = make(map[string]int)
+			res[dir] = make(map[string]*SxSRedirection)
+		}
+		counts[dir][r.DLL]++
+		res[dir][r.DLL] = r
+	}
+
+	for dir, dlls := range res {
+		for dll := range dlls {
+			if counts[dir][dll] < exeCounts[dir] {
+				delete(dlls, dll)
+			}
+		}
+	}
+	return res
+}
+
+// sxsByExe returns the redirections of each executable, keyed by DLL name
+func sxsByExe(redirections []*SxSRedirection) map[string]map[string]*SxSRedirection {
+	res := make(map[string]map[string]*SxSRedirection)
+	for _, r := range redirections {
+		if res[r.Path] == nil {
+			res[r.Path] = make(map[string]*SxSRedirection)
+		}
+		res[r.Path][r.DLL] = r
+	}
+	return res
+}
@@ -0,0 +1,52 @@
+package pelican
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const vc90Manifest = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<assembly xmlns="urn:schemas-microsoft-com:asm.v1" manifestVersion="1.0">
+  <noInheritable></noInheritable>
+  <assemblyIdentity type="win32" name="Microsoft.VC90.CRT" version="9.0.21022.8" processorArchitecture="x86" publicKeyToken="1fc8b3b9a1e18e3b"></assemblyIdentity>
+  <file name="msvcr90.dll"></file>
+  <file name="msvcp90.dll"></file>
+  <file name="msvcm90.dll"></file>
+</assembly>`
+
+func Test_PrivateAssemblies(t *testing.T) {
+	vc90 := &AssemblyIdentity{Name: "Microsoft.VC90.CRT", Version: "9.0.21022.8"}
+	files := map[string]*PeInfo{
+		"game.exe":        {DependentAssemblies: []*AssemblyIdentity{vc90}},
+		"tools/tool.exe":  {DependentAssemblies: []*AssemblyIdentity{vc90}},
+		"tools/other.exe": {},
+	}
+	fsys := fstest.MapFS{
+		"Microsoft.VC90.CRT/Microsoft.VC90.CRT.manifest": {Data: []byte(vc90Manifest)},
+	}
+
+	redirections, err := findSxSRedirections(fsys, files)
+	assert.NoError(t, err)
+
+	var got []string
+	for _, r := range redirections {
+		got = append(got, r.Path+" "+r.DLL+" "+r.Target)
+	}
+	assert.EqualValues(t, []string{
+		// private assembly
+		"game.exe msvcm90.dll Microsoft.VC90.CRT/msvcm90.dll",
+		"game.exe msvcp90.dll Microsoft.VC90.CRT/msvcp90.dll",
+		"game.exe msvcr90.dll Microsoft.VC90.CRT/msvcr90.dll",
+		// not next to tools/tool.exe, so it's the system one
+		"tools/tool.exe msvcm90.dll ",
+		"tools/tool.exe msvcp90.dll ",
+		"tools/tool.exe msvcr90.dll ",
+	}, got)
+
+	// tools/other.exe would load tools/msvcr90.dll
+	sxs := sxsByDir(files, redirections)
+	assert.NotNil(t, sxs["."]["msvcr90.dll"])
+	assert.Nil(t, sxs["tools"]["msvcr90.dll"])
+}
@@ -0,0 +1,37 @@
+#!/usr/bin/env python3
+# Generates hello32-firstthunk.exe from hello32-mingw.exe, with imports
+# laid out like old Borland linkers do: descriptors have no
+# OriginalFirstThunk, so names are only reachable through FirstThunk.
+# mingw puts the import address table after the import descriptors, in
+# the same section, like Borland does.
+import struct
+
+data = bytearray(open("../hello/hello32-mingw.exe", "rb").read())
+
+pe = struct.unpack_from("<I", data, 0x3c)[0]
+nsections = struct.unpack_from("<H", data, pe + 6)[0]
+optsize = struct.unpack_from("<H", data, pe + 20)[0]
+import_rva = struct.unpack_from("<I", data, pe + 24 + 104)[0]
+
+sections = []
+for i in range(nsections):
+    sh = pe + 24 + optsize + i * 40
+    vsize, va, size, offset = struct.unpack_from("<IIII", data, sh + 8)
+    sections.append((va, max(vsize, size), offset))
+
+
+def rva_to_offset(rva):
+    for va, size, offset in sections:
+        if va <= rva < va + size:
+            return offset + rva - va
+    raise ValueError("rva %x not in any section" % rva)
+
+
+desc = rva_to_offset(import_rva)
+while any(data[desc:desc + 20]):
+    first_thunk = struct.unpack_from("<I", data, desc + 16)[0]
+    assert first_thunk > import_rva
+    struct.pack_into("<I", data, desc, 0)
+    desc += 20
+
+open("hello32-firstthunk.exe", "wb").write(data)
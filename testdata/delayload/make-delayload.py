@@ -0,0 +1,136 @@
+#!/usr/bin/env python3
+# Generates minimal executables that import ExitProcess from KERNEL32.dll,
+# and delay-load (as delayimp.lib does):
+#
+#   d3dx9_43.dll: D3DXCreateTextureFromFileA, and ordinal 42
+#   fmodex.dll:   FMOD_System_Create. In delayload32.exe, its descriptor
+#                 uses virtual addresses instead of RVAs, as Visual C++ 6
+#                 did (attributes = 0)
+import struct
+
+FILE_ALIGNMENT = 0x200
+SECTION_ALIGNMENT = 0x1000
+TEXT_RVA = 0x1000
+RDATA_RVA = 0x2000
+
+
+def align(n, a):
+    return (n + a - 1) // a * a
+
+
+class Rdata:
+    def __init__(self):
+        self.data = bytearray()
+
+    def reserve(self, size):
+        rva = RDATA_RVA + len(self.data)
+        self.data.extend(bytes(size))
+        return rva
+
+    def add(self, blob):
+        rva = RDATA_RVA + len(self.data)
+        self.data.extend(blob)
+        self.data.extend(bytes(align(len(self.data), 8) - len(self.data)))
+        return rva
+
+    def pack(self, fmt, rva, *values):
+        struct.pack_into(fmt, self.data, rva - RDATA_RVA, *values)
+
+
+def make_exe(path, pe64, image_base):
+    ptr = "<Q" if pe64 else "<I"
+    ptr_size = 8 if pe64 else 4
+    ordinal_flag = 1 << 63 if pe64 else 1 << 31
+    rdata = Rdata()
+
+    def hint_name(name):
+        return rdata.add(struct.pack("<H", 0) + name.encode() + b"\0")
+
+    def thunks(values):
+        rva = rdata.reserve((len(values) + 1) * ptr_size)
+        for i, v in enumerate(values):
+            rdata.pack(ptr, rva + i * ptr_size, v)
+        return rva
+
+    # regular imports
+    import_dir = rdata.reserve(20 * 2)
+    exit_process = hint_name("ExitProcess")
+    rdata.pack("<IIIII", import_dir, thunks([exit_process]), 0, 0,
+               rdata.add(b"KERNEL32.dll\0"), thunks([exit_process]))
+
+    # delay-load imports
+    delay_dir = rdata.reserve(32 * 3)
+    libraries = [
+        ("d3dx9_43.dll", ["D3DXCreateTextureFromFileA", 42], True),
+        ("fmodex.dll", ["FMOD_System_Create"], pe64),
+    ]
+    for i, (dll, symbols, rva_based) in enumerate(libraries):
+        base = 0 if rva_based else image_base
+        values = [ordinal_flag | s if isinstance(s, int) else base + hint_name(s) for s in symbols]
+        name_table = thunks(values)
+        address_table = thunks([0] * len(values))
+        module_handle = rdata.reserve(ptr_size)
+        rdata.pack("<8I", delay_dir + i * 32,
+                   1 if rva_based else 0, base + rdata.add(dll.encode() + b"\0"),
+                   base + module_handle, base + address_table, base + name_table,
+                   0, 0, 0)
+
+    text = b"\xc3"
+    sections = [
+        (b".text", TEXT_RVA, text, 0x60000020),
+        (b".rdata", RDATA_RVA, bytes(rdata.data), 0x40000040),
+    ]
+
+    opt_size = 240 if pe64 else 224
+    headers = bytearray(FILE_ALIGNMENT)
+    headers[0:2] = b"MZ"
+    struct.pack_into("<I", headers, 0x3c, 0x40)
+    headers[0x40:0x44] = b"PE\0\0"
+    struct.pack_into("<HHIIIHH", headers, 0x44,
+                     0x8664 if pe64 else 0x14c, len(sections), 0, 0, 0, opt_size,
+                     0x22 if pe64 else 0x102)
+
+    size_of_image = align(RDATA_RVA + len(rdata.data), SECTION_ALIGNMENT)
+    oh = 0x58
+    if pe64:
+        struct.pack_into("<HBBIIIIIQIIHHHHHHIIIIHHQQQQII", headers, oh,
+                         0x20b, 14, 0,
+                         FILE_ALIGNMENT, FILE_ALIGNMENT, 0,
+                         TEXT_RVA, TEXT_RVA,
+                         image_base, SECTION_ALIGNMENT, FILE_ALIGNMENT,
+                         6, 0, 0, 0, 6, 0, 0,
+                         size_of_image, FILE_ALIGNMENT, 0,
+                         2, 0x8160,
+                         0x100000, 0x1000, 0x100000, 0x1000,
+                         0, 16)
+        dirs = oh + 112
+    else:
+        struct.pack_into("<HBBIIIIIIIIIHHHHHHIIIIHHIIIIII", headers, oh,
+                         0x10b, 14, 0,
+                         FILE_ALIGNMENT, FILE_ALIGNMENT, 0,
+                         TEXT_RVA, TEXT_RVA, RDATA_RVA,
+                         image_base, SECTION_ALIGNMENT, FILE_ALIGNMENT,
+                         6, 0, 0, 0, 6, 0, 0,
+                         size_of_image, FILE_ALIGNMENT, 0,
+                         2, 0x8140,
+                         0x100000, 0x1000, 0x100000, 0x1000,
+                         0, 16)
+        dirs = oh + 96
+    struct.pack_into("<II", headers, dirs + 1 * 8, import_dir, 40)
+    struct.pack_into("<II", headers, dirs + 13 * 8, delay_dir, 96)
+
+    body = bytearray()
+    sh = oh + opt_size
+    for i, (name, rva, data, characteristics) in enumerate(sections):
+        offset = FILE_ALIGNMENT + len(body)
+        size = align(len(data), FILE_ALIGNMENT)
+        struct.pack_into("<8sIIIIIIHHI", headers, sh + i * 40,
+                         name, len(data), rva, size, offset,
+                         0, 0, 0, 0, characteristics)
+        body.extend(data.ljust(size, b"\0"))
+
+    open(path, "wb").write(headers + body)
+
+
+make_exe("delayload32.exe", False, 0x400000)
+make_exe("delayload64.exe", True, 0x140000000)
@@ -0,0 +1,37 @@
+#!/usr/bin/env python3
+# Generates hello32-fake-delphi.exe from hello32-mingw.exe, made to look
+# like an old (2007 or earlier) Delphi binary: sections are renamed to
+# CODE, DATA and BSS, and imports have no OriginalFirstThunk.
+import struct
+
+data = bytearray(open("../hello/hello32-mingw.exe", "rb").read())
+
+pe = struct.unpack_from("<I", data, 0x3c)[0]
+nsections = struct.unpack_from("<H", data, pe + 6)[0]
+optsize = struct.unpack_from("<H", data, pe + 20)[0]
+import_rva = struct.unpack_from("<I", data, pe + 24 + 104)[0]
+
+renames = {b".text": b"CODE", b".data": b"DATA", b".bss": b"BSS"}
+sections = []
+for i in range(nsections):
+    sh = pe + 24 + optsize + i * 40
+    name = bytes(data[sh:sh + 8]).rstrip(b"\0")
+    if name in renames:
+        data[sh:sh + 8] = renames[name].ljust(8, b"\0")
+    vsize, va, size, offset = struct.unpack_from("<IIII", data, sh + 8)
+    sections.append((va, max(vsize, size), offset))
+
+
+def rva_to_offset(rva):
+    for va, size, offset in sections:
+        if va <= rva < va + size:
+            return offset + rva - va
+    raise ValueError("rva %x not in any section" % rva)
+
+
+desc = rva_to_offset(import_rva)
+while any(data[desc:desc + 20]):
+    struct.pack_into("<I", data, desc, 0)
+    desc += 20
+
+open("hello32-fake-delphi.exe", "wb").write(data)
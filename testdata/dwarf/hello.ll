@@ -0,0 +1,23 @@
+; Compiled with: llc -filetype=obj -function-sections hello.ll -o hello-dwarf5.obj
+target triple = "x86_64-pc-windows-gnu"
+
+define i32 @hello() !dbg !6 {
+  ret i32 0, !dbg !9
+}
+
+define i32 @world() !dbg !10 {
+  ret i32 1, !dbg !11
+}
+
+!llvm.dbg.cu = !{!0}
+!llvm.module.flags = !{!3, !4}
+
+!0 = distinct !DICompileUnit(language: DW_LANG_C99, file: !1, producer: "pelican", isOptimized: false, runtimeVersion: 0, emissionKind: FullDebug)
+!1 = !DIFile(filename: "hello.c", directory: "/tmp")
+!3 = !{i32 7, !"Dwarf Version", i32 5}
+!4 = !{i32 2, !"Debug Info Version", i32 3}
+!5 = !DISubroutineType(types: !{})
+!6 = distinct !DISubprogram(name: "hello", scope: !1, file: !1, line: 1, type: !5, unit: !0, spFlags: DISPFlagDefinition)
+!9 = !DILocation(line: 1, column: 1, scope: !6)
+!10 = distinct !DISubprogram(name: "world", scope: !1, file: !1, line: 2, type: !5, unit: !0, spFlags: DISPFlagDefinition)
+!11 = !DILocation(line: 2, column: 1, scope: !10)
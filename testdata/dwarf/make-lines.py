@@ -0,0 +1,26 @@
+#!/usr/bin/env python3
+# Generates reloc-lines.obj from reloc.obj, with COFF line number
+# records for the .text section appended at the end of the file.
+# Current toolchains don't emit them anymore.
+import struct
+
+data = bytearray(open("reloc.obj", "rb").read())
+
+# (symbol table index or virtual address, line number)
+records = [
+    (12, 0),  # hello
+    (0, 1),
+    (2, 2),
+    (13, 0),  # world
+    (3, 1),
+    (5, 2),
+]
+
+sh = 20  # .text is the first section
+assert data[sh:sh + 8].rstrip(b"\0") == b".text"
+struct.pack_into("<I", data, sh + 28, len(data))
+struct.pack_into("<H", data, sh + 34, len(records))
+for rec in records:
+    data += struct.pack("<IH", *rec)
+
+open("reloc-lines.obj", "wb").write(data)
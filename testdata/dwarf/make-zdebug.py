@@ -0,0 +1,44 @@
+#!/usr/bin/env python3
+# Generates hello-dwarf5-zdebug.obj from hello-dwarf5.obj, with every
+# .debug_* section compressed into a .zdebug_* section, like the Go
+# linker and some MinGW toolchains do.
+import struct
+import zlib
+
+data = bytearray(open("hello-dwarf5.obj", "rb").read())
+
+nsections, _, symtab, nsyms = struct.unpack_from("<HIII", data, 2)
+strtab = symtab + nsyms * 18
+strtab_size = struct.unpack_from("<I", data, strtab)[0]
+assert strtab + strtab_size == len(data)
+
+strings = bytearray(data[strtab:])
+for i in range(nsections):
+    sh = 20 + i * 40
+    name = data[sh:sh + 8].rstrip(b"\0").decode()
+    if not name.startswith("/"):
+        continue
+    offset = int(name[1:])
+    long_name = bytes(strings[offset:strings.index(b"\0", offset)])
+    if long_name.startswith(b".debug_"):
+        new_offset = len(strings)
+        strings += b".z" + long_name[1:] + b"\0"
+        data[sh:sh + 8] = ("/%d" % new_offset).encode().ljust(8, b"\0")
+
+struct.pack_into("<I", strings, 0, len(strings))
+out = data[:strtab] + strings
+for i in range(nsections):
+    sh = 20 + i * 40
+    name = out[sh:sh + 8].rstrip(b"\0").decode()
+    if not name.startswith("/"):
+        continue
+    offset = int(name[1:])
+    if not strings[offset:].startswith(b".zdebug_"):
+        continue
+    size, pointer = struct.unpack_from("<II", out, sh + 16)
+    raw = bytes(data[pointer:pointer + size])
+    compressed = b"ZLIB" + struct.pack(">Q", len(raw)) + zlib.compress(raw)
+    struct.pack_into("<II", out, sh + 16, len(compressed), len(out))
+    out += compressed
+
+open("hello-dwarf5-zdebug.obj", "wb").write(out)
@@ -0,0 +1,52 @@
+# Hand-written DWARF 4 whose relocations are against global symbols
+# (rather than section symbols), so it's only readable once relocated.
+#
+# Assembled with: llvm-mc -triple x86_64-windows-gnu -filetype=obj reloc.s -o reloc.obj
+
+	.text
+	.globl	hello
+hello:
+	xorl	%eax, %eax
+	retq
+	.globl	world
+world:
+	movl	$1, %eax
+	retq
+
+	.section	.debug_abbrev,"dr"
+	.byte	1, 0x11, 1	# 1: DW_TAG_compile_unit, has children
+	.byte	0x03, 0x0e	# DW_AT_name, DW_FORM_strp
+	.byte	0, 0
+	.byte	2, 0x2e, 0	# 2: DW_TAG_subprogram, no children
+	.byte	0x03, 0x0e	# DW_AT_name, DW_FORM_strp
+	.byte	0x11, 0x01	# DW_AT_low_pc, DW_FORM_addr
+	.byte	0, 0
+	.byte	0
+
+	.section	.debug_info,"dr"
+	.long	info_end - info_start
+info_start:
+	.short	4		# version
+	.long	0		# abbrev offset
+	.byte	8		# address size
+	.uleb128	1
+	.secrel32	str_cu
+	.uleb128	2
+	.secrel32	str_hello
+	.quad	hello
+	.uleb128	2
+	.secrel32	str_world
+	.quad	world
+	.byte	0
+info_end:
+
+	.section	.debug_str,"dr"
+	.globl	str_cu
+str_cu:
+	.asciz	"reloc.s"
+	.globl	str_hello
+str_hello:
+	.asciz	"hello"
+	.globl	str_world
+str_world:
+	.asciz	"world"
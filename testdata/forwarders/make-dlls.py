@@ -0,0 +1,111 @@
+#!/usr/bin/env python3
+# Generates minimal 32-bit DLLs whose exports forward to each other:
+#
+#   engine.dll: Alloc -> core.Alloc (fine)
+#               Free -> core.Free (core doesn't export Free)
+#               Loop -> core.Loop -> engine.Loop (loop)
+#               Heap -> NTDLL.RtlAllocateHeap (KnownDLL, not checked)
+#               Init -> physx.Init (not in the directory, not checked)
+#               Render (code)
+#   core.dll:   Alloc (code)
+#               Loop -> engine.Loop (loop)
+import struct
+
+FILE_ALIGNMENT = 0x200
+SECTION_ALIGNMENT = 0x1000
+TEXT_RVA = 0x1000
+EDATA_RVA = 0x2000
+
+
+def align(n, a):
+    return (n + a - 1) // a * a
+
+
+def export_directory(dll_name, exports):
+    exports = sorted(exports)
+    n = len(exports)
+    eat = 40
+    npt = eat + n * 4
+    ot = npt + n * 4
+    strings = bytearray()
+    strings_offset = ot + n * 2
+
+    def add_string(s):
+        rva = EDATA_RVA + strings_offset + len(strings)
+        strings.extend(s.encode() + b"\0")
+        return rva
+
+    name_rva = add_string(dll_name)
+    functions = []
+    names = []
+    for name, target in exports:
+        names.append(add_string(name))
+        functions.append(add_string(target) if target else TEXT_RVA)
+
+    data = bytearray(strings_offset)
+    struct.pack_into("<IIHHIIIIIII", data, 0,
+                     0, 0, 0, 0, name_rva, 1, n, n,
+                     EDATA_RVA + eat, EDATA_RVA + npt, EDATA_RVA + ot)
+    for i in range(n):
+        struct.pack_into("<I", data, eat + i * 4, functions[i])
+        struct.pack_into("<I", data, npt + i * 4, names[i])
+        struct.pack_into("<H", data, ot + i * 2, i)
+    return bytes(data + strings)
+
+
+def make_dll(path, exports):
+    dll_name = path
+    edata = export_directory(dll_name, exports)
+    text = b"\xc3"
+
+    sections = [
+        (b".text", TEXT_RVA, text, 0x60000020),
+        (b".edata", EDATA_RVA, edata, 0x40000040),
+    ]
+
+    headers = bytearray(FILE_ALIGNMENT)
+    headers[0:2] = b"MZ"
+    struct.pack_into("<I", headers, 0x3c, 0x40)
+    headers[0x40:0x44] = b"PE\0\0"
+    struct.pack_into("<HHIIIHH", headers, 0x44,
+                     0x14c, len(sections), 0, 0, 0, 224, 0x2102)
+
+    size_of_image = align(EDATA_RVA + len(edata), SECTION_ALIGNMENT)
+    oh = 0x58
+    struct.pack_into("<HBBIIIIIIIIIHHHHHHIIIIHHIIIIII", headers, oh,
+                     0x10b, 14, 0,
+                     FILE_ALIGNMENT, FILE_ALIGNMENT, 0,
+                     0, TEXT_RVA, EDATA_RVA,
+                     0x10000000, SECTION_ALIGNMENT, FILE_ALIGNMENT,
+                     6, 0, 0, 0, 6, 0, 0,
+                     size_of_image, FILE_ALIGNMENT, 0,
+                     2, 0x140,
+                     0x100000, 0x1000, 0x100000, 0x1000,
+                     0, 16)
+    struct.pack_into("<II", headers, oh + 96, EDATA_RVA, len(edata))
+
+    body = bytearray()
+    sh = oh + 224
+    for i, (name, rva, data, characteristics) in enumerate(sections):
+        offset = FILE_ALIGNMENT + len(body)
+        size = align(len(data), FILE_ALIGNMENT)
+        struct.pack_into("<8sIIIIIIHHI", headers, sh + i * 40,
+                         name, len(data), rva, size, offset,
+                         0, 0, 0, 0, characteristics)
+        body.extend(data.ljust(size, b"\0"))
+
+    open(path, "wb").write(headers + body)
+
+
+make_dll("engine.dll", [
+    ("Alloc", "core.Alloc"),
+    ("Free", "core.Free"),
+    ("Loop", "core.Loop"),
+    ("Heap", "NTDLL.RtlAllocateHeap"),
+    ("Init", "physx.Init"),
+    ("Render", None),
+])
+make_dll("core.dll", [
+    ("Alloc", None),
+    ("Loop", "engine.Loop"),
+])
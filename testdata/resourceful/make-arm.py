@@ -0,0 +1,17 @@
+#!/usr/bin/env python3
+# Generates resourceful-arm.exe from resourceful32-mingw.exe, with the
+# machine set to ARMNT (32-bit ARM, Thumb-2). Only that field is patched:
+# the code and the relocations are still x86, so the fixture only covers
+# what pelican derives from the machine field (arch, bitness, etc.) and
+# the PE32 structures shared with x86 (headers, imports, resources).
+# It is not an ARM binary: nothing specific to real ARM builds, like
+# Thumb entry points or ARM relocation types, is exercised.
+import struct
+
+data = bytearray(open("resourceful32-mingw.exe", "rb").read())
+
+pe_offset = struct.unpack_from("<I", data, 0x3c)[0]
+assert struct.unpack_from("<H", data, pe_offset + 4)[0] == 0x14c
+struct.pack_into("<H", data, pe_offset + 4, 0x1c4)
+
+open("resourceful-arm.exe", "wb").write(data)
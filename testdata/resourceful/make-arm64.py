@@ -0,0 +1,17 @@
+#!/usr/bin/env python3
+# Generates resourceful-arm64.exe from resourceful64-mingw.exe, with the
+# machine set to ARM64. Only that field is patched: the code, the
+# exception data and the relocations are still x64, so the fixture only
+# covers what pelican derives from the machine field (arch, bitness, etc.)
+# and the PE32+ structures shared with x64 (headers, imports, resources).
+# It is not an ARM64 binary: nothing specific to real ARM64 builds, like
+# ARM64 unwind codes, ARM64EC or ARM64X, is exercised.
+import struct
+
+data = bytearray(open("resourceful64-mingw.exe", "rb").read())
+
+pe_offset = struct.unpack_from("<I", data, 0x3c)[0]
+assert struct.unpack_from("<H", data, pe_offset + 4)[0] == 0x8664
+struct.pack_into("<H", data, pe_offset + 4, 0xaa64)
+
+open("resourceful-arm64.exe", "wb").write(data)
@@ -0,0 +1,21 @@
+#!/usr/bin/env python3
+# Generates resourceful32-blobs.exe from resourceful32-mingw.exe, with
+# configuration files stashed in RCDATA and HTML resources:
+#
+#   CONFIG     RCDATA "config.json"
+#   7          RCDATA "settings.xml"
+#   CONFIG     RCDATA "config-fr.json" (French)
+#   README.HTM HTML   "readme.htm"
+from rsrc import PE
+
+config = b'{"launch": "game.exe", "args": ["--fullscreen"]}'
+config_fr = b'{"launch": "jeu.exe"}'
+settings = b'<?xml version="1.0"?><settings><vsync>true</vsync></settings>'
+readme = b"<html><body><h1>Read me</h1></body></html>"
+
+pe = PE("resourceful32-mingw.exe")
+tree = pe.read_tree()
+tree[10] = {"CONFIG": {1033: (config, 0), 1036: (config_fr, 0)}, 7: {0: (settings, 0)}}
+tree[23] = {"README.HTM": {1033: (readme, 0)}}
+pe.write_tree(tree)
+pe.save("resourceful32-blobs.exe")
@@ -0,0 +1,58 @@
+#!/usr/bin/env python3
+# Generates resourceful32-cursor.exe from resourceful32-mingw.exe, where the
+# icon group is turned into a cursor group (and the icons into cursors), and
+# the root resource directory carries a timestamp and version, as some
+# resource compilers write them.
+import struct
+
+data = bytearray(open("resourceful32-mingw.exe", "rb").read())
+
+pe = struct.unpack_from("<I", data, 0x3c)[0]
+nsections = struct.unpack_from("<H", data, pe + 6)[0]
+opt_size = struct.unpack_from("<H", data, pe + 20)[0]
+opt = pe + 24
+rsrc_rva = struct.unpack_from("<I", data, opt + 96 + 2 * 8)[0]
+
+for i in range(nsections):
+    sh = opt + opt_size + i * 40
+    if data[sh:sh + 8].rstrip(b"\0") == b".rsrc":
+        rva, raw_size, base = struct.unpack_from("<III", data, sh + 12)
+        assert rva == rsrc_rva
+
+
+def entries(off):
+    named, ids = struct.unpack_from("<HH", data, base + off + 12)
+    for i in range(named + ids):
+        yield base + off + 16 + i * 8
+
+
+def leaf(off):
+    while True:
+        name_id, child = struct.unpack_from("<II", data, next(entries(off)))
+        if not child & 0x80000000:
+            return child
+        off = child & 0x7fffffff
+
+
+# TimeDateStamp, MajorVersion, MinorVersion
+struct.pack_into("<IHH", data, base + 4, 1536000000, 4, 2)
+
+for entry in entries(0):
+    type_id, child = struct.unpack_from("<II", data, entry)
+    if type_id == 3:  # RT_ICON => RT_CURSOR
+        struct.pack_into("<I", data, entry, 1)
+    elif type_id == 14:  # RT_GROUP_ICON => RT_GROUP_CURSOR
+        struct.pack_into("<I", data, entry, 12)
+        data_entry = base + leaf(child & 0x7fffffff)
+        rva, size = struct.unpack_from("<II", data, data_entry)
+        group = base + rva - rsrc_rva
+        reserved, typ, count = struct.unpack_from("<HHH", data, group)
+        assert typ == 1
+        struct.pack_into("<H", data, group + 2, 2)
+        for i in range(count):
+            e = group + 6 + i * 14
+            width, height, colors, _, planes, bits, size, id = struct.unpack_from("<BBBBHHIH", data, e)
+            # CURSORDIR has 16-bit dimensions, and the height includes the mask
+            struct.pack_into("<HHHHIH", data, e, width or 256, (height or 256) * 2, 1, bits, size, id)
+
+open("resourceful32-cursor.exe", "wb").write(data)
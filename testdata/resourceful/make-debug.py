@@ -0,0 +1,14 @@
+#!/usr/bin/env python3
+# Generates resourceful32-debug.exe from resourceful32-mingw.exe, with
+# the VS_FF_DEBUG and VS_FF_PRERELEASE flags set in VS_FIXEDFILEINFO,
+# as resource compilers do for debug builds.
+import struct
+
+data = bytearray(open("resourceful32-mingw.exe", "rb").read())
+
+ffi = data.index(struct.pack("<I", 0xFEEF04BD))
+assert data.count(struct.pack("<I", 0xFEEF04BD)) == 1
+# dwFileFlagsMask, dwFileFlags
+struct.pack_into("<II", data, ffi + 24, 0x3F, 0x3)
+
+open("resourceful32-debug.exe", "wb").write(data)
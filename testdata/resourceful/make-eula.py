@@ -0,0 +1,44 @@
+#!/usr/bin/env python3
+# Generates resourceful32-eula.exe from resourceful32-mingw.exe, with
+# license agreements stashed where installers keep them:
+#
+#   EULA     RCDATA "END USER LICENSE AGREEMENT" (UTF-16, with a BOM)
+#   LICENSE  RTF    GPL-2.0 (custom resource type)
+#   7        HTML   MIT (only recognizable by its contents)
+#   CONFIG   RCDATA "config.json" (not a license)
+#
+# and an overlay with an Apache-2.0 notice between two binary blobs.
+import struct
+
+from rsrc import PE
+
+eula = "﻿END USER LICENSE AGREEMENT\r\n\r\nThis software is licensed, not sold.".encode("utf-16-le")
+gpl = (b"{\\rtf1\\ansi\\deff0 {\\fonttbl {\\f0 Arial;}}\\f0\\fs20 "
+       b"GNU GENERAL PUBLIC LICENSE\\par Version 2, June 1991\\par }")
+mit = (b"<html><body><p>Copyright (c) 2021 Pelican Games</p>"
+       b"<p>Permission is hereby granted, free of charge, to any person obtaining a copy "
+       b"of this software and associated documentation files.</p></body></html>")
+config = b'{"launch": "game.exe"}'
+
+apache = b"""
+                                 Apache License
+                           Version 2.0, January 2004
+                        http://www.apache.org/licenses/
+
+   TERMS AND CONDITIONS FOR USE, REPRODUCTION, AND DISTRIBUTION
+
+   1. Definitions.
+
+      "License" shall mean the terms and conditions for use, reproduction,
+      and distribution as defined by Sections 1 through 9 of this document.
+"""
+blob = bytes((i * 7 + 3) % 256 for i in range(4096))
+
+pe = PE("resourceful32-mingw.exe")
+tree = pe.read_tree()
+tree[10] = {"EULA": {1033: (eula, 0)}, "CONFIG": {0: (config, 0)}}
+tree["RTF"] = {"LICENSE": {1033: (gpl, 0)}}
+tree[23] = {7: {1033: (mit, 0)}}
+pe.write_tree(tree)
+pe.data += blob + apache + blob
+pe.save("resourceful32-eula.exe")
@@ -0,0 +1,49 @@
+#!/usr/bin/env python3
+# Generates resourceful32-libcef.dll from resourceful32-mingw.exe, with
+# the version info of libcef.dll (CEF 120, Chromium 120.0.6099.129)
+import struct
+
+from rsrc import PE, align, utf16z
+
+version = "120.1.10+g3ce3184+chromium-120.0.6099.129"
+strings = [
+    ("CompanyName", "The Chromium Embedded Framework Authors"),
+    ("FileDescription", "Chromium Embedded Framework (CEF) Dynamic Link Library"),
+    ("FileVersion", version),
+    ("InternalName", "libcef"),
+    ("LegalCopyright", "Copyright (C) 2023 The Chromium Embedded Framework Authors"),
+    ("OriginalFilename", "libcef.dll"),
+    ("ProductName", "CEF Dynamic Link Library"),
+    ("ProductVersion", version),
+]
+
+
+def block(key, value=b"", text=False, children=()):
+    out = bytearray(6) + utf16z(key)
+    out += bytes(align(len(out), 4) - len(out)) + value
+    for child in children:
+        out += bytes(align(len(out), 4) - len(out)) + child
+    value_length = len(value) // 2 if text else len(value)
+    struct.pack_into("<HHH", out, 0, len(out), value_length, 1 if text else 0)
+    return bytes(out)
+
+
+fixed = struct.pack("<13I", 0xfeef04bd, 0x10000, 120 << 16 | 1, 10 << 16, 120 << 16 | 1, 10 << 16,
+                    0x3f, 0, 0x40004, 2, 0, 0, 0)
+info = block("VS_VERSION_INFO", fixed, children=[
+    block("StringFileInfo", children=[
+        block("040904b0", children=[block(k, utf16z(v), text=True) for k, v in strings]),
+    ]),
+    block("VarFileInfo", children=[
+        block("Translation", struct.pack("<HH", 0x409, 1200)),
+    ]),
+])
+
+pe = PE("resourceful32-mingw.exe")
+tree = pe.read_tree()
+tree[16] = {1: {1033: (info, 0)}}
+pe_offset = struct.unpack_from("<I", pe.data, 0x3c)[0]
+characteristics = struct.unpack_from("<H", pe.data, pe_offset + 22)[0]
+struct.pack_into("<H", pe.data, pe_offset + 22, characteristics | 0x2000)
+pe.write_tree(tree)
+pe.save("resourceful32-libcef.dll")
@@ -0,0 +1,68 @@
+#!/usr/bin/env python3
+# Generates resourceful32-ja.exe and resourceful32-ru.exe from
+# resourceful32-mingw.exe, with version info that isn't in English:
+#
+#   ja: a Shift-JIS (932) string table, some of its values widened
+#       byte-by-byte to UTF-16 instead of being converted
+#   ru: a German table, then a Russian one that doesn't specify
+#       a code page, all widened from Windows-1251. The Translation
+#       lists the Russian one, with code page 1251.
+import struct
+
+from rsrc import PE, align, utf16z
+
+
+def block(key, value=b"", text=False, children=()):
+    out = bytearray(6) + utf16z(key)
+    out += bytes(align(len(out), 4) - len(out)) + value
+    for child in children:
+        out += bytes(align(len(out), 4) - len(out)) + child
+    value_length = len(value) // 2 if text else len(value)
+    struct.pack_into("<HHH", out, 0, len(out), value_length, 1 if text else 0)
+    return bytes(out)
+
+
+def widened(s, codec):
+    return "".join(chr(b) for b in s.encode(codec))
+
+
+def version_info(tables, translations):
+    fixed = struct.pack("<13I", 0xfeef04bd, 0x10000, 1 << 16, 0, 1 << 16, 0,
+                        0x3f, 0, 0x40004, 1, 0, 0, 0)
+    return block("VS_VERSION_INFO", fixed, children=[
+        block("StringFileInfo", children=[
+            block(key, children=[block(k, utf16z(v), text=True) for k, v in strings])
+            for key, strings in tables
+        ]),
+        block("VarFileInfo", children=[
+            block("Translation", b"".join(struct.pack("<HH", *t) for t in translations)),
+        ]),
+    ])
+
+
+ja = version_info([
+    ("041103a4", [
+        ("CompanyName", "Pelican Games"),
+        ("FileDescription", widened("ゲーム ランチャー", "shift_jis")),
+        ("ProductName", "ペリカン・アドベンチャー"),
+        ("ProductVersion", "1.0.0"),
+    ]),
+], [(0x411, 932)])
+
+ru = version_info([
+    ("040704e4", [
+        ("ProductName", widened("Pelikan-Abenteuer", "cp1252")),
+    ]),
+    ("04190000", [
+        ("CompanyName", widened("Пеликан Геймс", "cp1251")),
+        ("ProductName", widened("Приключения пеликана", "cp1251")),
+        ("ProductVersion", "1.0.0"),
+    ]),
+], [(0x419, 1251)])
+
+for name, info in [("ja", ja), ("ru", ru)]:
+    pe = PE("resourceful32-mingw.exe")
+    tree = pe.read_tree()
+    tree[16] = {1: {1033: (info, 0)}}
+    pe.write_tree(tree)
+    pe.save("resourceful32-%s.exe" % name)
@@ -0,0 +1,69 @@
+#!/usr/bin/env python3
+# Generates resourceful32-menus.exe from resourceful32-mingw.exe, with
+# a MENU, a MENUEX and an ACCELERATORS resource, as windres would compile:
+#
+#   1 MENU
+#   BEGIN
+#     POPUP "&File"
+#     BEGIN
+#       MENUITEM "&Open...\tCtrl+O", 100
+#       MENUITEM SEPARATOR
+#       MENUITEM "E&xit", 101
+#     END
+#     POPUP "&Help"
+#     BEGIN
+#       MENUITEM "&About", 200, GRAYED
+#     END
+#   END
+#
+#   2 MENUEX
+#   BEGIN
+#     POPUP "&Edit", 0, 0, 0, 42
+#     BEGIN
+#       MENUITEM "&Copy", 300, MFT_STRING, MFS_DEFAULT
+#     END
+#   END
+#
+#   1 ACCELERATORS
+#   BEGIN
+#     "O", 100, VIRTKEY, CONTROL
+#     VK_F1, 200, VIRTKEY
+#     "?", 200, ASCII, ALT
+#   END
+import struct
+
+from rsrc import PE, align, utf16z
+
+MF_GRAYED, MF_POPUP, MF_END, MF_SEPARATOR = 0x1, 0x10, 0x80, 0x800
+
+menu = struct.pack("<HH", 0, 0)
+menu += struct.pack("<H", MF_POPUP) + utf16z("&File")
+menu += struct.pack("<HH", 0, 100) + utf16z("&Open...\tCtrl+O")
+menu += struct.pack("<HH", MF_SEPARATOR, 0) + utf16z("")
+menu += struct.pack("<HH", MF_END, 101) + utf16z("E&xit")
+menu += struct.pack("<H", MF_POPUP | MF_END) + utf16z("&Help")
+menu += struct.pack("<HH", MF_END | MF_GRAYED, 200) + utf16z("&About")
+
+
+def menuex_item(typ, state, id, flags, text):
+    item = struct.pack("<IIIH", typ, state, id, flags) + utf16z(text)
+    return item + bytes(align(len(item), 4) - len(item))
+
+
+MFS_DEFAULT = 0x1000
+menuex = struct.pack("<HHI", 1, 4, 0)
+menuex += menuex_item(0, 0, 0, 0x01 | 0x80, "&Edit") + struct.pack("<I", 42)
+menuex += menuex_item(0, MFS_DEFAULT, 300, 0x80, "&Copy")
+
+FVIRTKEY, FCONTROL, FALT = 0x01, 0x08, 0x10
+VK_F1 = 0x70
+accel = struct.pack("<HHHH", FVIRTKEY | FCONTROL, ord("O"), 100, 0)
+accel += struct.pack("<HHHH", FVIRTKEY, VK_F1, 200, 0)
+accel += struct.pack("<HHHH", FALT | 0x80, ord("?"), 200, 0)
+
+pe = PE("resourceful32-mingw.exe")
+tree = pe.read_tree()
+tree[4] = {1: {1033: (menu, 0)}, 2: {1033: (menuex, 0)}}
+tree[9] = {1: {1033: (accel, 0)}}
+pe.write_tree(tree)
+pe.save("resourceful32-menus.exe")
@@ -0,0 +1,56 @@
+#!/usr/bin/env python3
+# Generates resourceful32-packed.exe from resourceful32-mingw.exe, mimicking
+# what some packers do to resources:
+#   - the version info is moved (uncompressed) out of .rsrc, over the code in .text
+#   - the icon subtree points past the end of the resource section
+import struct
+
+src = open("resourceful32-mingw.exe", "rb").read()
+data = bytearray(src)
+
+pe = struct.unpack_from("<I", data, 0x3c)[0]
+nsections = struct.unpack_from("<H", data, pe + 6)[0]
+opt_size = struct.unpack_from("<H", data, pe + 20)[0]
+opt = pe + 24
+rsrc_rva = struct.unpack_from("<I", data, opt + 96 + 2 * 8)[0]
+
+sections = {}
+for i in range(nsections):
+    sh = opt + opt_size + i * 40
+    name = data[sh:sh + 8].rstrip(b"\0").decode()
+    vsize, rva, raw_size, raw_off = struct.unpack_from("<IIII", data, sh + 8)
+    sections[name] = (rva, raw_size, raw_off)
+
+rsrc = sections[".rsrc"]
+assert rsrc[0] == rsrc_rva
+base = rsrc[2]
+
+
+def entries(off):
+    named, ids = struct.unpack_from("<HH", data, base + off + 12)
+    for i in range(named + ids):
+        yield base + off + 16 + i * 8
+
+
+def leaf(off):
+    while True:
+        name_id, child = struct.unpack_from("<II", data, next(entries(off)))
+        if not child & 0x80000000:
+            return child
+        off = child & 0x7fffffff
+
+
+for entry in entries(0):
+    type_id, child = struct.unpack_from("<II", data, entry)
+    if type_id == 16:  # RT_VERSION
+        data_entry = base + leaf(child & 0x7fffffff)
+        rva, size = struct.unpack_from("<II", data, data_entry)
+        blob = src[base + rva - rsrc_rva:base + rva - rsrc_rva + size]
+        text_rva, text_raw_size, text_raw_off = sections[".text"]
+        assert size <= text_raw_size
+        data[text_raw_off:text_raw_off + size] = blob
+        struct.pack_into("<I", data, data_entry, text_rva)
+    elif type_id == 3:  # RT_ICON
+        struct.pack_into("<I", data, entry + 4, 0x80000000 | 0x7ffff0)
+
+open("resourceful32-packed.exe", "wb").write(data)
@@ -0,0 +1,130 @@
+# Helpers to add resources to a PE file whose .rsrc section comes last,
+# by re-laying out the whole resource section. Used by the make-*.py scripts.
+import struct
+
+
+def align(n, a):
+    return (n + a - 1) // a * a
+
+
+class PE:
+    def __init__(self, path):
+        self.data = bytearray(open(path, "rb").read())
+        d = self.data
+        pe = struct.unpack_from("<I", d, 0x3c)[0]
+        self.nsections = struct.unpack_from("<H", d, pe + 6)[0]
+        opt_size = struct.unpack_from("<H", d, pe + 20)[0]
+        self.opt = pe + 24
+        magic = struct.unpack_from("<H", d, self.opt)[0]
+        self.dirs = self.opt + (96 if magic == 0x10b else 112)
+        self.section_alignment, self.file_alignment = struct.unpack_from("<II", d, self.opt + 32)
+        self.sections = []
+        for i in range(self.nsections):
+            sh = self.opt + opt_size + i * 40
+            name = d[sh:sh + 8].rstrip(b"\0").decode()
+            vsize, rva, raw_size, raw_off = struct.unpack_from("<IIII", d, sh + 8)
+            self.sections.append((name, sh, vsize, rva, raw_size, raw_off))
+        self.rsrc = self.sections[-1]
+        assert self.rsrc[0] == ".rsrc"
+
+    def read_tree(self):
+        """returns {type: {name: {language: (data, codepage)}}}, with
+        integer IDs or string names as keys"""
+        d = self.data
+        _, _, _, rva, _, base = self.rsrc
+
+        def name_or_id(v):
+            if v & 0x80000000:
+                off = base + (v & 0x7fffffff)
+                n = struct.unpack_from("<H", d, off)[0]
+                return d[off + 2:off + 2 + n * 2].decode("utf-16-le")
+            return v
+
+        def directory(off, level):
+            named, ids = struct.unpack_from("<HH", d, base + off + 12)
+            res = {}
+            for i in range(named + ids):
+                key, child = struct.unpack_from("<II", d, base + off + 16 + i * 8)
+                if level < 2:
+                    res[name_or_id(key)] = directory(child & 0x7fffffff, level + 1)
+                else:
+                    data_rva, size, codepage, _ = struct.unpack_from("<IIII", d, base + child)
+                    blob = bytes(d[base + data_rva - rva:base + data_rva - rva + size])
+                    res[key] = (blob, codepage)
+            return res
+
+        return directory(0, 0)
+
+    def write_tree(self, tree):
+        _, sh, _, rva, _, base = self.rsrc
+
+        def keyorder(k):
+            # named entries first, sorted by name, then IDs in ascending order
+            return (0, k.upper(), 0) if isinstance(k, str) else (1, "", k)
+
+        # directories first, then data entries, names and data
+        dirs = []
+        queue = [(tree, 0)]
+        while queue:
+            node, level = queue.pop(0)
+            dirs.append((node, level))
+            if level < 2:
+                for k in sorted(node, key=keyorder):
+                    queue.append((node[k], level + 1))
+
+        dir_offsets = []
+        off = 0
+        for node, _ in dirs:
+            dir_offsets.append(off)
+            off += 16 + 8 * len(node)
+        leaves = [leaf for node, level in dirs if level == 2 for _, leaf in sorted(node.items(), key=lambda kv: keyorder(kv[0]))]
+        data_entries_off = off
+        off += 16 * len(leaves)
+        names = sorted({k for node, _ in dirs for k in node if isinstance(k, str)})
+        name_offsets = {}
+        for n in names:
+            name_offsets[n] = off
+            off += 2 + 2 * len(n)
+        blob_offsets = []
+        for blob, _ in leaves:
+            off = align(off, 8)
+            blob_offsets.append(off)
+            off += len(blob)
+
+        out = bytearray(off)
+        next_dir = 1
+        leaf_index = 0
+        for (node, level), doff in zip(dirs, dir_offsets):
+            keys = sorted(node, key=keyorder)
+            named = sum(1 for k in keys if isinstance(k, str))
+            struct.pack_into("<IIHHHH", out, doff, 0, 0, 0, 0, named, len(keys) - named)
+            for i, k in enumerate(keys):
+                key = (0x80000000 | name_offsets[k]) if isinstance(k, str) else k
+                if level < 2:
+                    child = 0x80000000 | dir_offsets[next_dir]
+                    next_dir += 1
+                else:
+                    child = data_entries_off + 16 * leaf_index
+                    leaf_index += 1
+                struct.pack_into("<II", out, doff + 16 + 8 * i, key, child)
+        for i, (blob, codepage) in enumerate(leaves):
+            struct.pack_into("<IIII", out, data_entries_off + 16 * i, rva + blob_offsets[i], len(blob), codepage, 0)
+            out[blob_offsets[i]:blob_offsets[i] + len(blob)] = blob
+        for n, noff in name_offsets.items():
+            struct.pack_into("<H", out, noff, len(n))
+            out[noff + 2:noff + 2 + 2 * len(n)] = n.encode("utf-16-le")
+
+        raw_size = align(len(out), self.file_alignment)
+        out += bytes(raw_size - len(out))
+        self.data[base:] = out
+        struct.pack_into("<I", self.data, sh + 8, len(out))
+        struct.pack_into("<I", self.data, sh + 16, raw_size)
+        struct.pack_into("<I", self.data, self.opt + 56, align(rva + raw_size, self.section_alignment))
+        struct.pack_into("<II", self.data, self.dirs + 2 * 8, rva, len(out))
+
+    def save(self, path):
+        open(path, "wb").write(self.data)
+
+
+def utf16z(s):
+    return (s + "\0").encode("utf-16-le")
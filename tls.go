@@ -0,0 +1,22 @@
+package pelican
+
+import (
+	"github.com/itchio/pelican/pe"
+	"github.com/pkg/errors"
+)
+
+// parseTLS fills info.TLSCallbacks
+func (params *ProbeParams) parseTLS(info *PeInfo, pf *pe.File) error {
+	td, err := pf.TLSDirectory()
+	if err != nil {
+		if params.Strict {
+			return errors.WithMessage(err, "while parsing TLS directory")
+		}
+		params.warn(info, WarningDataDirectoryInvalid, err, "Could not parse TLS directory")
+		return nil
+	}
+	if td != nil {
+		info.TLSCallbacks = td.Callbacks
+	}
+	return nil
+}
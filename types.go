@@ -1,21 +1,169 @@
 package pelican
 
+import (
+	"encoding/json"
+
+	"github.com/itchio/pelican/resources"
+)
+
+// Arch is the architecture a binary was built for, named like GOARCH
 type Arch string
 
 const (
-	Arch386   = "386"
-	ArchAmd64 = "amd64"
+	// ArchUnknown is used for architectures pelican doesn't recognize
+	ArchUnknown Arch = ""
+	Arch386     Arch = "386"
+	ArchAmd64   Arch = "amd64"
+	ArchArm64   Arch = "arm64"
+	ArchArm     Arch = "arm"
+)
+
+// Is64 returns true for 64-bit architectures
+func (a Arch) Is64() bool {
+	return a.Bits() == 64
+}
+
+// Bits returns the pointer size of the architecture, or 0 if unknown
+func (a Arch) Bits() int {
+	switch a {
+	case Arch386, ArchArm:
+		return 32
+	case ArchAmd64, ArchArm64:
+		return 64
+	default:
+		return 0
+	}
+}
+
+func (a Arch) String() string {
+	if a == ArchUnknown {
+		return "unknown"
+	}
+	return string(a)
+}
+
+type Subsystem string
+
+const (
+	SubsystemUnknown Subsystem = ""
+	SubsystemGUI     Subsystem = "gui"
+	SubsystemConsole Subsystem = "console"
+	SubsystemNative  Subsystem = "native"
+	SubsystemEFI     Subsystem = "efi"
+	SubsystemOther   Subsystem = "other"
 )
 
 // PeInfo contains the architecture of a binary file
 //
 // For command `PeInfo`
+//
+// PeInfo and the types it contains only use exported fields and
+// concrete types (no interface{}, no 8/16-bit integers), so that
+// they serialize cleanly with encoding/gob or map 1:1 to protobuf,
+// see proto/pelican.proto, which must be kept in sync.
 type PeInfo struct {
 	Arch                Arch                `json:"arch"`
+	Subsystem           Subsystem           `json:"subsystem,omitempty"`
+	Kind                ImageKind           `json:"kind,omitempty"`
 	VersionProperties   map[string]string   `json:"versionProperties"`
 	AssemblyInfo        *AssemblyInfo       `json:"assemblyInfo"`
 	DependentAssemblies []*AssemblyIdentity `json:"dependentAssemblies"`
 	Imports             []string            `json:"imports"`
+	// DLLs only loaded when one of their functions is first called
+	// (delay-load import directory), see pe.File.DelayImportedLibraries
+	DelayImports  []string            `json:"delayImports,omitempty"`
+	ImportMetrics *ImportMetrics      `json:"importMetrics,omitempty"`
+	Dialogs       []*DialogTemplate   `json:"dialogs,omitempty"`
+	Menus         []*MenuTemplate     `json:"menus,omitempty"`
+	Accelerators  []*AcceleratorTable `json:"accelerators,omitempty"`
+	Compatibility *Compatibility      `json:"compatibility,omitempty"`
+
+	// Size of the file, in bytes
+	Size int64 `json:"size"`
+	// Set if the binary has an icon (RT_GROUP_ICON resource)
+	HasIcon bool `json:"hasIcon,omitempty"`
+
+	// Toolchain or packer that generated the code at the entry point,
+	// guessed from its first bytes
+	EntryPointStub EntryPointStub `json:"entryPointStub,omitempty"`
+	// Set if the binary looks like it was built with Delphi or C++ Builder
+	Delphi *DelphiInfo `json:"delphi,omitempty"`
+	// Set if the binary opens, attaches to or hides a console
+	// regardless of its subsystem
+	Console *ConsoleBehavior `json:"console,omitempty"`
+	// Set if the binary is, or imports, the Chromium Embedded Framework
+	CEF *CEFInfo `json:"cef,omitempty"`
+	// Set if the binary looks like an installer built with a well-known tool
+	Installer *InstallerInfo `json:"installer,omitempty"`
+	// Set for small executables that seem to start another one
+	Launcher *LauncherInfo `json:"launcher,omitempty"`
+
+	// Set if the version info says so, or if the binary imports a debug
+	// build of the Visual C++ runtime (msvcrtd.dll, ucrtbased.dll, etc.)
+	IsDebugBuild bool `json:"isDebugBuild,omitempty"`
+	// Set if the version info says so
+	IsPrerelease bool `json:"isPrerelease,omitempty"`
+	// Set if the binary has a CodeView record, which
+	// tells which PDB file holds its debug information
+	PDB *PDBInfo `json:"pdb,omitempty"`
+
+	// Lower-cased ProductName (or FileDescription) without versions,
+	// architectures or trademark symbols, to group the builds of
+	// a product, see NormalizeName
+	CanonicalProductName string `json:"canonicalProductName,omitempty"`
+
+	// How the C runtime is linked, nil if unknown
+	CRT *CRTInfo `json:"crt,omitempty"`
+
+	// Well-known third-party libraries this binary is, or embeds
+	BundledLibraries []*BundledLibrary `json:"bundledLibraries,omitempty"`
+
+	// DRM and anti-cheat components, which usually require extra services
+	Protections []*Protection `json:"protections,omitempty"`
+
+	// Known problems when running under Wine or Proton
+	WineNotes []*WineNote `json:"wineNotes,omitempty"`
+	// Whether it runs on Windows on ARM, nil if the architecture is unknown
+	ArmEmulation *ArmEmulation `json:"armEmulation,omitempty"`
+
+	// URLs, registry keys, etc. embedded in the binary, nil if none
+	Indicators *Indicators `json:"indicators,omitempty"`
+	// What build paths reveal about where the binary was built
+	Provenance []*ProvenanceFinding `json:"provenance,omitempty"`
+
+	// Only set when ProbeParams.Entropy is enabled
+	Entropy *EntropyInfo `json:"entropy,omitempty"`
+
+	// Set if the binary has a certificate table (Authenticode signature)
+	Signature *SignatureInfo `json:"signature,omitempty"`
+
+	// RVAs of the TLS callbacks, which run before the entry point. The
+	// MinGW runtime has two, otherwise they're usually used by packers
+	// or anti-debugging code. See pe.File.TLSDirectory.
+	TLSCallbacks []uint32 `json:"tlsCallbacks,omitempty"`
+	// Exploit mitigations (/GS, SafeSEH, Control Flow Guard), set
+	// if the binary has a load configuration directory
+	LoadConfig *LoadConfig `json:"loadConfig,omitempty"`
+	// Tools that built the binary, if it was linked by Microsoft's linker
+	RichHeader *RichHeader `json:"richHeader,omitempty"`
+
+	// SHA-256 of the DOS header, PE headers and section table,
+	// see Reprobe
+	HeadersSHA256 string `json:"headersSha256,omitempty"`
+
+	// Only set when ProbeParams.ElevationHeuristics is enabled,
+	// see RequiresElevationHeuristic
+	ElevationReasons []string `json:"elevationReasons,omitempty"`
+
+	// Only set when ProbeParams.AssetDir is set
+	Assets []*Asset `json:"assets,omitempty"`
+
+	// Results of ProbeParams.Extensions, keyed by name, see Extension
+	Extensions map[string]json.RawMessage `json:"extensions,omitempty"`
+
+	// Non-fatal problems found while probing (only in non-strict mode
+	// for the more severe ones), see WarningsAtLeast
+	Warnings []*ProbeWarning `json:"warnings,omitempty"`
 }
 
 func (pi *PeInfo) RequiresElevation() bool {
@@ -31,19 +179,29 @@ func (pi *PeInfo) RequiresElevation() bool {
 	}
 }
 
-type AssemblyInfo struct {
-	Identity    *AssemblyIdentity `json:"identity"`
-	Description string            `json:"description"`
+// SignatureInfo locates the certificate table of a binary,
+// which holds its Authenticode signature
+type SignatureInfo struct {
+	// File offset and size of the certificate table
+	Offset int64 `json:"offset"`
+	Size   int64 `json:"size"`
 
-	RequestedExecutionLevel string `json:"requestedExecutionLevel,omitempty"`
+	// SHA-256 of the certificate the binary is signed with (not those of
+	// its chain or of the timestamp), in hex. It changes when the publisher
+	// renews their certificate, or switches to another one.
+	// Empty if the signature could not be parsed.
+	CertificateSHA256 string `json:"certificateSha256,omitempty"`
+	// Subject of that certificate, for example "CN=Sysprogs OU,O=Sysprogs OU,L=Maardu,C=EE"
+	CertificateSubject string `json:"certificateSubject,omitempty"`
 }
 
-type AssemblyIdentity struct {
-	Name    string `json:"name"`
-	Version string `json:"version"`
-	Type    string `json:"type"`
-
-	ProcessorArchitecture string `json:"processorArchitecture,omitempty"`
-	Language              string `json:"language,omitempty"`
-	PublicKeyToken        string `json:"publicKeyToken,omitempty"`
-}
+// Manifests are parsed by the resources package,
+// these are kept for compatibility.
+type (
+	AssemblyInfo     = resources.AssemblyInfo
+	AssemblyIdentity = resources.AssemblyIdentity
+	WindowsSettings  = resources.WindowsSettings
+	ComServer        = resources.ComServer
+	ComClass         = resources.ComClass
+	TypeLib          = resources.TypeLib
+)
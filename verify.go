@@ -0,0 +1,169 @@
+package pelican
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+	"gopkg.in/yaml.v2"
+)
+
+// Check identifies one of the checks of Verify
+type Check string
+
+const (
+	// The folder has executables for several architectures
+	CheckArchMix Check = "arch-mix"
+	// An executable loads an app-local DLL of another architecture
+	CheckBitnessMismatch Check = "bitness-mismatch"
+	// An executable imports a DLL that can't be found
+	CheckMissingDLL Check = "missing-dll"
+	// An executable asks for (or probably needs) administrator rights
+	CheckElevation Check = "elevation"
+	// A binary has no Authenticode signature
+	CheckUnsigned Check = "unsigned"
+	// A binary is a debug build, it usually depends on
+	// debug runtimes that can't be redistributed
+	CheckDebugBuild Check = "debug-build"
+)
+
+// CheckSeverities are the default severities of Verify findings
+var CheckSeverities = map[Check]Severity{
+	CheckArchMix:         SeverityInfo,
+	CheckBitnessMismatch: SeverityError,
+	CheckMissingDLL:      SeverityError,
+	CheckElevation:       SeverityWarn,
+	CheckUnsigned:        SeverityInfo,
+	CheckDebugBuild:      SeverityWarn,
+}
+
+// SeverityOff disables a check, see Rules
+const SeverityOff Severity = "off"
+
+// Rules change the severity of the checks of Verify, or disable them
+// with SeverityOff. Checks that aren't listed use CheckSeverities.
+type Rules map[Check]Severity
+
+// ParseRules parses rules written as YAML or JSON, for example:
+//
+//	unsigned: warn
+//	missing-dll: error
+//	arch-mix: off
+func ParseRules(data []byte) (Rules, error) {
+	var raw map[string]string
+	err := yaml.Unmarshal(data, &raw)
+	if err != nil {
+		return nil, errors.WithStack(err)
+	}
+
+	rules := make(Rules)
+	for check, severity := range raw {
+		err = rules.Set(Check(check), Severity(severity))
+		if err != nil {
+			return nil, err
+		}
+	}
+	return rules, nil
+}
+
+// Set sets the severity of check, or returns an error
+// if either of them is unknown
+func (r Rules) Set(check Check, severity Severity) error {
+	if _, ok := CheckSeverities[check]; !ok {
+		return errors.Errorf("unknown check %q", check)
+	}
+	switch severity {
+	case SeverityInfo, SeverityWarn, SeverityError, SeverityOff:
+	default:
+		return errors.Errorf("unknown severity %q for check %s (expected info, warn, error or off)", severity, check)
+	}
+	r[check] = severity
+	return nil
+}
+
+// Finding is a problem found by Verify
+type Finding struct {
+	Check    Check    `json:"check"`
+	Severity Severity `json:"severity"`
+	// Slash-separated path of the file, empty for the whole folder
+	Path    string `json:"path,omitempty"`
+	Message string `json:"message"`
+}
+
+// Verify looks for problems players could run into if the folder was
+// uploaded as-is: missing DLLs, builds that ask for administrator rights,
+// etc. rules can be nil, to use CheckSeverities.
+// Findings are sorted by path, then by check.
+func (di *DirInfo) Verify(rules Rules) []*Finding {
+	var res []*Finding
+	add := func(check Check, p string, format string, args ...interface{}) {
+		severity, ok := rules[check]
+		if !ok {
+			severity = CheckSeverities[check]
+		}
+		if severity == SeverityOff {
+			return
+		}
+		res = append(res, &Finding{
+			Check:    check,
+			Severity: severity,
+			Path:     p,
+			Message:  fmt.Sprintf(format, args...),
+		})
+	}
+
+	var paths []string
+	for p := range di.Files {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	archs := make(map[Arch]bool)
+	for _, p := range paths {
+		info := di.Files[p]
+		isExe := strings.ToLower(path.Ext(p)) == ".exe"
+
+		if isExe {
+			archs[info.Arch] = true
+			if elevate, reasons := info.RequiresElevationHeuristic(); elevate {
+				add(CheckElevation, p, "requires administrator rights: %s", strings.Join(reasons, ", "))
+			}
+		}
+		if info.Signature == nil {
+			add(CheckUnsigned, p, "isn't signed")
+		}
+		if info.IsDebugBuild {
+			add(CheckDebugBuild, p, "is a debug build")
+		}
+	}
+
+	if len(archs) > 1 {
+		var names []string
+		for a := range archs {
+			names = append(names, a.String())
+		}
+		sort.Strings(names)
+		add(CheckArchMix, "", "executables are built for several architectures: %s", strings.Join(names, ", "))
+	}
+
+	for _, bm := range di.BitnessMismatches {
+		add(CheckBitnessMismatch, bm.Path, "%s", bm.Message)
+	}
+	for _, r := range di.LoadOrder {
+		// the executable starts without delay-loaded DLLs, they're
+		// usually optional features
+		for _, msg := range r.missingDLLs(false) {
+			add(CheckMissingDLL, r.Path, "%s", msg)
+		}
+	}
+
+	sort.SliceStable(res, func(i, j int) bool {
+		if res[i].Path != res[j].Path {
+			return res[i].Path < res[j].Path
+		}
+		return res[i].Check < res[j].Check
+	})
+	return res
+}
@@ -0,0 +1,69 @@
+package pelican_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/itchio/pelican"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Verify(t *testing.T) {
+	fsys := fstest.MapFS{
+		"setup.exe":  fixtureFile(t, "./testdata/wincdemu/WinCDEmu-4.1.exe"),
+		"game.exe":   importing(t, "./testdata/hello/hello32-mingw.exe", "fmodex.dll"),
+		"debug.exe":  fixtureFile(t, "./testdata/resourceful/resourceful32-debug.exe"),
+		"game64.exe": fixtureFile(t, "./testdata/hello/hello64-mingw.exe"),
+	}
+	di, err := pelican.ProbeDir(fsys, testProbeParams(t))
+	assert.NoError(t, err)
+
+	type finding struct {
+		check    pelican.Check
+		severity pelican.Severity
+		path     string
+	}
+	summarize := func(findings []*pelican.Finding) []finding {
+		var res []finding
+		for _, f := range findings {
+			assert.NotEmpty(t, f.Message)
+			res = append(res, finding{f.Check, f.Severity, f.Path})
+		}
+		return res
+	}
+
+	assert.EqualValues(t, []finding{
+		{pelican.CheckArchMix, pelican.SeverityInfo, ""},
+		{pelican.CheckDebugBuild, pelican.SeverityWarn, "debug.exe"},
+		{pelican.CheckUnsigned, pelican.SeverityInfo, "debug.exe"},
+		{pelican.CheckMissingDLL, pelican.SeverityError, "game.exe"},
+		{pelican.CheckUnsigned, pelican.SeverityInfo, "game.exe"},
+		{pelican.CheckUnsigned, pelican.SeverityInfo, "game64.exe"},
+		{pelican.CheckElevation, pelican.SeverityWarn, "setup.exe"},
+	}, summarize(di.Verify(nil)))
+
+	rules, err := pelican.ParseRules([]byte("unsigned: error\narch-mix: off\n"))
+	assert.NoError(t, err)
+	findings := di.Verify(rules)
+	assert.EqualValues(t, 6, len(findings))
+	assert.EqualValues(t, pelican.CheckDebugBuild, findings[0].Check)
+	assert.EqualValues(t, pelican.SeverityWarn, findings[0].Severity)
+	assert.EqualValues(t, pelican.SeverityError, findings[1].Severity)
+}
+
+func Test_ParseRules(t *testing.T) {
+	rules, err := pelican.ParseRules([]byte(`{"unsigned": "warn", "missing-dll": "error", "debug-build": "off"}`))
+	assert.NoError(t, err)
+	assert.EqualValues(t, pelican.Rules{
+		pelican.CheckUnsigned:   pelican.SeverityWarn,
+		pelican.CheckMissingDLL: pelican.SeverityError,
+		pelican.CheckDebugBuild: pelican.SeverityOff,
+	}, rules)
+
+	_, err = pelican.ParseRules([]byte("unsigned: fatal\n"))
+	assert.Error(t, err)
+	_, err = pelican.ParseRules([]byte("antivirus: error\n"))
+	assert.Error(t, err)
+	_, err = pelican.ParseRules([]byte("- unsigned\n"))
+	assert.Error(t, err)
+}
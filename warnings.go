@@ -0,0 +1,180 @@
+package pelican
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Severity indicates how much a ProbeWarning should be worried about
+type Severity string
+
+const (
+	// SeverityInfo is for things that are unusual, but harmless
+	SeverityInfo Severity = "info"
+	// SeverityWarn is for things that may cause the results to be incomplete
+	SeverityWarn Severity = "warn"
+	// SeverityError is for parts of the file that could not be parsed at all
+	SeverityError Severity = "error"
+)
+
+var severityRanks = map[Severity]int{
+	SeverityInfo:  1,
+	SeverityWarn:  2,
+	SeverityError: 3,
+}
+
+// AtLeast returns true if s is as severe as other, or more
+func (s Severity) AtLeast(other Severity) bool {
+	return severityRanks[s] >= severityRanks[other]
+}
+
+// WarningCode identifies a class of problems found while probing,
+// so that pipelines can gate on them without parsing messages.
+type WarningCode string
+
+const (
+	// The import table could not be parsed
+	WarningImportsInvalid WarningCode = "W_IMPORTS_INVALID"
+	// A library is imported only by ordinal, and those aren't in
+	// OrdinalNames, so we don't know which symbols are used
+	WarningImportOrdinalOnly WarningCode = "W_IMPORT_ORDINAL_ONLY"
+
+	// A data directory points outside of the file or of its sections
+	WarningDataDirectoryInvalid WarningCode = "W_DATA_DIRECTORY_INVALID"
+	// The export table could not be parsed
+	WarningExportsInvalid WarningCode = "W_EXPORTS_INVALID"
+	// The contents of a section could not be read
+	WarningSectionUnreadable WarningCode = "W_SECTION_UNREADABLE"
+	// The raw data of a section extends past the end of the file
+	// (interrupted download, sparse upload), only part of it was read
+	WarningSectionTruncated WarningCode = "W_SECTION_TRUNCATED"
+	// The alignment values of the image violate the spec, sections were
+	// located the way the loader does (packed executable)
+	WarningAlignmentNonstandard WarningCode = "W_ALIGNMENT_NONSTANDARD"
+	// The Rich header has an end marker, but no valid start
+	WarningRichHeaderInvalid WarningCode = "W_RICH_HEADER_INVALID"
+	// The certificate table is not in the file, the headers were probably
+	// copied from another (signed) binary
+	WarningSignatureOutsideFile WarningCode = "W_SIGNATURE_OUTSIDE_FILE"
+	// The certificate table doesn't hold a valid Authenticode signature
+	WarningSignatureInvalid WarningCode = "W_SIGNATURE_INVALID"
+	// An optional analysis was skipped because of ProbeParams.MaxMemory
+	WarningMemoryBudgetExceeded WarningCode = "W_MEMORY_BUDGET_EXCEEDED"
+	// One of ProbeParams.Extensions returned an error
+	WarningExtensionFailed WarningCode = "W_EXTENSION_FAILED"
+
+	// The resource directory could not be parsed
+	WarningResourceDirectoryInvalid WarningCode = "W_RESOURCE_DIRECTORY_INVALID"
+	// Part of the resource directory could not be read, and was skipped
+	WarningResourceSubtreeUnreadable WarningCode = "W_RESOURCE_SUBTREE_UNREADABLE"
+	// A resource lies outside of the resource section (packed executable)
+	WarningResourcePacked WarningCode = "W_RESOURCE_PACKED"
+	// A resource extends past the end of the resource section
+	WarningResourceTruncated WarningCode = "W_RESOURCE_TRUNCATED"
+	// The manifest is not valid XML, or has an unexpected structure
+	WarningResourceManifestInvalid WarningCode = "W_RESOURCE_MANIFEST_INVALID"
+	// The version info block could not be parsed
+	WarningResourceVersionInvalid WarningCode = "W_RESOURCE_VERSION_INVALID"
+	// A dialog template could not be parsed
+	WarningResourceDialogInvalid WarningCode = "W_RESOURCE_DIALOG_INVALID"
+	// A menu template could not be parsed
+	WarningResourceMenuInvalid WarningCode = "W_RESOURCE_MENU_INVALID"
+	// An accelerator table could not be parsed
+	WarningResourceAcceleratorInvalid WarningCode = "W_RESOURCE_ACCELERATOR_INVALID"
+)
+
+var warningSeverities = map[WarningCode]Severity{
+	WarningImportsInvalid:    SeverityError,
+	WarningImportOrdinalOnly: SeverityInfo,
+
+	WarningDataDirectoryInvalid: SeverityWarn,
+	WarningExportsInvalid:       SeverityWarn,
+	WarningSectionUnreadable:    SeverityWarn,
+	WarningSectionTruncated:     SeverityWarn,
+	WarningAlignmentNonstandard: SeverityInfo,
+	WarningRichHeaderInvalid:    SeverityInfo,
+	WarningSignatureOutsideFile: SeverityInfo,
+	WarningSignatureInvalid:     SeverityWarn,
+	WarningMemoryBudgetExceeded: SeverityWarn,
+	WarningExtensionFailed:      SeverityWarn,
+
+	WarningResourceDirectoryInvalid:   SeverityError,
+	WarningResourceSubtreeUnreadable:  SeverityWarn,
+	WarningResourcePacked:             SeverityInfo,
+	WarningResourceTruncated:          SeverityWarn,
+	WarningResourceManifestInvalid:    SeverityError,
+	WarningResourceVersionInvalid:     SeverityError,
+	WarningResourceDialogInvalid:      SeverityWarn,
+	WarningResourceMenuInvalid:        SeverityWarn,
+	WarningResourceAcceleratorInvalid: SeverityWarn,
+}
+
+// Severity returns the severity of all warnings with this code
+func (wc WarningCode) Severity() Severity {
+	if s, ok := warningSeverities[wc]; ok {
+		return s
+	}
+	return SeverityWarn
+}
+
+// all resource-related codes share this prefix, see Reprobe
+const resourceWarningPrefix = "W_RESOURCE_"
+
+func (wc WarningCode) isResource() bool {
+	return strings.HasPrefix(string(wc), resourceWarningPrefix)
+}
+
+func (wc WarningCode) isSignature() bool {
+	return wc == WarningSignatureOutsideFile || wc == WarningSignatureInvalid
+}
+
+// ProbeWarning is a non-fatal problem found while probing
+type ProbeWarning struct {
+	Code     WarningCode `json:"code"`
+	Severity Severity    `json:"severity"`
+	Message  string      `json:"message"`
+}
+
+// WarningsAtLeast returns all warnings that are at least as severe as sev
+func (pi *PeInfo) WarningsAtLeast(sev Severity) []*ProbeWarning {
+	var res []*ProbeWarning
+	for _, w := range pi.Warnings {
+		if w.Severity.AtLeast(sev) {
+			res = append(res, w)
+		}
+	}
+	return res
+}
+
+// hasWarning returns true if pi has a warning with the given code
+func (pi *PeInfo) hasWarning(code WarningCode) bool {
+	for _, w := range pi.Warnings {
+		if w.Code == code {
+			return true
+		}
+	}
+	return false
+}
+
+// warn logs a warning and records it in info, if non-nil. If err is non-nil,
+// it's appended to msg (the stack trace is only logged, not recorded).
+func (params *ProbeParams) warn(info *PeInfo, code WarningCode, err error, msg string, args ...interface{}) {
+	params.noteIOError(err)
+
+	msg = fmt.Sprintf(msg, args...)
+	if err != nil {
+		params.Consumer.Warnf("%s: %+v", msg, err)
+		msg = fmt.Sprintf("%s: %v", msg, err)
+	} else {
+		params.Consumer.Warnf("%s", msg)
+	}
+
+	if info == nil {
+		return
+	}
+	info.Warnings = append(info.Warnings, &ProbeWarning{
+		Code:     code,
+		Severity: code.Severity(),
+		Message:  msg,
+	})
+}
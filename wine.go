@@ -0,0 +1,115 @@
+package pelican
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/itchio/pelican/pe"
+)
+
+// WineNote is something about a binary that's known to cause problems
+// under Wine or Proton. Its severity tells how bad it usually is:
+// SeverityError if it's unlikely to run at all, SeverityWarn if some
+// features are likely broken, SeverityInfo if it needs extra components
+// that aren't installed by default.
+type WineNote struct {
+	// For example "kernel-driver", "media-foundation"
+	Topic    string   `json:"topic"`
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+	// What gave it away, for example "imports mfplat.dll"
+	Evidence string `json:"evidence"`
+}
+
+type wineSignature struct {
+	topic    string
+	severity Severity
+	message  string
+}
+
+var (
+	wineKernelDriver    = wineSignature{"kernel-driver", SeverityError, "Loads a kernel driver, which Wine can't run"}
+	wineMediaFoundation = wineSignature{"media-foundation", SeverityWarn, "Uses Media Foundation, video playback often fails without extra codecs"}
+	wineWindowsMedia    = wineSignature{"windows-media", SeverityWarn, "Uses the Windows Media Format SDK, WMV playback often fails"}
+	wineDirect3D12      = wineSignature{"direct3d12", SeverityInfo, "Uses Direct3D 12, which requires vkd3d-proton (included in Proton)"}
+	wineDotNet          = wineSignature{"dotnet", SeverityInfo, "Is a .NET assembly, which requires wine-mono or the .NET runtime"}
+	wineGFWL            = wineSignature{"gfwl", SeverityError, "Uses Games for Windows Live, which doesn't work without a replacement xlive.dll"}
+)
+
+// keyed by lower-case library name
+var wineImports = map[string]wineSignature{
+	"mfplat.dll":      wineMediaFoundation,
+	"mfreadwrite.dll": wineMediaFoundation,
+	"mf.dll":          wineMediaFoundation,
+	"mfplay.dll":      wineMediaFoundation,
+	"wmvcore.dll":     wineWindowsMedia,
+	"d3d12.dll":       wineDirect3D12,
+	"mscoree.dll":     wineDotNet,
+	"xlive.dll":       wineGFWL,
+	// minifilter drivers are managed through this
+	"fltlib.dll": wineKernelDriver,
+	// only drivers import this
+	"ntoskrnl.exe": wineKernelDriver,
+}
+
+// keyed by symbol name, see pe.ImportedSymbol
+var wineSymbols = map[string]wineSignature{
+	"NtLoadDriver": wineKernelDriver,
+	"ZwLoadDriver": wineKernelDriver,
+}
+
+// protections that install kernel drivers
+var wineProtections = map[string]bool{
+	"safedisc":  true,
+	"securom":   true,
+	"starforce": true,
+}
+
+// Anti-cheat usually refuses to run, unless the developer opted into
+// Proton support, which can't be known from the binary
+var wineAntiCheat = wineSignature{"anticheat", SeverityWarn, "Uses anti-cheat, which only works under Proton if the developer enabled it"}
+
+// findWineNotes looks for imports and protections known to be
+// problematic under Wine or Proton
+func findWineNotes(info *PeInfo, symbols []pe.ImportedSymbol) []*WineNote {
+	var res []*WineNote
+	seen := make(map[string]bool)
+	add := func(sig wineSignature, evidence string, args ...interface{}) {
+		if seen[sig.topic] {
+			return
+		}
+		seen[sig.topic] = true
+		res = append(res, &WineNote{
+			Topic:    sig.topic,
+			Severity: sig.severity,
+			Message:  sig.message,
+			Evidence: fmt.Sprintf(evidence, args...),
+		})
+	}
+
+	for _, p := range info.Protections {
+		switch {
+		case wineProtections[p.Name]:
+			add(wineKernelDriver, "uses %s", p.Name)
+		case p.Kind == ProtectionAntiCheat:
+			add(wineAntiCheat, "uses %s", p.Name)
+		}
+	}
+
+	for _, lib := range info.Imports {
+		if sig, ok := wineImports[strings.ToLower(lib)]; ok {
+			add(sig, "imports %s", lib)
+		}
+	}
+
+	for _, sym := range symbols {
+		if sym.ByOrdinal() {
+			continue
+		}
+		if sig, ok := wineSymbols[sym.Name]; ok {
+			add(sig, "imports %s from %s", sym.Name, sym.DLL)
+		}
+	}
+
+	return res
+}
@@ -0,0 +1,28 @@
+package pelican
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_FindWineNotes(t *testing.T) {
+	info := &PeInfo{
+		Imports: []string{"KERNEL32.dll", "MFPlat.DLL", "MFReadWrite.dll", "d3d12.dll"},
+		Protections: []*Protection{
+			{Name: "steamworks", Kind: ProtectionDRM},
+			{Name: "easyanticheat", Kind: ProtectionAntiCheat},
+			{Name: "securom", Kind: ProtectionDRM},
+		},
+	}
+	symbols := []string{"CreateFileW:kernel32.dll", "NtLoadDriver:ntdll.dll"}
+
+	assert.EqualValues(t, []*WineNote{
+		{Topic: "anticheat", Severity: SeverityWarn, Message: wineAntiCheat.message, Evidence: "uses easyanticheat"},
+		{Topic: "kernel-driver", Severity: SeverityError, Message: wineKernelDriver.message, Evidence: "uses securom"},
+		{Topic: "media-foundation", Severity: SeverityWarn, Message: wineMediaFoundation.message, Evidence: "imports MFPlat.DLL"},
+		{Topic: "direct3d12", Severity: SeverityInfo, Message: wineDirect3D12.message, Evidence: "imports d3d12.dll"},
+	}, findWineNotes(info, importedSymbols(symbols...)))
+
+	assert.Nil(t, findWineNotes(&PeInfo{Imports: []string{"KERNEL32.dll"}}, nil))
+}